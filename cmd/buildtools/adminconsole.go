@@ -52,7 +52,7 @@ var updateAdminConsoleAddonCommand = &cli.Command{
 		latest = strings.TrimPrefix(latest, "v")
 
 		current := adminconsole.Metadata
-		if current.Version == latest && !c.Bool("force") {
+		if current.Version == latest && current.ChartDigest != "" && !c.Bool("force") {
 			logrus.Infof("admin console chart version is already up-to-date")
 			return nil
 		}
@@ -82,6 +82,12 @@ var updateAdminConsoleAddonCommand = &cli.Command{
 		}
 		newmeta.Images = metaImages
 
+		logrus.Infof("computing chart digest")
+		newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+		if err != nil {
+			return fmt.Errorf("failed to get chart digest: %w", err)
+		}
+
 		logrus.Infof("saving addon manifest")
 		if err := newmeta.Save("adminconsole"); err != nil {
 			return fmt.Errorf("failed to save admin console metadata: %w", err)
@@ -140,6 +140,12 @@ func updateOperatorAddonImages(ctx context.Context, hcli helm.Client, chartURL s
 	}
 	newmeta.Images = metaImages
 
+	logrus.Infof("computing chart digest")
+	newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get chart digest: %w", err)
+	}
+
 	logrus.Infof("saving addon manifest")
 	if err := newmeta.Save("embeddedclusteroperator"); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+var ingressNginxRepo = &repo.Entry{
+	Name: "ingress-nginx",
+	URL:  "https://kubernetes.github.io/ingress-nginx",
+}
+
+var ingressNginxImageComponents = map[string]addonComponent{
+	"registry.k8s.io/ingress-nginx/controller": {
+		name:             "controller",
+		useUpstreamImage: true,
+	},
+}
+
+var updateIngressNginxAddonCommand = &cli.Command{
+	Name:      "ingressnginx",
+	Usage:     "Updates the ingress-nginx addon",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating ingress-nginx addon")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		nextChartVersion := os.Getenv("INPUT_INGRESS_NGINX_CHART_VERSION")
+		if nextChartVersion != "" {
+			logrus.Infof("using input override from INPUT_INGRESS_NGINX_CHART_VERSION: %s", nextChartVersion)
+		} else {
+			logrus.Infof("fetching the latest ingress-nginx chart version")
+			latest, err := LatestChartVersion(hcli, ingressNginxRepo, "ingress-nginx")
+			if err != nil {
+				return fmt.Errorf("failed to get the latest ingress-nginx chart version: %v", err)
+			}
+			nextChartVersion = latest
+			logrus.Printf("latest ingress-nginx chart version: %s", latest)
+		}
+		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
+
+		current := ingressnginx.Metadata
+		if current.Version == nextChartVersion && current.ChartDigest != "" && !c.Bool("force") {
+			logrus.Infof("ingress-nginx chart version is already up-to-date")
+			return nil
+		}
+
+		logrus.Infof("mirroring ingress-nginx chart version %s", nextChartVersion)
+		if err := MirrorChart(hcli, ingressNginxRepo, "ingress-nginx", nextChartVersion); err != nil {
+			return fmt.Errorf("failed to mirror ingress-nginx chart: %v", err)
+		}
+
+		upstream := fmt.Sprintf("%s/ingress-nginx", os.Getenv("CHARTS_DESTINATION"))
+		withproto := fmt.Sprintf("oci://proxy.replicated.com/anonymous/%s", upstream)
+
+		logrus.Infof("updating ingress-nginx images")
+
+		err = updateIngressNginxAddonImages(c.Context, hcli, withproto, nextChartVersion)
+		if err != nil {
+			return fmt.Errorf("failed to update ingress-nginx images: %w", err)
+		}
+
+		logrus.Infof("successfully updated ingress-nginx addon")
+
+		return nil
+	},
+}
+
+var updateIngressNginxImagesCommand = &cli.Command{
+	Name:      "ingressnginx",
+	Usage:     "Updates the ingress-nginx images",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating ingress-nginx images")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		current := ingressnginx.Metadata
+
+		err = updateIngressNginxAddonImages(c.Context, hcli, current.Location, current.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update ingress-nginx images: %w", err)
+		}
+
+		logrus.Infof("successfully updated ingress-nginx images")
+
+		return nil
+	},
+}
+
+func updateIngressNginxAddonImages(ctx context.Context, hcli helm.Client, chartURL string, chartVersion string) error {
+	newmeta := release.AddonMetadata{
+		Version:  chartVersion,
+		Location: chartURL,
+		Images:   make(map[string]release.AddonImage),
+	}
+
+	values, err := release.GetValuesWithOriginalImages("ingressnginx")
+	if err != nil {
+		return fmt.Errorf("failed to get ingress-nginx values: %v", err)
+	}
+
+	logrus.Infof("extracting images from chart version %s", chartVersion)
+	images, err := helm.ExtractImagesFromChart(hcli, chartURL, chartVersion, values)
+	if err != nil {
+		return fmt.Errorf("failed to get images from ingress-nginx chart: %w", err)
+	}
+
+	metaImages, err := UpdateImages(ctx, ingressNginxImageComponents, ingressnginx.Metadata.Images, images)
+	if err != nil {
+		return fmt.Errorf("failed to update images: %w", err)
+	}
+	newmeta.Images = metaImages
+
+	logrus.Infof("computing chart digest")
+	newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get chart digest: %w", err)
+	}
+
+	logrus.Infof("saving addon manifest")
+	if err := newmeta.Save("ingressnginx"); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	return nil
+}
@@ -69,7 +69,7 @@ var updateOpenEBSAddonCommand = &cli.Command{
 		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
 
 		current := openebs.Metadata
-		if current.Version == nextChartVersion && !c.Bool("force") {
+		if current.Version == nextChartVersion && current.ChartDigest != "" && !c.Bool("force") {
 			logrus.Infof("openebs chart version is already up-to-date")
 			return nil
 		}
@@ -148,6 +148,12 @@ func updateOpenEBSAddonImages(ctx context.Context, hcli helm.Client, chartURL st
 	}
 	newmeta.Images = metaImages
 
+	logrus.Infof("computing chart digest")
+	newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get chart digest: %w", err)
+	}
+
 	logrus.Infof("saving addon manifest")
 	if err := newmeta.Save("openebs"); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
@@ -48,7 +48,7 @@ var updateRegistryAddonCommand = &cli.Command{
 		logrus.Printf("latest registry chart version: %s", latest)
 
 		current := registry.Metadata
-		if current.Version == latest && !c.Bool("force") {
+		if current.Version == latest && current.ChartDigest != "" && !c.Bool("force") {
 			logrus.Infof("registry version is already up-to-date")
 			return nil
 		}
@@ -83,6 +83,12 @@ var updateRegistryAddonCommand = &cli.Command{
 		}
 		newmeta.Images = metaImages
 
+		logrus.Infof("computing chart digest")
+		newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+		if err != nil {
+			return fmt.Errorf("failed to get chart digest: %w", err)
+		}
+
 		logrus.Infof("saving addon manifest")
 		if err := newmeta.Save("registry"); err != nil {
 			return fmt.Errorf("failed to save metadata: %w", err)
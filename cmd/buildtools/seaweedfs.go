@@ -57,7 +57,7 @@ var updateSeaweedFSAddonCommand = &cli.Command{
 		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
 
 		current := seaweedfs.Metadata
-		if current.Version == nextChartVersion && !c.Bool("force") {
+		if current.Version == nextChartVersion && current.ChartDigest != "" && !c.Bool("force") {
 			logrus.Infof("seaweedfs chart version is already up-to-date")
 			return nil
 		}
@@ -133,6 +133,12 @@ func updateSeaweedFSAddonImages(ctx context.Context, hcli helm.Client, chartURL
 	}
 	newmeta.Images = metaImages
 
+	logrus.Infof("computing chart digest")
+	newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get chart digest: %w", err)
+	}
+
 	logrus.Infof("saving addon manifest")
 	if err := newmeta.Save("seaweedfs"); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
@@ -24,6 +24,7 @@ var updateAddonCommand = &cli.Command{
 	},
 	Subcommands: []*cli.Command{
 		updateAdminConsoleAddonCommand,
+		updateIngressNginxAddonCommand,
 		updateOpenEBSAddonCommand,
 		updateOperatorAddonCommand,
 		updateRegistryAddonCommand,
@@ -37,6 +38,7 @@ var updateImagesCommand = &cli.Command{
 	Usage: "Update embedded cluster images",
 	Subcommands: []*cli.Command{
 		updateK0sImagesCommand,
+		updateIngressNginxImagesCommand,
 		updateOpenEBSImagesCommand,
 		updateOperatorImagesCommand,
 		updateSeaweedFSImagesCommand,
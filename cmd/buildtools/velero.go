@@ -86,7 +86,7 @@ var updateVeleroAddonCommand = &cli.Command{
 		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
 
 		current := velero.Metadata
-		if current.Version == nextChartVersion && !c.Bool("force") {
+		if current.Version == nextChartVersion && current.ChartDigest != "" && !c.Bool("force") {
 			logrus.Infof("velero chart version is already up-to-date")
 		} else {
 			logrus.Infof("mirroring velero chart version %s", nextChartVersion)
@@ -231,6 +231,12 @@ func updateVeleroAddonImages(ctx context.Context, hcli helm.Client, chartURL str
 	}
 	newmeta.Images = metaImages
 
+	logrus.Infof("computing chart digest")
+	newmeta.ChartDigest, err = helm.GetChartDigest(hcli, newmeta.Location, newmeta.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get chart digest: %w", err)
+	}
+
 	logrus.Infof("saving addon manifest")
 	if err := newmeta.Save("velero"); err != nil {
 		return fmt.Errorf("failed to save metadata: %w", err)
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/spf13/cobra"
+)
+
+func AirgapCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "airgap",
+		Short: "Manage air gap bundles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			os.Exit(1)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(AirgapVerifyCmd(ctx, name))
+
+	return cmd
+}
+
+func AirgapVerifyCmd(ctx context.Context, name string) *cobra.Command {
+	var publicKeyFile string
+
+	cmd := &cobra.Command{
+		Use:   "verify <bundle>",
+		Short: "Verify the integrity and the signature of an air gap bundle",
+		Long: `Verify the integrity and the signature of an air gap bundle without installing it.
+
+Every file in the bundle is checked against the digests listed in the bundle manifest and
+the manifest signature is checked with the signing key of the release embedded in this
+binary. This command does not need network access.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			publicKey, err := airgapSigningKey()
+			if err != nil {
+				return err
+			}
+			if publicKeyFile != "" {
+				data, err := os.ReadFile(publicKeyFile)
+				if err != nil {
+					return fmt.Errorf("unable to read public key: %w", err)
+				}
+				publicKey = string(data)
+			}
+
+			rawfile, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to open airgap file: %w", err)
+			}
+			defer rawfile.Close()
+
+			manifest, err := airgap.Verify(rawfile, publicKey)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case manifest == nil:
+				fmt.Println("Air gap bundle has no manifest, its integrity could not be verified.")
+			case publicKey == "":
+				fmt.Printf("Verified %d files. No signing key was found, the bundle signature was not verified.\n", len(manifest.Files))
+			default:
+				fmt.Printf("Verified %d files and the bundle signature.\n", len(manifest.Files))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&publicKeyFile, "public-key", "", "Path to a PEM encoded public key to verify the bundle signature with instead of the key of the embedded release")
+
+	return cmd
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/kotscli"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/spf13/cobra"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+)
+
+func BackupCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage the instance backups of the cluster",
+		Long: fmt.Sprintf(`Manage the instance backups of the cluster.
+
+Instance backups hold both the %s infrastructure and the application, and are used to restore
+the cluster with the restore command. Backups must be configured in the Admin Console first.`, name),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			os.Exit(1)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(BackupCreateCmd(ctx, name))
+	cmd.AddCommand(BackupListCmd(ctx, name))
+	cmd.AddCommand(BackupDescribeCmd(ctx, name))
+	cmd.AddCommand(BackupDeleteCmd(ctx, name))
+	cmd.AddCommand(BackupPruneCmd(ctx, name))
+
+	return cmd
+}
+
+// preRunBackup is shared by the backup subcommands. These must be run as root on a controller
+// node.
+func preRunBackup(cmd *cobra.Command, args []string) error {
+	if os.Getuid() != 0 {
+		return fmt.Errorf("backup %s command must be run as root", cmd.Name())
+	}
+
+	rcutil.InitBestRuntimeConfig(cmd.Context())
+
+	os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+	os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
+
+	return nil
+}
+
+func BackupCreateCmd(ctx context.Context, name string) *cobra.Command {
+	var wait bool
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an instance backup",
+		Long: `Create an instance backup of the cluster and of the application.
+
+By default the command waits for the backup to finish and fails if it does not complete
+successfully, so it can be scheduled with cron.`,
+		Args:    cobra.NoArgs,
+		PreRunE: preRunBackup,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := createBackup(cmd.Context(), name, wait); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&wait, "wait", true, "Wait for the backup to finish")
+
+	return cmd
+}
+
+// createBackup creates an instance backup. If wait is true, it waits for the backup to finish
+// and returns it, failing if it did not complete successfully.
+func createBackup(ctx context.Context, name string, wait bool) (*disasterrecovery.ReplicatedBackup, error) {
+	// velero timestamps have a one second precision.
+	requestedAt := time.Now().Truncate(time.Second)
+	if err := kotscli.Backup(kotscli.BackupOptions{
+		Namespace: runtimeconfig.KotsadmNamespace,
+		Wait:      wait,
+	}); err != nil {
+		return nil, err
+	}
+	if !wait {
+		return nil, nil
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	backups, err := disasterrecovery.ListReplicatedBackups(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list backups: %w", err)
+	}
+	backup, err := findCreatedBackup(backups, requestedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w, run '%s backup list' for details", err, name)
+	}
+
+	if phase := backup.GetPhase(); phase != velerov1.BackupPhaseCompleted {
+		return nil, fmt.Errorf("backup %s finished with status %s, run '%s backup describe %s' for details", backup.GetName(), phase, name, backup.GetName())
+	}
+	fmt.Printf("Backup %s completed successfully.\n", backup.GetName())
+	return backup, nil
+}
+
+// findCreatedBackup returns the backup created since requestedAt. An error is returned if there
+// is none or if there are several, e.g. when a scheduled backup ran at the same time, as the
+// one just created cannot be told apart.
+func findCreatedBackup(backups []disasterrecovery.ReplicatedBackup, requestedAt time.Time) (*disasterrecovery.ReplicatedBackup, error) {
+	created := []disasterrecovery.ReplicatedBackup{}
+	for _, backup := range backups {
+		if len(backup) == 0 {
+			continue
+		}
+		createdAt := backup[0].CreationTimestamp.Time
+		for _, b := range backup[1:] {
+			if b.CreationTimestamp.Time.Before(createdAt) {
+				createdAt = b.CreationTimestamp.Time
+			}
+		}
+		if !createdAt.Before(requestedAt) {
+			created = append(created, backup)
+		}
+	}
+
+	switch len(created) {
+	case 0:
+		return nil, fmt.Errorf("no backup created since %s found", requestedAt.Format(time.RFC3339))
+	case 1:
+		return &created[0], nil
+	default:
+		names := []string{}
+		for _, backup := range created {
+			names = append(names, backup.GetName())
+		}
+		return nil, fmt.Errorf("found %d backups created since %s (%s), unable to tell which one was just created", len(created), requestedAt.Format(time.RFC3339), strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/spf13/cobra"
+)
+
+func BackupDeleteCmd(ctx context.Context, name string) *cobra.Command {
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete BACKUP",
+		Short: "Delete an instance backup",
+		Long: `Delete an instance backup, both from the cluster and from the backup storage location.
+
+The deletion is carried out by velero in the background.`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunBackup,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			backup, err := getReplicatedBackup(ctx, kcli, args[0])
+			if err != nil {
+				return err
+			}
+			if backup.IsInProgress() {
+				return fmt.Errorf("backup %s is still in progress", backup.GetName())
+			}
+
+			fmt.Printf("Backup %s will be permanently deleted.\n", backup.GetName())
+			if !assumeYes && !prompts.New().Confirm("Do you want to continue?", false) {
+				return fmt.Errorf("aborted")
+			}
+
+			if err := disasterrecovery.DeleteReplicatedBackup(ctx, kcli, backup); err != nil {
+				return fmt.Errorf("unable to delete backup %s: %w", backup.GetName(), err)
+			}
+			fmt.Printf("Deletion of backup %s requested.\n", backup.GetName())
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+
+	return cmd
+}
+
+func BackupPruneCmd(ctx context.Context, name string) *cobra.Command {
+	var keep int
+	var dryRun bool
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the old instance backups",
+		Long: `Delete the old instance backups, keeping only the most recent completed ones.
+
+Failed and incomplete backups are deleted as well, while the backups still in progress are left
+untouched. The deletion is carried out by velero in the background.`,
+		Args:    cobra.NoArgs,
+		PreRunE: preRunBackup,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if keep < 1 {
+				return fmt.Errorf("at least one backup must be kept")
+			}
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			backups, err := disasterrecovery.ListReplicatedBackups(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to list backups: %w", err)
+			}
+
+			prune := disasterrecovery.BackupsToPrune(backups, keep)
+			if len(prune) == 0 {
+				fmt.Println("No backups to delete.")
+				return nil
+			}
+
+			writer := table.NewWriter()
+			writer.AppendHeader(table.Row{"name", "status", "started"})
+			for _, backup := range prune {
+				s := summarizeBackup(backup, backupRestoreTarget{})
+				writer.AppendRow(table.Row{s.Name, s.Status, s.Started})
+			}
+			fmt.Printf("The following %d backups will be permanently deleted:\n%s\n", len(prune), writer.Render())
+			if dryRun {
+				return nil
+			}
+			if !assumeYes && !prompts.New().Confirm("Do you want to continue?", false) {
+				return fmt.Errorf("aborted")
+			}
+
+			for _, backup := range prune {
+				if err := disasterrecovery.DeleteReplicatedBackup(ctx, kcli, backup); err != nil {
+					return fmt.Errorf("unable to delete backup %s: %w", backup.GetName(), err)
+				}
+			}
+			fmt.Printf("Deletion of %d backups requested.\n", len(prune))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&keep, "keep", 0, "Number of the most recent completed backups to keep")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the backups that would be deleted")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	if err := cmd.MarkFlagRequired("keep"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
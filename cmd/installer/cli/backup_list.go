@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// backupRestoreTarget is what the backups are checked against to tell if they can be restored
+// with this binary.
+type backupRestoreTarget struct {
+	rel      *release.ChannelRelease
+	isAirgap bool
+	k0sCfg   *k0sv1beta1.ClusterConfig
+}
+
+// backupSummary holds what is shown for each instance backup by the backup list and describe
+// commands.
+type backupSummary struct {
+	Name       string
+	Status     string
+	Started    string
+	Completed  string
+	ECVersion  string
+	AppVersion string
+	Restorable string
+	// Reason is why the backup can not be restored, if it can not.
+	Reason string
+}
+
+func BackupListCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the instance backups",
+		Long: fmt.Sprintf(`List the instance backups, from the oldest to the newest.
+
+The restorable column tells if the backup can be restored with this %s binary, run the backup
+describe command for the reason a backup can not be restored.`, name),
+		Args:    cobra.NoArgs,
+		PreRunE: preRunBackup,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			target, err := getBackupRestoreTarget(ctx, kcli)
+			if err != nil {
+				return err
+			}
+
+			backups, err := disasterrecovery.ListReplicatedBackups(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to list backups: %w", err)
+			}
+			if len(backups) == 0 {
+				fmt.Println("No backups found.")
+				return nil
+			}
+
+			writer := table.NewWriter()
+			writer.AppendHeader(table.Row{"name", "status", "started", "completed", "version", "app version", "restorable"})
+			for _, backup := range backups {
+				s := summarizeBackup(backup, target)
+				writer.AppendRow(table.Row{s.Name, s.Status, s.Started, s.Completed, s.ECVersion, s.AppVersion, s.Restorable})
+			}
+			fmt.Printf("%s\n", writer.Render())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func BackupDescribeCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "describe BACKUP",
+		Short:   "Describe an instance backup",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunBackup,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			target, err := getBackupRestoreTarget(ctx, kcli)
+			if err != nil {
+				return err
+			}
+
+			backup, err := getReplicatedBackup(ctx, kcli, args[0])
+			if err != nil {
+				return err
+			}
+
+			s := summarizeBackup(backup, target)
+			fmt.Printf("Name:         %s\n", s.Name)
+			fmt.Printf("Status:       %s\n", s.Status)
+			fmt.Printf("Started:      %s\n", s.Started)
+			fmt.Printf("Completed:    %s\n", s.Completed)
+			fmt.Printf("Version:      %s\n", s.ECVersion)
+			fmt.Printf("App version:  %s\n", s.AppVersion)
+			fmt.Printf("Restorable:   %s\n", s.Restorable)
+			if s.Reason != "" {
+				fmt.Printf("Reason:       backup %s\n", s.Reason)
+			}
+
+			writer := table.NewWriter()
+			writer.AppendHeader(table.Row{"velero backup", "type", "status", "errors", "warnings", "expires"})
+			for _, b := range backup {
+				expires := ""
+				if b.Status.Expiration != nil {
+					expires = formatBackupTime(b.Status.Expiration.Time)
+				}
+				writer.AppendRow(table.Row{
+					b.Name, disasterrecovery.GetInstanceBackupType(b), b.Status.Phase,
+					b.Status.Errors, b.Status.Warnings, expires,
+				})
+			}
+			fmt.Printf("\n%s\n", writer.Render())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// getReplicatedBackup returns the instance backup with the provided name.
+func getReplicatedBackup(ctx context.Context, kcli client.Client, name string) (disasterrecovery.ReplicatedBackup, error) {
+	backup, err := disasterrecovery.GetReplicatedBackup(ctx, kcli, runtimeconfig.VeleroNamespace, name)
+	if errors.Is(err, disasterrecovery.ErrBackupNotFound) {
+		return nil, fmt.Errorf("backup %q not found", name)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get backup: %w", err)
+	}
+	return backup, nil
+}
+
+// getBackupRestoreTarget returns what the backups are checked against. The release is read
+// from the binary while the network configuration and the air gap mode are the ones of the
+// current cluster.
+func getBackupRestoreTarget(ctx context.Context, kcli client.Client) (backupRestoreTarget, error) {
+	var target backupRestoreTarget
+
+	rel, err := release.GetChannelRelease()
+	if err != nil {
+		return target, fmt.Errorf("unable to get release from binary: %w", err)
+	}
+	target.rel = rel
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return target, fmt.Errorf("unable to get installation: %w", err)
+	}
+	target.isAirgap = in.Spec.AirGap
+
+	k0sCfg, err := getK0sConfigFromDisk()
+	if err != nil {
+		return target, fmt.Errorf("unable to get k0s config from disk: %w", err)
+	}
+	target.k0sCfg = k0sCfg
+
+	return target, nil
+}
+
+// summarizeBackup returns the summary of the instance backup, including whether it can be
+// restored with this binary.
+func summarizeBackup(backup disasterrecovery.ReplicatedBackup, target backupRestoreTarget) backupSummary {
+	summary := backupSummary{
+		Name:       backup.GetName(),
+		Status:     string(backup.GetPhase()),
+		AppVersion: backupAppVersion(backup),
+		Restorable: "Yes",
+	}
+
+	if infra := backup.GetInfraBackup(); infra != nil && infra.Status.StartTimestamp != nil {
+		summary.Started = formatBackupTime(infra.Status.StartTimestamp.Time)
+	}
+	if completed := backup.GetCompletionTimestamp(); !completed.IsZero() {
+		summary.Completed = formatBackupTime(completed.Time)
+	}
+	if v, ok := backup.GetAnnotation("kots.io/embedded-cluster-version"); ok {
+		summary.ECVersion = v
+	}
+
+	if target.rel == nil {
+		summary.Restorable = "Unknown"
+		summary.Reason = "can not be checked, no release found in binary"
+	} else if ok, reason := isReplicatedBackupRestorable(backup, target.rel, target.isAirgap, target.k0sCfg); !ok {
+		summary.Restorable = "No"
+		summary.Reason = reason
+	}
+	return summary
+}
+
+// backupAppVersion returns the version of the applications in the instance backup.
+func backupAppVersion(backup disasterrecovery.ReplicatedBackup) string {
+	val, ok := backup.GetAnnotation("kots.io/apps-versions")
+	if !ok {
+		return ""
+	}
+	appsVersions := map[string]string{}
+	if err := json.Unmarshal([]byte(val), &appsVersions); err != nil {
+		return ""
+	}
+	versions := []string{}
+	for _, version := range appsVersions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ", ")
+}
+
+func formatBackupTime(t time.Time) string {
+	return t.Local().Format(time.DateTime)
+}
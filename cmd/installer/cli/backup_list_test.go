@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	clitesting "github.com/replicatedhq/embedded-cluster/cmd/installer/cli/testing"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/stretchr/testify/assert"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_summarizeBackup(t *testing.T) {
+	release.SetReleaseDataForTests(embedFSToMap(t, clitesting.RestoreReleaseDataNewDR))
+
+	started := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2022, 1, 3, 0, 5, 0, 0, time.UTC)
+	newBackup := func(name, backupType string) velerov1.Backup {
+		return velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "velero",
+				Labels: map[string]string{
+					disasterrecovery.InstanceBackupNameLabel: "app-slug-abcd",
+				},
+				Annotations: map[string]string{
+					disasterrecovery.BackupIsECAnnotation:            "true",
+					disasterrecovery.InstanceBackupVersionAnnotation: disasterrecovery.InstanceBackupVersionCurrent,
+					disasterrecovery.InstanceBackupTypeAnnotation:    backupType,
+					disasterrecovery.InstanceBackupCountAnnotation:   "2",
+					"kots.io/embedded-cluster-version":               "v0.0.0",
+					"kots.io/apps-versions":                          `{"app-slug":"1.0.0"}`,
+					"kots.io/is-airgap":                              "false",
+				},
+			},
+			Status: velerov1.BackupStatus{
+				Phase:               velerov1.BackupPhaseCompleted,
+				StartTimestamp:      &metav1.Time{Time: started},
+				CompletionTimestamp: &metav1.Time{Time: completed},
+			},
+		}
+	}
+	backup := disasterrecovery.ReplicatedBackup{
+		newBackup("instance-abcd", disasterrecovery.InstanceBackupTypeInfra),
+		newBackup("application-abcd", disasterrecovery.InstanceBackupTypeApp),
+	}
+	target := backupRestoreTarget{
+		rel:    &release.ChannelRelease{VersionLabel: "1.0.0", AppSlug: "app-slug"},
+		k0sCfg: &k0sv1beta1.ClusterConfig{},
+	}
+
+	got := summarizeBackup(backup, target)
+	assert.Equal(t, backupSummary{
+		Name:       "app-slug-abcd",
+		Status:     "Completed",
+		Started:    formatBackupTime(started),
+		Completed:  formatBackupTime(completed),
+		ECVersion:  "v0.0.0",
+		AppVersion: "1.0.0",
+		Restorable: "Yes",
+	}, got)
+
+	// the backup of an older app version is upgraded once restored.
+	target.rel.VersionLabel = "2.0.0"
+	got = summarizeBackup(backup, target)
+	assert.Equal(t, "Yes", got.Restorable)
+
+	// the backup of a newer app version can not be restored with this binary.
+	target.rel.VersionLabel = "0.9.0"
+	got = summarizeBackup(backup, target)
+	assert.Equal(t, "No", got.Restorable)
+	assert.Equal(t, `has a newer app version ("1.0.0") than the current version ("0.9.0")`, got.Reason)
+	target.rel.VersionLabel = "1.0.0"
+
+	got = summarizeBackup(backup, backupRestoreTarget{})
+	assert.Equal(t, "Unknown", got.Restorable)
+
+	// a backup missing the app backup is incomplete and can not be restored.
+	got = summarizeBackup(backup[:1], target)
+	assert.Equal(t, "Incomplete", got.Status)
+	assert.Equal(t, "", got.Completed)
+	assert.Equal(t, "No", got.Restorable)
+}
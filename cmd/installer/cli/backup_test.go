@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_findCreatedBackup(t *testing.T) {
+	requestedAt := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	newBackup := func(name string, createdAt time.Time) disasterrecovery.ReplicatedBackup {
+		return disasterrecovery.ReplicatedBackup{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              name,
+					Namespace:         "velero",
+					CreationTimestamp: metav1.NewTime(createdAt),
+				},
+			},
+		}
+	}
+	older := newBackup("older", requestedAt.Add(-time.Hour))
+	created := newBackup("created", requestedAt)
+	scheduled := newBackup("scheduled", requestedAt.Add(time.Second))
+
+	got, err := findCreatedBackup([]disasterrecovery.ReplicatedBackup{older, created}, requestedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "created", got.GetName())
+
+	_, err = findCreatedBackup([]disasterrecovery.ReplicatedBackup{older}, requestedAt)
+	assert.EqualError(t, err, "no backup created since 2022-01-03T00:00:00Z found")
+
+	_, err = findCreatedBackup([]disasterrecovery.ReplicatedBackup{older, created, scheduled}, requestedAt)
+	assert.EqualError(t, err, "found 2 backups created since 2022-01-03T00:00:00Z (created, scheduled), unable to tell which one was just created")
+}
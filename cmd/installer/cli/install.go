@@ -56,6 +56,9 @@ import (
 type InstallCmdFlags struct {
 	adminConsolePassword    string
 	adminConsolePort        int
+	adminConsoleHostname    string
+	adminConsoleTLSCert     string
+	adminConsoleTLSKey      string
 	airgapBundle            string
 	isAirgap                bool
 	dataDir                 string
@@ -67,6 +70,7 @@ type InstallCmdFlags struct {
 	skipHostPreflights      bool
 	ignoreHostPreflights    bool
 	configValues            string
+	installConfig           string
 
 	networkInterface string
 
@@ -89,6 +93,11 @@ func InstallCmd(ctx context.Context, name string) *cobra.Command {
 			if err := preRunInstall(cmd, &flags); err != nil {
 				return err
 			}
+			// the license may come from the install config file so it can't be marked as
+			// required in the flag set.
+			if flags.licenseFile == "" {
+				return fmt.Errorf(`required flag(s) "license" not set`)
+			}
 
 			return nil
 		},
@@ -151,16 +160,18 @@ func addInstallFlags(cmd *cobra.Command, flags *InstallCmdFlags) error {
 	}
 	cmd.Flags().BoolVar(&flags.ignoreHostPreflights, "ignore-host-preflights", false, "Allow bypassing host preflight failures")
 
+	addInstallConfigFlag(cmd, &flags.installConfig)
+
 	return nil
 }
 
 func addInstallAdminConsoleFlags(cmd *cobra.Command, flags *InstallCmdFlags) error {
 	cmd.Flags().StringVar(&flags.adminConsolePassword, "admin-console-password", "", "Password for the Admin Console")
 	cmd.Flags().IntVar(&flags.adminConsolePort, "admin-console-port", ecv1beta1.DefaultAdminConsolePort, "Port on which the Admin Console will be served")
+	cmd.Flags().StringVar(&flags.adminConsoleHostname, "admin-console-hostname", "", "Hostname the Admin Console is exposed at, over TLS, through the built-in ingress controller")
+	cmd.Flags().StringVar(&flags.adminConsoleTLSCert, "admin-console-tls-cert", "", "Path to the PEM encoded certificate served for the Admin Console hostname. A self-signed certificate is generated if not provided")
+	cmd.Flags().StringVar(&flags.adminConsoleTLSKey, "admin-console-tls-key", "", "Path to the PEM encoded private key of the Admin Console certificate")
 	cmd.Flags().StringVarP(&flags.licenseFile, "license", "l", "", "Path to the license file")
-	if err := cmd.MarkFlagRequired("license"); err != nil {
-		panic(err)
-	}
 	cmd.Flags().StringVar(&flags.configValues, "config-values", "", "Path to the config values to use when installing")
 
 	return nil
@@ -171,6 +182,12 @@ func preRunInstall(cmd *cobra.Command, flags *InstallCmdFlags) error {
 		return fmt.Errorf("install command must be run as root")
 	}
 
+	if flags.installConfig != "" {
+		if err := applyInstallConfigFile(cmd, flags.installConfig); err != nil {
+			return err
+		}
+	}
+
 	p, err := parseProxyFlags(cmd)
 	if err != nil {
 		return err
@@ -217,6 +234,10 @@ func preRunInstall(cmd *cobra.Command, flags *InstallCmdFlags) error {
 		}
 	}
 
+	if _, _, err := readAdminConsoleTLS(*flags); err != nil {
+		return err
+	}
+
 	flags.isAirgap = flags.airgapBundle != ""
 
 	runtimeconfig.ApplyFlags(cmd.Flags())
@@ -231,14 +252,118 @@ func preRunInstall(cmd *cobra.Command, flags *InstallCmdFlags) error {
 }
 
 func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metricsReporter preflights.MetricsReporter) error {
-	if err := runInstallVerifyAndPrompt(ctx, name, &flags); err != nil {
+	logrus.Debugf("getting install state")
+	state := getInstallState()
+	logrus.Debugf("install state is: %q", state.Phase)
+
+	if state.Phase != installPhaseNew {
+		if state.LastError != "" {
+			logrus.Infof("A previous installation attempt failed: %s", state.LastError)
+		}
+		shouldResume := flags.assumeYes
+		if !shouldResume {
+			shouldResume = prompts.New().Confirm("A previous installation attempt was detected. Would you like to resume?", true)
+			logrus.Info("")
+		}
+		if !shouldResume {
+			state = newInstallState()
+		}
+	}
+
+	// once the cluster has been installed we can't verify there is no previous installation
+	// on the host, it is the one we are resuming.
+	resumingCluster := state.isPast(installPhaseHostPreflights)
+	if err := runInstallVerifyAndPrompt(ctx, name, &flags, resumingCluster); err != nil {
 		return err
 	}
 
-	if err := ensureAdminConsolePassword(&flags); err != nil {
+	if !state.isPast(installPhaseInstallAddOns) {
+		if err := ensureAdminConsolePassword(&flags); err != nil {
+			return err
+		}
+	}
+
+	if err := runInstallPhases(ctx, flags, state, metricsReporter); err != nil {
+		state.setError(err)
+		return err
+	}
+
+	if err := removeInstallState(); err != nil {
+		logrus.Warnf("Unable to remove install state: %v", err)
+	}
+
+	if err := support.CreateHostSupportBundle(); err != nil {
+		logrus.Warnf("Unable to create host support bundle: %v", err)
+	}
+
+	ingress, err := ingressForInstall(flags.overrides, flags.adminConsoleHostname)
+	if err != nil {
+		return fmt.Errorf("unable to get ingress: %w", err)
+	}
+	if err := printSuccessMessage(flags.license, flags.networkInterface, ingressHostname(ingress)); err != nil {
 		return err
 	}
 
+	return nil
+}
+
+// runInstallPhases runs the installation phases starting from the one recorded in the
+// install state. Phases that have already completed are skipped.
+func runInstallPhases(ctx context.Context, flags InstallCmdFlags, state *installState, metricsReporter preflights.MetricsReporter) error {
+	var k0sCfg *k0sv1beta1.ClusterConfig
+
+	switch state.Phase {
+	case installPhaseNew, installPhaseHostConfig:
+		if err := state.setPhase(installPhaseHostConfig); err != nil {
+			return fmt.Errorf("unable to set install phase: %w", err)
+		}
+
+		if err := runInstallHostConfig(ctx, flags); err != nil {
+			return err
+		}
+
+		fallthrough
+
+	case installPhaseHostPreflights:
+		if err := state.setPhase(installPhaseHostPreflights); err != nil {
+			return fmt.Errorf("unable to set install phase: %w", err)
+		}
+
+		logrus.Debugf("running install preflights")
+		if err := runInstallPreflights(ctx, flags, metricsReporter); err != nil {
+			if errors.Is(err, preflights.ErrPreflightsHaveFail) {
+				return NewErrorNothingElseToAdd(err)
+			}
+			return fmt.Errorf("unable to run install preflights: %w", err)
+		}
+
+		fallthrough
+
+	case installPhaseInstallCluster:
+		resuming := state.Phase == installPhaseInstallCluster
+		if err := state.setPhase(installPhaseInstallCluster); err != nil {
+			return fmt.Errorf("unable to set install phase: %w", err)
+		}
+
+		cfg, err := installOrResumeCluster(ctx, flags, state, resuming)
+		if err != nil {
+			return fmt.Errorf("unable to install cluster: %w", err)
+		}
+		k0sCfg = cfg
+
+		fallthrough
+
+	case installPhaseRecordInstallation, installPhaseInstallAddOns, installPhaseInstallExtensions:
+		if err := runInstallClusterPhases(ctx, flags, state, k0sCfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runInstallHostConfig materializes the embedded files and configures the host.
+func runInstallHostConfig(ctx context.Context, flags InstallCmdFlags) error {
 	logrus.Debugf("materializing binaries")
 	if err := materializeFiles(flags.airgapBundle); err != nil {
 		return fmt.Errorf("unable to materialize files: %w", err)
@@ -265,19 +390,54 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 		return fmt.Errorf("unable to configure network manager: %w", err)
 	}
 
-	logrus.Debugf("running install preflights")
-	if err := runInstallPreflights(ctx, flags, metricsReporter); err != nil {
-		if errors.Is(err, preflights.ErrPreflightsHaveFail) {
-			return NewErrorNothingElseToAdd(err)
+	return nil
+}
+
+// installOrResumeCluster installs and starts the cluster. When resuming a failed attempt
+// where k0s has already been started we only wait for the node to be ready again. If the
+// attempt failed before k0s was started, what it left behind is removed first.
+func installOrResumeCluster(ctx context.Context, flags InstallCmdFlags, state *installState, resuming bool) (*k0sv1beta1.ClusterConfig, error) {
+	if resuming && state.K0sStarted {
+		logrus.Debugf("k0s already started, waiting for it to be ready")
+		loading := spinner.Start()
+		defer loading.Close()
+		loading.Infof("Waiting for %s node to be ready", runtimeconfig.BinaryName())
+		if err := waitForK0s(); err != nil {
+			return nil, fmt.Errorf("wait for node: %w", err)
 		}
-		return fmt.Errorf("unable to run install preflights: %w", err)
+		loading.Infof("Node installation finished!")
+		return getK0sConfigFromDisk()
 	}
 
-	k0sCfg, err := installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.overrides, nil)
-	if err != nil {
-		return fmt.Errorf("unable to install cluster: %w", err)
+	if resuming {
+		if err := removePartialK0sInstall(); err != nil {
+			return nil, fmt.Errorf("remove previous attempt: %w", err)
+		}
 	}
 
+	return installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.overrides, nil, state.setK0sStarted)
+}
+
+// removePartialK0sInstall removes the k0s configuration and service left by an install attempt
+// that failed before k0s was started, so k0s can be installed again.
+func removePartialK0sInstall() error {
+	if _, err := os.Stat("/etc/systemd/system/k0scontroller.service"); err == nil {
+		logrus.Debugf("resetting the k0s service of the previous attempt")
+		if err := stopAndResetK0s(runtimeconfig.EmbeddedClusterK0sSubDir()); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("stat k0s service: %w", err)
+	}
+	if err := os.Remove(runtimeconfig.PathToK0sConfig()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove k0s config: %w", err)
+	}
+	return nil
+}
+
+// runInstallClusterPhases runs the phases that happen once the cluster is up. The k0s
+// config is read from disk if not provided, this is the case when resuming.
+func runInstallClusterPhases(ctx context.Context, flags InstallCmdFlags, state *installState, k0sCfg *k0sv1beta1.ClusterConfig) error {
 	kcli, err := kubeutils.KubeClient()
 	if err != nil {
 		return fmt.Errorf("unable to create kube client: %w", err)
@@ -291,15 +451,108 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 		return fmt.Errorf("unable to check if disaster recovery is enabled: %w", err)
 	}
 
-	in, err := recordInstallation(ctx, kcli, flags, k0sCfg, disasterRecoveryEnabled)
+	airgapChartsPath := ""
+	if flags.isAirgap {
+		airgapChartsPath = runtimeconfig.EmbeddedClusterChartsSubDir()
+	}
+
+	hcli, err := helm.NewClient(helm.HelmOptions{
+		KubeConfig: runtimeconfig.PathToKubeConfig(),
+		K0sVersion: versions.K0sVersion,
+		AirgapPath: airgapChartsPath,
+	})
 	if err != nil {
-		return fmt.Errorf("unable to record installation: %w", err)
+		return fmt.Errorf("unable to create helm client: %w", err)
+	}
+	defer hcli.Close()
+
+	var in *ecv1beta1.Installation
+
+	switch state.Phase {
+	case installPhaseInstallCluster, installPhaseRecordInstallation:
+		resuming := state.Phase == installPhaseRecordInstallation
+		if err := state.setPhase(installPhaseRecordInstallation); err != nil {
+			return fmt.Errorf("unable to set install phase: %w", err)
+		}
+
+		if k0sCfg == nil {
+			k0sCfg, err = getK0sConfigFromDisk()
+			if err != nil {
+				return fmt.Errorf("unable to get k0s config: %w", err)
+			}
+		}
+
+		if resuming {
+			// a previous attempt may have already created the installation object.
+			in, err = kubeutils.GetLatestInstallation(ctx, kcli)
+			if err != nil && !errors.Is(err, kubeutils.ErrNoInstallations{}) {
+				return fmt.Errorf("unable to get installation: %w", err)
+			}
+		}
+		if in == nil {
+			in, err = recordInstallation(ctx, kcli, flags, k0sCfg, disasterRecoveryEnabled)
+			if err != nil {
+				return fmt.Errorf("unable to record installation: %w", err)
+			}
+		}
+
+		if err := createVersionMetadataConfigmap(ctx, kcli); err != nil {
+			return fmt.Errorf("unable to create version metadata configmap: %w", err)
+		}
+
+		fallthrough
+
+	case installPhaseInstallAddOns:
+		if err := state.setPhase(installPhaseInstallAddOns); err != nil {
+			return fmt.Errorf("unable to set install phase: %w", err)
+		}
+
+		storage, err := storageForInstall(flags.overrides)
+		if err != nil {
+			return fmt.Errorf("unable to get storage: %w", err)
+		}
+		// an existing storage class may be deployed by an extension, e.g. a CSI driver, so
+		// the extensions are installed first for the volumes of the addons to be provisioned.
+		if !storage.IsOpenEBS() {
+			if err := installExtensions(ctx, kcli, hcli, state); err != nil {
+				return err
+			}
+		}
+		if err := addons.EnsureStorageClass(ctx, kcli, storage); err != nil {
+			return fmt.Errorf("unable to check storage class: %w", err)
+		}
+
+		if err := installAddOns(ctx, hcli, flags, state, storage, disasterRecoveryEnabled); err != nil {
+			return err
+		}
+
+		fallthrough
+
+	case installPhaseInstallExtensions:
+		if err := state.setPhase(installPhaseInstallExtensions); err != nil {
+			return fmt.Errorf("unable to set install phase: %w", err)
+		}
+
+		if err := installExtensions(ctx, kcli, hcli, state); err != nil {
+			return err
+		}
+	}
+
+	if in == nil {
+		in, err = kubeutils.GetLatestInstallation(ctx, kcli)
+		if err != nil {
+			return fmt.Errorf("unable to get installation: %w", err)
+		}
 	}
 
-	if err := createVersionMetadataConfigmap(ctx, kcli); err != nil {
-		return fmt.Errorf("unable to create version metadata configmap: %w", err)
+	if err := kubeutils.SetInstallationState(ctx, kcli, in, ecv1beta1.InstallationStateInstalled, "Installed"); err != nil {
+		return fmt.Errorf("unable to update installation: %w", err)
 	}
 
+	return nil
+}
+
+func installAddOns(ctx context.Context, hcli helm.Client, flags InstallCmdFlags, state *installState, storage *ecv1beta1.StorageSpec, disasterRecoveryEnabled bool) error {
 	// TODO (@salah): update installation status to reflect what's happening
 
 	embCfg, err := release.GetEmbeddedClusterConfig()
@@ -320,20 +573,14 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 		euCfgSpec = &euCfg.Spec
 	}
 
-	airgapChartsPath := ""
-	if flags.isAirgap {
-		airgapChartsPath = runtimeconfig.EmbeddedClusterChartsSubDir()
+	ingress, err := ingressForInstall(flags.overrides, flags.adminConsoleHostname)
+	if err != nil {
+		return fmt.Errorf("unable to get ingress: %w", err)
 	}
-
-	hcli, err := helm.NewClient(helm.HelmOptions{
-		KubeConfig: runtimeconfig.PathToKubeConfig(),
-		K0sVersion: versions.K0sVersion,
-		AirgapPath: airgapChartsPath,
-	})
+	tlsCert, tlsKey, err := readAdminConsoleTLS(flags)
 	if err != nil {
-		return fmt.Errorf("unable to create helm client: %w", err)
+		return err
 	}
-	defer hcli.Close()
 
 	logrus.Debugf("installing addons")
 	if err := addons.Install(ctx, hcli, addons.InstallOptions{
@@ -346,6 +593,10 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 		DisasterRecoveryEnabled: disasterRecoveryEnabled,
 		EmbeddedConfigSpec:      embCfgSpec,
 		EndUserConfigSpec:       euCfgSpec,
+		Storage:                 storage,
+		Ingress:                 ingress,
+		AdminConsoleTLSCert:     tlsCert,
+		AdminConsoleTLSKey:      tlsKey,
 		KotsInstaller: func(msg *spinner.MessageWriter) error {
 			opts := kotscli.InstallOptions{
 				AppSlug:          flags.license.Spec.AppSlug,
@@ -356,38 +607,36 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 			}
 			return kotscli.Install(opts, msg)
 		},
+		SkipAddOns:       state.InstalledAddOns,
+		OnAddOnInstalled: state.addInstalledAddOn,
 	}); err != nil {
 		return fmt.Errorf("unable to install addons: %w", err)
 	}
 
+	return nil
+}
+
+func installExtensions(ctx context.Context, kcli client.Client, hcli helm.Client, state *installState) error {
 	logrus.Debugf("installing extensions")
-	if err := extensions.Install(ctx, hcli); err != nil {
+	if err := extensions.Install(ctx, kcli, hcli, extensions.InstallOptions{
+		SkipExtensions:       state.InstalledExtensions,
+		OnExtensionInstalled: state.addInstalledExtension,
+	}); err != nil {
 		return fmt.Errorf("unable to install extensions: %w", err)
 	}
 
-	if err := kubeutils.SetInstallationState(ctx, kcli, in, ecv1beta1.InstallationStateInstalled, "Installed"); err != nil {
-		return fmt.Errorf("unable to update installation: %w", err)
-	}
-
-	if err = support.CreateHostSupportBundle(); err != nil {
-		logrus.Warnf("Unable to create host support bundle: %v", err)
-	}
-
-	if err := printSuccessMessage(flags.license, flags.networkInterface); err != nil {
-		return err
-	}
-
 	return nil
 }
 
-func runInstallVerifyAndPrompt(ctx context.Context, name string, flags *InstallCmdFlags) error {
-	logrus.Debugf("checking if k0s is already installed")
-	err := verifyNoInstallation(name, "reinstall")
-	if err != nil {
-		return err
+func runInstallVerifyAndPrompt(ctx context.Context, name string, flags *InstallCmdFlags, resuming bool) error {
+	if !resuming {
+		logrus.Debugf("checking if k0s is already installed")
+		if err := verifyNoInstallation(name, "reinstall"); err != nil {
+			return err
+		}
 	}
 
-	err = verifyChannelRelease("installation", flags.isAirgap, flags.assumeYes)
+	err := verifyChannelRelease("installation", flags.isAirgap, flags.assumeYes)
 	if err != nil {
 		return err
 	}
@@ -415,6 +664,20 @@ func runInstallVerifyAndPrompt(ctx context.Context, name string, flags *InstallC
 		}
 	}
 
+	logrus.Debugf("checking storage configuration")
+	if _, err := storageForInstall(flags.overrides); err != nil {
+		return fmt.Errorf("invalid storage configuration: %w", err)
+	}
+
+	logrus.Debugf("checking ingress configuration")
+	ingress, err := ingressForInstall(flags.overrides, flags.adminConsoleHostname)
+	if err != nil {
+		return fmt.Errorf("invalid ingress configuration: %w", err)
+	}
+	if flags.adminConsoleTLSCert != "" && ingressHostname(ingress) == "" {
+		return fmt.Errorf("an admin console hostname is required with --admin-console-tls-cert")
+	}
+
 	if err := preflights.ValidateApp(); err != nil {
 		return err
 	}
@@ -422,6 +685,82 @@ func runInstallVerifyAndPrompt(ctx context.Context, name string, flags *InstallC
 	return nil
 }
 
+// storageForInstall returns the storage the cluster is installed with, as configured in the
+// end user and the embedded cluster configs.
+func storageForInstall(overrides string) (*ecv1beta1.StorageSpec, error) {
+	var embCfgSpec, euCfgSpec *ecv1beta1.ConfigSpec
+	embCfg, err := release.GetEmbeddedClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("get embedded cluster config: %w", err)
+	}
+	if embCfg != nil {
+		embCfgSpec = &embCfg.Spec
+	}
+	euCfg, err := helpers.ParseEndUserConfig(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("process overrides file: %w", err)
+	}
+	if euCfg != nil {
+		euCfgSpec = &euCfg.Spec
+	}
+	return addons.StorageForInstall(embCfgSpec, euCfgSpec)
+}
+
+// ingressForInstall returns the ingress the cluster is installed with, as configured in the
+// end user and the embedded cluster configs and by the admin console hostname flag. Nil is
+// returned if the ingress controller is not enabled.
+func ingressForInstall(overrides string, adminConsoleHostname string) (*ecv1beta1.IngressSpec, error) {
+	var embCfgSpec, euCfgSpec *ecv1beta1.ConfigSpec
+	embCfg, err := release.GetEmbeddedClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("get embedded cluster config: %w", err)
+	}
+	if embCfg != nil {
+		embCfgSpec = &embCfg.Spec
+	}
+	euCfg, err := helpers.ParseEndUserConfig(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("process overrides file: %w", err)
+	}
+	if euCfg != nil {
+		euCfgSpec = &euCfg.Spec
+	}
+	return addons.IngressForInstall(embCfgSpec, euCfgSpec, adminConsoleHostname)
+}
+
+// ingressHostname returns the hostname the admin console is exposed at through the ingress
+// controller, if any.
+func ingressHostname(ingress *ecv1beta1.IngressSpec) string {
+	if ingress == nil {
+		return ""
+	}
+	return ingress.AdminConsoleHostname
+}
+
+// readAdminConsoleTLS reads the certificate and key provided for the admin console hostname
+// and checks they form a valid key pair. Empty strings are returned if none was provided.
+func readAdminConsoleTLS(flags InstallCmdFlags) (string, string, error) {
+	if flags.adminConsoleTLSCert == "" && flags.adminConsoleTLSKey == "" {
+		return "", "", nil
+	}
+	if flags.adminConsoleTLSCert == "" || flags.adminConsoleTLSKey == "" {
+		return "", "", fmt.Errorf("--admin-console-tls-cert and --admin-console-tls-key must be provided together")
+	}
+
+	cert, err := os.ReadFile(flags.adminConsoleTLSCert)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read admin console tls certificate: %w", err)
+	}
+	key, err := os.ReadFile(flags.adminConsoleTLSKey)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read admin console tls key: %w", err)
+	}
+	if err := adminconsole.ValidateTLS(string(cert), string(key)); err != nil {
+		return "", "", fmt.Errorf("invalid admin console tls certificate: %w", err)
+	}
+	return string(cert), string(key), nil
+}
+
 func ensureAdminConsolePassword(flags *InstallCmdFlags) error {
 	if flags.adminConsolePassword == "" {
 		// no password was provided
@@ -579,6 +918,11 @@ func materializeFiles(airgapBundle string) error {
 	if airgapBundle != "" {
 		mat.Infof("Materializing air gap installation files")
 
+		publicKey, err := airgapSigningKey()
+		if err != nil {
+			return err
+		}
+
 		// read file from path
 		rawfile, err := os.Open(airgapBundle)
 		if err != nil {
@@ -586,7 +930,7 @@ func materializeFiles(airgapBundle string) error {
 		}
 		defer rawfile.Close()
 
-		if err := airgap.MaterializeAirgap(rawfile); err != nil {
+		if err := airgap.MaterializeAirgap(rawfile, publicKey); err != nil {
 			err = fmt.Errorf("materialize airgap files: %w", err)
 			return err
 		}
@@ -597,7 +941,7 @@ func materializeFiles(airgapBundle string) error {
 	return nil
 }
 
-func installAndStartCluster(ctx context.Context, networkInterface string, airgapBundle string, proxy *ecv1beta1.ProxySpec, cidrCfg *CIDRConfig, overrides string, mutate func(*k0sv1beta1.ClusterConfig) error) (*k0sv1beta1.ClusterConfig, error) {
+func installAndStartCluster(ctx context.Context, networkInterface string, airgapBundle string, proxy *ecv1beta1.ProxySpec, cidrCfg *CIDRConfig, overrides string, mutate func(*k0sv1beta1.ClusterConfig) error, onStarted func() error) (*k0sv1beta1.ClusterConfig, error) {
 	loading := spinner.Start()
 	defer loading.Close()
 	loading.Infof("Installing %s node", runtimeconfig.BinaryName())
@@ -616,6 +960,11 @@ func installAndStartCluster(ctx context.Context, networkInterface string, airgap
 	if err := k0s.Install(networkInterface); err != nil {
 		return nil, fmt.Errorf("install cluster: %w", err)
 	}
+	if onStarted != nil {
+		if err := onStarted(); err != nil {
+			return nil, fmt.Errorf("record k0s started: %w", err)
+		}
+	}
 	loading.Infof("Waiting for %s node to be ready", runtimeconfig.BinaryName())
 	logrus.Debugf("waiting for k0s to be ready")
 	if err := waitForK0s(); err != nil {
@@ -694,6 +1043,42 @@ func checkAirgapMatches(airgapBundle string) error {
 	return nil
 }
 
+// airgapSigningKey returns the public key the airgap bundles of the embedded release are signed
+// with or an empty string if the release does not sign them.
+func airgapSigningKey() (string, error) {
+	rel, err := release.GetChannelRelease()
+	if err != nil {
+		return "", fmt.Errorf("failed to get release from binary: %w", err)
+	}
+	if rel == nil {
+		return "", nil
+	}
+	return rel.AirgapSigningKey, nil
+}
+
+// verifyAirgapBundle reads the whole airgap bundle and verifies it against its manifest.
+func verifyAirgapBundle(airgapBundle string) error {
+	publicKey, err := airgapSigningKey()
+	if err != nil {
+		return err
+	}
+
+	rawfile, err := os.Open(airgapBundle)
+	if err != nil {
+		return fmt.Errorf("failed to open airgap file: %w", err)
+	}
+	defer rawfile.Close()
+
+	loading := spinner.Start()
+	loading.Infof("Verifying air gap bundle")
+	if _, err := airgap.Verify(rawfile, publicKey); err != nil {
+		loading.CloseWithError()
+		return err
+	}
+	loading.Closef("Air gap bundle verified!")
+	return nil
+}
+
 // maybePromptForAppUpdate warns the user if the embedded release is not the latest for the current
 // channel. If stdout is a terminal, it will prompt the user to continue installing the out-of-date
 // release and return an error if the user chooses not to continue.
@@ -968,6 +1353,16 @@ func recordInstallation(ctx context.Context, kcli client.Client, flags InstallCm
 		}
 	}
 
+	storage, err := storageForInstall(flags.overrides)
+	if err != nil {
+		return nil, fmt.Errorf("get storage: %w", err)
+	}
+
+	ingress, err := ingressForInstall(flags.overrides, flags.adminConsoleHostname)
+	if err != nil {
+		return nil, fmt.Errorf("get ingress: %w", err)
+	}
+
 	installation := &ecv1beta1.Installation{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: ecv1beta1.GroupVersion.String(),
@@ -985,6 +1380,8 @@ func recordInstallation(ctx context.Context, kcli client.Client, flags InstallCm
 			Config:                    cfgspec,
 			RuntimeConfig:             runtimeconfig.Get(),
 			EndUserK0sConfigOverrides: euOverrides,
+			Storage:                   storage,
+			Ingress:                   ingress,
 			BinaryName:                runtimeconfig.BinaryName(),
 			LicenseInfo: &ecv1beta1.LicenseInfo{
 				IsDisasterRecoverySupported: disasterRecoveryEnabled,
@@ -1037,8 +1434,8 @@ func createInstallationCRD(ctx context.Context, kcli client.Client) error {
 		crd.Annotations["meta.helm.sh/release-name"] = "embedded-cluster-operator"
 		crd.Annotations["meta.helm.sh/release-namespace"] = "embedded-cluster"
 
-		// apply the CRD
-		if err := kcli.Create(ctx, &crd); err != nil {
+		// apply the CRD, it may already exist if a previous installation attempt was interrupted
+		if err := kcli.Create(ctx, &crd); err != nil && !k8serrors.IsAlreadyExists(err) {
 			return fmt.Errorf("apply installation CRD: %w", err)
 		}
 
@@ -1083,7 +1480,7 @@ func createVersionMetadataConfigmap(ctx context.Context, kcli client.Client) err
 		},
 	}
 
-	if err := kcli.Create(ctx, configmap); err != nil {
+	if err := kcli.Create(ctx, configmap); err != nil && !k8serrors.IsAlreadyExists(err) {
 		return fmt.Errorf("unable to create version metadata config map: %w", err)
 	}
 	return nil
@@ -1214,8 +1611,8 @@ func copyLicenseFileToDataDir(licenseFile, dataDir string) error {
 	return nil
 }
 
-func printSuccessMessage(license *kotsv1beta1.License, networkInterface string) error {
-	adminConsoleURL := getAdminConsoleURL(networkInterface, runtimeconfig.AdminConsolePort())
+func printSuccessMessage(license *kotsv1beta1.License, networkInterface string, hostname string) error {
+	adminConsoleURL := getAdminConsoleURL(networkInterface, runtimeconfig.AdminConsolePort(), hostname)
 
 	successColor := "\033[32m"
 	colorReset := "\033[0m"
@@ -1234,7 +1631,13 @@ func printSuccessMessage(license *kotsv1beta1.License, networkInterface string)
 	return nil
 }
 
-func getAdminConsoleURL(networkInterface string, port int) string {
+// getAdminConsoleURL returns the URL of the admin console. The admin console is served over
+// https at its hostname when it is exposed through the ingress controller, and over its node
+// port otherwise.
+func getAdminConsoleURL(networkInterface string, port int, hostname string) string {
+	if hostname != "" {
+		return fmt.Sprintf("https://%s", hostname)
+	}
 	ipaddr := runtimeconfig.TryDiscoverPublicIP()
 	if ipaddr == "" {
 		var err error
@@ -23,6 +23,9 @@ func InstallRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 			if err := preRunInstall(cmd, &flags); err != nil {
 				return err
 			}
+			if flags.licenseFile == "" {
+				return fmt.Errorf(`required flag(s) "license" not set`)
+			}
 
 			return nil
 		},
@@ -49,7 +52,7 @@ func InstallRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 }
 
 func runInstallRunPreflights(ctx context.Context, name string, flags InstallCmdFlags) error {
-	if err := runInstallVerifyAndPrompt(ctx, name, &flags); err != nil {
+	if err := runInstallVerifyAndPrompt(ctx, name, &flags, false); err != nil {
 		return err
 	}
 
@@ -93,6 +96,11 @@ func runInstallPreflights(ctx context.Context, flags InstallCmdFlags, metricsRep
 		return fmt.Errorf("unable to find first valid address: %w", err)
 	}
 
+	ingress, err := ingressForInstall(flags.overrides, flags.adminConsoleHostname)
+	if err != nil {
+		return fmt.Errorf("unable to get ingress: %w", err)
+	}
+
 	if err := preflights.PrepareAndRun(ctx, preflights.PrepareAndRunOptions{
 		ReplicatedAPIURL:     replicatedAPIURL,
 		ProxyRegistryURL:     proxyRegistryURL,
@@ -107,6 +115,7 @@ func runInstallPreflights(ctx context.Context, flags InstallCmdFlags, metricsRep
 		IgnoreHostPreflights: flags.ignoreHostPreflights,
 		AssumeYes:            flags.assumeYes,
 		MetricsReporter:      metricsReported,
+		IngressEnabled:       ingress.IsEnabled(),
 	}); err != nil {
 		return err
 	}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+type installPhase string
+
+const (
+	installPhaseNew                installPhase = "new"
+	installPhaseHostConfig         installPhase = "host-config"
+	installPhaseHostPreflights     installPhase = "host-preflights"
+	installPhaseInstallCluster     installPhase = "install-cluster"
+	installPhaseRecordInstallation installPhase = "record-installation"
+	installPhaseInstallAddOns      installPhase = "install-addons"
+	installPhaseInstallExtensions  installPhase = "install-extensions"
+)
+
+// installPhases holds all the phases of an installation, in the order they are run.
+var installPhases = []installPhase{
+	installPhaseNew,
+	installPhaseHostConfig,
+	installPhaseHostPreflights,
+	installPhaseInstallCluster,
+	installPhaseRecordInstallation,
+	installPhaseInstallAddOns,
+	installPhaseInstallExtensions,
+}
+
+// installState is the progress of an installation as recorded on disk. The phase is set
+// before the phase starts so that, if it fails, a subsequent install resumes from it.
+type installState struct {
+	Version   string       `json:"version"`
+	Phase     installPhase `json:"phase"`
+	LastError string       `json:"lastError,omitempty"`
+	// K0sStarted is set once the k0s service has been installed and started. An attempt that
+	// failed before that is not resumed, what it left is removed and k0s is installed again.
+	K0sStarted          bool      `json:"k0sStarted,omitempty"`
+	InstalledAddOns     []string  `json:"installedAddOns,omitempty"`
+	InstalledExtensions []string  `json:"installedExtensions,omitempty"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+func newInstallState() *installState {
+	return &installState{
+		Version: versions.Version,
+		Phase:   installPhaseNew,
+	}
+}
+
+// getInstallState reads the install state from disk. A new state is returned if none
+// exists or if it can't be used by this binary.
+func getInstallState() *installState {
+	data, err := os.ReadFile(runtimeconfig.PathToInstallState())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Debugf("unable to read install state: %v", err)
+		}
+		return newInstallState()
+	}
+
+	var state installState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		logrus.Debugf("unable to unmarshal install state: %v", err)
+		return newInstallState()
+	}
+
+	if state.Version != versions.Version {
+		logrus.Debugf("install state was recorded by version %s, ignoring", state.Version)
+		return newInstallState()
+	}
+	if !slices.Contains(installPhases, state.Phase) {
+		logrus.Debugf("unknown install phase %q, ignoring", state.Phase)
+		return newInstallState()
+	}
+
+	return &state
+}
+
+// isPast returns true if the state is further along than the given phase.
+func (s *installState) isPast(phase installPhase) bool {
+	return slices.Index(installPhases, s.Phase) > slices.Index(installPhases, phase)
+}
+
+// setPhase records the given phase as the one currently running.
+func (s *installState) setPhase(phase installPhase) error {
+	logrus.Debugf("setting install phase to %q", phase)
+	s.Phase = phase
+	s.LastError = ""
+	return s.write()
+}
+
+// setError records the error that made the current phase fail.
+func (s *installState) setError(err error) {
+	s.LastError = err.Error()
+	if err := s.write(); err != nil {
+		logrus.Debugf("unable to record install error: %v", err)
+	}
+}
+
+// setK0sStarted records that the k0s service has been installed and started.
+func (s *installState) setK0sStarted() error {
+	s.K0sStarted = true
+	return s.write()
+}
+
+func (s *installState) addInstalledAddOn(name string) error {
+	s.InstalledAddOns = append(s.InstalledAddOns, name)
+	return s.write()
+}
+
+func (s *installState) addInstalledExtension(name string) error {
+	s.InstalledExtensions = append(s.InstalledExtensions, name)
+	return s.write()
+}
+
+func (s *installState) write() error {
+	s.UpdatedAt = time.Now().UTC()
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("unable to marshal install state: %w", err)
+	}
+	location := runtimeconfig.PathToInstallState()
+	if err := os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+		return fmt.Errorf("unable to create install state directory: %w", err)
+	}
+	if err := os.WriteFile(location, data, 0600); err != nil {
+		return fmt.Errorf("unable to write install state: %w", err)
+	}
+	return nil
+}
+
+// removeInstallState deletes the install state from disk once the installation has
+// completed.
+func removeInstallState() error {
+	if err := os.Remove(runtimeconfig.PathToInstallState()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove install state: %w", err)
+	}
+	return nil
+}
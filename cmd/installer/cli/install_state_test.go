@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_installState(t *testing.T) {
+	rc := ecv1beta1.GetDefaultRuntimeConfig()
+	rc.DataDir = t.TempDir()
+	runtimeconfig.Set(rc)
+	t.Cleanup(func() { runtimeconfig.Set(ecv1beta1.GetDefaultRuntimeConfig()) })
+
+	// no state on disk
+	state := getInstallState()
+	assert.Equal(t, installPhaseNew, state.Phase)
+
+	require.NoError(t, state.setPhase(installPhaseInstallCluster))
+	assert.False(t, getInstallState().K0sStarted)
+	require.NoError(t, state.setK0sStarted())
+	assert.True(t, getInstallState().K0sStarted)
+
+	require.NoError(t, state.setPhase(installPhaseInstallAddOns))
+	require.NoError(t, state.addInstalledAddOn("OpenEBS"))
+	state.setError(assert.AnError)
+
+	state = getInstallState()
+	assert.Equal(t, installPhaseInstallAddOns, state.Phase)
+	assert.Equal(t, []string{"OpenEBS"}, state.InstalledAddOns)
+	assert.Equal(t, assert.AnError.Error(), state.LastError)
+	assert.True(t, state.isPast(installPhaseInstallCluster))
+	assert.False(t, state.isPast(installPhaseInstallAddOns))
+
+	// moving to the next phase clears the error
+	require.NoError(t, state.setPhase(installPhaseInstallExtensions))
+	assert.Empty(t, getInstallState().LastError)
+
+	// state recorded by another version is ignored
+	state.Version = "v0.0.0-other"
+	require.NoError(t, state.write())
+	assert.Equal(t, installPhaseNew, getInstallState().Phase)
+
+	require.NoError(t, removeInstallState())
+	_, err := os.Stat(runtimeconfig.PathToInstallState())
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, removeInstallState())
+}
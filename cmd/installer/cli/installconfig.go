@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func addInstallConfigFlag(cmd *cobra.Command, installConfig *string) {
+	cmd.Flags().StringVar(installConfig, "config", "", "Path to an InstallConfig file. Flags provided on the command line take precedence over values in the file.")
+}
+
+// applyInstallConfigFile reads, validates and applies the InstallConfig found at the
+// given path to the command flags.
+func applyInstallConfigFile(cmd *cobra.Command, fpath string) error {
+	cfg, err := helpers.ParseInstallConfig(fpath)
+	if err != nil {
+		return err
+	}
+	if err := validateInstallConfig(cfg); err != nil {
+		return fmt.Errorf("install config %s is not valid: %w", fpath, err)
+	}
+	if err := applyInstallConfig(cmd, cfg); err != nil {
+		return fmt.Errorf("unable to apply install config: %w", err)
+	}
+	return nil
+}
+
+// validateInstallConfig validates the InstallConfig structure as well as the parts of it
+// that depend on the host, such as the existence of the referenced files.
+func validateInstallConfig(cfg *ecv1beta1.InstallConfig) error {
+	errs := cfg.Validate()
+
+	spec := field.NewPath("spec")
+	files := map[*field.Path]string{
+		spec.Child("license"):      cfg.Spec.License,
+		spec.Child("airgapBundle"): cfg.Spec.AirgapBundle,
+		spec.Child("configValues"): cfg.Spec.ConfigValues,
+		spec.Child("overrides"):    cfg.Spec.Overrides,
+	}
+	for i, ca := range cfg.Spec.PrivateCAs {
+		files[spec.Child("privateCAs").Index(i)] = ca
+	}
+	for fld, fpath := range files {
+		if fpath == "" {
+			continue
+		}
+		if _, err := os.Stat(fpath); err != nil {
+			errs = append(errs, field.Invalid(fld, fpath, "file can not be read"))
+		}
+	}
+
+	if cidr := cfg.Spec.Network.CIDR; cidr != "" {
+		if err := netutils.ValidateCIDR(cidr, 16, true); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("network", "cidr"), cidr, err.Error()))
+		}
+	}
+
+	if pass := cfg.Spec.AdminConsole.Password; pass != "" && len(pass) < minAdminPasswordLength {
+		errs = append(errs, field.Invalid(spec.Child("adminConsole", "password"), "<redacted>", fmt.Sprintf("must have at least %d characters", minAdminPasswordLength)))
+	}
+
+	return errs.ToAggregate()
+}
+
+// applyInstallConfig sets the command flags from the InstallConfig. Flags explicitly
+// provided by the user are left untouched so they can override single fields. Fields
+// for flags the command does not support (e.g. the license on restore) are ignored.
+func applyInstallConfig(cmd *cobra.Command, cfg *ecv1beta1.InstallConfig) error {
+	values := []struct {
+		flag  string
+		value string
+	}{
+		{"license", cfg.Spec.License},
+		{"airgap-bundle", cfg.Spec.AirgapBundle},
+		{"data-dir", cfg.Spec.DataDir},
+		{"admin-console-password", cfg.Spec.AdminConsole.Password},
+		{"admin-console-port", intToFlag(cfg.Spec.AdminConsole.Port)},
+		{"local-artifact-mirror-port", intToFlag(cfg.Spec.LocalArtifactMirror.Port)},
+		{"network-interface", cfg.Spec.NetworkInterface},
+		{"http-proxy", cfg.Spec.Proxy.HTTPProxy},
+		{"https-proxy", cfg.Spec.Proxy.HTTPSProxy},
+		{"no-proxy", cfg.Spec.Proxy.NoProxy},
+		{"private-ca", strings.Join(cfg.Spec.PrivateCAs, ",")},
+		{"config-values", cfg.Spec.ConfigValues},
+		{"overrides", cfg.Spec.Overrides},
+		{"ignore-host-preflights", boolToFlag(cfg.Spec.IgnoreHostPreflights)},
+		{"yes", boolToFlag(cfg.Spec.AssumeYes)},
+	}
+
+	// the network cidrs are handled as a whole as --cidr can not be combined with
+	// --pod-cidr or --service-cidr.
+	if !cmd.Flags().Changed("cidr") && !cmd.Flags().Changed("pod-cidr") && !cmd.Flags().Changed("service-cidr") {
+		values = append(values, []struct {
+			flag  string
+			value string
+		}{
+			{"cidr", cfg.Spec.Network.CIDR},
+			{"pod-cidr", cfg.Spec.Network.PodCIDR},
+			{"service-cidr", cfg.Spec.Network.ServiceCIDR},
+		}...)
+	}
+
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+		if cmd.Flags().Lookup(v.flag) == nil || cmd.Flags().Changed(v.flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(v.flag, v.value); err != nil {
+			return fmt.Errorf("unable to set %s flag: %w", v.flag, err)
+		}
+	}
+
+	return nil
+}
+
+func intToFlag(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
+
+func boolToFlag(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
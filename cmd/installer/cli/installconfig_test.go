@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyInstallConfig(t *testing.T) {
+	cfg := &ecv1beta1.InstallConfig{
+		Spec: ecv1beta1.InstallConfigSpec{
+			License:             "/opt/license.yaml",
+			DataDir:             "/opt/ec",
+			AdminConsole:        ecv1beta1.InstallConfigAdminConsole{Password: "password", Port: 31000},
+			LocalArtifactMirror: ecv1beta1.LocalArtifactMirrorSpec{Port: 51000},
+			Network:             ecv1beta1.InstallConfigNetwork{CIDR: "172.16.0.0/16"},
+			Proxy:               ecv1beta1.InstallConfigProxy{HTTPProxy: "http://proxy:3128"},
+			PrivateCAs:          []string{"/opt/ca1.crt", "/opt/ca2.crt"},
+			AssumeYes:           true,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		admin    bool
+		args     []string
+		validate func(t *testing.T, cmd *cobra.Command, flags *InstallCmdFlags)
+	}{
+		{
+			name:  "values from file",
+			admin: true,
+			validate: func(t *testing.T, cmd *cobra.Command, flags *InstallCmdFlags) {
+				assert.Equal(t, "/opt/license.yaml", flags.licenseFile)
+				assert.Equal(t, "/opt/ec", flags.dataDir)
+				assert.Equal(t, "password", flags.adminConsolePassword)
+				assert.Equal(t, 31000, flags.adminConsolePort)
+				assert.Equal(t, 51000, flags.localArtifactMirrorPort)
+				assert.Equal(t, []string{"/opt/ca1.crt", "/opt/ca2.crt"}, flags.privateCAs)
+				assert.True(t, flags.assumeYes)
+				cidr, _ := cmd.Flags().GetString("cidr")
+				assert.Equal(t, "172.16.0.0/16", cidr)
+				proxy, _ := cmd.Flags().GetString("http-proxy")
+				assert.Equal(t, "http://proxy:3128", proxy)
+			},
+		},
+		{
+			name:  "flags override values from file",
+			admin: true,
+			args:  []string{"--data-dir", "/var/lib/ec", "--pod-cidr", "10.0.0.0/16"},
+			validate: func(t *testing.T, cmd *cobra.Command, flags *InstallCmdFlags) {
+				assert.Equal(t, "/var/lib/ec", flags.dataDir)
+				assert.Equal(t, "/opt/license.yaml", flags.licenseFile)
+				assert.False(t, cmd.Flags().Changed("cidr"))
+				podCIDR, _ := cmd.Flags().GetString("pod-cidr")
+				assert.Equal(t, "10.0.0.0/16", podCIDR)
+			},
+		},
+		{
+			name:  "fields without a flag are ignored",
+			admin: false,
+			validate: func(t *testing.T, cmd *cobra.Command, flags *InstallCmdFlags) {
+				assert.Equal(t, "", flags.licenseFile)
+				assert.Equal(t, "/opt/ec", flags.dataDir)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var flags InstallCmdFlags
+			cmd := &cobra.Command{}
+			require.NoError(t, addInstallFlags(cmd, &flags))
+			if tt.admin {
+				require.NoError(t, addInstallAdminConsoleFlags(cmd, &flags))
+			}
+			require.NoError(t, cmd.Flags().Parse(tt.args))
+
+			require.NoError(t, applyInstallConfig(cmd, cfg))
+			tt.validate(t, cmd, &flags)
+		})
+	}
+}
@@ -38,6 +38,8 @@ type JoinCmdFlags struct {
 	assumeYes              bool
 	skipHostPreflights     bool
 	ignoreHostPreflights   bool
+	bundle                 string
+	bundleFingerprint      string
 }
 
 // This is the upcoming version of join without the operator and where
@@ -49,7 +51,7 @@ func JoinCmd(ctx context.Context, name string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "join <url> <token>",
 		Short: fmt.Sprintf("Join %s", name),
-		Args:  cobra.ExactArgs(2),
+		Args:  joinArgs(&flags),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if err := preRunJoin(&flags); err != nil {
 				return err
@@ -63,10 +65,9 @@ func JoinCmd(ctx context.Context, name string) *cobra.Command {
 			runtimeconfig.Cleanup()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logrus.Debugf("fetching join token remotely")
-			jcmd, err := kotsadm.GetJoinToken(ctx, args[0], args[1])
+			jcmd, err := getJoinCommand(ctx, flags, args)
 			if err != nil {
-				return fmt.Errorf("unable to get join token: %w", err)
+				return err
 			}
 			metricsReporter := NewJoinReporter(jcmd.InstallationSpec.MetricsBaseURL, jcmd.ClusterID, cmd.CalledAs())
 			metricsReporter.ReportJoinStarted(ctx)
@@ -85,6 +86,7 @@ func JoinCmd(ctx context.Context, name string) *cobra.Command {
 	}
 
 	cmd.AddCommand(JoinRunPreflightsCmd(ctx, name))
+	cmd.AddCommand(JoinPrintBundleCmd(ctx, name))
 
 	return cmd
 }
@@ -94,15 +96,53 @@ func preRunJoin(flags *JoinCmdFlags) error {
 		return fmt.Errorf("join command must be run as root")
 	}
 
+	// the bundle carries the key it is signed with, the fingerprint is what ties it to the
+	// cluster.
+	if flags.bundle != "" && flags.bundleFingerprint == "" {
+		return fmt.Errorf("--bundle-fingerprint is required with --bundle, it is printed by 'join print-bundle'")
+	}
+
 	flags.isAirgap = flags.airgapBundle != ""
 
 	return nil
 }
 
+// joinArgs requires the admin console url and the join token unless a join bundle is
+// provided, in which case no arguments are accepted.
+func joinArgs(flags *JoinCmdFlags) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if flags.bundle != "" {
+			if len(args) != 0 {
+				return fmt.Errorf("no arguments are accepted when --bundle is set")
+			}
+			return nil
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	}
+}
+
+// getJoinCommand reads the join command from the join bundle if one was provided, otherwise
+// it is fetched from the admin console api.
+func getJoinCommand(ctx context.Context, flags JoinCmdFlags, args []string) (*kotsadm.JoinCommandResponse, error) {
+	if flags.bundle != "" {
+		logrus.Debugf("reading join command from bundle %s", flags.bundle)
+		return readJoinBundle(flags.bundle, flags.bundleFingerprint)
+	}
+
+	logrus.Debugf("fetching join token remotely")
+	jcmd, err := kotsadm.GetJoinToken(ctx, args[0], args[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to get join token: %w", err)
+	}
+	return jcmd, nil
+}
+
 func addJoinFlags(cmd *cobra.Command, flags *JoinCmdFlags) error {
 	cmd.Flags().StringVar(&flags.airgapBundle, "airgap-bundle", "", "Path to the air gap bundle. If set, the installation will complete without internet access.")
 	cmd.Flags().StringVar(&flags.networkInterface, "network-interface", "", "The network interface to use for the cluster")
 	cmd.Flags().BoolVar(&flags.ignoreHostPreflights, "ignore-host-preflights", false, "Run host preflight checks, but prompt the user to continue if they fail instead of exiting.")
+	cmd.Flags().StringVar(&flags.bundle, "bundle", "", "Path to a join bundle created with 'join print-bundle'. If set, the admin console url and token are not required.")
+	cmd.Flags().StringVar(&flags.bundleFingerprint, "bundle-fingerprint", "", "Fingerprint of the key the join bundle must be signed with, as printed by 'join print-bundle'. Required with --bundle.")
 
 	cmd.Flags().BoolVar(&flags.enableHighAvailability, "enable-ha", false, "Enable high availability.")
 	if err := cmd.Flags().MarkHidden("enable-ha"); err != nil {
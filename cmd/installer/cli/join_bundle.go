@@ -0,0 +1,418 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// joinBundleKeySecretName is the name of the secret holding the key used to sign join
+	// bundles. It is created the first time a bundle is printed.
+	joinBundleKeySecretName = "embedded-cluster-join-bundle-key"
+	joinBundleKeySecretKey  = "privateKey"
+	defaultJoinBundleTTL    = 24 * time.Hour
+)
+
+// joinBundle holds everything a node needs to join the cluster without reaching the
+// admin console api.
+type joinBundle struct {
+	Role        string                      `json:"role"`
+	IssuedAt    time.Time                   `json:"issuedAt"`
+	ExpiresAt   time.Time                   `json:"expiresAt"`
+	JoinCommand kotsadm.JoinCommandResponse `json:"joinCommand"`
+}
+
+// signedJoinBundle is the format of the join bundle file. The payload is the json encoded
+// joinBundle, signed with the ed25519 key stored in the cluster.
+type signedJoinBundle struct {
+	Payload   []byte `json:"payload"`
+	PublicKey []byte `json:"publicKey"`
+	Signature []byte `json:"signature"`
+}
+
+func JoinPrintBundleCmd(ctx context.Context, name string) *cobra.Command {
+	var role, output string
+	var ttl time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "print-bundle",
+		Short: "Write a signed join bundle for nodes that can not reach the admin console",
+		Long: fmt.Sprintf(
+			"Write a signed and expiring file holding everything a node needs to join the cluster. "+
+				"Copy it to the new node and run '%s join --bundle <file> --bundle-fingerprint <fingerprint>' there, "+
+				"with the fingerprint printed by this command. This must be run on a controller node.",
+			name,
+		),
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getuid() != 0 {
+				return fmt.Errorf("join print-bundle command must be run as root")
+			}
+			if role == "" {
+				return fmt.Errorf(`required flag(s) "role" not set`)
+			}
+			if ttl <= 0 {
+				return fmt.Errorf("--ttl must be greater than zero")
+			}
+
+			rcutil.InitBestRuntimeConfig(cmd.Context())
+			os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+			os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to get latest installation: %w", err)
+			}
+
+			bundle, err := buildJoinBundle(ctx, kcli, in, role, ttl)
+			if err != nil {
+				return err
+			}
+
+			key, err := getOrCreateJoinBundleKey(ctx, kcli)
+			if err != nil {
+				return err
+			}
+
+			data, err := signJoinBundle(bundle, key)
+			if err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(output, data, 0600); err != nil {
+				return fmt.Errorf("unable to write join bundle: %w", err)
+			}
+
+			logrus.Infof("Join bundle for role %s written to %s", role, output)
+			logrus.Infof("The bundle expires at %s and must be kept secret as it allows nodes to join the cluster.", bundle.ExpiresAt.Format(time.RFC3339))
+			fingerprint := joinBundleKeyFingerprint(key.Public().(ed25519.PublicKey))
+			logrus.Infof("Bundle signing key fingerprint: %s", fingerprint)
+			logrus.Infof("Join the node with '%s join --bundle %s --bundle-fingerprint %s'.", name, filepath.Base(output), fingerprint)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&role, "role", "", "The role of the node that will join the cluster.")
+	cmd.Flags().DurationVar(&ttl, "ttl", defaultJoinBundleTTL, "How long the bundle is valid for.")
+	cmd.Flags().StringVarP(&output, "output", "o", "join-bundle.json", "Path of the file the bundle is written to.")
+
+	return cmd
+}
+
+// buildJoinBundle assembles the same join command the admin console api would return for
+// a node with the provided role.
+func buildJoinBundle(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, role string, ttl time.Duration) (*joinBundle, error) {
+	var roles ecv1beta1.Roles
+	if in.Spec.Config != nil {
+		roles = in.Spec.Config.Roles
+	}
+	isController, labels, err := joinRoleLabels(roles, role)
+	if err != nil {
+		return nil, err
+	}
+
+	k0sRole := "worker"
+	if isController {
+		k0sRole = "controller"
+	}
+
+	token, err := createK0sJoinToken(k0sRole, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterID, err := uuid.Parse(in.Spec.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse cluster id: %w", err)
+	}
+
+	ecVersion := versions.Version
+	if in.Spec.Config != nil && in.Spec.Config.Version != "" {
+		ecVersion = in.Spec.Config.Version
+	}
+
+	airgapRegistryAddress := ""
+	if in.Spec.AirGap {
+		serviceCIDR, err := installationServiceCIDR(in)
+		if err != nil {
+			return nil, err
+		}
+		registryIP, err := registry.GetRegistryClusterIP(serviceCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get registry cluster ip: %w", err)
+		}
+		airgapRegistryAddress = fmt.Sprintf("%s:5000", registryIP)
+	}
+
+	tcpConnections, err := joinTCPConnectionsRequired(ctx, kcli, isController)
+	if err != nil {
+		return nil, err
+	}
+
+	joinCommand := []string{
+		runtimeconfig.K0sBinaryPath(), "install", k0sRole,
+		"--labels", strings.Join(labels, ","),
+	}
+	if isController {
+		joinCommand = append(joinCommand, "--enable-worker", "--no-taints")
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	return &joinBundle{
+		Role:      role,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+		JoinCommand: kotsadm.JoinCommandResponse{
+			K0sJoinCommand:         strings.Join(joinCommand, " "),
+			K0sToken:               token,
+			ClusterID:              clusterID,
+			EmbeddedClusterVersion: ecVersion,
+			AirgapRegistryAddress:  airgapRegistryAddress,
+			TCPConnectionsRequired: tcpConnections,
+			InstallationSpec:       in.Spec,
+		},
+	}, nil
+}
+
+// joinRoleLabels returns whether the role is the controller role and the node labels for
+// it, in the format expected by 'k0s install --labels'. If no custom roles are configured
+// the plain "worker" role is accepted as well.
+func joinRoleLabels(roles ecv1beta1.Roles, role string) (bool, []string, error) {
+	controllerRole := roles.Controller.Name
+	if controllerRole == "" {
+		controllerRole = "controller"
+	}
+
+	var nodeRole *ecv1beta1.NodeRole
+	isController := false
+	if role == controllerRole {
+		nodeRole = &roles.Controller
+		isController = true
+	}
+	for i := range roles.Custom {
+		if nodeRole == nil && roles.Custom[i].Name == role {
+			nodeRole = &roles.Custom[i]
+		}
+	}
+	if nodeRole == nil && (role != "worker" || len(roles.Custom) > 0) {
+		valid := []string{controllerRole}
+		for _, custom := range roles.Custom {
+			valid = append(valid, custom.Name)
+		}
+		if len(roles.Custom) == 0 {
+			valid = append(valid, "worker")
+		}
+		return false, nil, fmt.Errorf("unknown role %q, valid roles are: %s", role, strings.Join(valid, ", "))
+	}
+
+	labels := []string{
+		fmt.Sprintf("%s=%s", customRoleLabel, role),
+		"kots.io/embedded-cluster-role=total-1",
+	}
+	if nodeRole != nil {
+		for k, v := range nodeRole.Labels {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	return isController, labels, nil
+}
+
+// createK0sJoinToken creates a k0s join token for the given k0s role. The token expires
+// together with the bundle.
+func createK0sJoinToken(k0sRole string, ttl time.Duration) (string, error) {
+	out, err := helpers.RunCommand(
+		k0sBinPath, "token", "create",
+		"--role", k0sRole,
+		"--expiry", ttl.String(),
+		"--data-dir", runtimeconfig.EmbeddedClusterK0sSubDir(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("unable to create k0s join token: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func installationServiceCIDR(in *ecv1beta1.Installation) (string, error) {
+	if in.Spec.Network != nil && in.Spec.Network.ServiceCIDR != "" {
+		return in.Spec.Network.ServiceCIDR, nil
+	}
+	_, serviceCIDR, err := netutils.SplitNetworkCIDR(ecv1beta1.DefaultNetworkCIDR)
+	if err != nil {
+		return "", fmt.Errorf("unable to split default network CIDR: %w", err)
+	}
+	return serviceCIDR, nil
+}
+
+// joinTCPConnectionsRequired returns the addresses the joining node must be able to reach
+// on every controller node.
+func joinTCPConnectionsRequired(ctx context.Context, kcli client.Client, isController bool) ([]string, error) {
+	ports := []string{"6443", "9443"}
+	if isController {
+		ports = append(ports, "2380", "10250")
+	}
+
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes, client.MatchingLabels{controlPlaneLabel: "true"}); err != nil {
+		return nil, fmt.Errorf("unable to list controller nodes: %w", err)
+	}
+
+	addresses := []string{}
+	for _, node := range nodes.Items {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type != corev1.NodeInternalIP {
+				continue
+			}
+			for _, port := range ports {
+				addresses = append(addresses, fmt.Sprintf("%s:%s", addr.Address, port))
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// getOrCreateJoinBundleKey returns the key used to sign join bundles, creating it if it
+// does not exist yet.
+func getOrCreateJoinBundleKey(ctx context.Context, kcli client.Client) (ed25519.PrivateKey, error) {
+	var secret corev1.Secret
+	nsn := client.ObjectKey{Namespace: runtimeconfig.EmbeddedClusterNamespace, Name: joinBundleKeySecretName}
+	err := kcli.Get(ctx, nsn, &secret)
+	if err == nil {
+		key := secret.Data[joinBundleKeySecretKey]
+		if len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid join bundle key in secret %s", joinBundleKeySecretName)
+		}
+		return ed25519.PrivateKey(key), nil
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("unable to get join bundle key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate join bundle key: %w", err)
+	}
+	secret = corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      joinBundleKeySecretName,
+			Namespace: runtimeconfig.EmbeddedClusterNamespace,
+			Labels: map[string]string{
+				"replicated.com/disaster-recovery": "infra",
+			},
+		},
+		Data: map[string][]byte{joinBundleKeySecretKey: key},
+	}
+	if err := kcli.Create(ctx, &secret); err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			// someone else created it in the meantime.
+			return getOrCreateJoinBundleKey(ctx, kcli)
+		}
+		return nil, fmt.Errorf("unable to create join bundle key: %w", err)
+	}
+	return key, nil
+}
+
+// signJoinBundle encodes and signs the bundle with the provided key.
+func signJoinBundle(bundle *joinBundle, key ed25519.PrivateKey) ([]byte, error) {
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal join bundle: %w", err)
+	}
+	signed := signedJoinBundle{
+		Payload:   payload,
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, payload),
+	}
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal signed join bundle: %w", err)
+	}
+	return data, nil
+}
+
+// verifyJoinBundle checks the signature and the expiration of the bundle and returns its
+// content. The bundle must have been signed by the key with the provided fingerprint, the
+// public key it carries is not trusted on its own.
+func verifyJoinBundle(data []byte, fingerprint string, now time.Time) (*joinBundle, error) {
+	if fingerprint == "" {
+		return nil, fmt.Errorf("the fingerprint of the join bundle signing key is required")
+	}
+
+	var signed signedJoinBundle
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("unable to parse join bundle: %w", err)
+	}
+	if len(signed.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("join bundle has an invalid public key")
+	}
+	pubKey := ed25519.PublicKey(signed.PublicKey)
+	if !ed25519.Verify(pubKey, signed.Payload, signed.Signature) {
+		return nil, fmt.Errorf("join bundle signature is invalid")
+	}
+	if joinBundleKeyFingerprint(pubKey) != strings.ToLower(strings.TrimSpace(fingerprint)) {
+		return nil, fmt.Errorf("join bundle was not signed by the key with fingerprint %s", fingerprint)
+	}
+
+	var bundle joinBundle
+	if err := json.Unmarshal(signed.Payload, &bundle); err != nil {
+		return nil, fmt.Errorf("unable to parse join bundle payload: %w", err)
+	}
+	if now.After(bundle.ExpiresAt) {
+		return nil, fmt.Errorf("join bundle expired at %s", bundle.ExpiresAt.Format(time.RFC3339))
+	}
+	return &bundle, nil
+}
+
+// joinBundleKeyFingerprint returns the hex encoded sha256 sum of the public key.
+func joinBundleKeyFingerprint(pubKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(pubKey)
+	return hex.EncodeToString(sum[:])
+}
+
+// readJoinBundle reads and verifies the join bundle at the provided path.
+func readJoinBundle(path string, fingerprint string) (*kotsadm.JoinCommandResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read join bundle: %w", err)
+	}
+	bundle, err := verifyJoinBundle(data, fingerprint, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("using join bundle for role %s issued at %s", bundle.Role, bundle.IssuedAt.Format(time.RFC3339))
+	return &bundle.JoinCommand, nil
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_signAndVerifyJoinBundle(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	fingerprint := joinBundleKeyFingerprint(key.Public().(ed25519.PublicKey))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bundle := &joinBundle{
+		Role:      "worker",
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Hour),
+		JoinCommand: kotsadm.JoinCommandResponse{
+			K0sJoinCommand:         "/usr/local/bin/k0s install worker",
+			K0sToken:               "token",
+			ClusterID:              uuid.New(),
+			AirgapRegistryAddress:  "10.96.0.11:5000",
+			TCPConnectionsRequired: []string{"10.0.0.1:6443"},
+			InstallationSpec:       ecv1beta1.InstallationSpec{AirGap: true, SourceType: ecv1beta1.InstallationSourceTypeCRD},
+		},
+	}
+
+	data, err := signJoinBundle(bundle, key)
+	require.NoError(t, err)
+
+	got, err := verifyJoinBundle(data, fingerprint, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, bundle, got)
+
+	_, err = verifyJoinBundle(data, "", now.Add(time.Minute))
+	assert.ErrorContains(t, err, "fingerprint of the join bundle signing key is required")
+
+	_, err = verifyJoinBundle(data, "deadbeef", now.Add(time.Minute))
+	assert.ErrorContains(t, err, "not signed by the key")
+
+	_, err = verifyJoinBundle(data, fingerprint, now.Add(2*time.Hour))
+	assert.ErrorContains(t, err, "expired")
+
+	// a bundle forged with another key carries a valid signature for that key, it is the
+	// fingerprint that rejects it.
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged, err := signJoinBundle(bundle, otherKey)
+	require.NoError(t, err)
+	_, err = verifyJoinBundle(forged, fingerprint, now.Add(time.Minute))
+	assert.ErrorContains(t, err, "not signed by the key")
+
+	var signed signedJoinBundle
+	require.NoError(t, json.Unmarshal(data, &signed))
+	bundle.ExpiresAt = now.Add(48 * time.Hour)
+	signed.Payload, err = json.Marshal(bundle)
+	require.NoError(t, err)
+	tampered, err := json.Marshal(signed)
+	require.NoError(t, err)
+	_, err = verifyJoinBundle(tampered, fingerprint, now.Add(2*time.Hour))
+	assert.ErrorContains(t, err, "signature is invalid")
+}
+
+func Test_joinRoleLabels(t *testing.T) {
+	roles := ecv1beta1.Roles{
+		Controller: ecv1beta1.NodeRole{Name: "management", Labels: map[string]string{"tier": "mgmt"}},
+		Custom:     []ecv1beta1.NodeRole{{Name: "gpu", Labels: map[string]string{"gpu": "true"}}},
+	}
+
+	isController, labels, err := joinRoleLabels(roles, "management")
+	require.NoError(t, err)
+	assert.True(t, isController)
+	assert.ElementsMatch(t, []string{customRoleLabel + "=management", "kots.io/embedded-cluster-role=total-1", "tier=mgmt"}, labels)
+
+	isController, labels, err = joinRoleLabels(roles, "gpu")
+	require.NoError(t, err)
+	assert.False(t, isController)
+	assert.ElementsMatch(t, []string{customRoleLabel + "=gpu", "kots.io/embedded-cluster-role=total-1", "gpu=true"}, labels)
+
+	_, _, err = joinRoleLabels(roles, "worker")
+	assert.ErrorContains(t, err, "valid roles are: management, gpu")
+
+	isController, _, err = joinRoleLabels(ecv1beta1.Roles{}, "worker")
+	require.NoError(t, err)
+	assert.False(t, isController)
+
+	isController, _, err = joinRoleLabels(ecv1beta1.Roles{}, "controller")
+	require.NoError(t, err)
+	assert.True(t, isController)
+}
+
+func Test_joinTCPConnectionsRequired(t *testing.T) {
+	kcli := fake.NewClientBuilder().WithObjects(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "controller", Labels: map[string]string{controlPlaneLabel: "true"}},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: "controller"},
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.2"},
+			}},
+		},
+	).Build()
+
+	got, err := joinTCPConnectionsRequired(context.Background(), kcli, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:6443", "10.0.0.1:9443"}, got)
+
+	got, err = joinTCPConnectionsRequired(context.Background(), kcli, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:6443", "10.0.0.1:9443", "10.0.0.1:2380", "10.0.0.1:10250"}, got)
+}
+
+func Test_getOrCreateJoinBundleKey(t *testing.T) {
+	kcli := fake.NewClientBuilder().Build()
+	ctx := context.Background()
+
+	key, err := getOrCreateJoinBundleKey(ctx, kcli)
+	require.NoError(t, err)
+
+	again, err := getOrCreateJoinBundleKey(ctx, kcli)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+}
@@ -20,7 +20,7 @@ func JoinRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "run-preflights",
 		Short: fmt.Sprintf("Run join host preflights for %s", name),
-		Args:  cobra.ExactArgs(2),
+		Args:  joinArgs(&flags),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if err := preRunJoin(&flags); err != nil {
 				return err
@@ -32,10 +32,9 @@ func JoinRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 			runtimeconfig.Cleanup()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logrus.Debugf("fetching join token remotely")
-			jcmd, err := kotsadm.GetJoinToken(ctx, args[0], args[1])
+			jcmd, err := getJoinCommand(ctx, flags, args)
 			if err != nil {
-				return fmt.Errorf("unable to get join token: %w", err)
+				return err
 			}
 			if err := runJoinRunPreflights(cmd.Context(), name, flags, jcmd); err != nil {
 				return err
@@ -109,6 +108,7 @@ func runJoinPreflights(ctx context.Context, jcmd *kotsadm.JoinCommandResponse, f
 		AssumeYes:              flags.assumeYes,
 		TCPConnectionsRequired: jcmd.TCPConnectionsRequired,
 		IsJoin:                 true,
+		IngressEnabled:         jcmd.InstallationSpec.Ingress.IsEnabled(),
 	}); err != nil {
 		return err
 	}
@@ -2,7 +2,11 @@ package cli
 
 import (
 	"context"
+	"fmt"
+	"os"
 
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
 	"github.com/spf13/cobra"
 )
 
@@ -13,9 +17,15 @@ func NodeCmd(ctx context.Context, name string) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return nil
 		},
-		Hidden: true,
 	}
 
+	cmd.AddCommand(NodeListCmd(ctx, name))
+	cmd.AddCommand(NodeInspectCmd(ctx, name))
+	cmd.AddCommand(NodeDrainCmd(ctx, name))
+	cmd.AddCommand(NodeCordonCmd(ctx, name))
+	cmd.AddCommand(NodeUncordonCmd(ctx, name))
+	cmd.AddCommand(NodeRemoveCmd(ctx, name))
+
 	// here for legacy reasons
 	joinCmd := JoinCmd(ctx, name)
 	joinCmd.Hidden = true
@@ -27,3 +37,18 @@ func NodeCmd(ctx context.Context, name string) *cobra.Command {
 
 	return cmd
 }
+
+// preRunNode is shared by the node subcommands that talk to the cluster. These must be run
+// as root on a controller node.
+func preRunNode(cmd *cobra.Command, args []string) error {
+	if os.Getuid() != 0 {
+		return fmt.Errorf("node %s command must be run as root", cmd.Name())
+	}
+
+	rcutil.InitBestRuntimeConfig(cmd.Context())
+
+	os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+	os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func NodeDrainCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "drain NODE",
+		Short:   "Cordon a node and evict all of its pods",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunNode,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			// make sure the node exists, draining a missing node is not an error for k0s.
+			if _, err := getNode(cmd.Context(), kcli, args[0]); err != nil {
+				return err
+			}
+
+			logrus.Infof("Draining node %s...", args[0])
+			if err := drainNodeWithK0s(args[0]); err != nil {
+				return err
+			}
+			logrus.Infof("Node %s drained", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func NodeCordonCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cordon NODE",
+		Short:   "Mark a node as unschedulable",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunNode,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			if err := setNodeUnschedulable(cmd.Context(), kcli, args[0], true); err != nil {
+				return err
+			}
+			logrus.Infof("Node %s cordoned", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func NodeUncordonCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "uncordon NODE",
+		Short:   "Mark a node as schedulable",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunNode,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			if err := setNodeUnschedulable(cmd.Context(), kcli, args[0], false); err != nil {
+				return err
+			}
+			logrus.Infof("Node %s uncordoned", args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func getNode(ctx context.Context, kcli client.Client, nodeName string) (*corev1.Node, error) {
+	var node corev1.Node
+	if err := kcli.Get(ctx, client.ObjectKey{Name: nodeName}, &node); err != nil {
+		return nil, fmt.Errorf("unable to get node %s: %w", nodeName, err)
+	}
+	return &node, nil
+}
+
+// setNodeUnschedulable cordons or uncordons the named node.
+func setNodeUnschedulable(ctx context.Context, kcli client.Client, nodeName string, unschedulable bool) error {
+	node, err := getNode(ctx, kcli, nodeName)
+	if err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = unschedulable
+	if err := kcli.Patch(ctx, node, patch); err != nil {
+		return fmt.Errorf("unable to update node %s: %w", nodeName, err)
+	}
+	return nil
+}
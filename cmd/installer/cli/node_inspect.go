@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	preflightstypes "github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nodeDetails holds what is shown for a node by the node inspect command.
+type nodeDetails struct {
+	nodeSummary
+	KubernetesVersion string
+	InternalIP        string
+	OS                string
+	KernelVersion     string
+	ContainerRuntime  string
+	Taints            []string
+	// Problems are the node conditions other than Ready that are true, e.g. DiskPressure.
+	Problems []string
+	// PreflightIssues are the failed and warning host preflights of the node.
+	PreflightIssues []string
+}
+
+func NodeInspectCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "inspect NODE",
+		Short:   "Show the details of a cluster node",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunNode,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			details, err := inspectNode(cmd.Context(), kcli, args[0])
+			if err != nil {
+				return err
+			}
+
+			writer := table.NewWriter()
+			writer.AppendRows([]table.Row{
+				{"name", details.Name},
+				{"role", details.Role},
+				{"status", details.Status},
+				{"version", details.Version},
+				{"kubernetes version", details.KubernetesVersion},
+				{"internal ip", details.InternalIP},
+				{"os", details.OS},
+				{"kernel version", details.KernelVersion},
+				{"container runtime", details.ContainerRuntime},
+				{"taints", strings.Join(details.Taints, "\n")},
+				{"problems", strings.Join(details.Problems, "\n")},
+				{"host preflights", details.Preflights},
+			})
+			for _, issue := range details.PreflightIssues {
+				writer.AppendRow(table.Row{"", issue})
+			}
+			fmt.Printf("%s\n", writer.Render())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// inspectNode returns the details of the named node.
+func inspectNode(ctx context.Context, kcli client.Client, nodeName string) (*nodeDetails, error) {
+	node, err := getNode(ctx, kcli, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &nodeDetails{
+		nodeSummary: nodeSummary{
+			Name:       node.Name,
+			Role:       nodeRole(*node),
+			Version:    nodeVersion(*node),
+			Status:     nodeStatus(*node),
+			Preflights: "Unknown",
+		},
+		KubernetesVersion: node.Status.NodeInfo.KubeletVersion,
+		OS:                node.Status.NodeInfo.OSImage,
+		KernelVersion:     node.Status.NodeInfo.KernelVersion,
+		ContainerRuntime:  node.Status.NodeInfo.ContainerRuntimeVersion,
+		Taints:            []string{},
+		Problems:          []string{},
+		PreflightIssues:   []string{},
+	}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			details.InternalIP = addr.Address
+			break
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		details.Taints = append(details.Taints, taint.ToString())
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type != corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+			details.Problems = append(details.Problems, string(cond.Type))
+		}
+	}
+
+	var cms corev1.ConfigMapList
+	if err := kcli.List(
+		ctx, &cms,
+		client.InNamespace(runtimeconfig.EmbeddedClusterNamespace),
+		client.MatchingLabels{hostPreflightResultLabel: node.Name},
+	); err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("unable to list host preflight results: %w", err)
+	}
+	if len(cms.Items) > 0 {
+		details.Preflights = hostPreflightsStatus(cms.Items[0])
+		details.PreflightIssues = hostPreflightsIssues(cms.Items[0])
+	}
+
+	return details, nil
+}
+
+// hostPreflightsIssues returns the titles of the failed and warning host preflights stored
+// in the ConfigMap.
+func hostPreflightsIssues(cm corev1.ConfigMap) []string {
+	issues := []string{}
+	output, err := preflightstypes.OutputFromReader(strings.NewReader(cm.Data["results.json"]))
+	if err != nil {
+		return issues
+	}
+	for _, record := range output.Fail {
+		issues = append(issues, fmt.Sprintf("Failed: %s", record.Title))
+	}
+	for _, record := range output.Warn {
+		issues = append(issues, fmt.Sprintf("Warning: %s", record.Title))
+	}
+	return issues
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	preflightstypes "github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// hostPreflightResultLabel is set by the operator on the ConfigMaps holding the host
+	// preflight results of each node. The value is the node name.
+	hostPreflightResultLabel = "embedded-cluster/host-preflight-result"
+	controlPlaneLabel        = "node-role.kubernetes.io/control-plane"
+	customRoleLabel          = "kots.io/embedded-cluster-role-0"
+)
+
+// nodeSummary holds what is shown for each node by the node list command.
+type nodeSummary struct {
+	Name       string
+	Role       string
+	Version    string
+	Status     string
+	Preflights string
+}
+
+func NodeListCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the cluster nodes",
+		PreRunE: preRunNode,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			summaries, err := listNodeSummaries(cmd.Context(), kcli)
+			if err != nil {
+				return err
+			}
+
+			writer := table.NewWriter()
+			writer.AppendHeader(table.Row{"name", "role", "version", "status", "host preflights"})
+			for _, s := range summaries {
+				writer.AppendRow(table.Row{s.Name, s.Role, s.Version, s.Status, s.Preflights})
+			}
+			fmt.Printf("%s\n", writer.Render())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// listNodeSummaries returns a summary of every node in the cluster, sorted by name.
+func listNodeSummaries(ctx context.Context, kcli client.Client) ([]nodeSummary, error) {
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := kcli.List(
+		ctx, &cms,
+		client.InNamespace(runtimeconfig.EmbeddedClusterNamespace),
+		client.HasLabels{hostPreflightResultLabel},
+	); err != nil {
+		return nil, fmt.Errorf("unable to list host preflight results: %w", err)
+	}
+	preflightsByNode := map[string]corev1.ConfigMap{}
+	for _, cm := range cms.Items {
+		preflightsByNode[cm.Labels[hostPreflightResultLabel]] = cm
+	}
+
+	summaries := []nodeSummary{}
+	for _, node := range nodes.Items {
+		summary := nodeSummary{
+			Name:       node.Name,
+			Role:       nodeRole(node),
+			Version:    nodeVersion(node),
+			Status:     nodeStatus(node),
+			Preflights: "Unknown",
+		}
+		if cm, ok := preflightsByNode[node.Name]; ok {
+			summary.Preflights = hostPreflightsStatus(cm)
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries, nil
+}
+
+func isControllerNode(node corev1.Node) bool {
+	return node.Labels[controlPlaneLabel] == "true"
+}
+
+// nodeRole returns the k0s role of the node followed by the custom role name, if any.
+func nodeRole(node corev1.Node) string {
+	role := "worker"
+	if isControllerNode(node) {
+		role = "controller"
+	}
+	if custom := node.Labels[customRoleLabel]; custom != "" && custom != role {
+		return fmt.Sprintf("%s (%s)", role, custom)
+	}
+	return role
+}
+
+// nodeVersion returns the embedded cluster version the node runs, as recorded by the operator.
+func nodeVersion(node corev1.Node) string {
+	if version := node.Annotations[kubeutils.NodeVersionAnnotation]; version != "" {
+		return version
+	}
+	return "Unknown"
+}
+
+// nodeStatus returns the readiness of the node the same way kubectl does.
+func nodeStatus(node corev1.Node) string {
+	status := "Unknown"
+	for _, cond := range node.Status.Conditions {
+		if cond.Type != corev1.NodeReady {
+			continue
+		}
+		if cond.Status == corev1.ConditionTrue {
+			status = "Ready"
+		} else {
+			status = "NotReady"
+		}
+	}
+	if node.Spec.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	return status
+}
+
+// hostPreflightsStatus summarizes the host preflight results stored in the ConfigMap.
+func hostPreflightsStatus(cm corev1.ConfigMap) string {
+	data, ok := cm.Data["results.json"]
+	if !ok {
+		return "Unknown"
+	}
+	output, err := preflightstypes.OutputFromReader(strings.NewReader(data))
+	if err != nil {
+		return "Unknown"
+	}
+	if output.HasFail() {
+		return fmt.Sprintf("Failed (%d)", len(output.Fail))
+	}
+	if output.HasWarn() {
+		return fmt.Sprintf("Warnings (%d)", len(output.Warn))
+	}
+	return "Passed"
+}
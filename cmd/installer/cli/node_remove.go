@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	autopilot "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func NodeRemoveCmd(ctx context.Context, name string) *cobra.Command {
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:     "remove NODE",
+		Short:   "Remove a node from the cluster from another controller node",
+		Long:    fmt.Sprintf("Remove a node from the cluster. This is meant for nodes that can no longer be reached, to remove the current node use '%s reset' instead.", name),
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunNode,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			nodeName := args[0]
+
+			hostname, err := os.Hostname()
+			if err != nil {
+				return fmt.Errorf("unable to get hostname: %w", err)
+			}
+			if hostname == nodeName {
+				return fmt.Errorf("can not remove the current node, use '%s reset' instead", name)
+			}
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			node, err := getNode(ctx, kcli, nodeName)
+			if err != nil {
+				return err
+			}
+
+			isController := isControllerNode(*node)
+			if isController {
+				ncps, err := kubeutils.NumOfControlPlaneNodes(ctx, kcli)
+				if err != nil {
+					return fmt.Errorf("unable to check control plane nodes: %w", err)
+				}
+				if ncps <= 1 {
+					return fmt.Errorf("can not remove the last controller node")
+				}
+				if ncps == 3 {
+					logrus.Warn("WARNING: High-availability clusters must maintain at least three controller nodes, but removing this node will leave only two. You should add a third controller node as soon as possible.")
+					logrus.Info("")
+				}
+			}
+
+			in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to get latest installation: %w", err)
+			}
+			isOpenEBS := in.Spec.Storage.IsOpenEBS()
+
+			if isOpenEBS {
+				logrus.Infof("This will remove node %s from the cluster. Data stored in OpenEBS volumes on that node will be lost.", nodeName)
+			} else {
+				logrus.Infof("This will remove node %s from the cluster.", nodeName)
+			}
+			if !assumeYes && !prompts.New().Confirm("Do you want to continue?", false) {
+				return fmt.Errorf("Aborting")
+			}
+
+			return removeNode(ctx, kcli, nodeName, isController, isOpenEBS)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+
+	return cmd
+}
+
+// removeNode deletes the node from the cluster. For controllers the ControlNode object
+// and the etcd member are removed as well. If the cluster storage is provided by OpenEBS, the
+// volumes pinned to the node are cleaned up so the stateful pods using them can be rescheduled.
+func removeNode(ctx context.Context, kcli client.Client, nodeName string, isController, isOpenEBS bool) error {
+	logrus.Infof("Removing node %s from the cluster...", nodeName)
+	if err := deleteNodeObject(ctx, kcli, nodeName); err != nil {
+		return err
+	}
+
+	if isController {
+		logrus.Infof("Removing controller %s...", nodeName)
+		controlNode := &autopilot.ControlNode{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		if err := kcli.Delete(ctx, controlNode); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("unable to delete ControlNode: %w", err)
+		}
+
+		logrus.Infof("Removing etcd member %s...", nodeName)
+		if err := removeEtcdMember(nodeName); err != nil {
+			return err
+		}
+	}
+
+	if isOpenEBS {
+		logrus.Info("Cleaning up OpenEBS volumes...")
+		if err := openebs.CleanupStatefulPods(ctx, kcli); err != nil {
+			return fmt.Errorf("unable to clean up openebs volumes: %w", err)
+		}
+	}
+
+	logrus.Infof("Node %s removed", nodeName)
+	return nil
+}
+
+func deleteNodeObject(ctx context.Context, kcli client.Client, nodeName string) error {
+	node, err := getNode(ctx, kcli, nodeName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := kcli.Delete(ctx, node); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+// removeEtcdMember removes the etcd member with the given name from the etcd cluster.
+func removeEtcdMember(nodeName string) error {
+	out, err := helpers.RunCommand(k0sBinPath, "etcd", "member-list")
+	if err != nil {
+		return fmt.Errorf("unable to list etcd members: %w", err)
+	}
+
+	peerAddress, err := etcdPeerAddress(out, nodeName)
+	if err != nil {
+		return err
+	}
+	if peerAddress == "" {
+		logrus.Debugf("node %s is not an etcd member", nodeName)
+		return nil
+	}
+
+	out, err = helpers.RunCommand(k0sBinPath, "etcd", "leave", "--peer-address", peerAddress)
+	if err != nil {
+		return fmt.Errorf("unable to remove etcd member: %w, %s", err, out)
+	}
+	return nil
+}
+
+// etcdPeerAddress returns the peer address of the named member from the output of
+// 'k0s etcd member-list'. An empty string is returned if there is no such member.
+func etcdPeerAddress(memberList string, nodeName string) (string, error) {
+	members := etcdMembers{}
+	if err := json.Unmarshal([]byte(memberList), &members); err != nil {
+		return "", fmt.Errorf("unable to parse etcd members: %w", err)
+	}
+	peerURL, ok := members.Members[nodeName]
+	if !ok {
+		return "", nil
+	}
+	u, err := url.Parse(peerURL)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse etcd peer url %q: %w", peerURL, err)
+	}
+	return u.Hostname(), nil
+}
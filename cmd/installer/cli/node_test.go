@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_listNodeSummaries(t *testing.T) {
+	newNode := func(name string, labels map[string]string, ready corev1.ConditionStatus, unschedulable bool) *corev1.Node {
+		annotations := map[string]string{}
+		if name != "node-d" {
+			annotations[kubeutils.NodeVersionAnnotation] = "2.1.0+k8s-1.30"
+		}
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "2.1.0+k8s-1.30"},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		}
+	}
+	newPreflightCM := func(node string, results string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      node + "-host-preflight-results",
+				Namespace: "embedded-cluster",
+				Labels:    map[string]string{hostPreflightResultLabel: node},
+			},
+			Data: map[string]string{"results.json": results},
+		}
+	}
+
+	kcli := fake.NewClientBuilder().WithObjects(
+		newNode("node-b", map[string]string{controlPlaneLabel: "true", customRoleLabel: "management"}, corev1.ConditionTrue, false),
+		newNode("node-a", map[string]string{controlPlaneLabel: "true"}, corev1.ConditionTrue, true),
+		newNode("node-c", nil, corev1.ConditionFalse, false),
+		newNode("node-d", nil, corev1.ConditionTrue, false),
+		newPreflightCM("node-a", `{"pass":[{"title":"ok"}]}`),
+		newPreflightCM("node-b", `{"warn":[{"title":"w1"},{"title":"w2"}]}`),
+		newPreflightCM("node-c", `{"fail":[{"title":"f1"}],"warn":[{"title":"w1"}]}`),
+	).Build()
+
+	got, err := listNodeSummaries(context.Background(), kcli)
+	require.NoError(t, err)
+	assert.Equal(t, []nodeSummary{
+		{Name: "node-a", Role: "controller", Version: "2.1.0+k8s-1.30", Status: "Ready,SchedulingDisabled", Preflights: "Passed"},
+		{Name: "node-b", Role: "controller (management)", Version: "2.1.0+k8s-1.30", Status: "Ready", Preflights: "Warnings (2)"},
+		{Name: "node-c", Role: "worker", Version: "2.1.0+k8s-1.30", Status: "NotReady", Preflights: "Failed (1)"},
+		{Name: "node-d", Role: "worker", Version: "Unknown", Status: "Ready", Preflights: "Unknown"},
+	}, got)
+}
+
+func Test_inspectNode(t *testing.T) {
+	kcli := fake.NewClientBuilder().WithObjects(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "node-a",
+				Labels:      map[string]string{controlPlaneLabel: "true"},
+				Annotations: map[string]string{kubeutils.NodeVersionAnnotation: "2.1.0+k8s-1.30"},
+			},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{{Key: "node-role.kubernetes.io/master", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{
+					KubeletVersion:          "v1.30.5+k0s",
+					OSImage:                 "Ubuntu 22.04.4 LTS",
+					KernelVersion:           "5.15.0-1057",
+					ContainerRuntimeVersion: "containerd://1.7.22",
+				},
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeHostName, Address: "node-a"},
+					{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+				},
+				Conditions: []corev1.NodeCondition{
+					{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+					{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+					{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+				},
+			},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "node-a-host-preflight-results",
+				Namespace: "embedded-cluster",
+				Labels:    map[string]string{hostPreflightResultLabel: "node-a"},
+			},
+			Data: map[string]string{"results.json": `{"fail":[{"title":"f1"}],"warn":[{"title":"w1"}]}`},
+		},
+	).Build()
+	ctx := context.Background()
+
+	got, err := inspectNode(ctx, kcli, "node-a")
+	require.NoError(t, err)
+	assert.Equal(t, &nodeDetails{
+		nodeSummary: nodeSummary{
+			Name: "node-a", Role: "controller", Version: "2.1.0+k8s-1.30", Status: "Ready", Preflights: "Failed (1)",
+		},
+		KubernetesVersion: "v1.30.5+k0s",
+		InternalIP:        "10.0.0.1",
+		OS:                "Ubuntu 22.04.4 LTS",
+		KernelVersion:     "5.15.0-1057",
+		ContainerRuntime:  "containerd://1.7.22",
+		Taints:            []string{"node-role.kubernetes.io/master:NoSchedule"},
+		Problems:          []string{"DiskPressure"},
+		PreflightIssues:   []string{"Failed: f1", "Warning: w1"},
+	}, got)
+
+	// the preflight results of other nodes are not used
+	got, err = inspectNode(ctx, kcli, "node-b")
+	require.NoError(t, err)
+	assert.Equal(t, "Unknown", got.Version)
+	assert.Equal(t, "Unknown", got.Preflights)
+	assert.Empty(t, got.PreflightIssues)
+
+	_, err = inspectNode(ctx, kcli, "missing")
+	assert.Error(t, err)
+}
+
+func Test_setNodeUnschedulable(t *testing.T) {
+	kcli := fake.NewClientBuilder().WithObjects(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}},
+	).Build()
+	ctx := context.Background()
+
+	require.NoError(t, setNodeUnschedulable(ctx, kcli, "node", true))
+	var node corev1.Node
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Name: "node"}, &node))
+	assert.True(t, node.Spec.Unschedulable)
+
+	require.NoError(t, setNodeUnschedulable(ctx, kcli, "node", false))
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Name: "node"}, &node))
+	assert.False(t, node.Spec.Unschedulable)
+
+	assert.Error(t, setNodeUnschedulable(ctx, kcli, "missing", true))
+}
+
+func Test_etcdPeerAddress(t *testing.T) {
+	members := `{"members":{"node-a":"https://10.0.0.1:2380","node-b":"https://10.0.0.2:2380"}}`
+
+	got, err := etcdPeerAddress(members, "node-b")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", got)
+
+	got, err = etcdPeerAddress(members, "node-c")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = etcdPeerAddress("not json", "node-a")
+	assert.Error(t, err)
+}
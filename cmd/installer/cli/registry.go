@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/spf13/cobra"
+)
+
+func RegistryCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "registry",
+		Short: "Manage the registry of air gap installations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			os.Exit(1)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(RegistryGCCmd(ctx, name))
+	cmd.AddCommand(RegistryRotateCredentialsCmd(ctx, name))
+
+	return cmd
+}
+
+func RegistryGCCmd(ctx context.Context, name string) *cobra.Command {
+	var dryRun bool
+	var keep int
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the images no longer used from the registry",
+		Long: fmt.Sprintf(`Remove the images no longer used from the registry of an air gap installation.
+
+The images of the current installation and of the previous ones kept for rollbacks are kept, as
+are the images used by the workloads running in the cluster. The %s operator runs this garbage
+collection on a schedule.`, name),
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getuid() != 0 {
+				return fmt.Errorf("registry gc command must be run as root")
+			}
+
+			rcutil.InitBestRuntimeConfig(cmd.Context())
+			os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			installs, err := kubeutils.ListInstallations(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to list installations: %w", err)
+			}
+			if len(installs) == 0 {
+				return fmt.Errorf("no installation found")
+			}
+			current := &installs[0]
+			if !current.Spec.AirGap {
+				return fmt.Errorf("registry gc is only supported in air gap installations")
+			}
+			if !cmd.Flags().Changed("keep-previous-releases") {
+				keep = registry.KeepPreviousReleases(current)
+			}
+
+			reg, err := registry.NewClient(ctx, kcli, current)
+			if err != nil {
+				return fmt.Errorf("unable to connect to the registry: %w", err)
+			}
+
+			loading := spinner.Start()
+			loading.Infof("Looking for unused images")
+			plan, err := registry.BuildPlan(ctx, kcli, reg, installs, keep)
+			if err != nil {
+				loading.CloseWithError()
+				return fmt.Errorf("unable to plan registry garbage collection: %w", err)
+			}
+			if len(plan.Manifests) == 0 {
+				loading.Closef("No unused images found in the registry")
+				return nil
+			}
+			if dryRun {
+				loading.Closef("Found %d unused images", len(plan.Manifests))
+				printRegistryGCPlan(plan)
+				return nil
+			}
+
+			loading.Infof("Removing %d unused images", len(plan.Manifests))
+			if err := registry.Apply(ctx, kcli, reg, plan); err != nil {
+				loading.CloseWithError()
+				return fmt.Errorf("unable to garbage collect registry: %w", err)
+			}
+			loading.Closef("Removed %d unused images, freeing %s", len(plan.Manifests), plan.FreedSize())
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the images that would be removed and the space that would be freed")
+	cmd.Flags().IntVar(&keep, "keep-previous-releases", registry.DefaultKeepPreviousReleases, "Number of previous installations whose images are kept. Defaults to the installation setting.")
+
+	return cmd
+}
+
+func RegistryRotateCredentialsCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-credentials",
+		Short: "Rotate the credentials of the registry",
+		Long: `Rotate the credentials of the registry of an air gap installation.
+
+A new password is generated and set both in the registry and in all the image pull secrets
+holding credentials for it. If one of the secrets can not be updated, the ones already updated
+are restored. The registry is then restarted to use the new password.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getuid() != 0 {
+				return fmt.Errorf("registry rotate-credentials command must be run as root")
+			}
+
+			rcutil.InitBestRuntimeConfig(cmd.Context())
+			os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to get installation: %w", err)
+			}
+			if !in.Spec.AirGap {
+				return fmt.Errorf("registry rotate-credentials is only supported in air gap installations")
+			}
+
+			loading := spinner.Start()
+			loading.Infof("Rotating registry credentials")
+			updated, err := registry.RotateCredentials(ctx, kcli, in, helpers.RandString(20))
+			if err != nil {
+				loading.CloseWithError()
+				return fmt.Errorf("unable to rotate registry credentials: %w", err)
+			}
+			loading.Closef("Registry credentials rotated")
+
+			for _, secret := range updated {
+				fmt.Printf("Updated image pull secret %s\n", secret)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func printRegistryGCPlan(plan *registry.Plan) {
+	writer := table.NewWriter()
+	writer.AppendHeader(table.Row{"repository", "tags", "digest"})
+	for _, manifest := range plan.Manifests {
+		tags := "-"
+		if len(manifest.Tags) > 0 {
+			tags = strings.Join(manifest.Tags, ", ")
+		}
+		writer.AppendRow(table.Row{manifest.Repository, tags, manifest.Descriptor.Digest})
+	}
+	fmt.Printf("%s\n\n", writer.Render())
+	fmt.Printf("Images of installations kept: %s\n", strings.Join(plan.Kept, ", "))
+	fmt.Printf("Space that would be freed: %s\n", plan.FreedSize())
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
@@ -28,9 +29,40 @@ type apiChannelRelease struct {
 }
 
 func getCurrentAppChannelRelease(ctx context.Context, license *kotsv1beta1.License, channelID string) (*apiChannelRelease, error) {
+	// sending an empty string will return the latest channel release
+	releases, err := getPendingAppChannelReleases(ctx, license, channelID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(releases) == 0 {
+		return nil, errors.New("no app releases found")
+	}
+
+	return &releases[0], nil
+}
+
+// getAppChannelReleaseBySequence returns the release with the provided channel sequence.
+func getAppChannelReleaseBySequence(ctx context.Context, license *kotsv1beta1.License, channelID string, channelSequence int64) (*apiChannelRelease, error) {
+	// the api returns the releases newer than the provided channel sequence
+	releases, err := getPendingAppChannelReleases(ctx, license, channelID, strconv.FormatInt(channelSequence-1, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, release := range releases {
+		if release.ChannelSequence == channelSequence {
+			return &release, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no app release found with channel sequence %d", channelSequence)
+}
+
+func getPendingAppChannelReleases(ctx context.Context, license *kotsv1beta1.License, channelID string, channelSequence string) ([]apiChannelRelease, error) {
 	query := url.Values{}
 	query.Set("selectedChannelId", channelID)
-	query.Set("channelSequence", "") // sending an empty string will return the latest channel release
+	query.Set("channelSequence", channelSequence)
 	query.Set("isSemverSupported", "true")
 
 	apiURL := metrics.BaseURL(license)
@@ -64,9 +96,5 @@ func getCurrentAppChannelRelease(ctx context.Context, license *kotsv1beta1.Licen
 		return nil, fmt.Errorf("decode pending app releases: %w", err)
 	}
 
-	if len(releases.ChannelReleases) == 0 {
-		return nil, errors.New("no app releases found")
-	}
-
-	return &releases.ChannelReleases[0], nil
+	return releases.ChannelReleases, nil
 }
@@ -92,3 +92,38 @@ func Test_getCurrentAppChannelRelease(t *testing.T) {
 		})
 	}
 }
+
+func Test_getAppChannelReleaseBySequence(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/release/app-slug/pending" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		// releases newer than the provided channel sequence are returned
+		if r.URL.Query().Get("channelSequence") != "4" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"channelReleases": []}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"channelReleases": [
+			{"channelId": "channel-id", "channelSequence": 7, "versionLabel": "1.0.7"},
+			{"channelId": "channel-id", "channelSequence": 5, "versionLabel": "1.0.5"}
+		]}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	license := &kotsv1beta1.License{
+		Spec: kotsv1beta1.LicenseSpec{
+			LicenseID: "license-id",
+			AppSlug:   "app-slug",
+			Endpoint:  ts.URL,
+		},
+	}
+
+	got, err := getAppChannelReleaseBySequence(context.Background(), license, "channel-id", 5)
+	require.NoError(t, err)
+	assert.Equal(t, &apiChannelRelease{ChannelID: "channel-id", ChannelSequence: 5, VersionLabel: "1.0.5"}, got)
+
+	_, err = getAppChannelReleaseBySequence(context.Background(), license, "channel-id", 6)
+	assert.Error(t, err)
+}
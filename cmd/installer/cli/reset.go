@@ -42,11 +42,24 @@ type hostInfo struct {
 	RoleName         string
 }
 
+type resetOptions struct {
+	force     bool
+	assumeYes bool
+	plan      bool
+	noReboot  bool
+	keepData  bool
+}
+
+// resetAction is a single step of a reset. Errors from actions flagged as promptOnError
+// are handled by checkErrPrompt, others abort the reset.
+type resetAction struct {
+	description   string
+	promptOnError bool
+	run           func(ctx context.Context) error
+}
+
 func ResetCmd(ctx context.Context, name string) *cobra.Command {
-	var (
-		force     bool
-		assumeYes bool
-	)
+	var opts resetOptions
 
 	cmd := &cobra.Command{
 		Use:   "reset",
@@ -66,26 +79,40 @@ func ResetCmd(ctx context.Context, name string) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
-			if err := maybePrintHAWarning(ctx); err != nil && !force {
+			if err := maybePrintHAWarning(ctx); err != nil && !opts.force {
 				return err
 			}
 
-			logrus.Info("This will remove this node from the cluster and completely reset it, removing all data stored on the node.")
-			logrus.Info("This node will also reboot. Do not reset another node until this is complete.")
-			if !force && !assumeYes && !prompts.New().Confirm("Do you want to continue?", false) {
+			if opts.plan {
+				// populate options struct with host information, errors are ignored as
+				// we only want to know what would be done.
+				currentHost, _ := newHostInfo(ctx)
+				printResetPlan(ctx, currentHost, opts)
+				return nil
+			}
+
+			if opts.keepData {
+				logrus.Info("This will remove this node from the cluster and reset it. OpenEBS volumes stored on the node will be kept.")
+			} else {
+				logrus.Info("This will remove this node from the cluster and completely reset it, removing all data stored on the node.")
+			}
+			if !opts.noReboot {
+				logrus.Info("This node will also reboot. Do not reset another node until this is complete.")
+			}
+			if !opts.force && !opts.assumeYes && !prompts.New().Confirm("Do you want to continue?", false) {
 				return fmt.Errorf("Aborting")
 			}
 
 			// populate options struct with host information
 			currentHost, err := newHostInfo(ctx)
-			if !checkErrPrompt(assumeYes, force, err) {
+			if !checkErrPrompt(opts.assumeYes, opts.force, err) {
 				return err
 			}
 
 			// basic check to see if it's safe to remove this node from the cluster
 			if currentHost.Status.Role == "controller" {
-				safeToRemove, reason, err := currentHost.checkResetSafety(ctx, force)
-				if !checkErrPrompt(assumeYes, force, err) {
+				safeToRemove, reason, err := currentHost.checkResetSafety(ctx, opts.force)
+				if !checkErrPrompt(opts.assumeYes, opts.force, err) {
 					return err
 				}
 				if !safeToRemove {
@@ -93,128 +120,218 @@ func ResetCmd(ctx context.Context, name string) *cobra.Command {
 				}
 			}
 
-			var numControllerNodes int
-			if currentHost.KclientError == nil {
-				numControllerNodes, _ = kubeutils.NumOfControlPlaneNodes(ctx, currentHost.Kclient)
-			}
-			// do not drain node if this is the only controller node in the cluster
-			// if there is an error (numControllerNodes == 0), drain anyway to be safe
-			if currentHost.Status.Role != "controller" || numControllerNodes != 1 {
-				logrus.Info("Draining node...")
-				err = currentHost.drainNode()
-				if !checkErrPrompt(assumeYes, force, err) {
+			for _, action := range resetActions(ctx, &currentHost, opts) {
+				logrus.Debugf("reset: %s", action.description)
+				err := action.run(ctx)
+				if action.promptOnError {
+					if !checkErrPrompt(opts.assumeYes, opts.force, err) {
+						return err
+					}
+				} else if err != nil {
 					return err
 				}
+			}
 
-				// remove node from cluster
-				logrus.Info("Removing node from cluster...")
-				removeCtx, removeCancel := context.WithTimeout(ctx, time.Minute)
-				defer removeCancel()
-				err = currentHost.deleteNode(removeCtx)
-				if !checkErrPrompt(assumeYes, force, err) {
-					return err
-				}
+			if opts.noReboot {
+				logrus.Info("Node has been reset.")
+			}
+
+			return nil
+		},
+	}
 
-				// controller pre-reset
-				if currentHost.Status.Role == "controller" {
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Ignore errors encountered when resetting the node (implies ---yes)")
+	cmd.Flags().BoolVar(&opts.assumeYes, "yes", false, "Assume yes to all prompts.")
+	cmd.Flags().BoolVar(&opts.plan, "plan", false, "Print the actions the reset would take without performing them")
+	cmd.Flags().BoolVar(&opts.noReboot, "no-reboot", false, "Do not reboot the node, clean up mounts, network interfaces, iptables rules and kernel modules instead")
+	cmd.Flags().BoolVar(&opts.keepData, "keep-data", false, "Keep the OpenEBS volumes stored on the node")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
 
-					// delete controlNode object from cluster
-					deleteControlCtx, deleteCancel := context.WithTimeout(ctx, time.Minute)
-					defer deleteCancel()
-					err := currentHost.deleteControlNode(deleteControlCtx)
-					if !checkErrPrompt(assumeYes, force, err) {
-						return err
-					}
+	return cmd
+}
 
-					// try and leave etcd cluster
-					err = currentHost.leaveEtcdcluster()
-					if !checkErrPrompt(assumeYes, force, err) {
-						return err
-					}
+// printResetPlan prints every action reset would take on this node.
+func printResetPlan(ctx context.Context, currentHost hostInfo, opts resetOptions) {
+	logrus.Info("The following actions would be taken to reset this node:")
+	for i, action := range resetActions(ctx, &currentHost, opts) {
+		logrus.Infof("  %d. %s", i+1, action.description)
+	}
+}
 
-				}
-			}
+// resetActions returns, in order, the actions needed to reset the current host.
+func resetActions(ctx context.Context, currentHost *hostInfo, opts resetOptions) []resetAction {
+	actions := []resetAction{}
+
+	var numControllerNodes int
+	if currentHost.KclientError == nil && currentHost.Kclient != nil {
+		numControllerNodes, _ = kubeutils.NumOfControlPlaneNodes(ctx, currentHost.Kclient)
+	}
+	// do not drain node if this is the only controller node in the cluster
+	// if there is an error (numControllerNodes == 0), drain anyway to be safe
+	if currentHost.Status.Role != "controller" || numControllerNodes != 1 {
+		actions = append(actions,
+			resetAction{
+				description:   fmt.Sprintf("Drain node %s", currentHost.Hostname),
+				promptOnError: true,
+				run: func(ctx context.Context) error {
+					logrus.Info("Draining node...")
+					return currentHost.drainNode()
+				},
+			},
+			resetAction{
+				description:   fmt.Sprintf("Remove node %s from the cluster", currentHost.Hostname),
+				promptOnError: true,
+				run: func(ctx context.Context) error {
+					logrus.Info("Removing node from cluster...")
+					removeCtx, removeCancel := context.WithTimeout(ctx, time.Minute)
+					defer removeCancel()
+					return currentHost.deleteNode(removeCtx)
+				},
+			},
+		)
+
+		// controller pre-reset
+		if currentHost.Status.Role == "controller" {
+			actions = append(actions,
+				resetAction{
+					description:   fmt.Sprintf("Delete ControlNode %s", currentHost.Hostname),
+					promptOnError: true,
+					run: func(ctx context.Context) error {
+						deleteControlCtx, deleteCancel := context.WithTimeout(ctx, time.Minute)
+						defer deleteCancel()
+						return currentHost.deleteControlNode(deleteControlCtx)
+					},
+				},
+				resetAction{
+					description:   "Leave the etcd cluster",
+					promptOnError: true,
+					run: func(ctx context.Context) error {
+						return currentHost.leaveEtcdcluster()
+					},
+				},
+			)
+		}
+	}
 
-			// reset
+	actions = append(actions, resetAction{
+		description:   fmt.Sprintf("Stop and reset k0s (data dir %s)", runtimeconfig.EmbeddedClusterK0sSubDir()),
+		promptOnError: true,
+		run: func(ctx context.Context) error {
 			logrus.Infof("Resetting node...")
-			err = stopAndResetK0s(runtimeconfig.EmbeddedClusterK0sSubDir())
-			if !checkErrPrompt(assumeYes, force, err) {
-				return err
+			return stopAndResetK0s(runtimeconfig.EmbeddedClusterK0sSubDir())
+		},
+	})
+
+	// the pod volumes, including the bind mounts of the OpenEBS volumes, are still mounted once
+	// k0s is reset. They are unmounted before any directory is removed, otherwise the removal
+	// fails or deletes the data of the volumes through the mounts.
+	actions = append(actions, resetAction{
+		description:   "Unmount leftover mounts",
+		promptOnError: true,
+		run: func(ctx context.Context) error {
+			if err := unmountLeftovers(); err != nil {
+				return fmt.Errorf("failed to unmount leftover mounts: %w", err)
 			}
+			return nil
+		},
+	})
 
-			if err := helpers.RemoveAll(runtimeconfig.PathToK0sConfig()); err != nil {
-				return fmt.Errorf("failed to remove k0s config: %w", err)
-			}
+	actions = append(actions, removePathAction(runtimeconfig.PathToK0sConfig(), "k0s config"))
 
-			lamPath := "/etc/systemd/system/local-artifact-mirror.service"
+	lamPath := "/etc/systemd/system/local-artifact-mirror.service"
+	actions = append(actions, resetAction{
+		description: "Stop the local-artifact-mirror service",
+		run: func(ctx context.Context) error {
 			if _, err := os.Stat(lamPath); err == nil {
 				if _, err := helpers.RunCommand("systemctl", "stop", "local-artifact-mirror"); err != nil {
 					return err
 				}
 			}
-			if err := helpers.RemoveAll(lamPath); err != nil {
-				return fmt.Errorf("failed to remove local-artifact-mirror service file: %w", err)
-			}
-
-			lamPathD := "/etc/systemd/system/local-artifact-mirror.service.d"
-			if err := helpers.RemoveAll(lamPathD); err != nil {
-				return fmt.Errorf("failed to remove local-artifact-mirror config directory: %w", err)
-			}
-
-			proxyControllerPath := "/etc/systemd/system/k0scontroller.service.d"
-			if err := helpers.RemoveAll(proxyControllerPath); err != nil {
-				return fmt.Errorf("failed to remove proxy controller config directory: %w", err)
-			}
-
-			proxyWorkerPath := "/etc/systemd/system/k0sworker.service.d"
-			if err := helpers.RemoveAll(proxyWorkerPath); err != nil {
-				return fmt.Errorf("failed to remove proxy worker config directory: %w", err)
-			}
-
-			// Now that k0s is nested under the data directory, we see the following error in the
-			// dev environment because k0s is mounted in the docker container:
-			//  "failed to remove embedded cluster directory: remove k0s: unlinkat /var/lib/embedded-cluster/k0s: device or resource busy"
-			if err := helpers.RemoveAll(runtimeconfig.EmbeddedClusterHomeDirectory()); err != nil {
-				logrus.Debugf("Failed to remove embedded cluster directory: %v", err)
-			}
-
-			if err := helpers.RemoveAll(runtimeconfig.EmbeddedClusterLogsSubDir()); err != nil {
-				return fmt.Errorf("failed to remove logs directory: %w", err)
-			}
-
-			if err := helpers.RemoveAll(runtimeconfig.EmbeddedClusterOpenEBSLocalSubDir()); err != nil {
-				return fmt.Errorf("failed to remove openebs storage: %w", err)
-			}
+			return nil
+		},
+	})
+	actions = append(actions,
+		removePathAction(lamPath, "local-artifact-mirror service file"),
+		removePathAction("/etc/systemd/system/local-artifact-mirror.service.d", "local-artifact-mirror config directory"),
+		removePathAction("/etc/systemd/system/k0scontroller.service.d", "proxy controller config directory"),
+		removePathAction("/etc/systemd/system/k0sworker.service.d", "proxy worker config directory"),
+	)
 
-			if err := helpers.RemoveAll("/etc/NetworkManager/conf.d/embedded-cluster.conf"); err != nil {
-				return fmt.Errorf("failed to remove NetworkManager configuration: %w", err)
-			}
+	homeDir := runtimeconfig.EmbeddedClusterHomeDirectory()
+	openEBSDir := runtimeconfig.EmbeddedClusterOpenEBSLocalSubDir()
+	if opts.keepData {
+		actions = append(actions, resetAction{
+			description: fmt.Sprintf("Remove %s, keeping %s", homeDir, openEBSDir),
+			run: func(ctx context.Context) error {
+				if err := removeAllExcept(homeDir, openEBSDir); err != nil {
+					logrus.Debugf("Failed to remove embedded cluster directory: %v", err)
+				}
+				return nil
+			},
+		})
+	} else {
+		actions = append(actions, resetAction{
+			description: fmt.Sprintf("Remove %s", homeDir),
+			run: func(ctx context.Context) error {
+				// Now that k0s is nested under the data directory, we see the following error in the
+				// dev environment because k0s is mounted in the docker container:
+				//  "failed to remove embedded cluster directory: remove k0s: unlinkat /var/lib/embedded-cluster/k0s: device or resource busy"
+				if err := helpers.RemoveAll(homeDir); err != nil {
+					logrus.Debugf("Failed to remove embedded cluster directory: %v", err)
+				}
+				return nil
+			},
+		})
+	}
 
-			if err := helpers.RemoveAll("/usr/local/bin/k0s"); err != nil {
-				return fmt.Errorf("failed to remove k0s binary: %w", err)
-			}
+	actions = append(actions, removePathAction(runtimeconfig.EmbeddedClusterLogsSubDir(), "logs directory"))
+	if !opts.keepData {
+		actions = append(actions, removePathAction(openEBSDir, "openebs storage"))
+	}
+	actions = append(actions,
+		removePathAction("/etc/NetworkManager/conf.d/embedded-cluster.conf", "NetworkManager configuration"),
+		removePathAction(k0sBinPath, "k0s binary"),
+		removePathAction(runtimeconfig.PathToECConfig(), "embedded cluster data config"),
+		removePathAction("/etc/sysctl.d/99-embedded-cluster.conf", "embedded cluster sysctl config"),
+		removePathAction("/etc/modules-load.d/99-embedded-cluster.conf", "embedded cluster kernel modules config"),
+	)
 
-			if err := helpers.RemoveAll(runtimeconfig.PathToECConfig()); err != nil {
-				return fmt.Errorf("failed to remove embedded cluster data config: %w", err)
-			}
+	if opts.noReboot {
+		actions = append(actions, resetAction{
+			description: "Delete calico network interfaces, remove kube-proxy and calico iptables rules and unload kernel modules",
+			run: func(ctx context.Context) error {
+				logrus.Info("Cleaning up host...")
+				if err := cleanupHostAfterReset(); err != nil {
+					return fmt.Errorf("failed to clean up host: %w", err)
+				}
+				return nil
+			},
+		})
+	} else {
+		actions = append(actions, resetAction{
+			description: "Reboot the node",
+			run: func(ctx context.Context) error {
+				_, err := helpers.RunCommand("reboot")
+				return err
+			},
+		})
+	}
 
-			if err := helpers.RemoveAll("/etc/sysctl.d/99-embedded-cluster.conf"); err != nil {
-				return fmt.Errorf("failed to remove embedded cluster sysctl config: %w", err)
-			}
+	return actions
+}
 
-			if _, err := helpers.RunCommand("reboot"); err != nil {
-				return err
+// removePathAction returns an action that removes the provided path. What describes the
+// path in error messages.
+func removePathAction(path string, what string) resetAction {
+	return resetAction{
+		description: fmt.Sprintf("Remove %s", path),
+		run: func(ctx context.Context) error {
+			if err := helpers.RemoveAll(path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", what, err)
 			}
-
 			return nil
 		},
 	}
-
-	cmd.Flags().BoolVar(&force, "force", false, "Ignore errors encountered when resetting the node (implies ---yes)")
-	cmd.Flags().BoolVar(&assumeYes, "yes", false, "Assume yes to all prompts.")
-	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
-
-	return cmd
 }
 
 func checkErrPrompt(noPrompt bool, force bool, err error) bool {
@@ -342,13 +459,19 @@ var (
 // drainNode uses k0s to initiate a node drain
 func (h *hostInfo) drainNode() error {
 	os.Setenv("KUBECONFIG", h.Status.Vars.KubeletAuthConfigPath)
+	return drainNodeWithK0s(h.Hostname)
+}
+
+// drainNodeWithK0s uses k0s to drain the named node, using the kubeconfig set in the
+// environment.
+func drainNodeWithK0s(nodeName string) error {
 	drainArgList := []string{
 		"kubectl",
 		"drain",
 		"--ignore-daemonsets",
 		"--delete-emptydir-data",
 		"--timeout", "60s",
-		h.Hostname,
+		nodeName,
 	}
 	out, err := helpers.RunCommand(k0sBinPath, drainArgList...)
 	if err != nil {
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/sirupsen/logrus"
+)
+
+// iptablesChainPrefixes are the prefixes of the chains (and rule comments) created by
+// kube-proxy and calico.
+var iptablesChainPrefixes = []string{"KUBE-", "cali-", "cali:"}
+
+// resetMountPrefixes returns the directories under which mounts left behind by k0s, the
+// kubelet and containerd are looked for.
+func resetMountPrefixes() []string {
+	return []string{
+		runtimeconfig.EmbeddedClusterK0sSubDir(),
+		"/var/lib/kubelet",
+		"/run/k0s",
+		"/run/containerd",
+		"/run/netns",
+	}
+}
+
+// cleanupHostAfterReset removes what is left behind after k0s has been reset and that
+// would otherwise be cleared by a reboot. Leftover mounts are unmounted earlier, before the
+// directories are removed.
+func cleanupHostAfterReset() error {
+	var me helpers.MultiError
+
+	logrus.Debugf("deleting calico interfaces")
+	if err := deleteCalicoInterfaces(); err != nil {
+		me.Add(fmt.Errorf("delete calico interfaces: %w", err))
+	}
+
+	logrus.Debugf("flushing iptables rules")
+	if err := flushIPTablesRules(); err != nil {
+		me.Add(fmt.Errorf("flush iptables rules: %w", err))
+	}
+
+	logrus.Debugf("unloading kernel modules")
+	if err := configutils.UnloadKernelModules(); err != nil {
+		// modules may be in use by something else on the host, this is not fatal.
+		logrus.Debugf("unable to unload kernel modules: %v", err)
+	}
+
+	return me.ErrorOrNil()
+}
+
+// unmountLeftovers lazily unmounts everything mounted under the reset mount prefixes,
+// deepest mounts first.
+func unmountLeftovers() error {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return fmt.Errorf("open mounts: %w", err)
+	}
+	defer f.Close()
+
+	mounts, err := mountsUnder(f, resetMountPrefixes())
+	if err != nil {
+		return fmt.Errorf("read mounts: %w", err)
+	}
+
+	var me helpers.MultiError
+	for _, mount := range mounts {
+		if _, err := helpers.RunCommand("umount", "-l", mount); err != nil {
+			me.Add(fmt.Errorf("umount %s: %w", mount, err))
+		}
+	}
+	return me.ErrorOrNil()
+}
+
+// mountsUnder parses a mounts file (as in /proc/self/mounts) and returns the mount points
+// located under any of the provided prefixes, sorted so that nested mounts come first.
+func mountsUnder(mounts io.Reader, prefixes []string) ([]string, error) {
+	var result []string
+	scanner := bufio.NewScanner(mounts)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// spaces and other special characters are octal escaped in the mounts file.
+		mount := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(fields[1])
+		for _, prefix := range prefixes {
+			prefix = filepath.Clean(prefix)
+			if mount == prefix || strings.HasPrefix(mount, prefix+"/") {
+				result = append(result, mount)
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.Count(result[i], "/") > strings.Count(result[j], "/")
+	})
+	return result, nil
+}
+
+// deleteCalicoInterfaces deletes the network interfaces created by calico.
+func deleteCalicoInterfaces() error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return fmt.Errorf("list interfaces: %w", err)
+	}
+
+	var me helpers.MultiError
+	for _, iface := range ifaces {
+		if !isCalicoInterface(iface.Name) {
+			continue
+		}
+		if _, err := helpers.RunCommand("ip", "link", "delete", iface.Name); err != nil {
+			me.Add(fmt.Errorf("delete interface %s: %w", iface.Name, err))
+		}
+	}
+	return me.ErrorOrNil()
+}
+
+func isCalicoInterface(name string) bool {
+	return strings.HasPrefix(name, "cali") || name == "vxlan.calico" || name == "vxlan-v6.calico"
+}
+
+// flushIPTablesRules removes the iptables chains and rules created by kube-proxy and
+// calico, leaving everything else in place.
+func flushIPTablesRules() error {
+	var me helpers.MultiError
+	for _, bin := range []string{"iptables", "ip6tables"} {
+		if _, err := exec.LookPath(bin + "-save"); err != nil {
+			logrus.Debugf("%s-save not found, skipping", bin)
+			continue
+		}
+		out, err := helpers.RunCommand(bin + "-save")
+		if err != nil {
+			me.Add(fmt.Errorf("%s-save: %w", bin, err))
+			continue
+		}
+		rules := filterIPTablesRules(out)
+		opts := helpers.RunCommandOptions{Stdin: strings.NewReader(rules)}
+		if err := helpers.RunCommandWithOptions(opts, bin+"-restore"); err != nil {
+			me.Add(fmt.Errorf("%s-restore: %w", bin, err))
+		}
+	}
+	return me.ErrorOrNil()
+}
+
+// filterIPTablesRules removes from an iptables-save output all chains and rules that
+// reference chains created by kube-proxy or calico.
+func filterIPTablesRules(rules string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(rules, "\n") {
+		if line == "" {
+			continue
+		}
+		if referencesIPTablesChains(line) {
+			continue
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+func referencesIPTablesChains(line string) bool {
+	for _, prefix := range iptablesChainPrefixes {
+		if strings.Contains(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeAllExcept removes everything inside dir except for the keep path and its parent
+// directories. If keep is not inside dir everything is removed.
+func removeAllExcept(dir string, keep string) error {
+	rel, err := filepath.Rel(dir, keep)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return helpers.RemoveAll(dir)
+	}
+	if rel == "." {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read directory: %w", err)
+	}
+
+	var me helpers.MultiError
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if path == filepath.Clean(keep) {
+			continue
+		}
+		if entry.IsDir() && strings.HasPrefix(keep, path+"/") {
+			if err := removeAllExcept(path, keep); err != nil {
+				me.Add(err)
+			}
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			me.Add(fmt.Errorf("remove %s: %w", entry.Name(), err))
+		}
+	}
+	return me.ErrorOrNil()
+}
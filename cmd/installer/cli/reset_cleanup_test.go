@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mountsUnder(t *testing.T) {
+	mounts := `proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/sda1 / ext4 rw,relatime 0 0
+tmpfs /var/lib/embedded-cluster/k0s/kubelet/pods/abc/volumes/kubernetes.io~projected/kube-api-access tmpfs rw 0 0
+shm /run/containerd/io.containerd.grpc.v1.cri/sandboxes/123/shm tmpfs rw 0 0
+overlay /run/k0s/containerd/io.containerd.runtime.v2.task/k8s.io/123/rootfs overlay rw 0 0
+tmpfs /var/lib/kubelet-other tmpfs rw 0 0
+tmpfs /var/lib/embedded-cluster/k0s/with\040space tmpfs rw 0 0
+`
+	got, err := mountsUnder(strings.NewReader(mounts), []string{
+		"/var/lib/embedded-cluster/k0s",
+		"/var/lib/kubelet",
+		"/run/k0s",
+		"/run/containerd",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/var/lib/embedded-cluster/k0s/kubelet/pods/abc/volumes/kubernetes.io~projected/kube-api-access",
+		"/run/k0s/containerd/io.containerd.runtime.v2.task/k8s.io/123/rootfs",
+		"/run/containerd/io.containerd.grpc.v1.cri/sandboxes/123/shm",
+		"/var/lib/embedded-cluster/k0s/with space",
+	}, got)
+}
+
+func Test_filterIPTablesRules(t *testing.T) {
+	rules := `# Generated by iptables-save
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:KUBE-FIREWALL - [0:0]
+:cali-INPUT - [0:0]
+:DOCKER - [0:0]
+-A INPUT -m comment --comment "cali:Cz_u1IQiXIMmKD4c" -j cali-INPUT
+-A INPUT -j KUBE-FIREWALL
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A FORWARD -j DOCKER
+COMMIT
+`
+	expected := `# Generated by iptables-save
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:DOCKER - [0:0]
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A FORWARD -j DOCKER
+COMMIT
+`
+	assert.Equal(t, expected, filterIPTablesRules(rules))
+}
+
+func Test_removeAllExcept(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{
+		"bin/k0s",
+		"openebs-local/pvc-1/data",
+		"support/host-support-bundle.yaml",
+		"nested/keep/file",
+		"nested/other/file",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, p), []byte("data"), 0644))
+	}
+
+	require.NoError(t, removeAllExcept(dir, filepath.Join(dir, "openebs-local")))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.FileExists(t, filepath.Join(dir, "openebs-local/pvc-1/data"))
+
+	// nested keep path
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested/keep"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested/other"), 0755))
+	require.NoError(t, removeAllExcept(dir, filepath.Join(dir, "nested/keep")))
+	assert.DirExists(t, filepath.Join(dir, "nested/keep"))
+	assert.NoDirExists(t, filepath.Join(dir, "nested/other"))
+	assert.NoDirExists(t, filepath.Join(dir, "openebs-local"))
+
+	// keep path outside of the directory removes everything
+	require.NoError(t, removeAllExcept(dir, "/somewhere/else"))
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func Test_resetActionsUnmountBeforeRemovingDirectories(t *testing.T) {
+	for _, opts := range []resetOptions{
+		{},
+		{noReboot: true},
+		{noReboot: true, keepData: true},
+	} {
+		var descriptions []string
+		for _, action := range resetActions(context.Background(), &hostInfo{Hostname: "node1"}, opts) {
+			descriptions = append(descriptions, action.description)
+		}
+
+		resetK0s := slices.IndexFunc(descriptions, func(d string) bool { return strings.HasPrefix(d, "Stop and reset k0s") })
+		unmount := slices.Index(descriptions, "Unmount leftover mounts")
+		require.NotEqual(t, -1, resetK0s, "reset k0s action not found in %v", descriptions)
+		assert.Equal(t, resetK0s+1, unmount, "leftover mounts must be unmounted right after k0s is reset: %v", descriptions)
+		for _, d := range descriptions[:unmount] {
+			assert.False(t, strings.HasPrefix(d, "Remove "+runtimeconfig.EmbeddedClusterHomeDirectory()), "directory removed before unmounting: %s", d)
+		}
+	}
+}
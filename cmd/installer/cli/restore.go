@@ -21,7 +21,9 @@ import (
 	"github.com/replicatedhq/embedded-cluster/cmd/installer/kotscli"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
 	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/replicatedhq/embedded-cluster/pkg/backupstore"
 	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/constants"
 	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
@@ -64,6 +66,7 @@ const (
 	ecRestoreStateRestoreECO           ecRestoreState = "restore-embedded-cluster-operator"
 	ecRestoreStateRestoreExtensions    ecRestoreState = "restore-extensions"
 	ecRestoreStateRestoreApp           ecRestoreState = "restore-app"
+	ecRestoreStateUpgrade              ecRestoreState = "upgrade"
 )
 
 var ecRestoreStates = []ecRestoreState{
@@ -78,22 +81,55 @@ var ecRestoreStates = []ecRestoreState{
 	ecRestoreStateRestoreECO,
 	ecRestoreStateRestoreExtensions,
 	ecRestoreStateRestoreApp,
+	ecRestoreStateUpgrade,
 }
 
 const (
 	resourceModifiersCMName = "restore-resource-modifiers"
+	// resourceModifiersHACMName holds the restore resource modifiers of the restores into a
+	// running high availability cluster, which keep the admin console replicas.
+	resourceModifiersHACMName = "restore-resource-modifiers-ha"
 )
 
 func RestoreCmd(ctx context.Context, name string) *cobra.Command {
 	var flags InstallCmdFlags
 
 	var s3Store s3BackupStore
+	var backupStoreURL string
 	var skipStoreValidation bool
 
+	var store *backupstore.Store
+
+	var partial partialRestoreFlags
+
 	cmd := &cobra.Command{
 		Use:   "restore",
 		Short: fmt.Sprintf("Restore a %s cluster", name),
+		Long: fmt.Sprintf(`Restore a %s cluster from an instance backup.
+
+By default a new cluster is restored in full. With --component or --namespace, only the given
+component or namespaces are restored into the running cluster instead, after a backup of it is
+taken. Add --replace-volumes to also restore the data of the volumes of the namespaces, their
+workloads and persistent volume claims are then deleted before restoring.`, name),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if partial.isSet() {
+				if backupStoreURL != "" || s3BackupStoreHasAnyData(&s3Store) {
+					return fmt.Errorf("--component and --namespace cannot be used with the backup storage location flags")
+				}
+				return preRunPartialRestore(cmd, partial)
+			}
+
+			if backupStoreURL != "" {
+				if s3BackupStoreHasAnyData(&s3Store) {
+					return fmt.Errorf("--backup-store cannot be used with the s3 flags")
+				}
+				s, err := backupstore.Parse(backupStoreURL)
+				if err != nil {
+					return fmt.Errorf("invalid --backup-store: %w", err)
+				}
+				store = s
+			}
+
 			if err := preRunInstall(cmd, &flags); err != nil {
 				return err
 			}
@@ -104,7 +140,11 @@ func RestoreCmd(ctx context.Context, name string) *cobra.Command {
 			runtimeconfig.Cleanup()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := runRestore(cmd.Context(), name, flags, s3Store, skipStoreValidation); err != nil {
+			if partial.isSet() {
+				return runPartialRestore(cmd.Context(), name, partial, flags.assumeYes)
+			}
+
+			if err := runRestore(cmd.Context(), name, flags, s3Store, store, skipStoreValidation); err != nil {
 				return err
 			}
 
@@ -113,7 +153,9 @@ func RestoreCmd(ctx context.Context, name string) *cobra.Command {
 	}
 
 	addS3Flags(cmd, &s3Store)
+	cmd.Flags().StringVar(&backupStoreURL, "backup-store", "", "Backup storage location not reachable through S3, either a directory on this host (file:///path) or an NFS share (nfs://server/path)")
 	cmd.Flags().BoolVar(&skipStoreValidation, "skip-store-validation", false, "Skip validation of the backup storage location")
+	addPartialRestoreFlags(cmd, &partial)
 
 	if err := addInstallFlags(cmd, &flags); err != nil {
 		panic(err)
@@ -122,7 +164,7 @@ func RestoreCmd(ctx context.Context, name string) *cobra.Command {
 	return cmd
 }
 
-func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store s3BackupStore, skipStoreValidation bool) error {
+func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store s3BackupStore, store *backupstore.Store, skipStoreValidation bool) error {
 	err := verifyChannelRelease("restore", flags.isAirgap, flags.assumeYes)
 	if err != nil {
 		return err
@@ -182,7 +224,7 @@ func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store
 
 	switch state {
 	case ecRestoreStateNew:
-		err = runRestoreStepNew(ctx, name, flags, &s3Store, skipStoreValidation)
+		err = runRestoreStepNew(ctx, name, flags, &s3Store, store, skipStoreValidation)
 		if err != nil {
 			return err
 		}
@@ -227,7 +269,7 @@ func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreAdminConsole(ctx, backupToRestore)
+		err = runRestoreAdminConsole(ctx, flags, backupToRestore)
 		if err != nil {
 			return err
 		}
@@ -330,6 +372,25 @@ func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store
 			return err
 		}
 
+		fallthrough
+
+	case ecRestoreStateUpgrade:
+		logrus.Debugf("setting restore state to %q", ecRestoreStateUpgrade)
+		err := setECRestoreState(ctx, ecRestoreStateUpgrade, backupToRestore.GetName())
+		if err != nil {
+			return fmt.Errorf("unable to set restore state: %w", err)
+		}
+
+		err = runRestoreUpgrade(ctx, flags, backupToRestore)
+		if err != nil {
+			return err
+		}
+
+		logrus.Debugf("resetting restore state")
+		if err := resetECRestoreState(ctx); err != nil {
+			return fmt.Errorf("unable to reset restore state: %w", err)
+		}
+
 	default:
 		return fmt.Errorf("unknown restore state: %q", state)
 	}
@@ -337,25 +398,36 @@ func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store
 	return nil
 }
 
-func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags, s3Store *s3BackupStore, skipStoreValidation bool) error {
+func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags, s3Store *s3BackupStore, store *backupstore.Store, skipStoreValidation bool) error {
 	logrus.Debugf("checking if k0s is already installed")
 	err := verifyNoInstallation(name, "restore")
 	if err != nil {
 		return err
 	}
 
-	if !s3BackupStoreHasData(s3Store) {
-		logrus.Infof("You'll be guided through the process of restoring %s from a backup.\n", name)
-		logrus.Info("Enter information to configure access to your backup storage location.\n")
+	if store != nil {
+		// the store is served through s3 once the cluster is up, only a local directory can
+		// be validated beforehand.
+		if !skipStoreValidation {
+			logrus.Debugf("validating backup store %s", store)
+			if err := store.ValidateLocal(); err != nil {
+				return fmt.Errorf("unable to validate backup store: %w", err)
+			}
+		}
+	} else {
+		if !s3BackupStoreHasData(s3Store) {
+			logrus.Infof("You'll be guided through the process of restoring %s from a backup.\n", name)
+			logrus.Info("Enter information to configure access to your backup storage location.\n")
 
-		promptForS3BackupStore(s3Store)
-	}
-	s3Store.prefix = strings.TrimPrefix(s3Store.prefix, "/")
+			promptForS3BackupStore(s3Store)
+		}
+		s3Store.prefix = strings.TrimPrefix(s3Store.prefix, "/")
 
-	if !skipStoreValidation {
-		logrus.Debugf("validating backup store configuration")
-		if err := validateS3BackupStore(s3Store); err != nil {
-			return fmt.Errorf("unable to validate backup store: %w", err)
+		if !skipStoreValidation {
+			logrus.Debugf("validating backup store configuration")
+			if err := validateS3BackupStore(s3Store); err != nil {
+				return fmt.Errorf("unable to validate backup store: %w", err)
+			}
 		}
 	}
 
@@ -387,7 +459,7 @@ func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags,
 		return fmt.Errorf("unable to run install preflights: %w", err)
 	}
 
-	_, err = installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.overrides, nil)
+	_, err = installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.overrides, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -417,6 +489,17 @@ func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags,
 
 	// TODO (@salah): update installation status to reflect what's happening
 
+	storage, err := storageForInstall(flags.overrides)
+	if err != nil {
+		return fmt.Errorf("unable to get storage: %w", err)
+	}
+
+	// extensions are only installed once the installation is restored, the storage class
+	// must exist beforehand.
+	if err := addons.EnsureStorageClass(ctx, kcli, storage); err != nil {
+		return fmt.Errorf("unable to check storage class: %w", err)
+	}
+
 	logrus.Debugf("installing addons")
 	if err := addons.Install(ctx, hcli, addons.InstallOptions{
 		IsAirgap:    flags.airgapBundle != "",
@@ -424,10 +507,20 @@ func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags,
 		PrivateCAs:  flags.privateCAs,
 		ServiceCIDR: flags.cidrCfg.ServiceCIDR,
 		IsRestore:   true,
+		Storage:     storage,
 	}); err != nil {
 		return err
 	}
 
+	if store != nil {
+		logrus.Debugf("deploying backup store")
+		served, err := deployBackupStore(ctx, kcli, store, skipStoreValidation)
+		if err != nil {
+			return err
+		}
+		s3Store = served
+	}
+
 	logrus.Debugf("configuring velero backup storage location")
 	if err := kotscli.VeleroConfigureOtherS3(kotscli.VeleroConfigureOtherS3Options{
 		Endpoint:        s3Store.endpoint,
@@ -465,7 +558,17 @@ func runRestoreStepConfirmBackup(ctx context.Context, flags InstallCmdFlags) (*d
 	backupToRestore := pickBackupToRestore(backups)
 	logrus.Debugf("backup to restore: %s", backupToRestore.GetName())
 
+	rel, err := release.GetChannelRelease()
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to get release from binary: %w", err)
+	} else if rel == nil {
+		return nil, false, fmt.Errorf("no release found in binary")
+	}
+
 	logrus.Info("")
+	if plan := buildRestoreUpgradePlan(*backupToRestore, rel, flags.isAirgap); plan != nil {
+		printRestoreUpgradePlan(plan)
+	}
 	completionTimestamp := backupToRestore.GetCompletionTimestamp().Format("2006-01-02 15:04:05 UTC")
 	shouldRestore := prompts.New().Confirm(fmt.Sprintf("Restore from backup %q (%s)?", backupToRestore.GetName(), completionTimestamp), true)
 	logrus.Info("")
@@ -479,7 +582,7 @@ func runRestoreStepConfirmBackup(ctx context.Context, flags InstallCmdFlags) (*d
 
 func runRestoreECInstall(ctx context.Context, backupToRestore *disasterrecovery.ReplicatedBackup) error {
 	logrus.Debugf("restoring embedded cluster installation from backup %q", backupToRestore.GetName())
-	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentECInstall, true); err != nil {
+	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentECInstall, true, drRestoreOptions{}); err != nil {
 		return fmt.Errorf("unable to restore from backup: %w", err)
 	}
 
@@ -496,15 +599,54 @@ func runRestoreECInstall(ctx context.Context, backupToRestore *disasterrecovery.
 	return nil
 }
 
-func runRestoreAdminConsole(ctx context.Context, backupToRestore *disasterrecovery.ReplicatedBackup) error {
+func runRestoreAdminConsole(ctx context.Context, flags InstallCmdFlags, backupToRestore *disasterrecovery.ReplicatedBackup) error {
+	logrus.Debugf("installing the ingress controller if enabled in backup %q", backupToRestore.GetName())
+	if err := restoreIngressController(ctx, flags); err != nil {
+		return fmt.Errorf("unable to install ingress controller: %w", err)
+	}
+
 	logrus.Debugf("restoring admin console from backup %q", backupToRestore.GetName())
-	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentAdminConsole, true); err != nil {
+	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentAdminConsole, true, drRestoreOptions{}); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// restoreIngressController installs the ingress controller if the restored installation was
+// installed with it, so the admin console is reachable at its hostname once restored.
+func restoreIngressController(ctx context.Context, flags InstallCmdFlags) error {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get latest installation: %w", err)
+	}
+	if !in.Spec.Ingress.IsEnabled() {
+		return nil
+	}
+
+	airgapChartsPath := ""
+	if flags.isAirgap {
+		airgapChartsPath = runtimeconfig.EmbeddedClusterChartsSubDir()
+	}
+
+	hcli, err := helm.NewClient(helm.HelmOptions{
+		KubeConfig: runtimeconfig.PathToKubeConfig(),
+		K0sVersion: versions.K0sVersion,
+		AirgapPath: airgapChartsPath,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create helm client: %w", err)
+	}
+	defer hcli.Close()
+
+	return addons.RestoreIngress(ctx, hcli, in)
+}
+
 func runRestoreWaitForNodes(ctx context.Context, flags InstallCmdFlags, backupToRestore *disasterrecovery.ReplicatedBackup) error {
 	logrus.Debugf("checking if backup is high availability")
 	highAvailability, err := isHighAvailabilityReplicatedBackup(*backupToRestore)
@@ -559,7 +701,7 @@ func runRestoreEnableAdminConsoleHA(ctx context.Context, flags InstallCmdFlags,
 	}
 	defer hcli.Close()
 
-	err = addons.EnableAdminConsoleHA(ctx, kcli, hcli, flags.isAirgap, flags.cidrCfg.ServiceCIDR, flags.proxy, in.Spec.Config)
+	err = addons.EnableAdminConsoleHA(ctx, kcli, hcli, flags.isAirgap, flags.cidrCfg.ServiceCIDR, flags.proxy, in.Spec.Storage, in.Spec.Config)
 	if err != nil {
 		return err
 	}
@@ -579,7 +721,7 @@ func runRestoreSeaweedFS(ctx context.Context, flags InstallCmdFlags, backupToRes
 	}
 
 	logrus.Debugf("restoring seaweedfs from backup %q", backupToRestore.GetName())
-	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentSeaweedFS, true); err != nil {
+	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentSeaweedFS, true, drRestoreOptions{}); err != nil {
 		return err
 	}
 
@@ -593,7 +735,7 @@ func runRestoreRegistry(ctx context.Context, flags InstallCmdFlags, backupToRest
 	}
 
 	logrus.Debugf("restoring embedded cluster registry from backup %q", backupToRestore.GetName())
-	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentRegistry, true); err != nil {
+	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentRegistry, true, drRestoreOptions{}); err != nil {
 		return err
 	}
 
@@ -611,7 +753,7 @@ func runRestoreRegistry(ctx context.Context, flags InstallCmdFlags, backupToRest
 
 func runRestoreECO(ctx context.Context, backupToRestore *disasterrecovery.ReplicatedBackup) error {
 	logrus.Debugf("restoring embedded cluster operator from backup %q", backupToRestore.GetName())
-	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentECO, true); err != nil {
+	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentECO, true, drRestoreOptions{}); err != nil {
 		return err
 	}
 
@@ -634,8 +776,13 @@ func runRestoreExtensions(ctx context.Context, flags InstallCmdFlags) error {
 	}
 	defer hcli.Close()
 
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
 	logrus.Debugf("installing extensions")
-	if err := extensions.Install(ctx, hcli); err != nil {
+	if err := extensions.Install(ctx, kcli, hcli, extensions.InstallOptions{}); err != nil {
 		return fmt.Errorf("unable to install extensions: %w", err)
 	}
 
@@ -660,15 +807,10 @@ func runRestoreApp(ctx context.Context, backupToRestore *disasterrecovery.Replic
 	}
 
 	logrus.Debugf("restoring app from backup %q", backupToRestore.GetName())
-	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentApp, true); err != nil {
+	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentApp, true, drRestoreOptions{}); err != nil {
 		return err
 	}
 
-	logrus.Debugf("resetting restore state")
-	if err := resetECRestoreState(ctx); err != nil {
-		return fmt.Errorf("unable to reset restore state: %w", err)
-	}
-
 	return nil
 }
 
@@ -856,6 +998,59 @@ func s3BackupStoreHasData(store *s3BackupStore) bool {
 	return store.endpoint != "" && store.region != "" && store.bucket != "" && store.accessKeyID != "" && store.secretAccessKey != ""
 }
 
+// s3BackupStoreHasAnyData checks if any of the s3 flags is set.
+func s3BackupStoreHasAnyData(store *s3BackupStore) bool {
+	return *store != s3BackupStore{}
+}
+
+// deployBackupStore serves a backup store not reachable through S3 from within the cluster
+// and returns the S3 configuration velero can use to reach it.
+func deployBackupStore(ctx context.Context, kcli client.Client, store *backupstore.Store, skipStoreValidation bool) (*s3BackupStore, error) {
+	loading := spinner.Start()
+	loading.Infof("Serving backup store %s", store)
+
+	opts := backupstore.DeployOptions{
+		Store: store,
+		Image: embeddedclusteroperator.Metadata.Images["embedded-cluster-operator"].String(),
+	}
+	if store.Type == backupstore.TypeFile {
+		hostname, err := os.Hostname()
+		if err != nil {
+			loading.CloseWithError()
+			return nil, fmt.Errorf("unable to get hostname: %w", err)
+		}
+		// node names are the lowercased hostname.
+		opts.NodeName = strings.ToLower(hostname)
+	}
+
+	deployment, err := backupstore.Deploy(ctx, kcli, opts)
+	if err != nil {
+		loading.CloseWithError()
+		return nil, fmt.Errorf("unable to deploy backup store: %w", err)
+	}
+
+	s3Store := &s3BackupStore{
+		endpoint:        deployment.ServiceEndpoint,
+		region:          backupstore.Region,
+		bucket:          backupstore.Bucket,
+		accessKeyID:     deployment.AccessKeyID,
+		secretAccessKey: deployment.SecretAccessKey,
+	}
+	if !skipStoreValidation {
+		// the service is reached through its cluster ip as the cluster dns does not resolve
+		// from the host.
+		logrus.Debugf("validating backup store configuration")
+		if err := validateS3BackupStore(s3Store); err != nil {
+			loading.CloseWithError()
+			return nil, fmt.Errorf("unable to validate backup store: %w", err)
+		}
+	}
+	s3Store.endpoint = deployment.Endpoint
+
+	loading.Closef("Backup store %s served", store)
+	return s3Store, nil
+}
+
 // promptForS3BackupStore prompts the user for S3 backup store configuration.
 func promptForS3BackupStore(store *s3BackupStore) {
 	for {
@@ -946,8 +1141,8 @@ func isBackupRestorable(backup *velerov1.Backup, rel *release.ChannelRelease, is
 		return false, "is not an embedded cluster backup"
 	}
 
-	if v := strings.TrimPrefix(backup.Annotations["kots.io/embedded-cluster-version"], "v"); v != strings.TrimPrefix(versions.Version, "v") {
-		return false, fmt.Sprintf("has a different embedded cluster version (%q) than the current version (%q)", v, versions.Version)
+	if reason := checkBackupECVersion(backup.Annotations["kots.io/embedded-cluster-version"]); reason != "" {
+		return false, reason
 	}
 
 	if backup.Status.Phase != velerov1.BackupPhaseCompleted {
@@ -975,8 +1170,8 @@ func isBackupRestorable(backup *velerov1.Backup, rel *release.ChannelRelease, is
 		return false, fmt.Sprintf("does not contain the %q application", rel.AppSlug)
 	}
 
-	if versionLabel := appsVersions[rel.AppSlug]; versionLabel != rel.VersionLabel {
-		return false, fmt.Sprintf("has a different app version (%q) than the current version (%q)", versionLabel, rel.VersionLabel)
+	if reason := checkBackupAppVersion(appsVersions[rel.AppSlug], rel.VersionLabel); reason != "" {
+		return false, reason
 	}
 
 	if _, ok := backup.Annotations["kots.io/is-airgap"]; !ok {
@@ -994,21 +1189,15 @@ func isBackupRestorable(backup *velerov1.Backup, rel *release.ChannelRelease, is
 		}
 	}
 
-	if _, ok := backup.Annotations["kots.io/embedded-cluster-pod-cidr"]; ok {
-		// kots.io/embedded-cluster-pod-cidr and kots.io/embedded-cluster-service-cidr should both exist if one does
+	// the registry service IP of airgap backups is derived from the service network, and is
+	// embedded in the images and in the pull secrets of the restored workloads.
+	if airgapLabelValue == "true" && newRestoreRemap(backup, k0sCfg).ServiceCIDRChanged() {
 		podCIDR := backup.Annotations["kots.io/embedded-cluster-pod-cidr"]
 		serviceCIDR := backup.Annotations["kots.io/embedded-cluster-service-cidr"]
-
-		if k0sCfg != nil && k0sCfg.Spec != nil && k0sCfg.Spec.Network != nil {
-			if k0sCfg.Spec.Network.PodCIDR != "" || k0sCfg.Spec.Network.ServiceCIDR != "" {
-				if podCIDR != k0sCfg.Spec.Network.PodCIDR || serviceCIDR != k0sCfg.Spec.Network.ServiceCIDR {
-					if adjacent, supernet, _ := netutils.NetworksAreAdjacentAndSameSize(podCIDR, serviceCIDR); adjacent {
-						return false, fmt.Sprintf("has a different network configuration than the current cluster. Please rerun with '--cidr %s'.", supernet)
-					}
-					return false, fmt.Sprintf("has a different network configuration than the current cluster. Please rerun with '--pod-cidr %s --service-cidr %s'.", podCIDR, serviceCIDR)
-				}
-			}
+		if adjacent, supernet, _ := netutils.NetworksAreAdjacentAndSameSize(podCIDR, serviceCIDR); adjacent {
+			return false, fmt.Sprintf("is an airgap backup with a different service network than the current cluster. Please rerun with '--cidr %s'.", supernet)
 		}
+		return false, fmt.Sprintf("is an airgap backup with a different service network than the current cluster. Please rerun with '--pod-cidr %s --service-cidr %s'.", podCIDR, serviceCIDR)
 	}
 
 	if v := backup.Annotations["kots.io/embedded-cluster-data-dir"]; v != "" && v != runtimeconfig.EmbeddedClusterHomeDirectory() {
@@ -1225,24 +1414,21 @@ func isHighAvailabilityBackup(backup *velerov1.Backup) (bool, error) {
 // Velero resource modifiers are used to modify the resources during a Velero restore by specifying json patches.
 // The json patches are applied to the resources before they are restored.
 // The json patches are specified in a configmap and the configmap is referenced in the restore object.
-func ensureRestoreResourceModifiers(ctx context.Context, backup *velerov1.Backup) error {
-	registryServiceIP, err := getRegistryIPFromBackup(backup)
+func ensureRestoreResourceModifiers(ctx context.Context, backup *velerov1.Backup, name string, keepAdminConsoleHA bool) error {
+	k0sCfg, err := getK0sConfigFromDisk()
 	if err != nil {
-		return fmt.Errorf("unable to get registry service IP from backup: %w", err)
+		return fmt.Errorf("unable to get k0s config from disk: %w", err)
 	}
 
-	seaweedFSS3ServiceIP, err := getSeaweedFSS3ServiceIPFromBackup(backup)
+	modifiersYAML, err := buildRestoreResourceModifiers(backup, k0sCfg, keepAdminConsoleHA)
 	if err != nil {
-		return fmt.Errorf("unable to get seaweedfs s3 service IP from backup: %w", err)
+		return err
 	}
 
-	modifiersYAML := strings.Replace(resourceModifiersYAML, "__REGISTRY_SERVICE_IP__", registryServiceIP, 1)
-	modifiersYAML = strings.Replace(modifiersYAML, "__SEAWEEDFS_S3_SERVICE_IP__", seaweedFSS3ServiceIP, 1)
-
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: runtimeconfig.VeleroNamespace,
-			Name:      resourceModifiersCMName,
+			Name:      name,
 		},
 		Data: map[string]string{
 			"resource-modifiers.yaml": modifiersYAML,
@@ -1260,6 +1446,53 @@ func ensureRestoreResourceModifiers(ctx context.Context, backup *velerov1.Backup
 	return nil
 }
 
+// buildRestoreResourceModifiers returns the restore resource modifiers for the backup. The
+// network of the installations is set to the one of the cluster described by the k0s config.
+// The admin console is converted to a single node unless keepAdminConsoleHA is set.
+func buildRestoreResourceModifiers(backup *velerov1.Backup, k0sCfg *k0sv1beta1.ClusterConfig, keepAdminConsoleHA bool) (string, error) {
+	registryServiceIP, err := getRegistryIPFromBackup(backup)
+	if err != nil {
+		return "", fmt.Errorf("unable to get registry service IP from backup: %w", err)
+	}
+
+	seaweedFSS3ServiceIP, err := getSeaweedFSS3ServiceIPFromBackup(backup)
+	if err != nil {
+		return "", fmt.Errorf("unable to get seaweedfs s3 service IP from backup: %w", err)
+	}
+
+	installationPatch, err := getRestoreInstallationPatch(k0sCfg)
+	if err != nil {
+		return "", fmt.Errorf("unable to get installation patch: %w", err)
+	}
+
+	modifiersYAML := strings.Replace(resourceModifiersYAML, "__REGISTRY_SERVICE_IP__", registryServiceIP, 1)
+	modifiersYAML = strings.Replace(modifiersYAML, "__SEAWEEDFS_S3_SERVICE_IP__", seaweedFSS3ServiceIP, 1)
+	modifiersYAML = strings.Replace(modifiersYAML, "__INSTALLATION_PATCH__", installationPatch, 1)
+	if !keepAdminConsoleHA {
+		return modifiersYAML, nil
+	}
+
+	var modifiers map[string]interface{}
+	if err := k8syaml.Unmarshal([]byte(modifiersYAML), &modifiers); err != nil {
+		return "", fmt.Errorf("unable to unmarshal resource modifiers: %w", err)
+	}
+	rules, _ := modifiers["resourceModifierRules"].([]interface{})
+	kept := []interface{}{}
+	for _, rule := range rules {
+		conditions, _ := rule.(map[string]interface{})["conditions"].(map[string]interface{})
+		if conditions["groupResource"] == "statefulsets.apps" && conditions["resourceNameRegex"] == "^kotsadm-rqlite$" {
+			continue
+		}
+		kept = append(kept, rule)
+	}
+	modifiers["resourceModifierRules"] = kept
+	out, err := k8syaml.Marshal(modifiers)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal resource modifiers: %w", err)
+	}
+	return string(out), nil
+}
+
 // waitForDRComponent waits for a disaster recovery component to be restored.
 func waitForDRComponent(ctx context.Context, drComponent disasterRecoveryComponent, restoreName string, isV2 bool) error {
 	loading := spinner.Start()
@@ -1356,8 +1589,34 @@ func waitForDRComponent(ctx context.Context, drComponent disasterRecoveryCompone
 	return nil
 }
 
+// drRestoreOptions tweak the velero restore created for a disaster recovery component. The
+// zero value is what the full restore uses.
+type drRestoreOptions struct {
+	// NameSuffix is appended to the name of the restore, so a component can be restored more
+	// than once from the same backup.
+	NameSuffix string
+	// Namespaces, if set, limits the restore to the resources in these namespaces.
+	Namespaces []string
+	// ExistingResourcePolicy tells velero what to do with the resources that already exist in
+	// the cluster. These are skipped if empty.
+	ExistingResourcePolicy velerov1.PolicyType
+	// KeepAdminConsoleHA keeps the replicas of the admin console rather than converting it to
+	// a single node, for restores into a running high availability cluster.
+	KeepAdminConsoleHA bool
+}
+
+// apply sets the options on the restore.
+func (o drRestoreOptions) apply(restore *velerov1.Restore) {
+	if len(o.Namespaces) > 0 {
+		restore.Spec.IncludedNamespaces = o.Namespaces
+	}
+	if o.ExistingResourcePolicy != "" {
+		restore.Spec.ExistingResourcePolicy = o.ExistingResourcePolicy
+	}
+}
+
 // restoreFromReplicatedBackup restores a disaster recovery component from a backup.
-func restoreFromReplicatedBackup(ctx context.Context, backup disasterrecovery.ReplicatedBackup, drComponent disasterRecoveryComponent, isV2 bool) error {
+func restoreFromReplicatedBackup(ctx context.Context, backup disasterrecovery.ReplicatedBackup, drComponent disasterRecoveryComponent, isV2 bool, opts drRestoreOptions) error {
 	if drComponent == disasterRecoveryComponentApp {
 		isImprovedDR, err := usesImprovedDR()
 		if err != nil {
@@ -1375,7 +1634,7 @@ func restoreFromReplicatedBackup(ctx context.Context, backup disasterrecovery.Re
 			if err != nil {
 				return fmt.Errorf("failed to get restore resource from backup: %w", err)
 			}
-			err = restoreAppFromBackup(ctx, b, r, isV2)
+			err = restoreAppFromBackup(ctx, b, r, isV2, opts)
 			if err != nil {
 				return fmt.Errorf("failed to restore app from backup: %w", err)
 			}
@@ -1386,7 +1645,7 @@ func restoreFromReplicatedBackup(ctx context.Context, backup disasterrecovery.Re
 	if b == nil {
 		return fmt.Errorf("unable to find infra backup")
 	}
-	err := restoreFromBackup(ctx, b, drComponent, isV2)
+	err := restoreFromBackup(ctx, b, drComponent, isV2, opts)
 	if err != nil {
 		return fmt.Errorf("failed to restore infra from backup: %w", err)
 	}
@@ -1407,13 +1666,13 @@ func usesImprovedDR() (bool, error) {
 
 // restoreAppFromBackup will either restore using the spec provided by the vendor as part of the
 // improved dr support.
-func restoreAppFromBackup(ctx context.Context, backup *velerov1.Backup, restore *velerov1.Restore, isV2 bool) error {
+func restoreAppFromBackup(ctx context.Context, backup *velerov1.Backup, restore *velerov1.Restore, isV2 bool, opts drRestoreOptions) error {
 	kcli, err := kubeutils.KubeClient()
 	if err != nil {
 		return fmt.Errorf("unable to create kube client: %w", err)
 	}
 
-	restoreName := fmt.Sprintf("%s.restore", backup.Name)
+	restoreName := fmt.Sprintf("%s.restore%s", backup.Name, opts.NameSuffix)
 
 	// check if a restore object already exists
 	rest := velerov1.Restore{}
@@ -1434,6 +1693,7 @@ func restoreAppFromBackup(ctx context.Context, backup *velerov1.Backup, restore
 		ensureImprovedDrMetadata(restore, backup)
 
 		restore.Spec.BackupName = backup.Name
+		opts.apply(restore)
 
 		logrus.Debugf("creating restore %s", restoreName)
 
@@ -1449,13 +1709,13 @@ func restoreAppFromBackup(ctx context.Context, backup *velerov1.Backup, restore
 
 // restoreFromBackup will use the "replicated.com/disaster-recovery" label value provided to create
 // a velero restore object which will restore one set of resources to the cluster.
-func restoreFromBackup(ctx context.Context, backup *velerov1.Backup, drComponent disasterRecoveryComponent, isV2 bool) error {
+func restoreFromBackup(ctx context.Context, backup *velerov1.Backup, drComponent disasterRecoveryComponent, isV2 bool, opts drRestoreOptions) error {
 	kcli, err := kubeutils.KubeClient()
 	if err != nil {
 		return fmt.Errorf("unable to create kube client: %w", err)
 	}
 
-	restoreName := fmt.Sprintf("%s.%s", backup.Name, string(drComponent))
+	restoreName := fmt.Sprintf("%s.%s%s", backup.Name, string(drComponent), opts.NameSuffix)
 
 	// check if a restore object already exists
 	rest := velerov1.Restore{}
@@ -1480,6 +1740,11 @@ func restoreFromBackup(ctx context.Context, backup *velerov1.Backup, drComponent
 			return fmt.Errorf("unknown disaster recovery component: %q", drComponent)
 		}
 
+		modifiersCMName := resourceModifiersCMName
+		if opts.KeepAdminConsoleHA {
+			modifiersCMName = resourceModifiersHACMName
+		}
+
 		restore := &velerov1.Restore{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: runtimeconfig.VeleroNamespace,
@@ -1498,15 +1763,16 @@ func restoreFromBackup(ctx context.Context, backup *velerov1.Backup, drComponent
 				IncludeClusterResources: ptr.To(true),
 				ResourceModifier: &corev1.TypedLocalObjectReference{
 					Kind: "ConfigMap",
-					Name: resourceModifiersCMName,
+					Name: modifiersCMName,
 				},
 			},
 		}
 
 		ensureImprovedDrMetadata(restore, backup)
+		opts.apply(restore)
 
 		// ensure restore resource modifiers first
-		if err := ensureRestoreResourceModifiers(ctx, backup); err != nil {
+		if err := ensureRestoreResourceModifiers(ctx, backup, modifiersCMName, opts.KeepAdminConsoleHA); err != nil {
 			return fmt.Errorf("unable to ensure restore resource modifiers: %w", err)
 		}
 
@@ -1547,7 +1813,15 @@ func waitForAdditionalNodes(ctx context.Context, highAvailability bool, networkI
 		return fmt.Errorf("unable to create kube client: %w", err)
 	}
 
-	adminConsoleURL := getAdminConsoleURL(networkInterface, runtimeconfig.AdminConsolePort())
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get latest installation: %w", err)
+	}
+	hostname := ""
+	if in.Spec.Ingress.IsEnabled() {
+		hostname = in.Spec.Ingress.AdminConsoleHostname
+	}
+	adminConsoleURL := getAdminConsoleURL(networkInterface, runtimeconfig.AdminConsolePort(), hostname)
 
 	successColor := "\033[32m"
 	colorReset := "\033[0m"
@@ -0,0 +1,502 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/extensions"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// restoreComponent is a part of the cluster that can be restored on its own into a running
+// cluster, as opposed to the full restore of a new cluster.
+type restoreComponent string
+
+const (
+	restoreComponentApp          restoreComponent = "app"
+	restoreComponentAdminConsole restoreComponent = "admin-console"
+	restoreComponentRegistry     restoreComponent = "registry"
+	restoreComponentExtensions   restoreComponent = "extensions"
+)
+
+var restoreComponents = []restoreComponent{
+	restoreComponentApp,
+	restoreComponentAdminConsole,
+	restoreComponentRegistry,
+	restoreComponentExtensions,
+}
+
+// managedNamespaces are the namespaces of the cluster infrastructure. The workloads and volumes
+// of these are only replaced through their component, never by namespace.
+var managedNamespaces = []string{
+	runtimeconfig.KotsadmNamespace,
+	runtimeconfig.VeleroNamespace,
+	runtimeconfig.EmbeddedClusterNamespace,
+	runtimeconfig.SeaweedFSNamespace,
+	runtimeconfig.RegistryNamespace,
+	runtimeconfig.IngressNamespace,
+	"openebs",
+	"k0s-autopilot",
+	"kube-system",
+	"kube-public",
+	"kube-node-lease",
+}
+
+// partialRestoreFlags holds the flags of the restore command that restore a single component
+// into a running cluster.
+type partialRestoreFlags struct {
+	component      string
+	namespaces     []string
+	backupName     string
+	replaceVolumes bool
+}
+
+func addPartialRestoreFlags(cmd *cobra.Command, flags *partialRestoreFlags) {
+	components := []string{}
+	for _, c := range restoreComponents {
+		components = append(components, string(c))
+	}
+	cmd.Flags().StringVar(&flags.component, "component", "", fmt.Sprintf("Restore only this component into the running cluster, one of %s", strings.Join(components, ", ")))
+	cmd.Flags().StringSliceVar(&flags.namespaces, "namespace", nil, "Restore only the resources of these namespaces into the running cluster, implies --component app if not set")
+	cmd.Flags().StringVar(&flags.backupName, "backup", "", "Name of the backup to restore the component from, defaults to the most recent restorable backup")
+	cmd.Flags().BoolVar(&flags.replaceVolumes, "replace-volumes", false, "Delete the workloads and persistent volume claims of the component before restoring them, so the data of their volumes is restored too. Requires --namespace with --component app")
+}
+
+// isSet returns true if the restore is limited to a component of a running cluster.
+func (f partialRestoreFlags) isSet() bool {
+	return f.component != "" || len(f.namespaces) > 0
+}
+
+// getComponent returns the component to restore. Restores limited to namespaces restore the
+// application unless told otherwise.
+func (f partialRestoreFlags) getComponent() restoreComponent {
+	if f.component == "" {
+		return restoreComponentApp
+	}
+	return restoreComponent(f.component)
+}
+
+func (f partialRestoreFlags) validate() error {
+	component := f.getComponent()
+	if !slices.Contains(restoreComponents, component) {
+		return fmt.Errorf("invalid --component %q, must be one of %v", f.component, restoreComponents)
+	}
+	if len(f.namespaces) > 0 && component != restoreComponentApp && component != restoreComponentExtensions {
+		return fmt.Errorf("--namespace can only be used with --component %s or %s", restoreComponentApp, restoreComponentExtensions)
+	}
+	if f.backupName != "" && component == restoreComponentExtensions {
+		return fmt.Errorf("--backup cannot be used with --component %s, extensions are restored from the installation", restoreComponentExtensions)
+	}
+	if f.replaceVolumes {
+		switch component {
+		case restoreComponentApp:
+			if len(f.namespaces) == 0 {
+				return fmt.Errorf("--replace-volumes requires --namespace with --component %s", restoreComponentApp)
+			}
+			if err := checkReplaceVolumesNamespaces(f.namespaces); err != nil {
+				return err
+			}
+		case restoreComponentAdminConsole, restoreComponentRegistry:
+		default:
+			return fmt.Errorf("--replace-volumes cannot be used with --component %s", component)
+		}
+	}
+	return nil
+}
+
+// checkReplaceVolumesNamespaces returns an error if any of the namespaces is managed by the
+// cluster, as all their workloads and volumes would be deleted.
+func checkReplaceVolumesNamespaces(namespaces []string) error {
+	for _, ns := range namespaces {
+		if slices.Contains(managedNamespaces, ns) {
+			return fmt.Errorf("--replace-volumes cannot be used with namespace %s, it is managed by the cluster", ns)
+		}
+	}
+	return nil
+}
+
+func preRunPartialRestore(cmd *cobra.Command, flags partialRestoreFlags) error {
+	if os.Getuid() != 0 {
+		return fmt.Errorf("restore command must be run as root")
+	}
+
+	if err := flags.validate(); err != nil {
+		return err
+	}
+
+	rcutil.InitBestRuntimeConfig(cmd.Context())
+
+	os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+	os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
+
+	return nil
+}
+
+// runPartialRestore restores a single component, optionally limited to some namespaces, into
+// the running cluster. Unlike the full restore, existing resources are updated to their state
+// in the backup. A backup of the cluster is taken first so the restore can be reverted, it is
+// marked so that it is not picked by the next restores.
+func runPartialRestore(ctx context.Context, name string, flags partialRestoreFlags, assumeYes bool) error {
+	component := flags.getComponent()
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get installation: %w", err)
+	}
+	if in.Status.State != ecv1beta1.InstallationStateInstalled {
+		return fmt.Errorf("the cluster must be healthy to restore a component, the installation is in state %s", in.Status.State)
+	}
+	if state := getECRestoreState(ctx); state != ecRestoreStateNew {
+		return fmt.Errorf("a restore of the cluster is in progress, run the restore command without --component and --namespace to resume it")
+	}
+	if component == restoreComponentRegistry && !in.Spec.AirGap {
+		return fmt.Errorf("the registry can only be restored in air gap installations")
+	}
+
+	var backupToRestore *disasterrecovery.ReplicatedBackup
+	if component != restoreComponentExtensions {
+		backupToRestore, err = pickPartialRestoreBackup(ctx, kcli, name, flags.backupName)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.Info("")
+	logrus.Info(describePartialRestore(component, flags.namespaces, flags.replaceVolumes, backupToRestore))
+	logrus.Info("A backup of the cluster is taken before restoring, so the restore can be reverted.")
+	logrus.Info("")
+	if !assumeYes && !prompts.New().Confirm("Do you want to continue?", false) {
+		return fmt.Errorf("aborted")
+	}
+
+	logrus.Debugf("taking a backup before restoring")
+	preRestoreBackup, err := createBackup(ctx, name, true)
+	if err != nil {
+		return fmt.Errorf("unable to take a backup before restoring: %w", err)
+	}
+	if err := disasterrecovery.MarkPreRestoreBackup(ctx, kcli, *preRestoreBackup); err != nil {
+		return fmt.Errorf("unable to mark the backup taken before restoring: %w", err)
+	}
+
+	opts := drRestoreOptions{
+		NameSuffix:             fmt.Sprintf(".%d", time.Now().Unix()),
+		Namespaces:             flags.namespaces,
+		ExistingResourcePolicy: velerov1.PolicyTypeUpdate,
+		KeepAdminConsoleHA:     in.Spec.HighAvailability,
+	}
+
+	if flags.replaceVolumes {
+		targets := partialRestoreWorkloadTargets(component, flags.namespaces, in.Spec.HighAvailability)
+		if err := deletePartialRestoreWorkloads(ctx, kcli, targets); err != nil {
+			return fmt.Errorf("unable to delete the workloads before restoring: %w", err)
+		}
+	}
+
+	switch component {
+	case restoreComponentApp:
+		logrus.Debugf("restoring app from backup %q", backupToRestore.GetName())
+		if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentApp, true, opts); err != nil {
+			return err
+		}
+
+	case restoreComponentAdminConsole:
+		logrus.Debugf("restoring admin console from backup %q", backupToRestore.GetName())
+		if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentAdminConsole, true, opts); err != nil {
+			return err
+		}
+
+	case restoreComponentRegistry:
+		// the registry data is stored in seaweedfs in high availability installations.
+		if in.Spec.HighAvailability {
+			logrus.Debugf("restoring seaweedfs from backup %q", backupToRestore.GetName())
+			if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentSeaweedFS, true, opts); err != nil {
+				return err
+			}
+		}
+		logrus.Debugf("restoring embedded cluster registry from backup %q", backupToRestore.GetName())
+		if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentRegistry, true, opts); err != nil {
+			return err
+		}
+
+	case restoreComponentExtensions:
+		if err := runPartialRestoreExtensions(ctx, kcli, in, flags.namespaces); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pickPartialRestoreBackup returns the backup with the provided name, or the most recent backup
+// that can be restored into the running cluster if no name is provided. The backups taken before
+// a previous restore are only restored by name, as they hold the state that was replaced.
+func pickPartialRestoreBackup(ctx context.Context, kcli client.Client, name string, backupName string) (*disasterrecovery.ReplicatedBackup, error) {
+	target, err := getBackupRestoreTarget(ctx, kcli)
+	if err != nil {
+		return nil, err
+	}
+	if target.rel == nil {
+		return nil, fmt.Errorf("no release found in binary")
+	}
+
+	if backupName != "" {
+		backup, err := getReplicatedBackup(ctx, kcli, backupName)
+		if err != nil {
+			return nil, err
+		}
+		if reason := checkPartialRestoreBackup(backup, target); reason != "" {
+			return nil, fmt.Errorf("backup %s cannot be restored: backup %s", backup.GetName(), reason)
+		}
+		return &backup, nil
+	}
+
+	backups, err := disasterrecovery.ListReplicatedBackups(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list backups: %w", err)
+	}
+
+	restorable := partialRestoreCandidates(backups, target)
+	if len(restorable) == 0 {
+		return nil, fmt.Errorf("no backup can be restored into the running cluster, run '%s backup list' for details", name)
+	}
+
+	return pickBackupToRestore(restorable), nil
+}
+
+// partialRestoreCandidates returns the backups the most recent one is picked from when no backup
+// name is provided.
+func partialRestoreCandidates(backups []disasterrecovery.ReplicatedBackup, target backupRestoreTarget) []disasterrecovery.ReplicatedBackup {
+	restorable := []disasterrecovery.ReplicatedBackup{}
+	for _, backup := range backups {
+		if backup.IsPreRestoreBackup() {
+			logrus.Debugf("skipping backup %s: taken before a previous restore", backup.GetName())
+			continue
+		}
+		if reason := checkPartialRestoreBackup(backup, target); reason != "" {
+			logrus.Debugf("skipping backup %s: %s", backup.GetName(), reason)
+			continue
+		}
+		restorable = append(restorable, backup)
+	}
+	return restorable
+}
+
+// checkPartialRestoreBackup returns why the backup can not be restored into the running
+// cluster, or an empty string if it can. On top of the checks of the full restore, only backups
+// taken with the current versions are accepted as the cluster is not upgraded afterwards.
+func checkPartialRestoreBackup(backup disasterrecovery.ReplicatedBackup, target backupRestoreTarget) string {
+	if ok, reason := isReplicatedBackupRestorable(backup, target.rel, target.isAirgap, target.k0sCfg); !ok {
+		return reason
+	}
+	if plan := buildRestoreUpgradePlan(backup, target.rel, target.isAirgap); plan != nil {
+		return fmt.Sprintf("was taken with embedded cluster version %q and app version %q, only backups of the current versions can be restored into a running cluster", plan.FromECVersion, plan.FromAppVersion)
+	}
+	return ""
+}
+
+// describePartialRestore returns what is about to be restored into the running cluster.
+func describePartialRestore(component restoreComponent, namespaces []string, replaceVolumes bool, backup *disasterrecovery.ReplicatedBackup) string {
+	var what string
+	switch component {
+	case restoreComponentApp:
+		what = "The application"
+	case restoreComponentAdminConsole:
+		what = "The Admin Console"
+	case restoreComponentRegistry:
+		what = "The registry"
+	case restoreComponentExtensions:
+		what = "The extensions"
+	}
+	if len(namespaces) > 0 {
+		what = fmt.Sprintf("%s resources in namespaces %s", what, strings.Join(namespaces, ", "))
+	}
+
+	if backup == nil {
+		return fmt.Sprintf("%s will be reinstalled from the installation configuration.", what)
+	}
+
+	completionTimestamp := backup.GetCompletionTimestamp().Format("2006-01-02 15:04:05 UTC")
+	if replaceVolumes {
+		return fmt.Sprintf(
+			"%s will be restored from backup %q (%s) into the running cluster. Its deployments, stateful sets, daemon sets, jobs, pods and persistent volume claims are deleted first, so the data of their volumes is restored. Workloads and volumes created since the backup are lost.",
+			what, backup.GetName(), completionTimestamp,
+		)
+	}
+	return fmt.Sprintf(
+		"%s will be restored from backup %q (%s) into the running cluster. Existing resources are updated to their state in the backup, resources created since are left in place and the data of existing volumes is not restored.",
+		what, backup.GetName(), completionTimestamp,
+	)
+}
+
+// partialRestoreWorkloadKinds are the kinds of objects deleted from the namespaces before their
+// volumes are restored. Controllers come first so they do not recreate the pods deleted after.
+var partialRestoreWorkloadKinds = []struct {
+	name string
+	obj  client.Object
+}{
+	{"cron jobs", &batchv1.CronJob{}},
+	{"jobs", &batchv1.Job{}},
+	{"deployments", &appsv1.Deployment{}},
+	{"stateful sets", &appsv1.StatefulSet{}},
+	{"daemon sets", &appsv1.DaemonSet{}},
+	{"replica sets", &appsv1.ReplicaSet{}},
+	{"pods", &corev1.Pod{}},
+	{"persistent volume claims", &corev1.PersistentVolumeClaim{}},
+}
+
+// partialRestoreWorkloadTarget selects the workloads and persistent volume claims deleted
+// before their volumes are restored.
+type partialRestoreWorkloadTarget struct {
+	namespace string
+	// labels select the objects of the namespace, all of them are if empty.
+	labels map[string]string
+}
+
+// partialRestoreWorkloadTargets returns the workloads of the component to delete before its
+// volumes are restored. These are selected as the component is restored from the backup.
+func partialRestoreWorkloadTargets(component restoreComponent, namespaces []string, highAvailability bool) []partialRestoreWorkloadTarget {
+	targets := []partialRestoreWorkloadTarget{}
+	switch component {
+	case restoreComponentApp:
+		for _, ns := range namespaces {
+			targets = append(targets, partialRestoreWorkloadTarget{namespace: ns})
+		}
+	case restoreComponentAdminConsole:
+		targets = append(targets, partialRestoreWorkloadTarget{
+			namespace: runtimeconfig.KotsadmNamespace,
+			labels:    map[string]string{"replicated.com/disaster-recovery-chart": string(disasterRecoveryComponentAdminConsole)},
+		})
+	case restoreComponentRegistry:
+		if highAvailability {
+			targets = append(targets, partialRestoreWorkloadTarget{
+				namespace: runtimeconfig.SeaweedFSNamespace,
+				labels:    map[string]string{"app.kubernetes.io/name": "seaweedfs"},
+			})
+		}
+		targets = append(targets, partialRestoreWorkloadTarget{
+			namespace: runtimeconfig.RegistryNamespace,
+			labels:    map[string]string{"app": "docker-registry"},
+		})
+	}
+	return targets
+}
+
+// deletePartialRestoreWorkloads deletes the workloads and the persistent volume claims of the
+// targets and waits for the pods and claims to be gone. Velero only restores the data of the
+// volumes it creates, the claims have to be restored from the backup for their data to be.
+// Whole namespaces managed by the cluster are refused.
+func deletePartialRestoreWorkloads(ctx context.Context, kcli client.Client, targets []partialRestoreWorkloadTarget) error {
+	for _, target := range targets {
+		if len(target.labels) == 0 {
+			if err := checkReplaceVolumesNamespaces([]string{target.namespace}); err != nil {
+				return err
+			}
+		}
+	}
+
+	loading := spinner.Start()
+	defer loading.Close()
+
+	loading.Infof("Deleting workloads and volumes")
+
+	for _, target := range targets {
+		for _, kind := range partialRestoreWorkloadKinds {
+			err := kcli.DeleteAllOf(
+				ctx, kind.obj,
+				client.InNamespace(target.namespace),
+				client.MatchingLabels(target.labels),
+				client.PropagationPolicy(metav1.DeletePropagationBackground),
+			)
+			if err != nil {
+				return fmt.Errorf("delete %s in namespace %s: %w", kind.name, target.namespace, err)
+			}
+		}
+	}
+
+	var lasterr error
+	if err := wait.ExponentialBackoffWithContext(ctx, kubeutils.DefaultBackoff, func(ctx context.Context) (bool, error) {
+		for _, target := range targets {
+			opts := []client.ListOption{client.InNamespace(target.namespace), client.MatchingLabels(target.labels)}
+			var pods corev1.PodList
+			if err := kcli.List(ctx, &pods, opts...); err != nil {
+				lasterr = fmt.Errorf("list pods in namespace %s: %w", target.namespace, err)
+				return false, nil
+			}
+			var pvcs corev1.PersistentVolumeClaimList
+			if err := kcli.List(ctx, &pvcs, opts...); err != nil {
+				lasterr = fmt.Errorf("list persistent volume claims in namespace %s: %w", target.namespace, err)
+				return false, nil
+			}
+			if len(pods.Items) > 0 || len(pvcs.Items) > 0 {
+				lasterr = fmt.Errorf("%d pods and %d persistent volume claims left in namespace %s", len(pods.Items), len(pvcs.Items), target.namespace)
+				return false, nil
+			}
+		}
+		return true, nil
+	}); err != nil {
+		if lasterr != nil {
+			return fmt.Errorf("timed out waiting for the workloads to be deleted: %w", lasterr)
+		}
+		return fmt.Errorf("timed out waiting for the workloads to be deleted")
+	}
+
+	loading.Infof("Workloads and volumes deleted!")
+
+	return nil
+}
+
+// runPartialRestoreExtensions reinstalls the extensions from the installation as these are not
+// part of the backups.
+func runPartialRestoreExtensions(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, namespaces []string) error {
+	hcli, err := newPartialRestoreHelmClient(in)
+	if err != nil {
+		return fmt.Errorf("unable to create helm client: %w", err)
+	}
+	defer hcli.Close()
+
+	logrus.Debugf("reapplying extensions")
+	if err := extensions.Reapply(ctx, kcli, hcli, in, extensions.ReapplyOptions{Namespaces: namespaces}); err != nil {
+		return fmt.Errorf("unable to restore extensions: %w", err)
+	}
+
+	return nil
+}
+
+func newPartialRestoreHelmClient(in *ecv1beta1.Installation) (helm.Client, error) {
+	airgapChartsPath := ""
+	if in.Spec.AirGap {
+		airgapChartsPath = runtimeconfig.EmbeddedClusterChartsSubDir()
+	}
+
+	return helm.NewClient(helm.HelmOptions{
+		KubeConfig: runtimeconfig.PathToKubeConfig(),
+		K0sVersion: versions.K0sVersion,
+		AirgapPath: airgapChartsPath,
+	})
+}
@@ -0,0 +1,295 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	clitesting "github.com/replicatedhq/embedded-cluster/cmd/installer/cli/testing"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_partialRestoreFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     partialRestoreFlags
+		isSet     bool
+		component restoreComponent
+		wantErr   string
+	}{
+		{
+			name:      "full restore",
+			flags:     partialRestoreFlags{},
+			isSet:     false,
+			component: restoreComponentApp,
+		},
+		{
+			name:      "component",
+			flags:     partialRestoreFlags{component: "admin-console"},
+			isSet:     true,
+			component: restoreComponentAdminConsole,
+		},
+		{
+			name:      "namespaces default to the app",
+			flags:     partialRestoreFlags{namespaces: []string{"app-ns"}},
+			isSet:     true,
+			component: restoreComponentApp,
+		},
+		{
+			name:      "extensions of namespaces",
+			flags:     partialRestoreFlags{component: "extensions", namespaces: []string{"ext-ns"}},
+			isSet:     true,
+			component: restoreComponentExtensions,
+		},
+		{
+			name:      "invalid component",
+			flags:     partialRestoreFlags{component: "seaweedfs"},
+			isSet:     true,
+			component: "seaweedfs",
+			wantErr:   `invalid --component "seaweedfs", must be one of [app admin-console registry extensions]`,
+		},
+		{
+			name:      "namespaces of the registry",
+			flags:     partialRestoreFlags{component: "registry", namespaces: []string{"registry"}},
+			isSet:     true,
+			component: restoreComponentRegistry,
+			wantErr:   "--namespace can only be used with --component app or extensions",
+		},
+		{
+			name:      "replace the volumes of namespaces",
+			flags:     partialRestoreFlags{namespaces: []string{"app-ns"}, replaceVolumes: true},
+			isSet:     true,
+			component: restoreComponentApp,
+		},
+		{
+			name:      "replace the volumes of the whole app",
+			flags:     partialRestoreFlags{component: "app", replaceVolumes: true},
+			isSet:     true,
+			component: restoreComponentApp,
+			wantErr:   "--replace-volumes requires --namespace with --component app",
+		},
+		{
+			name:      "replace the volumes of a managed namespace",
+			flags:     partialRestoreFlags{namespaces: []string{"app-ns", "kotsadm"}, replaceVolumes: true},
+			isSet:     true,
+			component: restoreComponentApp,
+			wantErr:   "--replace-volumes cannot be used with namespace kotsadm, it is managed by the cluster",
+		},
+		{
+			name:      "replace the volumes of the admin console",
+			flags:     partialRestoreFlags{component: "admin-console", replaceVolumes: true},
+			isSet:     true,
+			component: restoreComponentAdminConsole,
+		},
+		{
+			name:      "replace the volumes of the extensions",
+			flags:     partialRestoreFlags{component: "extensions", namespaces: []string{"ext-ns"}, replaceVolumes: true},
+			isSet:     true,
+			component: restoreComponentExtensions,
+			wantErr:   "--replace-volumes cannot be used with --component extensions",
+		},
+		{
+			name:      "backup of the extensions",
+			flags:     partialRestoreFlags{component: "extensions", backupName: "app-slug-abcd"},
+			isSet:     true,
+			component: restoreComponentExtensions,
+			wantErr:   "--backup cannot be used with --component extensions, extensions are restored from the installation",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.isSet, tt.flags.isSet())
+			assert.Equal(t, tt.component, tt.flags.getComponent())
+			err := tt.flags.validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkPartialRestoreBackup(t *testing.T) {
+	release.SetReleaseDataForTests(embedFSToMap(t, clitesting.RestoreReleaseDataNewDR))
+	setVersionForTests(t, "v1.20.0+k8s-1.30")
+
+	newBackup := func(ecVersion, appVersion string) disasterrecovery.ReplicatedBackup {
+		annotations := map[string]string{
+			disasterrecovery.BackupIsECAnnotation:          "true",
+			disasterrecovery.InstanceBackupCountAnnotation: "2",
+			"kots.io/embedded-cluster-version":             ecVersion,
+			"kots.io/apps-versions":                        `{"app-slug":"` + appVersion + `"}`,
+			"kots.io/is-airgap":                            "false",
+		}
+		newVeleroBackup := func(name, backupType string) velerov1.Backup {
+			b := velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}},
+				Status:     velerov1.BackupStatus{Phase: velerov1.BackupPhaseCompleted},
+			}
+			for k, v := range annotations {
+				b.Annotations[k] = v
+			}
+			b.Annotations[disasterrecovery.InstanceBackupTypeAnnotation] = backupType
+			return b
+		}
+		return disasterrecovery.ReplicatedBackup{
+			newVeleroBackup("instance-abcd", disasterrecovery.InstanceBackupTypeInfra),
+			newVeleroBackup("application-abcd", disasterrecovery.InstanceBackupTypeApp),
+		}
+	}
+	target := backupRestoreTarget{
+		rel:    &release.ChannelRelease{VersionLabel: "2.0.0", AppSlug: "app-slug"},
+		k0sCfg: &k0sv1beta1.ClusterConfig{},
+	}
+
+	assert.Equal(t, "", checkPartialRestoreBackup(newBackup("v1.20.0+k8s-1.30", "2.0.0"), target))
+
+	// backups of older versions are only restored into new clusters, which are upgraded
+	// afterwards.
+	assert.Equal(t,
+		`was taken with embedded cluster version "1.20.0+k8s-1.30" and app version "1.0.0", only backups of the current versions can be restored into a running cluster`,
+		checkPartialRestoreBackup(newBackup("v1.20.0+k8s-1.30", "1.0.0"), target),
+	)
+
+	assert.Equal(t,
+		`has a newer app version ("3.0.0") than the current version ("2.0.0")`,
+		checkPartialRestoreBackup(newBackup("v1.20.0+k8s-1.30", "3.0.0"), target),
+	)
+
+	// the backup taken before a restore is never picked, a retry would restore the state the
+	// restore was meant to replace.
+	restorable := newBackup("v1.20.0+k8s-1.30", "2.0.0")
+	preRestore := newBackup("v1.20.0+k8s-1.30", "2.0.0")
+	for i := range preRestore {
+		preRestore[i].Annotations[disasterrecovery.PreRestoreBackupAnnotation] = "true"
+	}
+	assert.Equal(t,
+		[]disasterrecovery.ReplicatedBackup{restorable},
+		partialRestoreCandidates([]disasterrecovery.ReplicatedBackup{restorable, preRestore}, target),
+	)
+}
+
+func Test_deletePartialRestoreWorkloads(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, appsv1.AddToScheme(scheme))
+	require.NoError(t, batchv1.AddToScheme(scheme))
+
+	objects := []client.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app-ns"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "app-ns"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "app-ns"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-db-0", Namespace: "app-ns"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "app-ns"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other-ns"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other-ns"}},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	targets := partialRestoreWorkloadTargets(restoreComponentApp, []string{"app-ns"}, false)
+	require.NoError(t, deletePartialRestoreWorkloads(context.Background(), kcli, targets))
+
+	var deployments appsv1.DeploymentList
+	require.NoError(t, kcli.List(context.Background(), &deployments))
+	assert.Empty(t, deployments.Items)
+	var statefulSets appsv1.StatefulSetList
+	require.NoError(t, kcli.List(context.Background(), &statefulSets))
+	assert.Empty(t, statefulSets.Items)
+
+	// only the workloads and volumes of the namespaces are deleted.
+	var pods corev1.PodList
+	require.NoError(t, kcli.List(context.Background(), &pods))
+	require.Len(t, pods.Items, 1)
+	assert.Equal(t, "other-ns", pods.Items[0].Namespace)
+	var pvcs corev1.PersistentVolumeClaimList
+	require.NoError(t, kcli.List(context.Background(), &pvcs))
+	require.Len(t, pvcs.Items, 1)
+	assert.Equal(t, "other-ns", pvcs.Items[0].Namespace)
+	var configMaps corev1.ConfigMapList
+	require.NoError(t, kcli.List(context.Background(), &configMaps))
+	assert.Len(t, configMaps.Items, 1)
+}
+
+func Test_deletePartialRestoreWorkloads_component(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, appsv1.AddToScheme(scheme))
+	require.NoError(t, batchv1.AddToScheme(scheme))
+
+	adminConsoleLabels := map[string]string{"replicated.com/disaster-recovery-chart": "admin-console"}
+	objects := []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "kotsadm-rqlite", Namespace: "kotsadm", Labels: adminConsoleLabels}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "kotsadm-rqlite-kotsadm-rqlite-0", Namespace: "kotsadm", Labels: adminConsoleLabels}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "kotsadm"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "app-data", Namespace: "kotsadm"}},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	// whole namespaces managed by the cluster are refused.
+	targets := partialRestoreWorkloadTargets(restoreComponentApp, []string{"kotsadm"}, false)
+	assert.EqualError(t,
+		deletePartialRestoreWorkloads(context.Background(), kcli, targets),
+		"--replace-volumes cannot be used with namespace kotsadm, it is managed by the cluster",
+	)
+
+	// only the admin console is deleted from the namespace it shares with the application.
+	targets = partialRestoreWorkloadTargets(restoreComponentAdminConsole, nil, false)
+	require.NoError(t, deletePartialRestoreWorkloads(context.Background(), kcli, targets))
+
+	var statefulSets appsv1.StatefulSetList
+	require.NoError(t, kcli.List(context.Background(), &statefulSets))
+	assert.Empty(t, statefulSets.Items)
+	var deployments appsv1.DeploymentList
+	require.NoError(t, kcli.List(context.Background(), &deployments))
+	assert.Len(t, deployments.Items, 1)
+	var pvcs corev1.PersistentVolumeClaimList
+	require.NoError(t, kcli.List(context.Background(), &pvcs))
+	require.Len(t, pvcs.Items, 1)
+	assert.Equal(t, "app-data", pvcs.Items[0].Name)
+}
+
+func Test_partialRestoreWorkloadTargets_registry(t *testing.T) {
+	assert.Equal(t,
+		[]partialRestoreWorkloadTarget{
+			{namespace: "registry", labels: map[string]string{"app": "docker-registry"}},
+		},
+		partialRestoreWorkloadTargets(restoreComponentRegistry, nil, false),
+	)
+	// the registry data is stored in seaweedfs in high availability installations.
+	assert.Equal(t,
+		[]partialRestoreWorkloadTarget{
+			{namespace: "seaweedfs", labels: map[string]string{"app.kubernetes.io/name": "seaweedfs"}},
+			{namespace: "registry", labels: map[string]string{"app": "docker-registry"}},
+		},
+		partialRestoreWorkloadTargets(restoreComponentRegistry, nil, true),
+	)
+}
+
+func Test_drRestoreOptions(t *testing.T) {
+	restore := &velerov1.Restore{}
+	drRestoreOptions{}.apply(restore)
+	assert.Equal(t, velerov1.RestoreSpec{}, restore.Spec)
+
+	restore = &velerov1.Restore{
+		Spec: velerov1.RestoreSpec{IncludedNamespaces: []string{"*"}},
+	}
+	drRestoreOptions{
+		NameSuffix:             ".1700000000",
+		Namespaces:             []string{"app-ns"},
+		ExistingResourcePolicy: velerov1.PolicyTypeUpdate,
+	}.apply(restore)
+	assert.Equal(t, []string{"app-ns"}, restore.Spec.IncludedNamespaces)
+	assert.Equal(t, velerov1.PolicyTypeUpdate, restore.Spec.ExistingResourcePolicy)
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+)
+
+// restoreRemap holds the service network of the cluster a backup was taken from next to the
+// one of the cluster it is restored into. Velero allocates the cluster IPs of the restored
+// services anew and the network of the installations is set by the restore resource
+// modifiers.
+//
+// The service network of airgap backups can not change: the registry service IP is derived
+// from it, and it is embedded in the images and in the pull secrets of the restored
+// workloads. The data directory can not change either, as the host paths of the restored
+// volumes are not rewritten. Node IPs are not remapped as no restored resource embeds them,
+// nodes join the restored cluster anew.
+type restoreRemap struct {
+	FromServiceCIDR string
+	ToServiceCIDR   string
+}
+
+func newRestoreRemap(backup *velerov1.Backup, k0sCfg *k0sv1beta1.ClusterConfig) restoreRemap {
+	remap := restoreRemap{
+		FromServiceCIDR: backup.Annotations["kots.io/embedded-cluster-service-cidr"],
+	}
+	if k0sCfg != nil && k0sCfg.Spec != nil && k0sCfg.Spec.Network != nil {
+		remap.ToServiceCIDR = k0sCfg.Spec.Network.ServiceCIDR
+	}
+	return remap
+}
+
+// ServiceCIDRChanged returns true if the service network of the cluster differs from the one
+// of the backup. Backups of older versions do not record it, in which case it is assumed not
+// to have changed.
+func (r restoreRemap) ServiceCIDRChanged() bool {
+	return r.FromServiceCIDR != "" && r.ToServiceCIDR != "" && r.FromServiceCIDR != r.ToServiceCIDR
+}
+
+// getRestoreInstallationPatch returns the json merge patch setting the network of the cluster
+// the backup is restored into in the installations.
+func getRestoreInstallationPatch(k0sCfg *k0sv1beta1.ClusterConfig) (string, error) {
+	spec := map[string]interface{}{}
+	if k0sCfg != nil && k0sCfg.Spec != nil && k0sCfg.Spec.Network != nil {
+		spec["network"] = map[string]interface{}{
+			"podCIDR":     nullIfEmpty(k0sCfg.Spec.Network.PodCIDR),
+			"serviceCIDR": nullIfEmpty(k0sCfg.Spec.Network.ServiceCIDR),
+		}
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return "", fmt.Errorf("marshal patch: %w", err)
+	}
+	return string(patch), nil
+}
+
+// nullIfEmpty returns nil for empty strings so that json merge patches remove the field.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
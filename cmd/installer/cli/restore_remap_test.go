@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"testing"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func Test_buildRestoreResourceModifiers(t *testing.T) {
+	backup := &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "instance-abcd",
+			Annotations: map[string]string{
+				"kots.io/is-airgap":                        "true",
+				"kots.io/embedded-cluster-is-ha":           "true",
+				"kots.io/embedded-registry":                "10.96.0.11:5000",
+				"kots.io/embedded-cluster-seaweedfs-s3-ip": "10.96.0.12",
+				"kots.io/embedded-cluster-pod-cidr":        "10.244.0.0/17",
+				"kots.io/embedded-cluster-service-cidr":    "10.96.0.0/12",
+			},
+		},
+	}
+	k0sCfg := &k0sv1beta1.ClusterConfig{
+		Spec: &k0sv1beta1.ClusterSpec{
+			Network: &k0sv1beta1.Network{PodCIDR: "10.100.0.0/17", ServiceCIDR: "10.96.0.0/12"},
+		},
+	}
+
+	type rule struct {
+		Conditions struct {
+			GroupResource     string `json:"groupResource"`
+			ResourceNameRegex string `json:"resourceNameRegex"`
+		} `json:"conditions"`
+		Patches []struct {
+			Path  string `json:"path"`
+			Value string `json:"value"`
+		} `json:"patches"`
+		MergePatches []struct {
+			PatchData string `json:"patchData"`
+		} `json:"mergePatches"`
+	}
+	got, err := buildRestoreResourceModifiers(backup, k0sCfg, false)
+	require.NoError(t, err)
+	var modifiers struct {
+		ResourceModifierRules []rule `json:"resourceModifierRules"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(got), &modifiers))
+	rules := map[string]rule{}
+	for _, r := range modifiers.ResourceModifierRules {
+		rules[r.Conditions.GroupResource+"/"+r.Conditions.ResourceNameRegex] = r
+	}
+
+	// the pinned service IPs are preserved.
+	assert.Equal(t, "10.96.0.11", rules["services/^registry$"].Patches[0].Value)
+	assert.Equal(t, "10.96.0.12", rules["services/^ec-seaweedfs-s3$"].Patches[0].Value)
+
+	installation := rules["installations.embeddedcluster.replicated.com/"]
+	require.Len(t, installation.MergePatches, 1)
+	assert.JSONEq(t, `{
+		"spec": {
+			"network": {"podCIDR": "10.100.0.0/17", "serviceCIDR": "10.96.0.0/12"}
+		}
+	}`, installation.MergePatches[0].PatchData)
+}
+
+func Test_buildRestoreResourceModifiers_keepAdminConsoleHA(t *testing.T) {
+	backup := &velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "instance-abcd",
+			Annotations: map[string]string{"kots.io/is-airgap": "false"},
+		},
+	}
+	hasRQLiteReplicasRule := func(t *testing.T, modifiersYAML string) bool {
+		var modifiers struct {
+			ResourceModifierRules []struct {
+				Conditions struct {
+					GroupResource     string `json:"groupResource"`
+					ResourceNameRegex string `json:"resourceNameRegex"`
+				} `json:"conditions"`
+			} `json:"resourceModifierRules"`
+		}
+		require.NoError(t, yaml.Unmarshal([]byte(modifiersYAML), &modifiers))
+		require.NotEmpty(t, modifiers.ResourceModifierRules)
+		for _, r := range modifiers.ResourceModifierRules {
+			if r.Conditions.GroupResource == "statefulsets.apps" && r.Conditions.ResourceNameRegex == "^kotsadm-rqlite$" {
+				return true
+			}
+		}
+		return false
+	}
+
+	got, err := buildRestoreResourceModifiers(backup, nil, false)
+	require.NoError(t, err)
+	assert.True(t, hasRQLiteReplicasRule(t, got), "the admin console is converted to a single node")
+
+	got, err = buildRestoreResourceModifiers(backup, nil, true)
+	require.NoError(t, err)
+	assert.False(t, hasRQLiteReplicasRule(t, got), "the admin console replicas are kept")
+}
+
+func Test_isBackupRestorable_remap(t *testing.T) {
+	previous := runtimeconfig.Get().DataDir
+	t.Cleanup(func() { runtimeconfig.SetDataDir(previous) })
+	runtimeconfig.SetDataDir("/var/lib/embedded-cluster")
+
+	rel := &release.ChannelRelease{VersionLabel: "1.0.0", AppSlug: "app-slug"}
+	newBackup := func(isAirgap string, dataDir string) *velerov1.Backup {
+		return &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "instance-abcd",
+				Annotations: map[string]string{
+					disasterrecovery.BackupIsECAnnotation:   "true",
+					"kots.io/embedded-cluster-version":      "v0.0.0",
+					"kots.io/apps-versions":                 `{"app-slug":"1.0.0"}`,
+					"kots.io/is-airgap":                     isAirgap,
+					"kots.io/embedded-cluster-pod-cidr":     "10.0.0.0/25",
+					"kots.io/embedded-cluster-service-cidr": "10.0.0.128/25",
+					"kots.io/embedded-cluster-data-dir":     dataDir,
+				},
+			},
+			Status: velerov1.BackupStatus{Phase: velerov1.BackupPhaseCompleted},
+		}
+	}
+	k0sCfg := &k0sv1beta1.ClusterConfig{
+		Spec: &k0sv1beta1.ClusterSpec{
+			Network: &k0sv1beta1.Network{PodCIDR: "10.100.0.0/17", ServiceCIDR: "10.100.128.0/17"},
+		},
+	}
+
+	// the network of online backups is remapped.
+	got, reason := isBackupRestorable(newBackup("false", "/var/lib/embedded-cluster"), rel, false, k0sCfg)
+	assert.True(t, got, reason)
+
+	// the registry service IP of airgap backups can not change.
+	got, reason = isBackupRestorable(newBackup("true", "/var/lib/embedded-cluster"), rel, true, k0sCfg)
+	assert.False(t, got)
+	assert.Equal(t, "is an airgap backup with a different service network than the current cluster. Please rerun with '--cidr 10.0.0.0/24'.", reason)
+
+	// nor can the data directory.
+	got, reason = isBackupRestorable(newBackup("false", "/opt/embedded-cluster"), rel, false, k0sCfg)
+	assert.False(t, got)
+	assert.Equal(t, "has a different data directory than the current cluster. Please rerun with '--data-dir /opt/embedded-cluster'.", reason)
+}
+
+func Test_restoreRemap(t *testing.T) {
+	k0sCfg := &k0sv1beta1.ClusterConfig{
+		Spec: &k0sv1beta1.ClusterSpec{
+			Network: &k0sv1beta1.Network{ServiceCIDR: "10.100.128.0/17"},
+		},
+	}
+
+	got := newRestoreRemap(&velerov1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"kots.io/embedded-cluster-service-cidr": "10.96.0.0/12",
+			},
+		},
+	}, k0sCfg)
+	assert.True(t, got.ServiceCIDRChanged())
+
+	// backups of older versions do not record the network.
+	got = newRestoreRemap(&velerov1.Backup{}, k0sCfg)
+	assert.False(t, got.ServiceCIDRChanged())
+}
@@ -5,11 +5,15 @@ import (
 	"embed"
 	"io"
 	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
 	clitesting "github.com/replicatedhq/embedded-cluster/cmd/installer/cli/testing"
+	"github.com/replicatedhq/embedded-cluster/pkg/backupstore"
 	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
@@ -179,6 +183,42 @@ func Test_isReplicatedBackupRestorable(t *testing.T) {
 			want:  true,
 			want1: "",
 		},
+		{
+			name:      "backup of an older app version should return true",
+			releaseFS: clitesting.RestoreReleaseDataNewDR,
+			args: args{
+				backup: disasterrecovery.ReplicatedBackup{
+					infraBackup,
+					appBackup,
+				},
+				rel: &release.ChannelRelease{
+					VersionLabel: "1.1.0",
+					AppSlug:      "app-slug",
+				},
+				isAirgap: false,
+				k0sCfg:   &k0sv1beta1.ClusterConfig{},
+			},
+			want:  true,
+			want1: "",
+		},
+		{
+			name:      "backup of a newer app version should fail",
+			releaseFS: clitesting.RestoreReleaseDataNewDR,
+			args: args{
+				backup: disasterrecovery.ReplicatedBackup{
+					infraBackup,
+					appBackup,
+				},
+				rel: &release.ChannelRelease{
+					VersionLabel: "0.9.0",
+					AppSlug:      "app-slug",
+				},
+				isAirgap: false,
+				k0sCfg:   &k0sv1beta1.ClusterConfig{},
+			},
+			want:  false,
+			want1: `has a newer app version ("1.0.0") than the current version ("0.9.0")`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -483,3 +523,32 @@ func embedFSToMap(t *testing.T, f embed.FS) map[string][]byte {
 	}
 	return files
 }
+
+func Test_validateS3BackupStore(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(backupstore.NewServer(dir, "access-key", "secret-key"))
+	defer srv.Close()
+
+	store := &s3BackupStore{
+		endpoint:        srv.URL,
+		region:          backupstore.Region,
+		bucket:          backupstore.Bucket,
+		accessKeyID:     "access-key",
+		secretAccessKey: "secret-key",
+	}
+	assert.ErrorContains(t, validateS3BackupStore(store), "no backups found")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "backups", "instance-abcd"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "backups", "instance-abcd", "velero-backup.json"), []byte("{}"), 0644))
+	assert.NoError(t, validateS3BackupStore(store))
+
+	// backups under a prefix are only found with the prefix.
+	store.prefix = "cluster-1"
+	assert.ErrorContains(t, validateS3BackupStore(store), "no backups found")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "cluster-1", "backups", "instance-abcd"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cluster-1", "backups", "instance-abcd", "velero-backup.json"), []byte("{}"), 0644))
+	assert.NoError(t, validateS3BackupStore(store))
+
+	store.secretAccessKey = "other"
+	assert.ErrorContains(t, validateS3BackupStore(store), "SignatureDoesNotMatch")
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/kotscli"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxRestoreMinorVersionSkew is how many minor versions the embedded cluster version of a
+	// backup can be behind the installer. Older backups must first be restored with an older
+	// installer.
+	maxRestoreMinorVersionSkew = 2
+	// maxRestoreKubernetesMinorVersionSkew is how many minor versions the Kubernetes version
+	// of a backup can be behind the installer. Upgrades never move a cluster more than one
+	// Kubernetes minor version at a time, so the backed up objects are only known to apply
+	// to the next one.
+	maxRestoreKubernetesMinorVersionSkew = 1
+	// restoreUpgradeTimeout is how long to wait for the upgrade of a cluster restored from a
+	// backup taken with an older version.
+	restoreUpgradeTimeout = 30 * time.Minute
+)
+
+var kubernetesVersionMetadataRegex = regexp.MustCompile(`k8s-([0-9]+)\.([0-9]+)`)
+
+// restoreUpgradePlan describes how a cluster restored from a backup taken with older versions
+// is brought to the versions of this installer. The backup is restored as it was taken, then
+// the release embedded in the installer is deployed through the admin console, which upgrades
+// the cluster through the operator as any other upgrade does.
+type restoreUpgradePlan struct {
+	FromECVersion  string
+	ToECVersion    string
+	FromAppVersion string
+	ToAppVersion   string
+	// Migrations are the changes applied to the restored cluster, in order.
+	Migrations []string
+}
+
+// UpgradesCluster returns true if the embedded cluster version changes, i.e. the operator
+// runs an upgrade once the release is deployed.
+func (p *restoreUpgradePlan) UpgradesCluster() bool {
+	return p.FromECVersion != p.ToECVersion
+}
+
+// checkBackupECVersion returns why a backup taken with the provided embedded cluster version
+// can not be restored by this installer, or an empty string if it can.
+func checkBackupECVersion(backupVersion string) string {
+	backupVersion = strings.TrimPrefix(backupVersion, "v")
+	currentVersion := strings.TrimPrefix(versions.Version, "v")
+	if backupVersion == currentVersion {
+		return ""
+	}
+
+	current, err := semver.NewVersion(currentVersion)
+	if err != nil {
+		// development builds can only restore their own backups.
+		return fmt.Sprintf("has a different embedded cluster version (%q) than the current version (%q)", backupVersion, versions.Version)
+	}
+	backup, err := semver.NewVersion(backupVersion)
+	if err != nil {
+		return fmt.Sprintf("has an invalid embedded cluster version (%q)", backupVersion)
+	}
+
+	if backup.GreaterThan(current) {
+		return fmt.Sprintf("has a newer embedded cluster version (%q) than the current version (%q)", backupVersion, versions.Version)
+	}
+	if backup.Major() != current.Major() || current.Minor()-backup.Minor() > maxRestoreMinorVersionSkew {
+		return fmt.Sprintf(
+			"has an embedded cluster version (%q) more than %d minor versions older than the current version (%q)",
+			backupVersion, maxRestoreMinorVersionSkew, versions.Version,
+		)
+	}
+
+	backupK8s, ok := kubernetesMinorVersion(backup)
+	if !ok {
+		return ""
+	}
+	currentK8s, ok := kubernetesMinorVersion(current)
+	if !ok {
+		return ""
+	}
+	if backupK8s[0] > currentK8s[0] || (backupK8s[0] == currentK8s[0] && backupK8s[1] > currentK8s[1]) {
+		return fmt.Sprintf(
+			"was taken with Kubernetes %d.%d, newer than the current Kubernetes %d.%d, and Kubernetes can not be downgraded",
+			backupK8s[0], backupK8s[1], currentK8s[0], currentK8s[1],
+		)
+	}
+	if backupK8s[0] != currentK8s[0] || currentK8s[1]-backupK8s[1] > maxRestoreKubernetesMinorVersionSkew {
+		return fmt.Sprintf(
+			"was taken with Kubernetes %d.%d, more than %d minor version older than the current Kubernetes %d.%d",
+			backupK8s[0], backupK8s[1], maxRestoreKubernetesMinorVersionSkew, currentK8s[0], currentK8s[1],
+		)
+	}
+
+	return ""
+}
+
+// checkBackupAppVersion returns why a backup of the provided app version can not be restored
+// by this installer, or an empty string if it can. Only backups of a newer version are
+// rejected, and only when both versions are semantic versions as the version labels can
+// not be ordered otherwise.
+func checkBackupAppVersion(backupVersion, currentVersion string) string {
+	if backupVersion == currentVersion {
+		return ""
+	}
+	backup, err := semver.NewVersion(backupVersion)
+	if err != nil {
+		return ""
+	}
+	current, err := semver.NewVersion(currentVersion)
+	if err != nil {
+		return ""
+	}
+	if backup.GreaterThan(current) {
+		return fmt.Sprintf("has a newer app version (%q) than the current version (%q)", backupVersion, currentVersion)
+	}
+	return ""
+}
+
+// kubernetesMinorVersion returns the major and minor Kubernetes versions from the build
+// metadata of an embedded cluster version, e.g. 1.30 for 1.19.0+k8s-1.30.
+func kubernetesMinorVersion(v *semver.Version) ([2]uint64, bool) {
+	matches := kubernetesVersionMetadataRegex.FindStringSubmatch(v.Metadata())
+	if matches == nil {
+		return [2]uint64{}, false
+	}
+	major, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return [2]uint64{}, false
+	}
+	minor, err := strconv.ParseUint(matches[2], 10, 64)
+	if err != nil {
+		return [2]uint64{}, false
+	}
+	return [2]uint64{major, minor}, true
+}
+
+// buildRestoreUpgradePlan returns what restoring the backup with this installer upgrades, or
+// nil if the backup was taken with the versions of this installer.
+func buildRestoreUpgradePlan(backup disasterrecovery.ReplicatedBackup, rel *release.ChannelRelease, isAirgap bool) *restoreUpgradePlan {
+	backupECVersion, _ := backup.GetAnnotation("kots.io/embedded-cluster-version")
+	plan := &restoreUpgradePlan{
+		FromECVersion:  strings.TrimPrefix(backupECVersion, "v"),
+		ToECVersion:    strings.TrimPrefix(versions.Version, "v"),
+		FromAppVersion: backupAppVersion(backup),
+		ToAppVersion:   rel.VersionLabel,
+	}
+	if !plan.UpgradesCluster() && plan.FromAppVersion == plan.ToAppVersion {
+		return nil
+	}
+
+	if plan.UpgradesCluster() {
+		backupK8s, backupOK := [2]uint64{}, false
+		if v, err := semver.NewVersion(plan.FromECVersion); err == nil {
+			backupK8s, backupOK = kubernetesMinorVersion(v)
+		}
+		currentK8s, currentOK := [2]uint64{}, false
+		if v, err := semver.NewVersion(plan.ToECVersion); err == nil {
+			currentK8s, currentOK = kubernetesMinorVersion(v)
+		}
+		if backupOK && currentOK && backupK8s != currentK8s {
+			plan.Migrations = append(plan.Migrations, fmt.Sprintf(
+				"The Kubernetes objects backed up from Kubernetes %d.%d are restored into Kubernetes %d.%d.",
+				backupK8s[0], backupK8s[1], currentK8s[0], currentK8s[1],
+			))
+		}
+
+		components := "the Embedded Cluster Operator and the Admin Console"
+		if isAirgap {
+			components = "the Embedded Cluster Operator, the Admin Console and the registry"
+		}
+		plan.Migrations = append(plan.Migrations,
+			fmt.Sprintf("The installation, %s are restored as they were in version %s.", components, plan.FromECVersion),
+		)
+	}
+
+	plan.Migrations = append(plan.Migrations, fmt.Sprintf(
+		"The application is restored at version %s, then version %s is deployed through the Admin Console.",
+		plan.FromAppVersion, plan.ToAppVersion,
+	))
+
+	if plan.UpgradesCluster() {
+		plan.Migrations = append(plan.Migrations,
+			fmt.Sprintf("The cluster is upgraded from version %s to %s: the add-ons and extensions are upgraded to the versions of %s, and the installation is updated with its configuration.", plan.FromECVersion, plan.ToECVersion, plan.ToECVersion),
+		)
+	}
+
+	return plan
+}
+
+func printRestoreUpgradePlan(plan *restoreUpgradePlan) {
+	logrus.Infof("The backup was taken with %s (app version %s) and will be upgraded to %s (app version %s):",
+		plan.FromECVersion, plan.FromAppVersion, plan.ToECVersion, plan.ToAppVersion)
+	for i, migration := range plan.Migrations {
+		logrus.Infof("  %d. %s", i+1, migration)
+	}
+	logrus.Info("")
+}
+
+// runRestoreUpgrade brings a cluster restored from a backup taken with older versions to the
+// versions of this installer. The release embedded in the installer is deployed through the
+// admin console as in any other upgrade, which in turn has the operator upgrade the cluster.
+func runRestoreUpgrade(ctx context.Context, flags InstallCmdFlags, backupToRestore *disasterrecovery.ReplicatedBackup) error {
+	rel, err := release.GetChannelRelease()
+	if err != nil {
+		return fmt.Errorf("unable to get release from binary: %w", err)
+	} else if rel == nil {
+		return fmt.Errorf("no release found in binary")
+	}
+
+	plan := buildRestoreUpgradePlan(*backupToRestore, rel, flags.isAirgap)
+	if plan == nil {
+		return nil
+	}
+	logrus.Debugf("upgrading restored cluster from %s (app %s) to %s (app %s)", plan.FromECVersion, plan.FromAppVersion, plan.ToECVersion, plan.ToAppVersion)
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	current, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get current installation: %w", err)
+	}
+	// the installation is already the new one if a previous attempt deployed the release.
+	target := &ecv1beta1.Config{Spec: ecv1beta1.ConfigSpec{Version: versions.Version}}
+	upgradeStarted := plan.UpgradesCluster() && !clusterUpgradeExpected(current, target)
+
+	if flags.isAirgap {
+		err = kotscli.AirgapUpdate(kotscli.AirgapUpdateOptions{
+			AppSlug:      rel.AppSlug,
+			Namespace:    runtimeconfig.KotsadmNamespace,
+			AirgapBundle: flags.airgapBundle,
+		})
+	} else {
+		err = kotscli.UpstreamUpgrade(kotscli.UpstreamUpgradeOptions{
+			AppSlug:      rel.AppSlug,
+			Namespace:    runtimeconfig.KotsadmNamespace,
+			VersionLabel: rel.VersionLabel,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if !plan.UpgradesCluster() {
+		return nil
+	}
+	if !upgradeStarted {
+		return waitForClusterUpgrade(ctx, kcli, current.Name, restoreUpgradeTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, restoreUpgradeTimeout)
+	defer cancel()
+	loading := spinner.Start()
+	loading.Infof("Upgrading the cluster")
+	if err := kubeutils.WaitForInstallation(ctx, kcli, loading); err != nil {
+		loading.CloseWithError()
+		return err
+	}
+	loading.Closef("Cluster upgraded!")
+	return nil
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setVersionForTests(t *testing.T, version string) {
+	previous := versions.Version
+	versions.Version = version
+	t.Cleanup(func() { versions.Version = previous })
+}
+
+func Test_checkBackupECVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		current       string
+		backupVersion string
+		want          string
+	}{
+		{
+			name:          "same version",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "v1.20.0+k8s-1.30",
+			want:          "",
+		},
+		{
+			name:          "older patch version",
+			current:       "v1.20.1+k8s-1.30",
+			backupVersion: "1.20.0+k8s-1.30",
+			want:          "",
+		},
+		{
+			name:          "older minor version within the window",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "1.18.2+k8s-1.30",
+			want:          "",
+		},
+		{
+			name:          "previous kubernetes minor version",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "1.19.0+k8s-1.29",
+			want:          "",
+		},
+		{
+			name:          "older minor version outside the window",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "1.17.0+k8s-1.30",
+			want:          `has an embedded cluster version ("1.17.0+k8s-1.30") more than 2 minor versions older than the current version ("v1.20.0+k8s-1.30")`,
+		},
+		{
+			name:          "older major version",
+			current:       "v2.0.0+k8s-1.30",
+			backupVersion: "1.20.0+k8s-1.30",
+			want:          `has an embedded cluster version ("1.20.0+k8s-1.30") more than 2 minor versions older than the current version ("v2.0.0+k8s-1.30")`,
+		},
+		{
+			name:          "kubernetes more than one minor version behind",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "1.19.0+k8s-1.28",
+			want:          "was taken with Kubernetes 1.28, more than 1 minor version older than the current Kubernetes 1.30",
+		},
+		{
+			name:          "newer kubernetes version",
+			current:       "v1.20.1+k8s-1.29",
+			backupVersion: "1.20.0+k8s-1.30",
+			want:          "was taken with Kubernetes 1.30, newer than the current Kubernetes 1.29, and Kubernetes can not be downgraded",
+		},
+		{
+			name:          "newer version",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "1.21.0+k8s-1.30",
+			want:          `has a newer embedded cluster version ("1.21.0+k8s-1.30") than the current version ("v1.20.0+k8s-1.30")`,
+		},
+		{
+			name:          "invalid backup version",
+			current:       "v1.20.0+k8s-1.30",
+			backupVersion: "dev",
+			want:          `has an invalid embedded cluster version ("dev")`,
+		},
+		{
+			name:          "development build",
+			current:       "dev-abcdef",
+			backupVersion: "1.20.0+k8s-1.30",
+			want:          `has a different embedded cluster version ("1.20.0+k8s-1.30") than the current version ("dev-abcdef")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setVersionForTests(t, tt.current)
+			assert.Equal(t, tt.want, checkBackupECVersion(tt.backupVersion))
+		})
+	}
+}
+
+func Test_checkBackupAppVersion(t *testing.T) {
+	assert.Equal(t, "", checkBackupAppVersion("1.0.0", "1.0.0"))
+	assert.Equal(t, "", checkBackupAppVersion("1.0.0", "1.1.0"))
+	assert.Equal(t, `has a newer app version ("1.1.0") than the current version ("1.0.0")`, checkBackupAppVersion("1.1.0", "1.0.0"))
+	// version labels that are not semantic versions can not be ordered.
+	assert.Equal(t, "", checkBackupAppVersion("beta", "alpha"))
+}
+
+func Test_buildRestoreUpgradePlan(t *testing.T) {
+	setVersionForTests(t, "v1.20.0+k8s-1.30")
+
+	newBackup := func(ecVersion, appVersion string) disasterrecovery.ReplicatedBackup {
+		return disasterrecovery.ReplicatedBackup{
+			velerov1.Backup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "instance-abcd",
+					Annotations: map[string]string{
+						disasterrecovery.InstanceBackupTypeAnnotation: disasterrecovery.InstanceBackupTypeInfra,
+						"kots.io/embedded-cluster-version":            ecVersion,
+						"kots.io/apps-versions":                       `{"app-slug":"` + appVersion + `"}`,
+					},
+				},
+			},
+		}
+	}
+	rel := &release.ChannelRelease{AppSlug: "app-slug", VersionLabel: "2.0.0"}
+
+	// nothing to upgrade when the backup was taken with the current versions.
+	assert.Nil(t, buildRestoreUpgradePlan(newBackup("v1.20.0+k8s-1.30", "2.0.0"), rel, false))
+
+	got := buildRestoreUpgradePlan(newBackup("v1.20.0+k8s-1.30", "1.0.0"), rel, false)
+	require.NotNil(t, got)
+	assert.False(t, got.UpgradesCluster())
+	assert.Equal(t, []string{
+		"The application is restored at version 1.0.0, then version 2.0.0 is deployed through the Admin Console.",
+	}, got.Migrations)
+
+	got = buildRestoreUpgradePlan(newBackup("v1.19.0+k8s-1.29", "1.0.0"), rel, true)
+	require.NotNil(t, got)
+	assert.True(t, got.UpgradesCluster())
+	assert.Equal(t, "1.19.0+k8s-1.29", got.FromECVersion)
+	assert.Equal(t, "1.20.0+k8s-1.30", got.ToECVersion)
+	assert.Equal(t, []string{
+		"The Kubernetes objects backed up from Kubernetes 1.29 are restored into Kubernetes 1.30.",
+		"The installation, the Embedded Cluster Operator, the Admin Console and the registry are restored as they were in version 1.19.0+k8s-1.29.",
+		"The application is restored at version 1.0.0, then version 2.0.0 is deployed through the Admin Console.",
+		"The cluster is upgraded from version 1.19.0+k8s-1.29 to 1.20.0+k8s-1.30: the add-ons and extensions are upgraded to the versions of 1.20.0+k8s-1.30, and the installation is updated with its configuration.",
+	}, got.Migrations)
+}
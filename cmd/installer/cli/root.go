@@ -94,8 +94,12 @@ func RootCmd(ctx context.Context, name string) *cobra.Command {
 	cmd.AddCommand(MaterializeCmd(ctx, name))
 	cmd.AddCommand(UpdateCmd(ctx, name))
 	cmd.AddCommand(RestoreCmd(ctx, name))
+	cmd.AddCommand(BackupCmd(ctx, name))
 	cmd.AddCommand(AdminConsoleCmd(ctx, name))
 	cmd.AddCommand(SupportBundleCmd(ctx, name))
+	cmd.AddCommand(StatusCmd(ctx, name))
+	cmd.AddCommand(AirgapCmd(ctx, name))
+	cmd.AddCommand(RegistryCmd(ctx, name))
 
 	return cmd
 }
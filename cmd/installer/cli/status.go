@@ -0,0 +1,511 @@
+package cli
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// helmReleaseNameAnnotation is set by helm on every resource it manages.
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	// certificateExpiryWarning is how close to its expiration a certificate is reported
+	// as unhealthy.
+	certificateExpiryWarning = 30 * 24 * time.Hour
+	// diskUsageThreshold is the data dir disk usage, in percent, above which the disk is
+	// reported as unhealthy.
+	diskUsageThreshold = 90
+)
+
+// clusterStatus is the health report printed by the status command.
+type clusterStatus struct {
+	Healthy      bool                `json:"healthy"`
+	Problems     []string            `json:"problems,omitempty"`
+	Installation installationHealth  `json:"installation"`
+	Nodes        []nodeHealth        `json:"nodes"`
+	AddOns       []releaseHealth     `json:"addons"`
+	SystemdUnits []systemdUnitHealth `json:"systemdUnits"`
+	Certificates []certificateHealth `json:"certificates"`
+	DataDir      dataDirHealth       `json:"dataDir"`
+}
+
+type installationHealth struct {
+	Name       string            `json:"name"`
+	State      string            `json:"state"`
+	Reason     string            `json:"reason,omitempty"`
+	Conditions []conditionHealth `json:"conditions,omitempty"`
+}
+
+type conditionHealth struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type nodeHealth struct {
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	Version         string `json:"version"`
+	ExpectedVersion string `json:"expectedVersion"`
+}
+
+type releaseHealth struct {
+	Name      string           `json:"name"`
+	Condition string           `json:"condition,omitempty"`
+	Workloads []workloadHealth `json:"workloads"`
+}
+
+type workloadHealth struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Ready   int32  `json:"ready"`
+	Desired int32  `json:"desired"`
+}
+
+type systemdUnitHealth struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+type certificateHealth struct {
+	Path     string    `json:"path"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+type dataDirHealth struct {
+	Path         string `json:"path"`
+	TotalBytes   uint64 `json:"totalBytes"`
+	UsedBytes    uint64 `json:"usedBytes"`
+	UsedPercent  int    `json:"usedPercent"`
+	ThresholdPct int    `json:"thresholdPercent"`
+}
+
+func StatusCmd(ctx context.Context, name string) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: fmt.Sprintf("Report the health of the %s installation", name),
+		Long: fmt.Sprintf(
+			"Report the health of the %s installation. The command exits with a non-zero code if any check fails, so it can be used for monitoring.",
+			name,
+		),
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getuid() != 0 {
+				return fmt.Errorf("status command must be run as root")
+			}
+			switch output {
+			case "table", "json", "yaml":
+			default:
+				return fmt.Errorf("invalid output format %q, must be one of: table, json, yaml", output)
+			}
+
+			rcutil.InitBestRuntimeConfig(cmd.Context())
+			os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			status := collectClusterStatus(ctx, kcli, time.Now())
+			if err := printClusterStatus(status, output); err != nil {
+				return err
+			}
+			if !status.Healthy {
+				return NewErrorNothingElseToAdd(errors.New("installation is not healthy"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format. One of: table, json, yaml.")
+
+	return cmd
+}
+
+// collectClusterStatus runs all health checks and returns the resulting report. Failing to
+// reach a component is reported as a problem rather than returned as an error.
+func collectClusterStatus(ctx context.Context, kcli client.Client, now time.Time) *clusterStatus {
+	status := &clusterStatus{Healthy: true}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		status.addProblem(fmt.Sprintf("unable to get installation: %v", err))
+	} else {
+		status.Installation = getInstallationHealth(in)
+		for _, problem := range installationProblems(status.Installation) {
+			status.addProblem(problem)
+		}
+	}
+
+	status.Nodes, err = getNodesHealth(ctx, kcli, expectedKubeletVersion())
+	if err != nil {
+		status.addProblem(err.Error())
+	}
+	for _, node := range status.Nodes {
+		if !strings.HasPrefix(node.Status, "Ready") {
+			status.addProblem(fmt.Sprintf("node %s is %s", node.Name, node.Status))
+		}
+		if node.Version != node.ExpectedVersion {
+			status.addProblem(fmt.Sprintf("node %s runs %s, expected %s", node.Name, node.Version, node.ExpectedVersion))
+		}
+	}
+
+	status.AddOns, err = getReleasesHealth(ctx, kcli, in)
+	if err != nil {
+		status.addProblem(err.Error())
+	}
+	for _, release := range status.AddOns {
+		for _, w := range release.Workloads {
+			if w.Ready < w.Desired {
+				status.addProblem(fmt.Sprintf("%s %s/%s has %d of %d replicas ready", strings.ToLower(w.Kind), release.Name, w.Name, w.Ready, w.Desired))
+			}
+		}
+	}
+
+	status.SystemdUnits, err = getSystemdUnitsHealth(ctx)
+	if err != nil {
+		status.addProblem(err.Error())
+	}
+	for _, unit := range status.SystemdUnits {
+		if !unit.Active {
+			status.addProblem(fmt.Sprintf("systemd unit %s is not active", unit.Name))
+		}
+	}
+
+	status.Certificates, err = getCertificatesHealth(filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), "pki"))
+	if err != nil {
+		status.addProblem(err.Error())
+	}
+	for _, cert := range status.Certificates {
+		if cert.NotAfter.Sub(now) < certificateExpiryWarning {
+			status.addProblem(fmt.Sprintf("certificate %s expires at %s", cert.Path, cert.NotAfter.Format(time.RFC3339)))
+		}
+	}
+
+	status.DataDir, err = getDataDirHealth(runtimeconfig.EmbeddedClusterHomeDirectory())
+	if err != nil {
+		status.addProblem(err.Error())
+	} else if status.DataDir.UsedPercent >= status.DataDir.ThresholdPct {
+		status.addProblem(fmt.Sprintf("data directory %s is %d%% full", status.DataDir.Path, status.DataDir.UsedPercent))
+	}
+
+	return status
+}
+
+func (s *clusterStatus) addProblem(problem string) {
+	s.Healthy = false
+	s.Problems = append(s.Problems, problem)
+}
+
+func getInstallationHealth(in *ecv1beta1.Installation) installationHealth {
+	health := installationHealth{
+		Name:   in.Name,
+		State:  in.Status.State,
+		Reason: in.Status.Reason,
+	}
+	for _, cond := range in.Status.Conditions {
+		health.Conditions = append(health.Conditions, conditionHealth{
+			Type:    cond.Type,
+			Status:  string(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		})
+	}
+	return health
+}
+
+// installationProblems reports an installation that is not in the installed state and any
+// condition, including the per addon ones, that is not true.
+func installationProblems(health installationHealth) []string {
+	problems := []string{}
+	if health.State != ecv1beta1.InstallationStateInstalled {
+		problems = append(problems, fmt.Sprintf("installation %s is in state %s: %s", health.Name, health.State, health.Reason))
+	}
+	for _, cond := range health.Conditions {
+		if cond.Status == "False" {
+			problems = append(problems, fmt.Sprintf("installation condition %s is false: %s", cond.Type, cond.Reason))
+		}
+	}
+	return problems
+}
+
+// expectedKubeletVersion takes versions like v1.30.5+k0s.0 and returns v1.30.5+k0s to match
+// the kubelet version reported by the nodes.
+func expectedKubeletVersion() string {
+	return strings.Split(versions.K0sVersion, "k0s")[0] + "k0s"
+}
+
+func getNodesHealth(ctx context.Context, kcli client.Client, expectedVersion string) ([]nodeHealth, error) {
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+	result := []nodeHealth{}
+	for _, node := range nodes.Items {
+		result = append(result, nodeHealth{
+			Name:            node.Name,
+			Status:          nodeStatus(node),
+			Version:         node.Status.NodeInfo.KubeletVersion,
+			ExpectedVersion: expectedVersion,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// getReleasesHealth groups the workloads managed by helm by release. Releases are named
+// <namespace>-<release>, the same as the installation conditions set for each addon, and
+// the matching condition status is reported if any.
+func getReleasesHealth(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation) ([]releaseHealth, error) {
+	workloads := map[string][]workloadHealth{}
+	add := func(obj client.Object, kind string, ready, desired int32) {
+		release, ok := obj.GetAnnotations()[helmReleaseNameAnnotation]
+		if !ok {
+			return
+		}
+		key := fmt.Sprintf("%s-%s", obj.GetNamespace(), release)
+		workloads[key] = append(workloads[key], workloadHealth{
+			Kind: kind, Name: obj.GetName(), Ready: ready, Desired: desired,
+		})
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := kcli.List(ctx, &deployments); err != nil {
+		return nil, fmt.Errorf("unable to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		add(&d, "Deployment", d.Status.ReadyReplicas, desired)
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := kcli.List(ctx, &statefulSets); err != nil {
+		return nil, fmt.Errorf("unable to list statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		desired := int32(1)
+		if s.Spec.Replicas != nil {
+			desired = *s.Spec.Replicas
+		}
+		add(&s, "StatefulSet", s.Status.ReadyReplicas, desired)
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := kcli.List(ctx, &daemonSets); err != nil {
+		return nil, fmt.Errorf("unable to list daemonsets: %w", err)
+	}
+	for _, d := range daemonSets.Items {
+		add(&d, "DaemonSet", d.Status.NumberReady, d.Status.DesiredNumberScheduled)
+	}
+
+	result := []releaseHealth{}
+	for name, wls := range workloads {
+		sort.Slice(wls, func(i, j int) bool {
+			return wls[i].Kind+wls[i].Name < wls[j].Kind+wls[j].Name
+		})
+		release := releaseHealth{Name: name, Workloads: wls}
+		if in != nil {
+			release.Condition = string(kubeutils.CheckInstallationConditionStatus(in.Status, name))
+		}
+		result = append(result, release)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// getSystemdUnitsHealth reports the k0s unit for the role of this node and the local
+// artifact mirror unit.
+func getSystemdUnitsHealth(ctx context.Context) ([]systemdUnitHealth, error) {
+	result := []systemdUnitHealth{}
+	for _, unit := range []string{"k0scontroller", "k0sworker", "local-artifact-mirror"} {
+		exists, err := systemd.UnitExists(ctx, unit)
+		if err != nil {
+			return result, fmt.Errorf("unable to check systemd unit %s: %w", unit, err)
+		}
+		if !exists {
+			continue
+		}
+		active, err := systemd.IsActive(ctx, unit)
+		if err != nil {
+			return result, fmt.Errorf("unable to check systemd unit %s: %w", unit, err)
+		}
+		result = append(result, systemdUnitHealth{Name: unit, Active: active})
+	}
+	return result, nil
+}
+
+// getCertificatesHealth returns the expiration of every certificate in the k0s pki
+// directory. Worker nodes have no pki directory, in which case nothing is returned.
+func getCertificatesHealth(dir string) ([]certificateHealth, error) {
+	result := []certificateHealth{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".crt" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return fmt.Errorf("unable to decode certificate %s", path)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("unable to parse certificate %s: %w", path, err)
+		}
+		result = append(result, certificateHealth{Path: path, NotAfter: cert.NotAfter.UTC()})
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("unable to read certificates: %w", err)
+	}
+	return result, nil
+}
+
+func getDataDirHealth(dir string) (dataDirHealth, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return dataDirHealth{}, fmt.Errorf("unable to get disk usage of %s: %w", dir, err)
+	}
+	total := stat.Blocks * uint64(stat.Bsize)
+	used := total - stat.Bfree*uint64(stat.Bsize)
+	percent := 0
+	if total > 0 {
+		percent = int(used * 100 / total)
+	}
+	return dataDirHealth{
+		Path:         dir,
+		TotalBytes:   total,
+		UsedBytes:    used,
+		UsedPercent:  percent,
+		ThresholdPct: diskUsageThreshold,
+	}, nil
+}
+
+func printClusterStatus(status *clusterStatus, output string) error {
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal status: %w", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := k8syaml.Marshal(status)
+		if err != nil {
+			return fmt.Errorf("unable to marshal status: %w", err)
+		}
+		fmt.Print(string(data))
+	default:
+		printClusterStatusTables(status)
+	}
+	return nil
+}
+
+func printClusterStatusTables(status *clusterStatus) {
+	writer := table.NewWriter()
+	writer.AppendHeader(table.Row{"installation", "state", "reason"})
+	writer.AppendRow(table.Row{status.Installation.Name, status.Installation.State, status.Installation.Reason})
+	fmt.Printf("%s\n\n", writer.Render())
+
+	if len(status.Installation.Conditions) > 0 {
+		writer = table.NewWriter()
+		writer.AppendHeader(table.Row{"condition", "status", "reason"})
+		for _, c := range status.Installation.Conditions {
+			writer.AppendRow(table.Row{c.Type, c.Status, c.Reason})
+		}
+		fmt.Printf("%s\n\n", writer.Render())
+	}
+
+	writer = table.NewWriter()
+	writer.AppendHeader(table.Row{"node", "status", "version", "expected version"})
+	for _, n := range status.Nodes {
+		writer.AppendRow(table.Row{n.Name, n.Status, n.Version, n.ExpectedVersion})
+	}
+	fmt.Printf("%s\n\n", writer.Render())
+
+	writer = table.NewWriter()
+	writer.AppendHeader(table.Row{"addon", "workload", "ready"})
+	for _, r := range status.AddOns {
+		for _, w := range r.Workloads {
+			writer.AppendRow(table.Row{r.Name, fmt.Sprintf("%s/%s", strings.ToLower(w.Kind), w.Name), fmt.Sprintf("%d/%d", w.Ready, w.Desired)})
+		}
+	}
+	fmt.Printf("%s\n\n", writer.Render())
+
+	writer = table.NewWriter()
+	writer.AppendHeader(table.Row{"systemd unit", "active"})
+	for _, u := range status.SystemdUnits {
+		writer.AppendRow(table.Row{u.Name, u.Active})
+	}
+	fmt.Printf("%s\n\n", writer.Render())
+
+	if len(status.Certificates) > 0 {
+		writer = table.NewWriter()
+		writer.AppendHeader(table.Row{"certificate", "expires"})
+		for _, c := range status.Certificates {
+			writer.AppendRow(table.Row{c.Path, c.NotAfter.Format(time.RFC3339)})
+		}
+		fmt.Printf("%s\n\n", writer.Render())
+	}
+
+	writer = table.NewWriter()
+	writer.AppendHeader(table.Row{"data directory", "used"})
+	writer.AppendRow(table.Row{status.DataDir.Path, fmt.Sprintf("%d%%", status.DataDir.UsedPercent)})
+	fmt.Printf("%s\n\n", writer.Render())
+
+	if status.Healthy {
+		fmt.Println("Status: Healthy")
+		return
+	}
+	fmt.Println("Status: Unhealthy")
+	for _, problem := range status.Problems {
+		fmt.Printf("  - %s\n", problem)
+	}
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
+	"github.com/replicatedhq/embedded-cluster/pkg/certs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_installationProblems(t *testing.T) {
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20240101000000"},
+		Status: ecv1beta1.InstallationStatus{
+			State: ecv1beta1.InstallationStateInstalled,
+			Conditions: []metav1.Condition{
+				{Type: "openebs-openebs", Status: metav1.ConditionTrue, Reason: "Upgraded"},
+			},
+		},
+	}
+	assert.Empty(t, installationProblems(getInstallationHealth(in)))
+
+	in.Status.State = ecv1beta1.InstallationStateHelmChartUpdateFailure
+	in.Status.Reason = "chart failed"
+	in.Status.Conditions = append(in.Status.Conditions, metav1.Condition{
+		Type: "kotsadm-admin-console", Status: metav1.ConditionFalse, Reason: "UpgradeFailed",
+	})
+	assert.Equal(t, []string{
+		"installation 20240101000000 is in state HelmChartUpdateFailure: chart failed",
+		"installation condition kotsadm-admin-console is false: UpgradeFailed",
+	}, installationProblems(getInstallationHealth(in)))
+}
+
+func Test_installationProblemsDrift(t *testing.T) {
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20240101000000"},
+		Status: ecv1beta1.InstallationStatus{
+			State: ecv1beta1.InstallationStateInstalled,
+		},
+	}
+	// the conditions set by the operator when it reconciles the drift of the addons.
+	in.Status.SetCondition(addons.DriftCondition(addons.AddOnDrift{AddOn: &openebs.OpenEBS{}}))
+	assert.Empty(t, installationProblems(getInstallationHealth(in)))
+
+	in.Status.SetCondition(addons.DriftCondition(addons.AddOnDrift{
+		AddOn:   &velero.Velero{},
+		Reasons: []string{"Deployment velero/velero differs at spec.replicas"},
+	}))
+	assert.Equal(t, []string{
+		"installation condition velero-velero-InSync is false: Drifted",
+	}, installationProblems(getInstallationHealth(in)))
+}
+
+func Test_getReleasesHealth(t *testing.T) {
+	helmAnnotations := func(release string) map[string]string {
+		return map[string]string{helmReleaseNameAnnotation: release}
+	}
+	kcli := fake.NewClientBuilder().WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "kotsadm", Namespace: "kotsadm", Annotations: helmAnnotations("admin-console")},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(1))},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "kotsadm-rqlite", Namespace: "kotsadm", Annotations: helmAnnotations("admin-console")},
+			Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(3))},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 2},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-agent", Namespace: "velero", Annotations: helmAnnotations("velero")},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberReady: 2},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-helm", Namespace: "default"},
+		},
+	).Build()
+
+	in := &ecv1beta1.Installation{
+		Status: ecv1beta1.InstallationStatus{
+			Conditions: []metav1.Condition{{Type: "kotsadm-admin-console", Status: metav1.ConditionTrue}},
+		},
+	}
+
+	got, err := getReleasesHealth(context.Background(), kcli, in)
+	require.NoError(t, err)
+	assert.Equal(t, []releaseHealth{
+		{
+			Name:      "kotsadm-admin-console",
+			Condition: "True",
+			Workloads: []workloadHealth{
+				{Kind: "Deployment", Name: "kotsadm", Ready: 1, Desired: 1},
+				{Kind: "StatefulSet", Name: "kotsadm-rqlite", Ready: 2, Desired: 3},
+			},
+		},
+		{
+			Name:      "velero-velero",
+			Workloads: []workloadHealth{{Kind: "DaemonSet", Name: "node-agent", Ready: 2, Desired: 2}},
+		},
+	}, got)
+}
+
+func Test_getCertificatesHealth(t *testing.T) {
+	dir := t.TempDir()
+	expiration := time.Now().Add(10 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	builder, err := certs.NewBuilder(certs.WithExpiration(expiration))
+	require.NoError(t, err)
+	crt, _, err := builder.Generate()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "etcd"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "etcd", "server.crt"), []byte(crt), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.key"), []byte("not a cert"), 0600))
+
+	got, err := getCertificatesHealth(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []certificateHealth{
+		{Path: filepath.Join(dir, "etcd", "server.crt"), NotAfter: expiration},
+	}, got)
+
+	got, err = getCertificatesHealth(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/replicatedhq/embedded-cluster/cmd/installer/kotscli"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
@@ -16,6 +17,8 @@ import (
 func UpdateCmd(ctx context.Context, name string) *cobra.Command {
 	var (
 		airgapBundle string
+		plan         bool
+		onlineOpts   onlineUpdateOptions
 	)
 
 	cmd := &cobra.Command{
@@ -26,6 +29,23 @@ func UpdateCmd(ctx context.Context, name string) *cobra.Command {
 				return fmt.Errorf("update command must be run as root")
 			}
 
+			sources := 0
+			for _, flag := range []string{"airgap-bundle", "channel-sequence", "latest"} {
+				if cmd.Flags().Changed(flag) {
+					sources++
+				}
+			}
+			if plan {
+				// the plan is always computed for the release embedded in this binary.
+				if cmd.Flags().Changed("channel-sequence") || cmd.Flags().Changed("latest") {
+					return fmt.Errorf("--plan cannot be used with --channel-sequence or --latest")
+				}
+			} else if sources != 1 {
+				return fmt.Errorf("exactly one of --airgap-bundle, --channel-sequence or --latest must be provided")
+			} else if airgapBundle == "" && onlineOpts.licenseFile == "" {
+				return fmt.Errorf(`required flag(s) "license" not set`)
+			}
+
 			if err := rcutil.InitRuntimeConfigFromCluster(ctx); err != nil {
 				return fmt.Errorf("failed to init runtime config from cluster: %w", err)
 			}
@@ -39,11 +59,14 @@ func UpdateCmd(ctx context.Context, name string) *cobra.Command {
 			runtimeconfig.Cleanup()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if airgapBundle != "" {
-				logrus.Debugf("checking airgap bundle matches binary")
-				if err := checkAirgapMatches(airgapBundle); err != nil {
-					return err // we want the user to see the error message without a prefix
+			if plan {
+				if airgapBundle != "" {
+					logrus.Debugf("checking airgap bundle matches binary")
+					if err := checkAirgapMatches(airgapBundle); err != nil {
+						return err // we want the user to see the error message without a prefix
+					}
 				}
+				return runUpdatePlan(cmd.Context())
 			}
 
 			rel, err := release.GetChannelRelease()
@@ -54,6 +77,21 @@ func UpdateCmd(ctx context.Context, name string) *cobra.Command {
 				return fmt.Errorf("no channel release found")
 			}
 
+			if airgapBundle == "" {
+				return runOnlineUpdate(cmd.Context(), rel, onlineOpts)
+			}
+
+			logrus.Debugf("checking airgap bundle matches binary")
+			if err := checkAirgapMatches(airgapBundle); err != nil {
+				return err // we want the user to see the error message without a prefix
+			}
+
+			// the bundle is verified before it is handed to kots so a truncated or corrupted
+			// copy fails before the update starts.
+			if err := verifyAirgapBundle(airgapBundle); err != nil {
+				return err // we want the user to see the error message without a prefix
+			}
+
 			if err := kotscli.AirgapUpdate(kotscli.AirgapUpdateOptions{
 				AppSlug:      rel.AppSlug,
 				Namespace:    runtimeconfig.KotsadmNamespace,
@@ -67,7 +105,14 @@ func UpdateCmd(ctx context.Context, name string) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&airgapBundle, "airgap-bundle", "", "Path to the air gap bundle. If set, the installation will complete without internet access.")
-	cmd.MarkFlagRequired("airgap-bundle")
+	cmd.Flags().Int64Var(&onlineOpts.channelSequence, "channel-sequence", 0, "Channel sequence of the release to update to. The release is downloaded from the internet.")
+	cmd.Flags().BoolVar(&onlineOpts.latest, "latest", false, "Update to the latest release in the channel. The release is downloaded from the internet.")
+	cmd.Flags().StringVarP(&onlineOpts.licenseFile, "license", "l", "", "Path to the license file. Required when updating without an air gap bundle.")
+	cmd.Flags().BoolVar(&onlineOpts.ignoreHostPreflights, "ignore-host-preflights", false, "Run host preflight checks, but prompt the user to continue if they fail instead of exiting.")
+	cmd.Flags().DurationVar(&onlineOpts.timeout, "timeout", 30*time.Minute, "How long to wait for the cluster upgrade to finish.")
+	cmd.Flags().BoolVar(&plan, "plan", false, "Print what updating the cluster to the release embedded in this binary would change, without updating it.")
+	cmd.Flags().BoolVarP(&onlineOpts.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
 
 	return cmd
 }
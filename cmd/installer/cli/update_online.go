@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/kotscli"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/replicatedhq/embedded-cluster/utils/pkg/embed"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type onlineUpdateOptions struct {
+	licenseFile          string
+	channelSequence      int64
+	latest               bool
+	assumeYes            bool
+	ignoreHostPreflights bool
+	timeout              time.Duration
+}
+
+// runOnlineUpdate deploys a new release without an air gap bundle. The host preflights of
+// the new release are run on this node before KOTS is asked to deploy it, then we follow
+// the Installation object until the cluster upgrade is finished.
+func runOnlineUpdate(ctx context.Context, rel *release.ChannelRelease, opts onlineUpdateOptions) error {
+	license, err := getLicenseFromFilepath(opts.licenseFile)
+	if err != nil {
+		return err
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	current, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get current installation: %w", err)
+	}
+	setProxyEnv(current.Spec.Proxy)
+
+	var target *apiChannelRelease
+	if opts.latest {
+		target, err = getCurrentAppChannelRelease(ctx, license, rel.ChannelID)
+	} else {
+		target, err = getAppChannelReleaseBySequence(ctx, license, rel.ChannelID, opts.channelSequence)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get app release: %w", err)
+	}
+	if target.VersionLabel == "" {
+		return fmt.Errorf("app release %d has no version label", target.ChannelSequence)
+	}
+	logrus.Infof("Updating to version %s (channel sequence %d)", target.VersionLabel, target.ChannelSequence)
+
+	loading := spinner.Start()
+	loading.Infof("Downloading release %s", target.VersionLabel)
+	targetData, err := downloadReleaseData(ctx, license, rel, target.VersionLabel)
+	if err != nil {
+		loading.CloseWithError()
+		return fmt.Errorf("unable to download release %s: %w", target.VersionLabel, err)
+	}
+	loading.Closef("Release %s downloaded!", target.VersionLabel)
+
+	if err := runUpdatePreflights(ctx, current, targetData, license, opts); err != nil {
+		if errors.Is(err, preflights.ErrPreflightsHaveFail) {
+			return NewErrorNothingElseToAdd(err)
+		}
+		return fmt.Errorf("unable to run host preflights: %w", err)
+	}
+
+	if err := kotscli.UpstreamUpgrade(kotscli.UpstreamUpgradeOptions{
+		AppSlug:      rel.AppSlug,
+		Namespace:    runtimeconfig.KotsadmNamespace,
+		VersionLabel: target.VersionLabel,
+	}); err != nil {
+		return err
+	}
+
+	targetConfig, err := targetData.GetEmbeddedClusterConfig()
+	if err != nil {
+		return fmt.Errorf("unable to read embedded cluster config of release %s: %w", target.VersionLabel, err)
+	}
+	if !clusterUpgradeExpected(current, targetConfig) {
+		logrus.Infof("Version %s does not change the cluster, update finished", target.VersionLabel)
+		return nil
+	}
+
+	return waitForClusterUpgrade(ctx, kcli, current.Name, opts.timeout)
+}
+
+// clusterUpgradeExpected returns true if deploying a release with the provided config
+// results in a new Installation object, i.e. the embedded cluster version changes.
+func clusterUpgradeExpected(current *ecv1beta1.Installation, target *ecv1beta1.Config) bool {
+	if target == nil || current.Spec.Config == nil {
+		return true
+	}
+	return strings.TrimPrefix(target.Spec.Version, "v") != strings.TrimPrefix(current.Spec.Config.Version, "v")
+}
+
+func runUpdatePreflights(ctx context.Context, in *ecv1beta1.Installation, data *release.ReleaseData, license *kotsv1beta1.License, opts onlineUpdateOptions) error {
+	hpf, err := data.GetHostPreflights()
+	if err != nil {
+		return fmt.Errorf("unable to read host preflights: %w", err)
+	}
+
+	podCIDR, serviceCIDR, err := netutils.SplitNetworkCIDR(ecv1beta1.DefaultNetworkCIDR)
+	if err != nil {
+		return fmt.Errorf("unable to split default network CIDR: %w", err)
+	}
+	if in.Spec.Network != nil {
+		if in.Spec.Network.PodCIDR != "" {
+			podCIDR = in.Spec.Network.PodCIDR
+		}
+		if in.Spec.Network.ServiceCIDR != "" {
+			serviceCIDR = in.Spec.Network.ServiceCIDR
+		}
+	}
+
+	nodeIP, err := netutils.FirstValidAddress("")
+	if err != nil {
+		return fmt.Errorf("unable to find first valid address: %w", err)
+	}
+
+	rc := in.Spec.RuntimeConfig
+	if rc == nil {
+		rc = ecv1beta1.GetDefaultRuntimeConfig()
+	}
+
+	// this node already runs the cluster, the checks for the ports, the data directory and
+	// the CIDRs to be available would fail.
+	return preflights.PrepareAndRun(ctx, preflights.PrepareAndRunOptions{
+		ReplicatedAPIURL:        license.Spec.Endpoint,
+		ProxyRegistryURL:        fmt.Sprintf("https://%s", runtimeconfig.ProxyRegistryAddress),
+		Proxy:                   in.Spec.Proxy,
+		PodCIDR:                 podCIDR,
+		ServiceCIDR:             serviceCIDR,
+		NodeIP:                  nodeIP,
+		IgnoreHostPreflights:    opts.ignoreHostPreflights,
+		AssumeYes:               opts.assumeYes,
+		IsUpgrade:               true,
+		IngressEnabled:          in.Spec.Ingress.IsEnabled(),
+		AdminConsolePort:        rc.AdminConsole.Port,
+		LocalArtifactMirrorPort: rc.LocalArtifactMirror.Port,
+		HostPreflightSpec:       hpf,
+	})
+}
+
+// downloadReleaseData downloads the embedded cluster release with the provided version label
+// and returns the release data embedded in its binary.
+func downloadReleaseData(ctx context.Context, license *kotsv1beta1.License, rel *release.ChannelRelease, versionLabel string) (*release.ReleaseData, error) {
+	url := fmt.Sprintf("%s/embedded/%s/%s/%s", metrics.BaseURL(license), rel.AppSlug, rel.ChannelSlug, versionLabel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", license.Spec.LicenseID)
+
+	// This will use the proxy from the environment if set by the cli command.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %s", resp.Status)
+	}
+
+	tmpdir, err := os.MkdirTemp(runtimeconfig.EmbeddedClusterTmpSubDir(), "update-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	binPath := filepath.Join(tmpdir, rel.AppSlug)
+	if err := extractFileFromTGZ(resp.Body, rel.AppSlug, binPath); err != nil {
+		return nil, err
+	}
+
+	data, err := embed.ExtractReleaseDataFromBinary(binPath)
+	if err != nil {
+		return nil, fmt.Errorf("extract release data: %w", err)
+	}
+	return release.NewReleaseDataFrom(data)
+}
+
+// extractFileFromTGZ writes the regular file with the provided name from a tar.gz stream
+// to dst.
+func extractFileFromTGZ(r io.Reader, name string, dst string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("create gzip reader: %w", err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("file %s not found in archive", name)
+		} else if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Clean(header.Name) != name {
+			continue
+		}
+
+		f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", dst, err)
+		}
+		defer f.Close()
+		if _, err := io.Copy(f, tr); err != nil {
+			return fmt.Errorf("write %s: %w", dst, err)
+		}
+		return nil
+	}
+}
+
+// waitForClusterUpgrade waits for KOTS to create a new Installation object and then follows
+// it until the upgrade either finishes or fails.
+func waitForClusterUpgrade(ctx context.Context, kcli client.Client, previous string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	loading := spinner.Start()
+	loading.Infof("Waiting for the cluster upgrade to start")
+	if err := waitForNewInstallation(ctx, kcli, previous, 2*time.Second); err != nil {
+		loading.CloseWithError()
+		return err
+	}
+
+	loading.Infof("Upgrading the cluster")
+	if err := kubeutils.WaitForInstallation(ctx, kcli, loading); err != nil {
+		loading.CloseWithError()
+		return err
+	}
+
+	loading.Closef("Cluster upgraded!")
+	return nil
+}
+
+// waitForNewInstallation polls until the latest Installation object is not the previous one.
+func waitForNewInstallation(ctx context.Context, kcli client.Client, previous string, interval time.Duration) error {
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+		if err != nil {
+			logrus.Debugf("unable to get latest installation: %v", err)
+			return false, nil
+		}
+		return in.Name != previous, nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for the cluster upgrade to start: %w", err)
+	}
+	return nil
+}
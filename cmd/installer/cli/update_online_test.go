@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_extractFileFromTGZ(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range map[string]string{"license.yaml": "license", "app-slug": "binary"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	dst := filepath.Join(t.TempDir(), "app-slug")
+	require.NoError(t, extractFileFromTGZ(bytes.NewReader(buf.Bytes()), "app-slug", dst))
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(content))
+
+	err = extractFileFromTGZ(bytes.NewReader(buf.Bytes()), "missing", dst)
+	assert.ErrorContains(t, err, "file missing not found in archive")
+}
+
+func Test_clusterUpgradeExpected(t *testing.T) {
+	current := &ecv1beta1.Installation{
+		Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{Version: "2.0.0+k8s-1.30"}},
+	}
+
+	assert.False(t, clusterUpgradeExpected(current, &ecv1beta1.Config{Spec: ecv1beta1.ConfigSpec{Version: "v2.0.0+k8s-1.30"}}))
+	assert.True(t, clusterUpgradeExpected(current, &ecv1beta1.Config{Spec: ecv1beta1.ConfigSpec{Version: "2.1.0+k8s-1.30"}}))
+	assert.True(t, clusterUpgradeExpected(current, nil))
+}
+
+func Test_waitForNewInstallation(t *testing.T) {
+	spec := ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{Version: "2.0.0+k8s-1.30"}}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(
+		&ecv1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "20240101000000"}, Spec: spec},
+	).Build()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := waitForNewInstallation(ctx, kcli, "20240101000000", 10*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for the cluster upgrade to start")
+
+	require.NoError(t, kcli.Create(context.Background(), &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20240102000000"},
+		Spec:       spec,
+	}))
+	err = waitForNewInstallation(context.Background(), kcli, "20240101000000", 10*time.Millisecond)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	oprelease "github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/extensions"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// updatePlan describes what updating the cluster to the release embedded in this binary
+// changes. Nothing is changed in the cluster while the plan is computed.
+type updatePlan struct {
+	K0s                 k0sPlan
+	AddOns              []addons.AddOnPlan
+	Extensions          []extensions.ExtensionPlan
+	AddedImages         []string
+	RemovedImages       []string
+	ClusterConfigImages []helm.ValueChange
+}
+
+type k0sPlan struct {
+	FromVersion string
+	ToVersion   string
+	// Nodes are the nodes autopilot upgrades, i.e. the ones not running the new version.
+	Nodes []nodeUpgradePlan
+}
+
+type nodeUpgradePlan struct {
+	Name    string
+	Role    string
+	Version string
+}
+
+func runUpdatePlan(ctx context.Context) error {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	current, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get current installation: %w", err)
+	}
+
+	hcli, err := helm.NewClient(helm.HelmOptions{
+		KubeConfig: runtimeconfig.PathToKubeConfig(),
+		K0sVersion: versions.K0sVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create helm client: %w", err)
+	}
+	defer hcli.Close()
+
+	plan, err := buildUpdatePlan(ctx, kcli, hcli, current)
+	if err != nil {
+		return err
+	}
+	printUpdatePlan(plan)
+	return nil
+}
+
+func buildUpdatePlan(ctx context.Context, kcli client.Client, hcli helm.Client, current *ecv1beta1.Installation) (*updatePlan, error) {
+	currentMeta, err := oprelease.MetadataFor(ctx, current, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get current release metadata: %w", err)
+	}
+
+	meta, err := gatherVersionMetadata(true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to gather release metadata: %w", err)
+	}
+
+	cfg, err := release.GetEmbeddedClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get embedded cluster config: %w", err)
+	}
+
+	// this is the installation object kots creates when the new release is deployed.
+	next := current.DeepCopy()
+	next.Spec.Config = &ecv1beta1.ConfigSpec{Version: versions.Version}
+	if cfg != nil {
+		next.Spec.Config = cfg.Spec.DeepCopy()
+		next.Spec.Config.Version = versions.Version
+	}
+
+	plan := &updatePlan{
+		K0s: k0sPlan{
+			FromVersion: currentMeta.Versions["Kubernetes"],
+			ToVersion:   versions.K0sVersion,
+		},
+		Extensions: extensions.PlanUpgrade(current.Spec.Config, next.Spec.Config),
+	}
+	plan.AddedImages, plan.RemovedImages = diffImages(currentMeta.Images, meta.Images)
+
+	plan.K0s.Nodes, err = nodesToUpgrade(ctx, kcli, expectedKubeletVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	plan.AddOns, err = addons.PlanUpgrade(ctx, kcli, hcli, next, meta, currentMeta)
+	if err != nil {
+		return nil, fmt.Errorf("unable to plan addons upgrade: %w", err)
+	}
+
+	plan.ClusterConfigImages, err = planClusterConfigImages(ctx, kcli)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// nodesToUpgrade returns the nodes whose kubelet does not run the provided version. These
+// are the nodes autopilot upgrades.
+func nodesToUpgrade(ctx context.Context, kcli client.Client, version string) ([]nodeUpgradePlan, error) {
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	result := []nodeUpgradePlan{}
+	for _, node := range nodes.Items {
+		if node.Status.NodeInfo.KubeletVersion == version {
+			continue
+		}
+		role := "worker"
+		if _, ok := node.Labels[controlPlaneLabel]; ok {
+			role = "controller"
+		}
+		result = append(result, nodeUpgradePlan{
+			Name:    node.Name,
+			Role:    role,
+			Version: node.Status.NodeInfo.KubeletVersion,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// diffImages returns the images present only in next and the images present only in prev.
+func diffImages(prev, next []string) ([]string, []string) {
+	prevSet := map[string]bool{}
+	for _, image := range prev {
+		prevSet[image] = true
+	}
+	nextSet := map[string]bool{}
+	for _, image := range next {
+		nextSet[image] = true
+	}
+
+	added, removed := []string{}, []string{}
+	for image := range nextSet {
+		if !prevSet[image] {
+			added = append(added, image)
+		}
+	}
+	for image := range prevSet {
+		if !nextSet[image] {
+			removed = append(removed, image)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// planClusterConfigImages compares the images in the k0s cluster config with the ones the
+// operator writes to it during the upgrade.
+func planClusterConfigImages(ctx context.Context, kcli client.Client) ([]helm.ValueChange, error) {
+	var currentCfg k0sv1beta1.ClusterConfig
+	if err := kcli.Get(ctx, client.ObjectKey{Name: "k0s", Namespace: "kube-system"}, &currentCfg); err != nil {
+		return nil, fmt.Errorf("unable to get cluster config: %w", err)
+	}
+	var currentImages *k0sv1beta1.ClusterImages
+	if currentCfg.Spec != nil {
+		currentImages = currentCfg.Spec.Images
+	}
+	return diffClusterImages(currentImages, config.RenderK0sConfig().Spec.Images)
+}
+
+func diffClusterImages(prev, next *k0sv1beta1.ClusterImages) ([]helm.ValueChange, error) {
+	prevValues, err := clusterImagesToValues(prev)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert current cluster config images: %w", err)
+	}
+	nextValues, err := clusterImagesToValues(next)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert new cluster config images: %w", err)
+	}
+	return helm.DiffValues(prevValues, nextValues)
+}
+
+func clusterImagesToValues(images *k0sv1beta1.ClusterImages) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if images == nil {
+		return values, nil
+	}
+	data, err := json.Marshal(images)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func printUpdatePlan(plan *updatePlan) {
+	writer := table.NewWriter()
+	writer.AppendHeader(table.Row{"component", "current version", "new version"})
+	writer.AppendRow(table.Row{"k0s", plan.K0s.FromVersion, plan.K0s.ToVersion})
+	fmt.Printf("%s\n\n", writer.Render())
+
+	if len(plan.K0s.Nodes) > 0 {
+		writer = table.NewWriter()
+		writer.AppendHeader(table.Row{"node to upgrade", "role", "current version"})
+		for _, node := range plan.K0s.Nodes {
+			writer.AppendRow(table.Row{node.Name, node.Role, node.Version})
+		}
+		fmt.Printf("%s\n\n", writer.Render())
+	} else {
+		fmt.Printf("All nodes already run k0s %s.\n\n", plan.K0s.ToVersion)
+	}
+
+	writer = table.NewWriter()
+	writer.AppendHeader(table.Row{"addon", "release", "current version", "new version", "values changed"})
+	for _, addon := range plan.AddOns {
+		from := addon.FromVersion
+		if from == "" {
+			from = "not installed"
+		}
+		releaseName := fmt.Sprintf("%s/%s", addon.Namespace, addon.ReleaseName)
+		writer.AppendRow(table.Row{addon.Name, releaseName, from, addon.ToVersion, len(addon.ValuesDiff)})
+	}
+	fmt.Printf("%s\n\n", writer.Render())
+
+	for _, addon := range plan.AddOns {
+		if len(addon.ValuesDiff) == 0 {
+			continue
+		}
+		fmt.Printf("%s values:\n", addon.Name)
+		printValueChanges(addon.ValuesDiff)
+		fmt.Println()
+	}
+
+	if len(plan.Extensions) > 0 {
+		writer = table.NewWriter()
+		writer.AppendHeader(table.Row{"extension", "namespace", "action", "current version", "new version"})
+		for _, ext := range plan.Extensions {
+			writer.AppendRow(table.Row{ext.Name, ext.Namespace, ext.Action, ext.FromVersion, ext.ToVersion})
+		}
+		fmt.Printf("%s\n\n", writer.Render())
+	}
+
+	if len(plan.AddedImages)+len(plan.RemovedImages) > 0 {
+		writer = table.NewWriter()
+		writer.AppendHeader(table.Row{"image", "change"})
+		for _, image := range plan.AddedImages {
+			writer.AppendRow(table.Row{image, "added"})
+		}
+		for _, image := range plan.RemovedImages {
+			writer.AppendRow(table.Row{image, "removed"})
+		}
+		fmt.Printf("%s\n\n", writer.Render())
+	}
+
+	if len(plan.ClusterConfigImages) > 0 {
+		fmt.Println("Cluster config images:")
+		printValueChanges(plan.ClusterConfigImages)
+		fmt.Println()
+	}
+}
+
+func printValueChanges(changes []helm.ValueChange) {
+	for _, change := range changes {
+		switch {
+		case change.From == "":
+			fmt.Printf("  + %s: %s\n", change.Path, change.To)
+		case change.To == "":
+			fmt.Printf("  - %s: %s\n", change.Path, change.From)
+		default:
+			fmt.Printf("  ~ %s: %s -> %s\n", change.Path, change.From, change.To)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_diffImages(t *testing.T) {
+	added, removed := diffImages(
+		[]string{"registry/a:1", "registry/b:1", "registry/c:1"},
+		[]string{"registry/a:1", "registry/b:2", "registry/c:1", "registry/d:1"},
+	)
+	assert.Equal(t, []string{"registry/b:2", "registry/d:1"}, added)
+	assert.Equal(t, []string{"registry/b:1"}, removed)
+}
+
+func Test_nodesToUpgrade(t *testing.T) {
+	node := func(name, version string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: version}},
+		}
+	}
+	kcli := fake.NewClientBuilder().WithObjects(
+		node("node-c", "v1.29.9+k0s", nil),
+		node("node-a", "v1.29.9+k0s", map[string]string{controlPlaneLabel: "true"}),
+		node("node-b", "v1.30.5+k0s", nil),
+	).Build()
+
+	got, err := nodesToUpgrade(context.Background(), kcli, "v1.30.5+k0s")
+	require.NoError(t, err)
+	assert.Equal(t, []nodeUpgradePlan{
+		{Name: "node-a", Role: "controller", Version: "v1.29.9+k0s"},
+		{Name: "node-c", Role: "worker", Version: "v1.29.9+k0s"},
+	}, got)
+}
+
+func Test_diffClusterImages(t *testing.T) {
+	prev := &k0sv1beta1.ClusterImages{
+		CoreDNS: k0sv1beta1.ImageSpec{Image: "proxy/coredns", Version: "1.11.3"},
+		Pause:   k0sv1beta1.ImageSpec{Image: "proxy/pause", Version: "3.9"},
+	}
+	next := &k0sv1beta1.ClusterImages{
+		CoreDNS: k0sv1beta1.ImageSpec{Image: "proxy/coredns", Version: "1.11.4"},
+		Pause:   k0sv1beta1.ImageSpec{Image: "proxy/pause", Version: "3.9"},
+	}
+
+	got, err := diffClusterImages(prev, next)
+	require.NoError(t, err)
+	assert.Equal(t, []helm.ValueChange{
+		{Path: "coredns.version", From: `"1.11.3"`, To: `"1.11.4"`},
+	}, got)
+
+	got, err = diffClusterImages(prev, prev)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -153,6 +153,100 @@ func AirgapUpdate(opts AirgapUpdateOptions) error {
 	return nil
 }
 
+type UpstreamUpgradeOptions struct {
+	AppSlug   string
+	Namespace string
+	// VersionLabel is the version to deploy. It is required so the version deployed is the
+	// one whose host preflights were run, not whatever is the latest at the time.
+	VersionLabel string
+}
+
+// UpstreamUpgrade checks for application updates online and deploys the requested version.
+func UpstreamUpgrade(opts UpstreamUpgradeOptions) error {
+	if opts.VersionLabel == "" {
+		return fmt.Errorf("a version label is required to deploy an application update")
+	}
+
+	materializer := goods.NewMaterializer()
+	kotsBinPath, err := materializer.InternalBinary("kubectl-kots")
+	if err != nil {
+		return fmt.Errorf("unable to materialize kubectl-kots binary: %w", err)
+	}
+	defer os.Remove(kotsBinPath)
+
+	upstreamUpgradeArgs := []string{
+		"upstream",
+		"upgrade",
+		opts.AppSlug,
+		"--namespace",
+		opts.Namespace,
+		"--wait",
+		"--deploy-version-label",
+		opts.VersionLabel,
+	}
+
+	loading := spinner.Start()
+	loading.Infof("Deploying the new application version")
+	runCommandOptions := helpers.RunCommandOptions{
+		Env: map[string]string{
+			"EMBEDDED_CLUSTER_ID": metrics.ClusterID().String(),
+		},
+	}
+	if err := helpers.RunCommandWithOptions(runCommandOptions, kotsBinPath, upstreamUpgradeArgs...); err != nil {
+		loading.CloseWithError()
+		return fmt.Errorf("unable to update the application: %w", err)
+	}
+
+	loading.Closef("Application version deployed!")
+	return nil
+}
+
+type BackupOptions struct {
+	Namespace string
+	// Wait makes the command wait for the backup to finish.
+	Wait bool
+}
+
+// Backup creates an instance backup of the cluster and of the application.
+func Backup(opts BackupOptions) error {
+	materializer := goods.NewMaterializer()
+	kotsBinPath, err := materializer.InternalBinary("kubectl-kots")
+	if err != nil {
+		return fmt.Errorf("unable to materialize kubectl-kots binary: %w", err)
+	}
+	defer os.Remove(kotsBinPath)
+
+	backupArgs := []string{
+		"backup",
+		"--namespace",
+		opts.Namespace,
+		fmt.Sprintf("--wait=%t", opts.Wait),
+	}
+
+	loading := spinner.Start()
+	if opts.Wait {
+		loading.Infof("Creating backup, this may take a while")
+	} else {
+		loading.Infof("Requesting backup")
+	}
+	runCommandOptions := helpers.RunCommandOptions{
+		Env: map[string]string{
+			"EMBEDDED_CLUSTER_ID": metrics.ClusterID().String(),
+		},
+	}
+	if err := helpers.RunCommandWithOptions(runCommandOptions, kotsBinPath, backupArgs...); err != nil {
+		loading.CloseWithError()
+		return fmt.Errorf("unable to create backup: %w", err)
+	}
+
+	if opts.Wait {
+		loading.Closef("Backup finished!")
+	} else {
+		loading.Closef("Backup requested!")
+	}
+	return nil
+}
+
 type VeleroConfigureOtherS3Options struct {
 	Endpoint        string
 	Region          string
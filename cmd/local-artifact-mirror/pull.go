@@ -18,12 +18,27 @@ import (
 
 // These constant define the expected names of the files in the registry.
 const (
-	EmbeddedClusterBinaryArtifactName = "embedded-cluster-amd64"
-	ImagesSrcArtifactName             = "images-amd64.tar"
-	ImagesDstArtifactName             = "ec-images-amd64.tar"
-	HelmChartsArtifactName            = "charts.tar.gz"
+	HelmChartsArtifactName = "charts.tar.gz"
 )
 
+// EmbeddedClusterBinaryArtifactName returns the expected name of the embedded cluster binary
+// file in the registry for the given architecture.
+func EmbeddedClusterBinaryArtifactName(arch string) string {
+	return fmt.Sprintf("embedded-cluster-%s", arch)
+}
+
+// ImagesSrcArtifactName returns the expected name of the images file in the registry for the
+// given architecture.
+func ImagesSrcArtifactName(arch string) string {
+	return fmt.Sprintf("images-%s.tar", arch)
+}
+
+// ImagesDstArtifactName returns the name of the images file once stored locally for the given
+// architecture.
+func ImagesDstArtifactName(arch string) string {
+	return fmt.Sprintf("ec-images-%s.tar", arch)
+}
+
 // kubecli holds a global reference to a Kubernetes client.
 var kubecli client.Client
 
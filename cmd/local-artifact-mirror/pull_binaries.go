@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
@@ -26,6 +27,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 		Short: "Pull binaries artifacts for an airgap installation",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			v.BindPFlag("data-dir", cmd.Flags().Lookup("data-dir"))
+			v.BindPFlag("arch", cmd.Flags().Lookup("arch"))
 
 			if len(args) != 1 {
 				return errors.New("expected installation name as argument")
@@ -36,6 +38,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			v := viper.GetViper()
 
 			dataDir := v.GetString("data-dir")
+			arch := v.GetString("arch")
 
 			// Support for older env vars
 			flag := cmd.Flags().Lookup("data-dir")
@@ -53,7 +56,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 				return err
 			}
 
-			from := in.Spec.Artifacts.EmbeddedClusterBinary
+			from := in.Spec.Artifacts.EmbeddedClusterBinaryFor(arch)
 			logrus.Infof("fetching embedded cluster binary artifact from %s", from)
 			location, err := pullArtifact(ctx, from)
 			if err != nil {
@@ -63,7 +66,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 				logrus.Infof("removing temporary directory %s", location)
 				os.RemoveAll(location)
 			}()
-			bin := filepath.Join(location, EmbeddedClusterBinaryArtifactName)
+			bin := filepath.Join(location, EmbeddedClusterBinaryArtifactName(arch))
 			namedBin := filepath.Join(location, in.Spec.BinaryName)
 			if err := os.Rename(bin, namedBin); err != nil {
 				return fmt.Errorf("unable to rename binary: %w", err)
@@ -94,6 +97,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 
 	cmd.Flags().String("data-dir", ecv1beta1.DefaultDataDir, "Path to the data directory")
 	cmd.MarkFlagRequired("data-dir")
+	cmd.Flags().String("arch", runtime.GOARCH, "Architecture of the artifacts to pull")
 
 	return cmd
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
@@ -25,6 +26,7 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 		Short: "Pull images artifacts for an airgap installation",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			v.BindPFlag("data-dir", cmd.Flags().Lookup("data-dir"))
+			v.BindPFlag("arch", cmd.Flags().Lookup("arch"))
 
 			if len(args) != 1 {
 				return errors.New("expected installation name as argument")
@@ -35,6 +37,7 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			v := viper.GetViper()
 
 			dataDir := v.GetString("data-dir")
+			arch := v.GetString("arch")
 
 			// Support for older env vars
 			flag := cmd.Flags().Lookup("data-dir")
@@ -52,7 +55,7 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 				return err
 			}
 
-			from := in.Spec.Artifacts.Images
+			from := in.Spec.Artifacts.ImagesFor(arch)
 			logrus.Infof("fetching images artifact from %s", from)
 			location, err := pullArtifact(ctx, from)
 			if err != nil {
@@ -63,8 +66,8 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 				os.RemoveAll(location)
 			}()
 
-			dst := filepath.Join(runtimeconfig.EmbeddedClusterImagesSubDir(), ImagesDstArtifactName)
-			src := filepath.Join(location, ImagesSrcArtifactName)
+			dst := filepath.Join(runtimeconfig.EmbeddedClusterImagesSubDir(), ImagesDstArtifactName(arch))
+			src := filepath.Join(location, ImagesSrcArtifactName(arch))
 			logrus.Infof("%s > %s", src, dst)
 			if err := helpers.MoveFile(src, dst); err != nil {
 				return fmt.Errorf("unable to move images bundle: %w", err)
@@ -77,6 +80,7 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 
 	cmd.Flags().String("data-dir", ecv1beta1.DefaultDataDir, "Path to the data directory")
 	cmd.MarkFlagRequired("data-dir")
+	cmd.Flags().String("arch", runtime.GOARCH, "Architecture of the artifacts to pull")
 
 	return cmd
 }
@@ -46,6 +46,37 @@ type BuiltInExtension struct {
 	// setting a new value for `images.tag` here will not prevent Embedded
 	// Cluster from setting `images.pullPolicy = IfNotPresent`, for example.
 	Values string `json:"values"`
+	// Patches are kustomize patches applied to the manifests rendered by the
+	// chart, for fields the chart does not expose as helm values.
+	// +optional
+	Patches []ChartPatch `json:"patches,omitempty"`
+	// Kustomization is a YAML-formatted kustomization applied to the manifests
+	// rendered by the chart. Its resources are set to the rendered manifests.
+	// +optional
+	Kustomization string `json:"kustomization,omitempty"`
+}
+
+// ChartPatch is a strategic merge or JSON6902 patch applied to the manifests
+// rendered by a chart.
+type ChartPatch struct {
+	// YAML-formatted strategic merge patch or JSON6902 patch.
+	Patch string `json:"patch"`
+	// Target selects the resources the patch is applied to. It is required
+	// for JSON6902 patches.
+	// +optional
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// PatchTarget selects the resources a patch is applied to. Empty fields match
+// all resources.
+type PatchTarget struct {
+	Group              string `json:"group,omitempty"`
+	Version            string `json:"version,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
 }
 
 // NodeRange contains a min and max or only one of them.
@@ -96,6 +127,68 @@ type Chart struct {
 	ForceUpgrade *bool `json:"forceUpgrade,omitempty"`
 	// +kubebuilder:validation:Optional
 	Order int `json:"order,omitempty"`
+	// Verification configures how the chart archive is verified before it is installed or
+	// upgraded. Installs and upgrades fail if the verification fails.
+	// +optional
+	Verification *ChartVerification `json:"verification,omitempty"`
+	// Patches are kustomize patches applied to the manifests rendered by the chart, for
+	// fields the chart does not expose as helm values.
+	// +optional
+	Patches []ChartPatch `json:"patches,omitempty"`
+	// Kustomization is a YAML-formatted kustomization applied to the manifests rendered by
+	// the chart. Its resources are set to the rendered manifests.
+	// +optional
+	Kustomization string `json:"kustomization,omitempty"`
+	// ValuesFrom references Secrets and ConfigMaps holding values for the chart. They are
+	// resolved when the chart is installed or upgraded and merged, in order, on top of Values.
+	// +optional
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+	// TemplateValues renders Values as a Go template before they are used. The template has
+	// access to .DataDir, .ServiceCIDR, .HTTPProxy, .HTTPSProxy, .NoProxy, .NodeCount,
+	// .IsAirgap and .RegistryAddress.
+	// +optional
+	TemplateValues bool `json:"templateValues,omitempty"`
+}
+
+// ValuesReference references a Secret or ConfigMap key holding values for a chart.
+type ValuesReference struct {
+	// Kind of the referenced object.
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+	// Name of the referenced object.
+	Name string `json:"name"`
+	// Namespace of the referenced object. Defaults to the namespace of the chart.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// ValuesKey is the data key holding the values. Defaults to values.yaml.
+	// +optional
+	ValuesKey string `json:"valuesKey,omitempty"`
+	// TargetPath, if set, is the path, in the notation of the helm --set flag, the value of
+	// ValuesKey is set to as a string instead of being merged as YAML.
+	// +optional
+	TargetPath string `json:"targetPath,omitempty"`
+	// Optional references do not fail installs and upgrades when the object or the key do
+	// not exist.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ChartVerification holds the keys used to verify the signature of a chart.
+type ChartVerification struct {
+	// Digest is the expected sha256 digest of the chart archive, in the sha256:<hex> form.
+	// The registry the chart is signed in is not reachable in airgap installations, charts
+	// verified with a cosign public key need a digest to be installed in airgap mode.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// Keyring is an ASCII armored PGP public keyring used to verify the chart provenance
+	// (.prov) file. In airgap installations the provenance file is expected next to the
+	// chart archive.
+	// +optional
+	Keyring string `json:"keyring,omitempty"`
+	// CosignPublicKey is a PEM encoded public key used to verify the cosign signature of the
+	// chart. It can only be used with oci chart references.
+	// +optional
+	CosignPublicKey string `json:"cosignPublicKey,omitempty"`
 }
 
 // BackwardCompatibleDuration is a metav1.Duration with a different JSON
@@ -133,6 +226,7 @@ func (b *BackwardCompatibleDuration) UnmarshalJSON(data []byte) error {
 
 // Helm contains helm extension settings
 type Helm struct {
+	// ConcurrencyLevel is the maximum number of charts installed at the same time.
 	// +kubebuilder:validation:Optional
 	ConcurrencyLevel int `json:"concurrencyLevel"`
 	// +kubebuilder:validation:Optional
@@ -145,6 +239,19 @@ type Extensions struct {
 	Helm *Helm `json:"helm,omitempty"`
 }
 
+// RollbackPolicy determines which helm releases are rolled back when an upgrade fails.
+// +kubebuilder:validation:Enum=never;addons-only;all
+type RollbackPolicy string
+
+const (
+	// RollbackPolicyNever leaves the releases as they are when an upgrade fails.
+	RollbackPolicyNever RollbackPolicy = "never"
+	// RollbackPolicyAddOnsOnly rolls back the built-in addons but not the extensions.
+	RollbackPolicyAddOnsOnly RollbackPolicy = "addons-only"
+	// RollbackPolicyAll rolls back both the built-in addons and the extensions.
+	RollbackPolicyAll RollbackPolicy = "all"
+)
+
 // ConfigSpec defines the desired state of Config
 type ConfigSpec struct {
 	Version string `json:"version,omitempty"`
@@ -158,6 +265,93 @@ type ConfigSpec struct {
 	Roles                Roles                `json:"roles,omitempty"`
 	UnsupportedOverrides UnsupportedOverrides `json:"unsupportedOverrides,omitempty"`
 	Extensions           Extensions           `json:"extensions,omitempty"`
+	// RollbackPolicy determines which helm releases are rolled back to their previous
+	// revision when an upgrade fails. Defaults to never.
+	// +optional
+	RollbackPolicy RollbackPolicy `json:"rollbackPolicy,omitempty"`
+	// AutoRemediateDrift makes the operator upgrade the built-in addons whose deployed state
+	// drifted from the expected one, for instance after a manual edit. Drift is reported in
+	// the installation conditions regardless of this setting.
+	// +optional
+	AutoRemediateDrift bool `json:"autoRemediateDrift,omitempty"`
+	// RegistryGC configures the removal of the images no longer used from the registry of
+	// airgap installations.
+	// +optional
+	RegistryGC RegistryGC `json:"registryGC,omitempty"`
+	// Storage configures the storage class the volumes of the cluster are provisioned with.
+	// It is only read at installation time, upgrades keep the storage the cluster was
+	// installed with.
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+	// Ingress configures the built-in ingress controller. It is read at installation time,
+	// upgrades keep the ingress the cluster was installed with.
+	// +optional
+	Ingress IngressSpec `json:"ingress,omitempty"`
+}
+
+// IngressSpec configures the built-in ingress controller.
+type IngressSpec struct {
+	// Enabled deploys the ingress-nginx controller on all the nodes, bound to the ports 80
+	// and 443 of the hosts. Applications can use its "nginx" ingress class.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// AdminConsoleHostname is the hostname the admin console is exposed at, over TLS, through
+	// the ingress controller. Setting it enables the ingress controller.
+	// +optional
+	AdminConsoleHostname string `json:"adminConsoleHostname,omitempty"`
+}
+
+// IsEnabled returns true if the ingress controller is deployed.
+func (s *IngressSpec) IsEnabled() bool {
+	return s != nil && (s.Enabled || s.AdminConsoleHostname != "")
+}
+
+// StorageProvider is the provider of the storage class the volumes are provisioned with.
+// +kubebuilder:validation:Enum=openebs;existing
+type StorageProvider string
+
+const (
+	// StorageProviderOpenEBS deploys OpenEBS LocalPV, provisioning the volumes in the data
+	// directory of the nodes.
+	StorageProviderOpenEBS StorageProvider = "openebs"
+	// StorageProviderExisting uses a storage class already present in the cluster. OpenEBS
+	// is not deployed.
+	StorageProviderExisting StorageProvider = "existing"
+)
+
+// StorageSpec configures the storage of the cluster.
+type StorageSpec struct {
+	// Provider is the provider of the storage class. Defaults to openebs.
+	// +optional
+	Provider StorageProvider `json:"provider,omitempty"`
+	// StorageClassName is the name of the existing storage class the volumes of the built-in
+	// addons are provisioned with. Required when the provider is existing. The volumes of the
+	// application use the default storage class of the cluster.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+// IsOpenEBS returns true if the volumes are provisioned by OpenEBS, the default when no
+// provider is set.
+func (s *StorageSpec) IsOpenEBS() bool {
+	return s == nil || s.Provider == "" || s.Provider == StorageProviderOpenEBS
+}
+
+// RegistryGC configures the garbage collection of the registry of airgap installations. Images
+// referenced by the current installation, by the previous installations kept for rollbacks and
+// by the workloads running in the cluster are never removed.
+type RegistryGC struct {
+	// Disabled disables the scheduled garbage collection of the registry.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// KeepPreviousReleases is the number of previous installations whose images are kept in
+	// the registry. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepPreviousReleases *int `json:"keepPreviousReleases,omitempty"`
+	// Interval is the time between two garbage collections of the registry. Defaults to 24h.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
 }
 
 // OverrideForBuiltIn returns the override for the built-in extension with the
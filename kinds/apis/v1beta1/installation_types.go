@@ -75,6 +75,31 @@ type ArtifactsLocation struct {
 	EmbeddedClusterBinary   string            `json:"embeddedClusterBinary"`
 	EmbeddedClusterMetadata string            `json:"embeddedClusterMetadata"`
 	AdditionalArtifacts     map[string]string `json:"additionalArtifacts,omitempty"`
+	// ImagesByArch holds the location of the images artifact for each architecture (e.g.
+	// amd64, arm64) of multi-architecture bundles. Images is used for the architectures
+	// not listed here.
+	ImagesByArch map[string]string `json:"imagesByArch,omitempty"`
+	// EmbeddedClusterBinaryByArch holds the location of the embedded cluster binary
+	// artifact for each architecture of multi-architecture bundles. EmbeddedClusterBinary
+	// is used for the architectures not listed here.
+	EmbeddedClusterBinaryByArch map[string]string `json:"embeddedClusterBinaryByArch,omitempty"`
+}
+
+// ImagesFor returns the location of the images artifact for the given architecture.
+func (a *ArtifactsLocation) ImagesFor(arch string) string {
+	if location, ok := a.ImagesByArch[arch]; ok {
+		return location
+	}
+	return a.Images
+}
+
+// EmbeddedClusterBinaryFor returns the location of the embedded cluster binary artifact for
+// the given architecture.
+func (a *ArtifactsLocation) EmbeddedClusterBinaryFor(arch string) string {
+	if location, ok := a.EmbeddedClusterBinaryByArch[arch]; ok {
+		return location
+	}
+	return a.EmbeddedClusterBinary
 }
 
 // ProxySpec holds the proxy configuration.
@@ -155,6 +180,12 @@ type InstallationSpec struct {
 	// EndUserK0sConfigOverrides holds the end user k0s config overrides
 	// used at installation time.
 	EndUserK0sConfigOverrides string `json:"endUserK0sConfigOverrides,omitempty"`
+	// Storage holds the storage the cluster was installed with. It is kept across upgrades.
+	// OpenEBS is used when empty.
+	Storage *StorageSpec `json:"storage,omitempty"`
+	// Ingress holds the ingress the cluster was installed with. It is kept across upgrades.
+	// The ingress controller is not deployed when empty.
+	Ingress *IngressSpec `json:"ingress,omitempty"`
 
 	Deprecated_AdminConsole        *AdminConsoleSpec        `json:"adminConsole,omitempty"`
 	Deprecated_LocalArtifactMirror *LocalArtifactMirrorSpec `json:"localArtifactMirror,omitempty"`
@@ -0,0 +1,208 @@
+package v1beta1
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// InstallConfigKind is the kind of the InstallConfig object.
+const InstallConfigKind = "InstallConfig"
+
+// InstallConfigAdminConsole holds the Admin Console settings used at installation time.
+type InstallConfigAdminConsole struct {
+	// Password holds the password for the Admin Console.
+	Password string `json:"password,omitempty"`
+	// Port holds the port on which the Admin Console will be served.
+	Port int `json:"port,omitempty"`
+	// Hostname holds the hostname the Admin Console is exposed at through the built-in
+	// ingress controller.
+	Hostname string `json:"hostname,omitempty"`
+	// TLSCert holds the path to the PEM encoded certificate served for the hostname. A self
+	// signed certificate is generated if empty.
+	TLSCert string `json:"tlsCert,omitempty"`
+	// TLSKey holds the path to the PEM encoded private key of the certificate.
+	TLSKey string `json:"tlsKey,omitempty"`
+}
+
+// InstallConfigNetwork holds the network settings used at installation time. CIDR can
+// not be used together with PodCIDR or ServiceCIDR.
+type InstallConfigNetwork struct {
+	// CIDR is the block of available private IP addresses (/16 or larger). It is split
+	// in half between pods and services.
+	CIDR string `json:"cidr,omitempty"`
+	// PodCIDR is the IP address range for Pods.
+	PodCIDR string `json:"podCIDR,omitempty"`
+	// ServiceCIDR is the IP address range for Services.
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+}
+
+// InstallConfigProxy holds the proxy settings used at installation time.
+type InstallConfigProxy struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
+
+// InstallConfigSpec holds everything that can otherwise be provided to the install and
+// restore commands through flags. Relative paths are resolved against the directory
+// containing the file.
+type InstallConfigSpec struct {
+	// License holds the path to the license file.
+	License string `json:"license,omitempty"`
+	// AirgapBundle holds the path to the air gap bundle.
+	AirgapBundle string `json:"airgapBundle,omitempty"`
+	// DataDir holds the data directory for the Embedded Cluster.
+	DataDir string `json:"dataDir,omitempty"`
+	// AdminConsole holds the Admin Console configuration.
+	AdminConsole InstallConfigAdminConsole `json:"adminConsole,omitempty"`
+	// LocalArtifactMirror holds the Local Artifact Mirror configuration.
+	LocalArtifactMirror LocalArtifactMirrorSpec `json:"localArtifactMirror,omitempty"`
+	// NetworkInterface is the network interface to use for the cluster.
+	NetworkInterface string `json:"networkInterface,omitempty"`
+	// Network holds the pod and service CIDRs.
+	Network InstallConfigNetwork `json:"network,omitempty"`
+	// Proxy holds the proxy configuration.
+	Proxy InstallConfigProxy `json:"proxy,omitempty"`
+	// PrivateCAs holds paths to trusted private CA certificate files.
+	PrivateCAs []string `json:"privateCAs,omitempty"`
+	// ConfigValues holds the path to the config values used when installing.
+	ConfigValues string `json:"configValues,omitempty"`
+	// Overrides holds the path to a file with an EmbeddedClusterConfig object used to
+	// override the default configuration.
+	Overrides string `json:"overrides,omitempty"`
+	// IgnoreHostPreflights allows bypassing host preflight failures.
+	IgnoreHostPreflights bool `json:"ignoreHostPreflights,omitempty"`
+	// AssumeYes assumes yes to all prompts.
+	AssumeYes bool `json:"assumeYes,omitempty"`
+}
+
+// InstallConfig is the declarative counterpart to the install command flags. It is read
+// from disk and is never stored in the cluster.
+type InstallConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec InstallConfigSpec `json:"spec,omitempty"`
+}
+
+// ResolvePaths makes all relative file paths in the spec relative to the provided
+// directory.
+func (c *InstallConfig) ResolvePaths(dir string) {
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	c.Spec.License = resolve(c.Spec.License)
+	c.Spec.AirgapBundle = resolve(c.Spec.AirgapBundle)
+	c.Spec.ConfigValues = resolve(c.Spec.ConfigValues)
+	c.Spec.Overrides = resolve(c.Spec.Overrides)
+	c.Spec.AdminConsole.TLSCert = resolve(c.Spec.AdminConsole.TLSCert)
+	c.Spec.AdminConsole.TLSKey = resolve(c.Spec.AdminConsole.TLSKey)
+	for i := range c.Spec.PrivateCAs {
+		c.Spec.PrivateCAs[i] = resolve(c.Spec.PrivateCAs[i])
+	}
+}
+
+// Validate performs a structural validation of the InstallConfig. Checks that depend on
+// the host (file existence, network interfaces, etc) are left to the caller.
+func (c *InstallConfig) Validate() field.ErrorList {
+	var errs field.ErrorList
+
+	if c.APIVersion != GroupVersion.String() {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{GroupVersion.String()}))
+	}
+	if c.Kind != InstallConfigKind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{InstallConfigKind}))
+	}
+
+	spec := field.NewPath("spec")
+
+	if c.Spec.DataDir != "" && !filepath.IsAbs(c.Spec.DataDir) {
+		errs = append(errs, field.Invalid(spec.Child("dataDir"), c.Spec.DataDir, "must be an absolute path"))
+	}
+
+	acPort := spec.Child("adminConsole", "port")
+	errs = append(errs, validatePort(acPort, c.Spec.AdminConsole.Port)...)
+	lamPort := spec.Child("localArtifactMirror", "port")
+	errs = append(errs, validatePort(lamPort, c.Spec.LocalArtifactMirror.Port)...)
+	if c.Spec.AdminConsole.Port != 0 && c.Spec.AdminConsole.Port == c.Spec.LocalArtifactMirror.Port {
+		errs = append(errs, field.Duplicate(lamPort, c.Spec.LocalArtifactMirror.Port))
+	}
+
+	ac := spec.Child("adminConsole")
+	if hostname := c.Spec.AdminConsole.Hostname; hostname != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(hostname) {
+			errs = append(errs, field.Invalid(ac.Child("hostname"), hostname, msg))
+		}
+	}
+	if c.Spec.AdminConsole.TLSCert != "" && c.Spec.AdminConsole.TLSKey == "" {
+		errs = append(errs, field.Required(ac.Child("tlsKey"), "must be set with tlsCert"))
+	}
+	if c.Spec.AdminConsole.TLSKey != "" && c.Spec.AdminConsole.TLSCert == "" {
+		errs = append(errs, field.Required(ac.Child("tlsCert"), "must be set with tlsKey"))
+	}
+	if c.Spec.AdminConsole.TLSCert != "" && c.Spec.AdminConsole.Hostname == "" {
+		errs = append(errs, field.Required(ac.Child("hostname"), "must be set with tlsCert"))
+	}
+
+	network := spec.Child("network")
+	if c.Spec.Network.CIDR != "" && (c.Spec.Network.PodCIDR != "" || c.Spec.Network.ServiceCIDR != "") {
+		errs = append(errs, field.Forbidden(network.Child("cidr"), "can not be used with podCIDR or serviceCIDR"))
+	}
+	errs = append(errs, validateCIDR(network.Child("cidr"), c.Spec.Network.CIDR)...)
+	errs = append(errs, validateCIDR(network.Child("podCIDR"), c.Spec.Network.PodCIDR)...)
+	errs = append(errs, validateCIDR(network.Child("serviceCIDR"), c.Spec.Network.ServiceCIDR)...)
+
+	proxy := spec.Child("proxy")
+	errs = append(errs, validateProxyURL(proxy.Child("httpProxy"), c.Spec.Proxy.HTTPProxy)...)
+	errs = append(errs, validateProxyURL(proxy.Child("httpsProxy"), c.Spec.Proxy.HTTPSProxy)...)
+
+	for i, ca := range c.Spec.PrivateCAs {
+		if ca == "" {
+			errs = append(errs, field.Required(spec.Child("privateCAs").Index(i), "path can not be empty"))
+		}
+	}
+
+	return errs
+}
+
+func validatePort(fld *field.Path, port int) field.ErrorList {
+	if port == 0 {
+		return nil
+	}
+	if port < 1 || port > 65535 {
+		return field.ErrorList{field.Invalid(fld, port, "must be between 1 and 65535")}
+	}
+	return nil
+}
+
+func validateCIDR(fld *field.Path, cidr string) field.ErrorList {
+	if cidr == "" {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return field.ErrorList{field.Invalid(fld, cidr, "must be a valid CIDR")}
+	}
+	return nil
+}
+
+func validateProxyURL(fld *field.Path, proxy string) field.ErrorList {
+	if proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return field.ErrorList{field.Invalid(fld, proxy, "must be a valid URL")}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return field.ErrorList{field.Invalid(fld, proxy, "scheme must be http or https")}
+	}
+	return nil
+}
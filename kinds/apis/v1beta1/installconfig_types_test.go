@@ -0,0 +1,131 @@
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInstallConfig_Validate(t *testing.T) {
+	typeMeta := metav1.TypeMeta{
+		APIVersion: GroupVersion.String(),
+		Kind:       InstallConfigKind,
+	}
+
+	tests := []struct {
+		name       string
+		cfg        InstallConfig
+		wantFields []string
+	}{
+		{
+			name: "empty spec is valid",
+			cfg:  InstallConfig{TypeMeta: typeMeta},
+		},
+		{
+			name: "full spec is valid",
+			cfg: InstallConfig{
+				TypeMeta: typeMeta,
+				Spec: InstallConfigSpec{
+					License: "license.yaml",
+					DataDir: "/var/lib/embedded-cluster",
+					AdminConsole: InstallConfigAdminConsole{
+						Password: "password",
+						Port:     30000,
+						Hostname: "admin.example.com",
+						TLSCert:  "tls.crt",
+						TLSKey:   "tls.key",
+					},
+					LocalArtifactMirror: LocalArtifactMirrorSpec{Port: 50000},
+					Network:             InstallConfigNetwork{CIDR: "10.0.0.0/16"},
+					Proxy: InstallConfigProxy{
+						HTTPProxy:  "http://proxy:3128",
+						HTTPSProxy: "https://proxy:3128",
+						NoProxy:    "localhost",
+					},
+					PrivateCAs: []string{"ca.crt"},
+				},
+			},
+		},
+		{
+			name:       "wrong kind and api version",
+			cfg:        InstallConfig{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Config"}},
+			wantFields: []string{"apiVersion", "kind"},
+		},
+		{
+			name: "invalid fields",
+			cfg: InstallConfig{
+				TypeMeta: typeMeta,
+				Spec: InstallConfigSpec{
+					DataDir:             "relative/path",
+					AdminConsole:        InstallConfigAdminConsole{Port: 70000},
+					LocalArtifactMirror: LocalArtifactMirrorSpec{Port: -1},
+					Network:             InstallConfigNetwork{PodCIDR: "not-a-cidr"},
+					Proxy:               InstallConfigProxy{HTTPProxy: "socks5://proxy:1080"},
+					PrivateCAs:          []string{""},
+				},
+			},
+			wantFields: []string{
+				"spec.dataDir",
+				"spec.adminConsole.port",
+				"spec.localArtifactMirror.port",
+				"spec.network.podCIDR",
+				"spec.proxy.httpProxy",
+				"spec.privateCAs[0]",
+			},
+		},
+		{
+			name: "cidr with pod cidr and duplicated ports",
+			cfg: InstallConfig{
+				TypeMeta: typeMeta,
+				Spec: InstallConfigSpec{
+					AdminConsole:        InstallConfigAdminConsole{Port: 30000},
+					LocalArtifactMirror: LocalArtifactMirrorSpec{Port: 30000},
+					Network:             InstallConfigNetwork{CIDR: "10.0.0.0/16", PodCIDR: "10.1.0.0/16"},
+				},
+			},
+			wantFields: []string{"spec.localArtifactMirror.port", "spec.network.cidr"},
+		},
+		{
+			name: "invalid hostname and certificate without key",
+			cfg: InstallConfig{
+				TypeMeta: typeMeta,
+				Spec: InstallConfigSpec{
+					AdminConsole: InstallConfigAdminConsole{Hostname: "Admin_Console", TLSCert: "tls.crt"},
+				},
+			},
+			wantFields: []string{"spec.adminConsole.hostname", "spec.adminConsole.tlsKey"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.cfg.Validate()
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			assert.ElementsMatch(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func TestInstallConfig_ResolvePaths(t *testing.T) {
+	cfg := InstallConfig{
+		Spec: InstallConfigSpec{
+			License:      "license.yaml",
+			AirgapBundle: "/abs/bundle.airgap",
+			ConfigValues: "values/config.yaml",
+			AdminConsole: InstallConfigAdminConsole{TLSCert: "tls/admin.crt", TLSKey: "tls/admin.key"},
+			PrivateCAs:   []string{"ca.crt", "/etc/ssl/ca.crt"},
+		},
+	}
+	cfg.ResolvePaths("/opt/install")
+
+	assert.Equal(t, "/opt/install/license.yaml", cfg.Spec.License)
+	assert.Equal(t, "/abs/bundle.airgap", cfg.Spec.AirgapBundle)
+	assert.Equal(t, "/opt/install/values/config.yaml", cfg.Spec.ConfigValues)
+	assert.Equal(t, "", cfg.Spec.Overrides)
+	assert.Equal(t, "/opt/install/tls/admin.crt", cfg.Spec.AdminConsole.TLSCert)
+	assert.Equal(t, "/opt/install/tls/admin.key", cfg.Spec.AdminConsole.TLSKey)
+	assert.Equal(t, []string{"/opt/install/ca.crt", "/etc/ssl/ca.crt"}, cfg.Spec.PrivateCAs)
+}
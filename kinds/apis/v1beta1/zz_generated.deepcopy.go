@@ -51,6 +51,20 @@ func (in *ArtifactsLocation) DeepCopyInto(out *ArtifactsLocation) {
 			(*out)[key] = val
 		}
 	}
+	if in.ImagesByArch != nil {
+		in, out := &in.ImagesByArch, &out.ImagesByArch
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EmbeddedClusterBinaryByArch != nil {
+		in, out := &in.EmbeddedClusterBinaryByArch, &out.EmbeddedClusterBinaryByArch
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactsLocation.
@@ -81,6 +95,13 @@ func (in *BackwardCompatibleDuration) DeepCopy() *BackwardCompatibleDuration {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuiltInExtension) DeepCopyInto(out *BuiltInExtension) {
 	*out = *in
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]ChartPatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuiltInExtension.
@@ -102,6 +123,23 @@ func (in *Chart) DeepCopyInto(out *Chart) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(ChartVerification)
+		**out = **in
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]ChartPatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Chart.
@@ -114,6 +152,41 @@ func (in *Chart) DeepCopy() *Chart {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartPatch) DeepCopyInto(out *ChartPatch) {
+	*out = *in
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(PatchTarget)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartPatch.
+func (in *ChartPatch) DeepCopy() *ChartPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartVerification) DeepCopyInto(out *ChartVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartVerification.
+func (in *ChartVerification) DeepCopy() *ChartVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
@@ -194,6 +267,9 @@ func (in *ConfigSpec) DeepCopyInto(out *ConfigSpec) {
 	in.Roles.DeepCopyInto(&out.Roles)
 	in.UnsupportedOverrides.DeepCopyInto(&out.UnsupportedOverrides)
 	in.Extensions.DeepCopyInto(&out.Extensions)
+	in.RegistryGC.DeepCopyInto(&out.RegistryGC)
+	out.Storage = in.Storage
+	out.Ingress = in.Ingress
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigSpec.
@@ -270,6 +346,108 @@ func (in *Helm) DeepCopy() *Helm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallConfig) DeepCopyInto(out *InstallConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallConfig.
+func (in *InstallConfig) DeepCopy() *InstallConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallConfigAdminConsole) DeepCopyInto(out *InstallConfigAdminConsole) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallConfigAdminConsole.
+func (in *InstallConfigAdminConsole) DeepCopy() *InstallConfigAdminConsole {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallConfigAdminConsole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallConfigNetwork) DeepCopyInto(out *InstallConfigNetwork) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallConfigNetwork.
+func (in *InstallConfigNetwork) DeepCopy() *InstallConfigNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallConfigNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallConfigProxy) DeepCopyInto(out *InstallConfigProxy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallConfigProxy.
+func (in *InstallConfigProxy) DeepCopy() *InstallConfigProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallConfigProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstallConfigSpec) DeepCopyInto(out *InstallConfigSpec) {
+	*out = *in
+	out.AdminConsole = in.AdminConsole
+	out.LocalArtifactMirror = in.LocalArtifactMirror
+	out.Network = in.Network
+	out.Proxy = in.Proxy
+	if in.PrivateCAs != nil {
+		in, out := &in.PrivateCAs, &out.PrivateCAs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallConfigSpec.
+func (in *InstallConfigSpec) DeepCopy() *InstallConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstallConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Installation) DeepCopyInto(out *Installation) {
 	*out = *in
@@ -367,6 +545,16 @@ func (in *InstallationSpec) DeepCopyInto(out *InstallationSpec) {
 		*out = new(NetworkSpec)
 		**out = **in
 	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		**out = **in
+	}
 	if in.Deprecated_AdminConsole != nil {
 		in, out := &in.Deprecated_AdminConsole, &out.Deprecated_AdminConsole
 		*out = new(AdminConsoleSpec)
@@ -558,6 +746,21 @@ func (in *NodeStatus) DeepCopy() *NodeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProxySpec) DeepCopyInto(out *ProxySpec) {
 	*out = *in
@@ -573,6 +776,31 @@ func (in *ProxySpec) DeepCopy() *ProxySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryGC) DeepCopyInto(out *RegistryGC) {
+	*out = *in
+	if in.KeepPreviousReleases != nil {
+		in, out := &in.KeepPreviousReleases, &out.KeepPreviousReleases
+		*out = new(int)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryGC.
+func (in *RegistryGC) DeepCopy() *RegistryGC {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryGC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Roles) DeepCopyInto(out *Roles) {
 	*out = *in
@@ -613,13 +841,30 @@ func (in *RuntimeConfigSpec) DeepCopy() *RuntimeConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UnsupportedOverrides) DeepCopyInto(out *UnsupportedOverrides) {
 	*out = *in
 	if in.BuiltInExtensions != nil {
 		in, out := &in.BuiltInExtensions, &out.BuiltInExtensions
 		*out = make([]BuiltInExtension, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
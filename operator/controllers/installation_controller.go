@@ -28,9 +28,15 @@ import (
 	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/metrics"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/openebs"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/registry"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -50,6 +56,9 @@ import (
 // interval.
 var requeueAfter = time.Hour
 
+// driftCheckInterval is how often the built-in addons are checked for drift.
+var driftCheckInterval = 15 * time.Minute
+
 const copyHostPreflightResultsJobPrefix = "copy-host-preflight-results-"
 const ecNamespace = "embedded-cluster"
 
@@ -128,6 +137,11 @@ type InstallationReconciler struct {
 	Discovery discovery.DiscoveryInterface
 	Scheme    *runtime.Scheme
 	Recorder  record.EventRecorder
+
+	// lastDriftCheck is when the built-in addons were last checked for drift.
+	lastDriftCheck time.Time
+	// lastRegistryGC is when the registry was last garbage collected.
+	lastRegistryGC time.Time
 }
 
 // NodeHasChanged returns true if the node configuration has changed when compared to
@@ -217,6 +231,34 @@ func (r *InstallationReconciler) ReconcileNodeStatuses(ctx context.Context, in *
 	return batch, nil
 }
 
+// ReconcileNodeVersions annotates the nodes with the embedded cluster version they run. This is
+// only known once the installation is installed, at that point every node runs its version.
+func (r *InstallationReconciler) ReconcileNodeVersions(ctx context.Context, in *v1beta1.Installation) error {
+	if in.Status.State != v1beta1.InstallationStateInstalled || in.Spec.Config == nil {
+		return nil
+	}
+	version := in.Spec.Config.Version
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		if node.Annotations[kubeutils.NodeVersionAnnotation] == version {
+			continue
+		}
+		patch := client.MergeFrom(node.DeepCopy())
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[kubeutils.NodeVersionAnnotation] = version
+		if err := r.Patch(ctx, &node, patch); err != nil {
+			return fmt.Errorf("failed to annotate node %s: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
 // ReportNodesChanges reports node changes to the metrics endpoint.
 func (r *InstallationReconciler) ReportNodesChanges(ctx context.Context, in *v1beta1.Installation, batch *NodeEventsBatch) {
 	for _, ev := range batch.NodesAdded {
@@ -239,6 +281,11 @@ func (r *InstallationReconciler) ReportNodesChanges(ctx context.Context, in *v1b
 func (r *InstallationReconciler) ReconcileOpenebs(ctx context.Context, in *v1beta1.Installation) error {
 	log := ctrl.LoggerFrom(ctx)
 
+	// nothing to cleanup if the volumes are not provisioned by openebs.
+	if !in.Spec.Storage.IsOpenEBS() {
+		return nil
+	}
+
 	err := openebs.CleanupStatefulPods(ctx, r.Client)
 	if err != nil {
 		// Conditions may be updated so we need to update the status
@@ -251,6 +298,159 @@ func (r *InstallationReconciler) ReconcileOpenebs(ctx context.Context, in *v1bet
 	return nil
 }
 
+// ReconcileAddOnDrift compares the deployed state of the built-in addons with the expected
+// one and reports it in events and in a condition per addon, true while the addon is in sync.
+// Drifted addons are upgraded if the installation opts in to auto-remediation. Drift is
+// checked at most once every driftCheckInterval and only while no upgrade is in progress.
+func (r *InstallationReconciler) ReconcileAddOnDrift(ctx context.Context, in *v1beta1.Installation) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if in.Status.State != v1beta1.InstallationStateInstalled {
+		return nil
+	}
+	if time.Since(r.lastDriftCheck) < driftCheckInterval {
+		return nil
+	}
+	r.lastDriftCheck = time.Now()
+
+	meta, err := release.MetadataFor(ctx, in, r.Client)
+	if err != nil {
+		return fmt.Errorf("failed to get release metadata: %w", err)
+	}
+
+	airgapChartsPath := ""
+	if in.Spec.AirGap {
+		airgapChartsPath = runtimeconfig.EmbeddedClusterChartsSubDir()
+	}
+	hcli, err := helm.NewClient(helm.HelmOptions{
+		K0sVersion: versions.K0sVersion,
+		AirgapPath: airgapChartsPath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create helm client: %w", err)
+	}
+	defer hcli.Close()
+
+	drifts, err := addons.DetectDrift(ctx, r.Client, hcli, in, meta)
+	if err != nil {
+		return fmt.Errorf("failed to detect addon drift: %w", err)
+	}
+
+	autoRemediate := in.Spec.Config != nil && in.Spec.Config.AutoRemediateDrift
+	for _, drift := range drifts {
+		name := drift.AddOn.Name()
+		conditionName := addons.DriftConditionName(drift.AddOn)
+		wasDrifted := kubeutils.CheckInstallationConditionStatus(in.Status, conditionName) == metav1.ConditionFalse
+		condition := addons.DriftCondition(drift)
+
+		if !drift.Drifted() {
+			if wasDrifted {
+				r.Recorder.Eventf(in, corev1.EventTypeNormal, "AddOnDriftResolved", "%s is back to its expected state", name)
+			}
+			in.Status.SetCondition(condition)
+			continue
+		}
+
+		log.Info("Addon drifted from its expected state", "addon", name, "reasons", condition.Message)
+		if !wasDrifted {
+			r.Recorder.Eventf(in, corev1.EventTypeWarning, "AddOnDrifted", "%s drifted from its expected state: %s", name, condition.Message)
+		}
+
+		if autoRemediate && in.Spec.AirGap {
+			// the operator has no access to the charts of airgap installations.
+			condition.Message = fmt.Sprintf("%s. Auto-remediation is not supported in airgap installations", condition.Message)
+		} else if autoRemediate {
+			log.Info("Remediating addon drift", "addon", name)
+			if err := addons.RemediateDrift(ctx, r.Client, hcli, in, drift.AddOn); err != nil {
+				log.Error(err, "Failed to remediate addon drift", "addon", name)
+				r.Recorder.Eventf(in, corev1.EventTypeWarning, "AddOnDriftRemediationFailed", "Failed to remediate the drift of %s: %s", name, helpers.CleanErrorMessage(err))
+				condition.Reason = "RemediationFailed"
+				condition.Message = fmt.Sprintf("%s. Remediation failed: %s", condition.Message, helpers.CleanErrorMessage(err))
+			} else {
+				r.Recorder.Eventf(in, corev1.EventTypeNormal, "AddOnDriftRemediated", "%s was upgraded back to its expected state", name)
+				condition.Status = metav1.ConditionTrue
+				condition.Reason = "Remediated"
+			}
+		}
+		in.Status.SetCondition(condition)
+	}
+
+	return nil
+}
+
+// ReconcileRegistryGC records the registry images used by the workloads and deletes from the
+// registry of airgap installations the images of the installations no longer kept for
+// rollbacks. Images are deleted at most once every registry gc interval and only while no
+// upgrade is in progress.
+func (r *InstallationReconciler) ReconcileRegistryGC(ctx context.Context, in *v1beta1.Installation) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !registry.Enabled(in) || in.Status.State != v1beta1.InstallationStateInstalled {
+		return nil
+	}
+	if err := registry.RecordAppImages(ctx, r.Client, in); err != nil {
+		return fmt.Errorf("failed to record app images: %w", err)
+	}
+	if time.Since(r.lastRegistryGC) < registry.Interval(in) {
+		return nil
+	}
+	r.lastRegistryGC = time.Now()
+
+	installs, err := kubeutils.ListInstallations(ctx, r.Client)
+	if err != nil {
+		return fmt.Errorf("failed to list installations: %w", err)
+	}
+	reg, err := registry.NewClient(ctx, r.Client, in)
+	if err != nil {
+		return fmt.Errorf("failed to create registry client: %w", err)
+	}
+	plan, err := registry.BuildPlan(ctx, r.Client, reg, installs, registry.KeepPreviousReleases(in))
+	if err != nil {
+		return fmt.Errorf("failed to plan registry garbage collection: %w", err)
+	}
+	if len(plan.Manifests) == 0 {
+		log.Info("No image to remove from the registry")
+		return nil
+	}
+
+	log.Info("Removing images from the registry", "manifests", len(plan.Manifests), "size", plan.FreedSize())
+	if err := registry.Apply(ctx, r.Client, reg, plan); err != nil {
+		r.Recorder.Eventf(in, corev1.EventTypeWarning, "RegistryGCFailed", "Failed to remove unused images from the registry: %s", helpers.CleanErrorMessage(err))
+		return fmt.Errorf("failed to garbage collect registry: %w", err)
+	}
+	r.Recorder.Eventf(in, corev1.EventTypeNormal, "RegistryGarbageCollected", "Removed %d unused image manifests from the registry, freeing %s", len(plan.Manifests), plan.FreedSize())
+	return nil
+}
+
+// ReconcileRegistryTLS renews the certificate of the registry of airgap installations before
+// it expires and makes the nodes trust it, restarting the registry once they do.
+func (r *InstallationReconciler) ReconcileRegistryTLS(ctx context.Context, in *v1beta1.Installation) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if !in.Spec.AirGap || in.Status.State != v1beta1.InstallationStateInstalled {
+		return nil
+	}
+
+	status, err := registry.ReconcileTLS(ctx, r.Client, in, os.Getenv("EMBEDDEDCLUSTER_UTILS_IMAGE"))
+	if err != nil {
+		r.Recorder.Eventf(in, corev1.EventTypeWarning, "RegistryCertificateRenewalFailed", "Failed to renew the registry certificate: %s", helpers.CleanErrorMessage(err))
+		return fmt.Errorf("failed to reconcile registry tls: %w", err)
+	}
+	if status == nil {
+		return nil
+	}
+	if status.Renewed {
+		r.Recorder.Eventf(in, corev1.EventTypeNormal, "RegistryCertificateRenewed", "The registry certificate has been renewed, it expires on %s", status.NotAfter.Format(time.RFC3339))
+	}
+	if status.Restarted {
+		r.Recorder.Eventf(in, corev1.EventTypeNormal, "RegistryRestarted", "The registry has been restarted to serve its renewed certificate")
+	}
+	if len(status.PendingNodes) > 0 {
+		log.Info("Waiting for the nodes to trust the registry certificate", "nodes", status.PendingNodes)
+	}
+	return nil
+}
+
 // CoalesceInstallations goes through all the installation objects and make sure that the
 // status of the newest one is coherent with whole cluster status. Returns the newest
 // installation object.
@@ -343,9 +543,11 @@ func constructHostPreflightResultsJob(in *v1beta1.Installation, nodeName string)
 	return job
 }
 
-//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;patch
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=embeddedcluster.replicated.com,resources=installations,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=embeddedcluster.replicated.com,resources=installations/status,verbs=get;update;patch
@@ -407,11 +609,33 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, fmt.Errorf("failed to copy host preflight results: %w", err)
 	}
 
+	// record the version each node runs, it is shown by the node commands.
+	if err := r.ReconcileNodeVersions(ctx, in); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile node versions: %w", err)
+	}
+
 	// cleanup openebs stateful pods
 	if err := r.ReconcileOpenebs(ctx, in); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to reconcile openebs: %w", err)
 	}
 
+	// detect, and optionally remediate, drift of the built-in addons. failures are not
+	// fatal as they must not prevent the node statuses from being saved.
+	if err := r.ReconcileAddOnDrift(ctx, in); err != nil {
+		log.Error(err, "Failed to reconcile addon drift")
+	}
+
+	// remove from the registry of airgap installations the images no longer used. failures
+	// are not fatal either.
+	if err := r.ReconcileRegistryGC(ctx, in); err != nil {
+		log.Error(err, "Failed to reconcile registry garbage collection")
+	}
+
+	// renew the registry certificate before it expires. failures are not fatal either.
+	if err := r.ReconcileRegistryTLS(ctx, in); err != nil {
+		log.Error(err, "Failed to reconcile registry tls")
+	}
+
 	// save the installation status. nothing more to do with it.
 	if err := r.Status().Update(ctx, in); err != nil {
 		if k8serrors.IsConflict(err) {
@@ -427,7 +651,7 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	log.Info("Installation reconciliation ended")
-	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	return ctrl.Result{RequeueAfter: min(requeueAfter, driftCheckInterval)}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
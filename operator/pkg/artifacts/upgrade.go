@@ -36,8 +36,8 @@ const (
 
 // copyArtifactsJob is a job we create everytime we need to sync files into all nodes. This job
 // mounts the data directory from the node and uses binaries that are present there. This is not
-// yet a complete version of the job as it misses some env variables and a node selector, those are
-// populated during the reconcile cycle.
+// yet a complete version of the job as it misses some env variables (including the architecture of
+// the node) and a node selector, those are populated during the reconcile cycle.
 var copyArtifactsJob = &batchv1.Job{
 	TypeMeta: metav1.TypeMeta{
 		APIVersion: "batch/v1",
@@ -77,11 +77,11 @@ var copyArtifactsJob = &batchv1.Job{
 							"/bin/sh",
 							"-ex",
 							"-c",
-							"/usr/local/bin/local-artifact-mirror pull binaries --data-dir /embedded-cluster $INSTALLATION_DATA\n" +
-								"/usr/local/bin/local-artifact-mirror pull images --data-dir /embedded-cluster $INSTALLATION_DATA\n" +
+							"/usr/local/bin/local-artifact-mirror pull binaries --data-dir /embedded-cluster --arch $ARCH $INSTALLATION_DATA\n" +
+								"/usr/local/bin/local-artifact-mirror pull images --data-dir /embedded-cluster --arch $ARCH $INSTALLATION_DATA\n" +
 								"/usr/local/bin/local-artifact-mirror pull helmcharts --data-dir /embedded-cluster $INSTALLATION_DATA\n" +
 								"mv /embedded-cluster/bin/k0s /embedded-cluster/bin/k0s-upgrade\n" +
-								"rm /embedded-cluster/images/images-amd64-* || true\n" +
+								"rm /embedded-cluster/images/images-$ARCH-* || true\n" +
 								"echo 'done'",
 						},
 					},
@@ -222,6 +222,7 @@ func getArtifactJobForNode(ctx context.Context, cli client.Client, in *clusterv1
 		job.Spec.Template.Spec.Containers[0].Env,
 		corev1.EnvVar{Name: "INSTALLATION", Value: in.Name},
 		corev1.EnvVar{Name: "INSTALLATION_DATA", Value: inDataEncoded},
+		corev1.EnvVar{Name: "ARCH", Value: NodeArch(node)},
 	)
 
 	job.Spec.Template.Spec.Containers[0].Image = localArtifactMirrorImage
@@ -255,19 +256,10 @@ func CreateAutopilotAirgapPlanCommand(ctx context.Context, cli client.Client, in
 		allNodes = append(allNodes, node.Name)
 	}
 
-	imageURL := fmt.Sprintf(
-		"http://127.0.0.1:%d/images/ec-images-amd64.tar",
-		runtimeconfig.LocalArtifactMirrorPort(),
-	)
-
 	return &autopilotv1beta2.PlanCommand{
 		AirgapUpdate: &autopilotv1beta2.PlanCommandAirgapUpdate{
-			Version: meta.Versions["Kubernetes"],
-			Platforms: map[string]autopilotv1beta2.PlanResourceURL{
-				fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH): {
-					URL: imageURL,
-				},
-			},
+			Version:   meta.Versions["Kubernetes"],
+			Platforms: airgapPlatforms(nodes.Items),
 			Workers: autopilotv1beta2.PlanCommandTarget{
 				Discovery: autopilotv1beta2.PlanCommandTargetDiscovery{
 					Static: &autopilotv1beta2.PlanCommandTargetDiscoveryStatic{
@@ -279,6 +271,35 @@ func CreateAutopilotAirgapPlanCommand(ctx context.Context, cli client.Client, in
 	}, nil
 }
 
+// airgapPlatforms returns, for each architecture in the cluster, the url from where autopilot
+// can fetch the images bundle. Each node serves, through the local artifact mirror, the bundle
+// for its own architecture.
+func airgapPlatforms(nodes []corev1.Node) map[string]autopilotv1beta2.PlanResourceURL {
+	platforms := map[string]autopilotv1beta2.PlanResourceURL{}
+	for _, node := range nodes {
+		arch := NodeArch(node)
+		platforms[fmt.Sprintf("%s-%s", runtime.GOOS, arch)] = autopilotv1beta2.PlanResourceURL{
+			URL: fmt.Sprintf(
+				"http://127.0.0.1:%d/images/ec-images-%s.tar",
+				runtimeconfig.LocalArtifactMirrorPort(), arch,
+			),
+		}
+	}
+	return platforms
+}
+
+// NodeArch returns the architecture of the node. If the node does not report it the
+// architecture of the operator is assumed.
+func NodeArch(node corev1.Node) string {
+	if arch := node.Labels[corev1.LabelArchStable]; arch != "" {
+		return arch
+	}
+	if arch := node.Status.NodeInfo.Architecture; arch != "" {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
 func applyArtifactsJobAnnotations(annotations map[string]string, in *clusterv1beta1.Installation, hash string) map[string]string {
 	if annotations == nil {
 		annotations = make(map[string]string)
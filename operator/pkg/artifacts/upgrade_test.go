@@ -2,12 +2,14 @@ package artifacts
 
 import (
 	"context"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
+	autopilotv1beta2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
 	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/stretchr/testify/assert"
@@ -63,12 +65,14 @@ func TestEnsureArtifactsJobForNodes(t *testing.T) {
 			initRuntimeObjs: []client.Object{
 				&corev1.Node{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "node1",
+						Name:   "node1",
+						Labels: map[string]string{corev1.LabelArchStable: "amd64"},
 					},
 				},
 				&corev1.Node{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "node2",
+						Name:   "node2",
+						Labels: map[string]string{corev1.LabelArchStable: "arm64"},
 					},
 				},
 				&corev1.Namespace{
@@ -106,6 +110,7 @@ func TestEnsureArtifactsJobForNodes(t *testing.T) {
 				assert.Equal(t, "test-installation", job.ObjectMeta.Annotations[InstallationNameAnnotation])
 				assert.Equal(t, artifactsHash, job.ObjectMeta.Annotations[ArtifactsConfigHashAnnotation])
 				assert.Equal(t, "local-artifact-mirror", job.Spec.Template.Spec.Containers[0].Image)
+				assert.Contains(t, job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: "ARCH", Value: "amd64"})
 
 				err = cli.Get(context.Background(), client.ObjectKey{Namespace: ecNamespace, Name: copyArtifactsJobPrefix + "node2"}, job)
 				require.NoError(t, err)
@@ -113,6 +118,7 @@ func TestEnsureArtifactsJobForNodes(t *testing.T) {
 				assert.Equal(t, "test-installation", job.ObjectMeta.Annotations[InstallationNameAnnotation])
 				assert.Equal(t, artifactsHash, job.ObjectMeta.Annotations[ArtifactsConfigHashAnnotation])
 				assert.Equal(t, "local-artifact-mirror", job.Spec.Template.Spec.Containers[0].Image)
+				assert.Contains(t, job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: "ARCH", Value: "arm64"})
 			},
 		},
 		{
@@ -417,3 +423,24 @@ func TestListArtifactsJobForNodes(t *testing.T) {
 		})
 	}
 }
+
+func Test_airgapPlatforms(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{corev1.LabelArchStable: "amd64"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{corev1.LabelArchStable: "amd64"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node3"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{Architecture: "arm64"}},
+		},
+	}
+
+	platforms := airgapPlatforms(nodes)
+	assert.Equal(t, map[string]autopilotv1beta2.PlanResourceURL{
+		runtime.GOOS + "-amd64": {URL: "http://127.0.0.1:50000/images/ec-images-amd64.tar"},
+		runtime.GOOS + "-arm64": {URL: "http://127.0.0.1:50000/images/ec-images-arm64.tar"},
+	}, platforms)
+}
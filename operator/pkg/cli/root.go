@@ -119,5 +119,6 @@ func addSubcommands(cmd *cobra.Command) {
 		UpgradeJobCmd(),
 		MigrateV2Cmd(),
 		VersionCmd(),
+		ServeBackupStoreCmd(),
 	)
 }
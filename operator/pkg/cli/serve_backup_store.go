@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/backupstore"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ServeBackupStoreCmd returns a cobra command for serving a backup directory through the S3 API.
+func ServeBackupStoreCmd() *cobra.Command {
+	var dir string
+	var address string
+
+	cmd := &cobra.Command{
+		Use:          "serve-backup-store",
+		Short:        "Serves a backup directory through the S3 API",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			accessKeyID := os.Getenv(backupstore.AccessKeyIDEnv)
+			secretAccessKey := os.Getenv(backupstore.SecretAccessKeyEnv)
+			if accessKeyID == "" || secretAccessKey == "" {
+				return fmt.Errorf("%s and %s must be set", backupstore.AccessKeyIDEnv, backupstore.SecretAccessKeyEnv)
+			}
+			if info, err := os.Stat(dir); err != nil {
+				return fmt.Errorf("failed to stat backup directory: %w", err)
+			} else if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			srv := &http.Server{
+				Addr:              address,
+				Handler:           backupstore.NewServer(dir, accessKeyID, secretAccessKey),
+				ReadHeaderTimeout: 30 * time.Second,
+			}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				if err := srv.Shutdown(shutdownCtx); err != nil {
+					logrus.Errorf("Failed to shut down the backup store server: %v", err)
+				}
+			}()
+
+			logrus.Infof("Serving backup directory %s on %s", dir, address)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("failed to serve backup store: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "/backups", "Path to the backup directory")
+	cmd.Flags().StringVar(&address, "address", fmt.Sprintf(":%d", backupstore.Port), "The address to listen on")
+
+	return cmd
+}
@@ -76,8 +76,8 @@ func UpgradeJobCmd() *cobra.Command {
 			defer hcli.Close()
 
 			if upgradeErr := performUpgrade(cmd.Context(), kcli, hcli, in); upgradeErr != nil {
-				// if this is the last attempt, mark the installation as failed
-				if err := maybeMarkAsFailed(cmd.Context(), kcli, in, upgradeErr); err != nil {
+				// if this is the last attempt, roll back the releases and mark the installation as failed
+				if err := maybeMarkAsFailed(cmd.Context(), kcli, hcli, in, upgradeErr); err != nil {
 					slog.Error("Failed to mark installation as failed", "error", err)
 				}
 				return upgradeErr
@@ -120,7 +120,7 @@ func performUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, i
 	return nil
 }
 
-func maybeMarkAsFailed(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, upgradeErr error) error {
+func maybeMarkAsFailed(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, upgradeErr error) error {
 	lastAttempt, err := isLastAttempt(ctx, kcli)
 	if err != nil {
 		return fmt.Errorf("check if last attempt: %w", err)
@@ -128,6 +128,10 @@ func maybeMarkAsFailed(ctx context.Context, kcli client.Client, in *ecv1beta1.In
 	if !lastAttempt {
 		return nil
 	}
+	// a failed rollback must not prevent the installation from being marked as failed.
+	if err := upgrade.RollbackFailedUpgrade(ctx, kcli, hcli, in); err != nil {
+		slog.Error("Failed to roll back upgrade", "error", err)
+	}
 	if err := kubeutils.SetInstallationState(ctx, kcli, in, ecv1beta1.InstallationStateFailed, helpers.CleanErrorMessage(upgradeErr)); err != nil {
 		return fmt.Errorf("set installation state: %w", err)
 	}
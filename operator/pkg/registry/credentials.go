@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AuthHashAnnotation is the annotation holding, in the registry pod template, the hash of the
+// htpasswd file the registry was last restarted with.
+const AuthHashAnnotation = "embedded-cluster.replicated.com/registry-auth-hash"
+
+// dockerConfig is the content of an image pull secret. Fields other than the credentials are
+// kept as they are.
+type dockerConfig struct {
+	Auths map[string]map[string]interface{} `json:"auths"`
+}
+
+// RotateCredentials sets a new password for the registry user. The htpasswd file of the
+// registry and all the image pull secrets holding credentials for the registry are updated
+// together: if one of the updates fails the secrets already updated are restored. The registry
+// is then restarted to load the new htpasswd file. Returns the image pull secrets updated.
+func RotateCredentials(ctx context.Context, cli client.Client, in *v1beta1.Installation, password string) ([]types.NamespacedName, error) {
+	address, err := Address(in)
+	if err != nil {
+		return nil, fmt.Errorf("get registry address: %w", err)
+	}
+
+	updates, err := pullSecretUpdates(ctx, cli, address, password)
+	if err != nil {
+		return nil, fmt.Errorf("update image pull secrets: %w", err)
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("no image pull secret found for registry %s", address)
+	}
+	var updated []types.NamespacedName
+	for _, secret := range updates {
+		updated = append(updated, client.ObjectKeyFromObject(secret))
+	}
+
+	htpasswd, err := registry.Htpasswd(password)
+	if err != nil {
+		return nil, fmt.Errorf("generate htpasswd: %w", err)
+	}
+	var auth corev1.Secret
+	nsn := types.NamespacedName{Namespace: runtimeconfig.RegistryNamespace, Name: registry.AuthSecretName}
+	if err := cli.Get(ctx, nsn, &auth); err != nil {
+		return nil, fmt.Errorf("get registry auth secret: %w", err)
+	}
+	auth.Data = map[string][]byte{"htpasswd": []byte(htpasswd)}
+	updates = append(updates, &auth)
+
+	if err := updateSecrets(ctx, cli, updates); err != nil {
+		return nil, err
+	}
+	if _, err := registry.Restart(ctx, cli, AuthHashAnnotation, hash([]byte(htpasswd))); err != nil {
+		return nil, fmt.Errorf("restart registry: %w", err)
+	}
+	return updated, nil
+}
+
+// pullSecretUpdates returns the image pull secrets holding credentials for the registry at the
+// address, sorted by namespace and name, with the password of the registry user replaced.
+func pullSecretUpdates(ctx context.Context, cli client.Client, address, password string) ([]*corev1.Secret, error) {
+	var secrets corev1.SecretList
+	if err := cli.List(ctx, &secrets); err != nil {
+		return nil, fmt.Errorf("list secrets: %w", err)
+	}
+
+	var updates []*corev1.Secret
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeDockerConfigJson {
+			continue
+		}
+		var config dockerConfig
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			continue
+		}
+		creds, ok := config.Auths[address]
+		if !ok || creds["username"] != registry.Username {
+			continue
+		}
+
+		creds["password"] = password
+		creds["auth"] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", registry.Username, password)))
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("marshal secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		update := secret.DeepCopy()
+		update.Data[corev1.DockerConfigJsonKey] = data
+		updates = append(updates, update)
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].Namespace != updates[j].Namespace {
+			return updates[i].Namespace < updates[j].Namespace
+		}
+		return updates[i].Name < updates[j].Name
+	})
+	return updates, nil
+}
+
+// updateSecrets updates the secrets in order. If an update fails, the data of the secrets
+// already updated is restored.
+func updateSecrets(ctx context.Context, cli client.Client, updates []*corev1.Secret) error {
+	var original []*corev1.Secret
+	for _, update := range updates {
+		var secret corev1.Secret
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(update), &secret); err != nil {
+			return fmt.Errorf("get secret %s/%s: %w", update.Namespace, update.Name, err)
+		}
+		original = append(original, &secret)
+	}
+
+	for i, update := range updates {
+		err := cli.Update(ctx, update)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("update secret %s/%s: %w", update.Namespace, update.Name, err)
+		for _, secret := range original[:i] {
+			if rerr := restoreSecret(ctx, cli, secret); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("restore secret %s/%s: %w", secret.Namespace, secret.Name, rerr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func restoreSecret(ctx context.Context, cli client.Client, original *corev1.Secret) error {
+	var secret corev1.Secret
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(original), &secret); err != nil {
+		return fmt.Errorf("get secret: %w", err)
+	}
+	secret.Data = original.Data
+	return cli.Update(ctx, &secret)
+}
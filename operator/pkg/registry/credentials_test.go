@@ -0,0 +1,152 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func testPullSecret(namespace, name string, auths map[string]string) *corev1.Secret {
+	config := `{"auths":{`
+	first := true
+	for address, password := range auths {
+		if !first {
+			config += ","
+		}
+		first = false
+		config += fmt.Sprintf(`%q:{"username":"embedded-cluster","password":%q,"email":"ec@example.com"}`, address, password)
+	}
+	config += `}}`
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(config)},
+	}
+}
+
+func testCredentialsObjects() []client.Object {
+	return []client.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-auth", Namespace: runtimeconfig.RegistryNamespace},
+			Data:       map[string][]byte{"htpasswd": []byte("embedded-cluster:old")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: runtimeconfig.RegistryNamespace},
+		},
+		testPullSecret("kotsadm", "registry-creds", map[string]string{"10.96.0.11:5000": "old"}),
+		testPullSecret("embedded-cluster", "registry-creds", map[string]string{"10.96.0.11:5000": "old"}),
+		testPullSecret("app", "app-registry", map[string]string{"10.96.0.11:5000": "old", "proxy.example.com": "other"}),
+		testPullSecret("app", "proxy-registry", map[string]string{"proxy.example.com": "other"}),
+	}
+}
+
+func pullSecretAuths(t *testing.T, kcli client.Client, namespace, name string) map[string]map[string]interface{} {
+	var secret corev1.Secret
+	err := kcli.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, &secret)
+	require.NoError(t, err)
+	var config dockerConfig
+	require.NoError(t, json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config))
+	return config.Auths
+}
+
+func TestRotateCredentials(t *testing.T) {
+	ctx := context.Background()
+	in := &v1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: v1beta1.InstallationSpec{
+			AirGap:  true,
+			Network: &v1beta1.NetworkSpec{ServiceCIDR: "10.96.0.0/12"},
+		},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(testCredentialsObjects()...).Build()
+
+	updated, err := RotateCredentials(ctx, kcli, in, "new")
+	require.NoError(t, err)
+	assert.Equal(t, []types.NamespacedName{
+		{Namespace: "app", Name: "app-registry"},
+		{Namespace: "embedded-cluster", Name: "registry-creds"},
+		{Namespace: "kotsadm", Name: "registry-creds"},
+	}, updated)
+
+	for _, nsn := range updated {
+		auths := pullSecretAuths(t, kcli, nsn.Namespace, nsn.Name)
+		assert.Equal(t, "new", auths["10.96.0.11:5000"]["password"])
+		assert.Equal(t, "ZW1iZWRkZWQtY2x1c3RlcjpuZXc=", auths["10.96.0.11:5000"]["auth"])
+		assert.Equal(t, "ec@example.com", auths["10.96.0.11:5000"]["email"])
+	}
+	assert.Equal(t, "other", pullSecretAuths(t, kcli, "app", "app-registry")["proxy.example.com"]["password"])
+	assert.Equal(t, "other", pullSecretAuths(t, kcli, "app", "proxy-registry")["proxy.example.com"]["password"])
+
+	var auth corev1.Secret
+	err = kcli.Get(ctx, types.NamespacedName{Namespace: runtimeconfig.RegistryNamespace, Name: "registry-auth"}, &auth)
+	require.NoError(t, err)
+	user, hashed, _ := strings.Cut(string(auth.Data["htpasswd"]), ":")
+	assert.Equal(t, "embedded-cluster", user)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hashed), []byte("new")))
+
+	var deploy appsv1.Deployment
+	err = kcli.Get(ctx, types.NamespacedName{Namespace: runtimeconfig.RegistryNamespace, Name: "registry"}, &deploy)
+	require.NoError(t, err)
+	assert.Equal(t, hash(auth.Data["htpasswd"]), deploy.Spec.Template.Annotations[AuthHashAnnotation])
+}
+
+func TestRotateCredentialsRollsBack(t *testing.T) {
+	ctx := context.Background()
+	in := &v1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: v1beta1.InstallationSpec{
+			AirGap:  true,
+			Network: &v1beta1.NetworkSpec{ServiceCIDR: "10.96.0.0/12"},
+		},
+	}
+	kcli := fake.NewClientBuilder().
+		WithScheme(kubeutils.Scheme).
+		WithObjects(testCredentialsObjects()...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, cli client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if obj.GetNamespace() == "kotsadm" {
+					return fmt.Errorf("forbidden")
+				}
+				return cli.Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	_, err := RotateCredentials(ctx, kcli, in, "new")
+	assert.ErrorContains(t, err, "update secret kotsadm/registry-creds: forbidden")
+
+	for _, nsn := range []types.NamespacedName{
+		{Namespace: "app", Name: "app-registry"},
+		{Namespace: "embedded-cluster", Name: "registry-creds"},
+		{Namespace: "kotsadm", Name: "registry-creds"},
+	} {
+		auths := pullSecretAuths(t, kcli, nsn.Namespace, nsn.Name)
+		assert.Equal(t, "old", auths["10.96.0.11:5000"]["password"], nsn.String())
+	}
+
+	var auth corev1.Secret
+	err = kcli.Get(ctx, types.NamespacedName{Namespace: runtimeconfig.RegistryNamespace, Name: "registry-auth"}, &auth)
+	require.NoError(t, err)
+	assert.Equal(t, "embedded-cluster:old", string(auth.Data["htpasswd"]))
+
+	var deploy appsv1.Deployment
+	err = kcli.Get(ctx, types.NamespacedName{Namespace: runtimeconfig.RegistryNamespace, Name: "registry"}, &deploy)
+	require.NoError(t, err)
+	assert.Empty(t, deploy.Spec.Template.Annotations)
+}
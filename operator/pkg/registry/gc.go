@@ -0,0 +1,658 @@
+// Package registry maintains the registry of airgap installations. Every airgap update pushes
+// a new set of images to the registry and nothing removes the old ones, so the images no
+// longer referenced are deleted from time to time. The certificate the registry serves is
+// renewed before it expires and its credentials can be rotated.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/artifacts"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	orasregistry "oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultKeepPreviousReleases is the number of previous installations whose images are
+	// kept when the installation does not configure it.
+	DefaultKeepPreviousReleases = 1
+	// DefaultInterval is the time between two garbage collections when the installation does
+	// not configure it.
+	DefaultInterval = 24 * time.Hour
+
+	// AppImagesConfigMapName is the name of the config map recording, for each installation,
+	// the registry images used by the workloads while the installation was the current one.
+	AppImagesConfigMapName = "registry-gc-app-images"
+
+	ecNamespace  = "embedded-cluster"
+	registryPort = 5000
+)
+
+// Plan lists the manifests garbage collection deletes from the registry.
+type Plan struct {
+	// Kept are the names of the installations whose images are kept.
+	Kept []string
+	// Manifests are the manifests to delete.
+	Manifests []Manifest
+	// FreedBytes is the size of the blobs only referenced by the manifests to delete, i.e.
+	// the space freed once the registry garbage collection removes them.
+	FreedBytes int64
+}
+
+// FreedSize returns the space freed by the plan in a human readable form.
+func (p *Plan) FreedSize() string {
+	size := float64(p.FreedBytes)
+	for _, unit := range []string{"B", "KiB", "MiB", "GiB"} {
+		if size < 1024 || unit == "GiB" {
+			if unit == "B" {
+				return fmt.Sprintf("%d B", p.FreedBytes)
+			}
+			return fmt.Sprintf("%.1f %s", size, unit)
+		}
+		size /= 1024
+	}
+	return ""
+}
+
+// Manifest is a manifest deleted from the registry.
+type Manifest struct {
+	Repository string
+	Descriptor ocispec.Descriptor
+	// Tags are the tags pointing to the manifest, if any. Manifests of the platforms of a
+	// multi-platform image have none.
+	Tags []string
+}
+
+// KeepPreviousReleases returns the number of previous installations whose images are kept.
+func KeepPreviousReleases(in *v1beta1.Installation) int {
+	if in.Spec.Config != nil && in.Spec.Config.RegistryGC.KeepPreviousReleases != nil {
+		return max(*in.Spec.Config.RegistryGC.KeepPreviousReleases, 0)
+	}
+	return DefaultKeepPreviousReleases
+}
+
+// Interval returns the time between two garbage collections of the registry.
+func Interval(in *v1beta1.Installation) time.Duration {
+	if in.Spec.Config != nil && in.Spec.Config.RegistryGC.Interval != nil && in.Spec.Config.RegistryGC.Interval.Duration > 0 {
+		return in.Spec.Config.RegistryGC.Interval.Duration
+	}
+	return DefaultInterval
+}
+
+// Enabled returns true if the registry of the installation is garbage collected on a schedule.
+func Enabled(in *v1beta1.Installation) bool {
+	if !in.Spec.AirGap {
+		return false
+	}
+	return in.Spec.Config == nil || !in.Spec.Config.RegistryGC.Disabled
+}
+
+// Address returns the address, host and port, of the registry of the installation.
+func Address(in *v1beta1.Installation) (string, error) {
+	serviceCIDR := ""
+	if in.Spec.Network != nil {
+		serviceCIDR = in.Spec.Network.ServiceCIDR
+	}
+	ip, err := registry.GetRegistryClusterIP(serviceCIDR)
+	if err != nil {
+		return "", fmt.Errorf("get registry cluster ip: %w", err)
+	}
+	return fmt.Sprintf("%s:%d", ip, registryPort), nil
+}
+
+// NewClient returns a client for the registry of the installation. Registries deployed
+// without tls are reached over plain http.
+func NewClient(ctx context.Context, cli client.Client, in *v1beta1.Installation) (*remote.Registry, error) {
+	address, err := Address(in)
+	if err != nil {
+		return nil, fmt.Errorf("get registry address: %w", err)
+	}
+
+	reg, err := artifacts.NewRegistry(ctx, cli, address, false)
+	if err != nil {
+		return nil, fmt.Errorf("create registry client: %w", err)
+	}
+	if err := reg.Ping(ctx); err == nil {
+		return reg, nil
+	}
+
+	// some versions of the registry were deployed without tls.
+	reg, err = artifacts.NewRegistry(ctx, cli, address, true)
+	if err != nil {
+		return nil, fmt.Errorf("create registry client: %w", err)
+	}
+	if err := reg.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("ping registry: %w", err)
+	}
+	return reg, nil
+}
+
+// BuildPlan computes the manifests to delete from the registry. Only the images known to
+// belong to installations no longer kept are deleted: the artifacts and images of their
+// release and the app images recorded while they were the current installation. Images
+// referenced by the current installation, by the keep previous ones or by the workloads
+// running in the cluster are never deleted, nor are the images pushed for an app release
+// not deployed yet. Installations are expected newest first. Nothing is changed in the
+// registry or in the cluster.
+func BuildPlan(ctx context.Context, cli client.Client, reg orasregistry.Registry, installs []v1beta1.Installation, keep int) (*Plan, error) {
+	kept, stale, plan, err := collectReferences(ctx, cli, installs, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := []string{}
+	if err := reg.Repositories(ctx, "", func(page []string) error {
+		repos = append(repos, page...)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list repositories: %w", err)
+	}
+	sort.Strings(repos)
+
+	// blobs holds the size of the blobs of all the manifests while keptBlobs holds the blobs
+	// of the manifests not deleted. blobs are shared among repositories.
+	blobs := map[string]int64{}
+	keptBlobs := map[string]bool{}
+	for _, name := range repos {
+		repo, err := reg.Repository(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get repository %s: %w", name, err)
+		}
+		deleted, err := planRepository(ctx, repo, name, kept, stale, blobs, keptBlobs)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %w", name, err)
+		}
+		plan.Manifests = append(plan.Manifests, deleted...)
+	}
+
+	for digest, size := range blobs {
+		if !keptBlobs[digest] {
+			plan.FreedBytes += size
+		}
+	}
+	return plan, nil
+}
+
+// planRepository returns the manifests of the repository to delete. A manifest is deleted if
+// all its tags, or its digest, are stale and none is kept. The blobs of the manifests are
+// added to blobs, and to keptBlobs for the manifests not deleted.
+func planRepository(ctx context.Context, repo orasregistry.Repository, name string, kept, stale *references, blobs map[string]int64, keptBlobs map[string]bool) ([]Manifest, error) {
+	tags := []string{}
+	if err := repo.Tags(ctx, "", func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	manifests := map[string]*Manifest{}
+	for _, tag := range tags {
+		desc, err := repo.Resolve(ctx, tag)
+		if err != nil {
+			return nil, fmt.Errorf("resolve tag %s: %w", tag, err)
+		}
+		digest := desc.Digest.String()
+		if _, ok := manifests[digest]; !ok {
+			manifests[digest] = &Manifest{Repository: name, Descriptor: desc}
+		}
+		manifests[digest].Tags = append(manifests[digest].Tags, tag)
+	}
+
+	digests := []string{}
+	deletable := map[string]bool{}
+	for digest, manifest := range manifests {
+		digests = append(digests, digest)
+		deletable[digest] = isDeletable(name, manifest.Tags, digest, kept, stale)
+	}
+	sort.Strings(digests)
+
+	// the manifests of the platforms of a multi-platform image are deleted with the image
+	// unless another image or a workload references them.
+	children := map[string][]ocispec.Descriptor{}
+	keptChildren := map[string]bool{}
+	for _, digest := range digests {
+		platforms, err := addBlobs(ctx, repo, manifests[digest].Descriptor, kept, blobs, keptBlobs, !deletable[digest])
+		if err != nil {
+			return nil, fmt.Errorf("read manifest %s: %w", digest, err)
+		}
+		children[digest] = platforms
+		for _, platform := range platforms {
+			if pd := platform.Digest.String(); !deletable[digest] || kept.digests[pd] {
+				keptChildren[pd] = true
+			}
+		}
+	}
+
+	deleted := []Manifest{}
+	for _, digest := range digests {
+		if !deletable[digest] {
+			continue
+		}
+		deleted = append(deleted, *manifests[digest])
+		for _, platform := range children[digest] {
+			if pd := platform.Digest.String(); !keptChildren[pd] && manifests[pd] == nil {
+				keptChildren[pd] = true // do not delete it twice.
+				deleted = append(deleted, Manifest{Repository: name, Descriptor: platform})
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// isDeletable returns true if the manifest tagged tags in the repository is stale and not
+// kept.
+func isDeletable(repo string, tags []string, digest string, kept, stale *references) bool {
+	if kept.digests[digest] {
+		return false
+	}
+	allStale := len(tags) > 0
+	for _, tag := range tags {
+		if kept.hasTag(repo, tag) {
+			return false
+		}
+		allStale = allStale && stale.hasTag(repo, tag)
+	}
+	return allStale || stale.digests[digest]
+}
+
+// manifestContent holds the fields of image manifests and image indexes, both oci and docker,
+// needed to find the blobs they reference.
+type manifestContent struct {
+	Config    *ocispec.Descriptor  `json:"config,omitempty"`
+	Layers    []ocispec.Descriptor `json:"layers,omitempty"`
+	Manifests []ocispec.Descriptor `json:"manifests,omitempty"`
+}
+
+// addBlobs records the blobs referenced by the manifest, and by its platform manifests if it
+// is an index. The platform manifests found in the repository are returned.
+func addBlobs(ctx context.Context, repo orasregistry.Repository, desc ocispec.Descriptor, refs *references, blobs map[string]int64, keptBlobs map[string]bool, kept bool) ([]ocispec.Descriptor, error) {
+	data, err := content.FetchAll(ctx, repo.Manifests(), desc)
+	if err != nil {
+		return nil, err
+	}
+	var manifest manifestContent
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %w", err)
+	}
+
+	layers := manifest.Layers
+	if manifest.Config != nil {
+		layers = append(layers, *manifest.Config)
+	}
+	for _, layer := range layers {
+		blobs[layer.Digest.String()] = layer.Size
+		if kept {
+			keptBlobs[layer.Digest.String()] = true
+		}
+	}
+
+	platforms := []ocispec.Descriptor{}
+	for _, platform := range manifest.Manifests {
+		// images are often pushed for a single platform, the other platform manifests of
+		// the index are missing from the registry.
+		platformKept := kept || refs.digests[platform.Digest.String()]
+		if _, err := addBlobs(ctx, repo, platform, refs, blobs, keptBlobs, platformKept); errors.Is(err, errdef.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms, nil
+}
+
+// Apply deletes the manifests of the plan from the registry and runs the registry garbage
+// collection, with the registry read-only, to remove the blobs no longer referenced. The app images recorded for the
+// installations no longer kept are forgotten.
+func Apply(ctx context.Context, cli client.Client, reg orasregistry.Registry, plan *Plan) error {
+	for _, manifest := range plan.Manifests {
+		repo, err := reg.Repository(ctx, manifest.Repository)
+		if err != nil {
+			return fmt.Errorf("get repository %s: %w", manifest.Repository, err)
+		}
+		err = repo.Manifests().Delete(ctx, manifest.Descriptor)
+		if errors.Is(err, errdef.ErrNotFound) {
+			continue
+		} else if isUnsupported(err) {
+			return fmt.Errorf("registry does not allow deletes, upgrade the registry addon first: %w", err)
+		} else if err != nil {
+			return fmt.Errorf("delete manifest %s@%s: %w", manifest.Repository, manifest.Descriptor.Digest, err)
+		}
+	}
+
+	if err := registry.RunGarbageCollection(ctx, cli); err != nil {
+		return fmt.Errorf("run registry garbage collection: %w", err)
+	}
+
+	if err := forgetAppImages(ctx, cli, plan.Kept); err != nil {
+		return fmt.Errorf("forget app images: %w", err)
+	}
+	return nil
+}
+
+func isUnsupported(err error) bool {
+	var errResp *errcode.ErrorResponse
+	if !errors.As(err, &errResp) {
+		return false
+	}
+	for _, e := range errResp.Errors {
+		if e.Code == errcode.ErrorCodeUnsupported {
+			return true
+		}
+	}
+	return errResp.StatusCode == 405
+}
+
+// collectReferences returns the references of the images kept and of the stale images, and a
+// plan naming the kept installations.
+func collectReferences(ctx context.Context, cli client.Client, installs []v1beta1.Installation, keep int) (*references, *references, *Plan, error) {
+	kept, stale := newReferences(), newReferences()
+	plan := &Plan{}
+
+	recorded, err := recordedAppImages(ctx, cli)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("read recorded app images: %w", err)
+	}
+
+	for i := range installs {
+		in := &installs[i]
+		refs := stale
+		if i <= keep {
+			refs = kept
+			plan.Kept = append(plan.Kept, in.Name)
+		}
+
+		for _, artifact := range artifactReferences(in.Spec.Artifacts) {
+			refs.add(artifact)
+		}
+		for _, image := range recorded[in.Name] {
+			refs.add(image)
+		}
+		if in.Spec.Config == nil || in.Spec.Config.Version == "" {
+			continue
+		}
+		meta, err := release.MetadataFor(ctx, in, cli)
+		if err != nil {
+			if refs == kept {
+				return nil, nil, nil, fmt.Errorf("get release metadata of installation %s: %w", in.Name, err)
+			}
+			// the metadata of old installations may be gone, their images are not
+			// collected then.
+			continue
+		}
+		for _, image := range meta.Images {
+			refs.add(image)
+		}
+	}
+
+	images, err := workloadImages(ctx, cli)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("list workload images: %w", err)
+	}
+	for _, image := range images {
+		kept.add(image)
+	}
+	return kept, stale, plan, nil
+}
+
+// artifactReferences returns the references of all the artifacts of an installation.
+func artifactReferences(loc *v1beta1.ArtifactsLocation) []string {
+	if loc == nil {
+		return nil
+	}
+	refs := []string{loc.Images, loc.HelmCharts, loc.EmbeddedClusterBinary, loc.EmbeddedClusterMetadata}
+	for _, m := range []map[string]string{loc.AdditionalArtifacts, loc.ImagesByArch, loc.EmbeddedClusterBinaryByArch} {
+		for _, ref := range m {
+			refs = append(refs, ref)
+		}
+	}
+	result := []string{}
+	for _, ref := range refs {
+		if ref != "" {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// RecordAppImages records the registry images used by the workloads as images of the
+// installation, so they are kept while the installation is. Images of the app releases
+// deployed while the installation was the current one are kept this way.
+func RecordAppImages(ctx context.Context, cli client.Client, in *v1beta1.Installation) error {
+	images, err := workloadImages(ctx, cli)
+	if err != nil {
+		return fmt.Errorf("list workload images: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = cli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: AppImagesConfigMapName}, cm)
+	if k8serrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ecNamespace, Name: AppImagesConfigMapName},
+			Data:       map[string]string{in.Name: strings.Join(images, "\n")},
+		}
+		if err := cli.Create(ctx, cm); err != nil {
+			return fmt.Errorf("create configmap: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get configmap: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, image := range splitImages(cm.Data[in.Name]) {
+		known[image] = true
+	}
+	changed := cm.Data == nil || cm.Data[in.Name] == ""
+	for _, image := range images {
+		if !known[image] {
+			known[image] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	all := []string{}
+	for image := range known {
+		all = append(all, image)
+	}
+	sort.Strings(all)
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[in.Name] = strings.Join(all, "\n")
+	if err := cli.Update(ctx, cm); err != nil {
+		return fmt.Errorf("update configmap: %w", err)
+	}
+	return nil
+}
+
+func recordedAppImages(ctx context.Context, cli client.Client) (map[string][]string, error) {
+	cm := &corev1.ConfigMap{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: AppImagesConfigMapName}, cm)
+	if k8serrors.IsNotFound(err) {
+		return map[string][]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	recorded := map[string][]string{}
+	for name, images := range cm.Data {
+		recorded[name] = splitImages(images)
+	}
+	return recorded, nil
+}
+
+// forgetAppImages removes the app images recorded for the installations not kept.
+func forgetAppImages(ctx context.Context, cli client.Client, kept []string) error {
+	cm := &corev1.ConfigMap{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: AppImagesConfigMapName}, cm)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get configmap: %w", err)
+	}
+
+	keep := map[string]bool{}
+	for _, name := range kept {
+		keep[name] = true
+	}
+	changed := false
+	for name := range cm.Data {
+		if !keep[name] {
+			delete(cm.Data, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := cli.Update(ctx, cm); err != nil {
+		return fmt.Errorf("update configmap: %w", err)
+	}
+	return nil
+}
+
+func splitImages(s string) []string {
+	images := []string{}
+	for _, image := range strings.Split(s, "\n") {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// workloadImages returns the images of the pods and of the pod templates of the workloads,
+// including the ones scaled down and the replica sets kept for rollbacks.
+func workloadImages(ctx context.Context, cli client.Client) ([]string, error) {
+	specs := []corev1.PodSpec{}
+
+	var pods corev1.PodList
+	if err := cli.List(ctx, &pods); err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		specs = append(specs, pod.Spec)
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := cli.List(ctx, &deployments); err != nil {
+		return nil, fmt.Errorf("list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		specs = append(specs, d.Spec.Template.Spec)
+	}
+
+	var replicaSets appsv1.ReplicaSetList
+	if err := cli.List(ctx, &replicaSets); err != nil {
+		return nil, fmt.Errorf("list replicasets: %w", err)
+	}
+	for _, rs := range replicaSets.Items {
+		specs = append(specs, rs.Spec.Template.Spec)
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := cli.List(ctx, &statefulSets); err != nil {
+		return nil, fmt.Errorf("list statefulsets: %w", err)
+	}
+	for _, sts := range statefulSets.Items {
+		specs = append(specs, sts.Spec.Template.Spec)
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := cli.List(ctx, &daemonSets); err != nil {
+		return nil, fmt.Errorf("list daemonsets: %w", err)
+	}
+	for _, ds := range daemonSets.Items {
+		specs = append(specs, ds.Spec.Template.Spec)
+	}
+
+	var cronJobs batchv1.CronJobList
+	if err := cli.List(ctx, &cronJobs); err != nil {
+		return nil, fmt.Errorf("list cronjobs: %w", err)
+	}
+	for _, cj := range cronJobs.Items {
+		specs = append(specs, cj.Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	seen := map[string]bool{}
+	images := []string{}
+	for _, spec := range specs {
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for _, c := range containers {
+				if c.Image != "" && !seen[c.Image] {
+					seen[c.Image] = true
+					images = append(images, c.Image)
+				}
+			}
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// references are images referenced by installations or workloads.
+type references struct {
+	digests map[string]bool
+	// tags holds "<repository>:<tag>" entries, the repository being the full path without
+	// the registry. Repositories sharing their last element are different images.
+	tags map[string]bool
+}
+
+func newReferences() *references {
+	return &references{digests: map[string]bool{}, tags: map[string]bool{}}
+}
+
+func (r *references) add(image string) {
+	repo, tag, digest := parseImage(image)
+	if digest != "" {
+		r.digests[digest] = true
+	}
+	if tag != "" {
+		r.tags[repo+":"+tag] = true
+	}
+}
+
+// hasTag returns true if the tag of the repository is referenced.
+func (r *references) hasTag(repo, tag string) bool {
+	return r.tags[repo+":"+tag]
+}
+
+// parseImage splits an image name in its repository, without the registry, tag and digest.
+func parseImage(image string) (repo string, tag string, digest string) {
+	repo = image
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, digest = repo[:i], repo[i+1:]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, tag = repo[:i], repo[i+1:]
+	}
+	if i := strings.Index(repo, "/"); i >= 0 {
+		host := repo[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			repo = repo[i+1:]
+		}
+	}
+	return repo, tag, digest
+}
@@ -0,0 +1,298 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"oras.land/oras-go/v2/registry/remote"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testRegistry implements the parts of the registry api used by the garbage collection.
+type testRegistry struct {
+	mutex sync.Mutex
+	// tags maps repositories to their tags and the digest they point to.
+	tags map[string]map[string]string
+	// manifests maps repositories to the content of their manifests by digest.
+	manifests map[string]map[string][]byte
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{tags: map[string]map[string]string{}, manifests: map[string]map[string][]byte{}}
+}
+
+// push adds a manifest to the repository, tagged with the tags, and returns its descriptor.
+func (r *testRegistry) push(repo string, mediaType string, manifest interface{}, tags ...string) ocispec.Descriptor {
+	data, _ := json.Marshal(manifest)
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(data), Size: int64(len(data))}
+	if r.manifests[repo] == nil {
+		r.manifests[repo] = map[string][]byte{}
+		r.tags[repo] = map[string]string{}
+	}
+	r.manifests[repo][desc.Digest.String()] = data
+	for _, tag := range tags {
+		r.tags[repo][tag] = desc.Digest.String()
+	}
+	return desc
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case path == "":
+		w.WriteHeader(http.StatusOK)
+	case path == "_catalog":
+		repos := []string{}
+		for repo := range r.tags {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+		json.NewEncoder(w).Encode(map[string]interface{}{"repositories": repos})
+	case strings.HasSuffix(path, "/tags/list"):
+		repo := strings.TrimSuffix(path, "/tags/list")
+		tags := []string{}
+		for tag := range r.tags[repo] {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": repo, "tags": tags})
+	case strings.Contains(path, "/manifests/"):
+		repo, ref, _ := strings.Cut(path, "/manifests/")
+		dgst := ref
+		if tagged, ok := r.tags[repo][ref]; ok {
+			dgst = tagged
+		}
+		data, ok := r.manifests[repo][dgst]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodDelete {
+			delete(r.manifests[repo], dgst)
+			for tag, d := range r.tags[repo] {
+				if d == dgst {
+					delete(r.tags[repo], tag)
+				}
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var manifest struct {
+			MediaType string `json:"mediaType"`
+		}
+		json.Unmarshal(data, &manifest)
+		w.Header().Set("Content-Type", manifest.MediaType)
+		w.Header().Set("Docker-Content-Digest", dgst)
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testBlob(name string, size int64) ocispec.Descriptor {
+	return ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayer, Digest: digest.FromString(name), Size: size}
+}
+
+// pushImage adds an image manifest to the repository and returns its descriptor.
+func (r *testRegistry) pushImage(repo string, config ocispec.Descriptor, layers []ocispec.Descriptor, tags ...string) ocispec.Descriptor {
+	manifest := ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Config: config, Layers: layers}
+	manifest.SchemaVersion = 2
+	return r.push(repo, ocispec.MediaTypeImageManifest, manifest, tags...)
+}
+
+func testInstallation(name string, images string) v1beta1.Installation {
+	return v1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1beta1.InstallationSpec{
+			AirGap:    true,
+			Artifacts: &v1beta1.ArtifactsLocation{Images: images},
+		},
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	reg := newTestRegistry()
+
+	shared := testBlob("shared", 1000)
+	nginx1 := reg.pushImage("app/nginx", testBlob("nginx-1-config", 10), []ocispec.Descriptor{shared, testBlob("nginx-1", 100)}, "1.0")
+	reg.pushImage("app/nginx", testBlob("nginx-2-config", 10), []ocispec.Descriptor{shared}, "2.0")
+	// pushed for an app release not deployed yet.
+	reg.pushImage("app/nginx", testBlob("nginx-3-config", 10), []ocispec.Descriptor{shared}, "3.0")
+	// shares the name and tag of a stale image but is a different repository.
+	reg.pushImage("other/nginx", testBlob("other-nginx-1-config", 10), []ocispec.Descriptor{testBlob("other-nginx-1", 100)}, "1.0")
+	// recorded for a stale installation but still used by a workload.
+	reg.pushImage("app/api", testBlob("api-1-config", 10), []ocispec.Descriptor{testBlob("api-1", 200)}, "1.0")
+	// also tagged with a tag not known to be stale.
+	reg.pushImage("app/worker", testBlob("worker-1-config", 10), []ocispec.Descriptor{testBlob("worker-1", 300)}, "1.0", "stable")
+
+	// a multi-platform image pushed for a single platform, the arm64 manifest is missing.
+	amd64 := reg.pushImage("app/redis", testBlob("redis-amd64-config", 10), []ocispec.Descriptor{testBlob("redis-amd64", 500)})
+	arm64 := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageManifest, Digest: digest.FromString("redis-arm64"), Size: 100}
+	index := ocispec.Index{MediaType: ocispec.MediaTypeImageIndex, Manifests: []ocispec.Descriptor{amd64, arm64}}
+	index.SchemaVersion = 2
+	redis1 := reg.push("app/redis", ocispec.MediaTypeImageIndex, index, "1.0")
+
+	images1 := reg.pushImage("embedded-cluster/images-amd64", testBlob("images-1-config", 10), []ocispec.Descriptor{testBlob("images-1", 5000)}, "v1")
+	reg.pushImage("embedded-cluster/images-amd64", testBlob("images-2-config", 10), []ocispec.Descriptor{testBlob("images-2", 5000)}, "v2")
+	reg.pushImage("embedded-cluster/images-amd64", testBlob("images-3-config", 10), []ocispec.Descriptor{testBlob("images-3", 5000)}, "v3")
+
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	installs := []v1beta1.Installation{
+		testInstallation("20240103000000", host+"/embedded-cluster/images-amd64:v3"),
+		testInstallation("20240102000000", host+"/embedded-cluster/images-amd64:v2"),
+		testInstallation("20240101000000", host+"/embedded-cluster/images-amd64:v1"),
+	}
+
+	recorded := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ecNamespace, Name: AppImagesConfigMapName},
+		Data: map[string]string{
+			"20240101000000": strings.Join([]string{
+				host + "/app/nginx:1.0",
+				host + "/app/api:1.0",
+				host + "/app/worker:1.0",
+				host + "/app/redis:1.0",
+			}, "\n"),
+			"20240102000000": host + "/app/nginx:2.0",
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "api"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "api", Image: host + "/app/api:1.0"}}},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(recorded, pod).Build()
+
+	rcli, err := remote.NewRegistry(host)
+	require.NoError(t, err)
+	rcli.PlainHTTP = true
+
+	plan, err := BuildPlan(context.Background(), kcli, rcli, installs, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"20240103000000", "20240102000000"}, plan.Kept)
+
+	deleted := []string{}
+	for _, manifest := range plan.Manifests {
+		deleted = append(deleted, manifest.Repository+"@"+manifest.Descriptor.Digest.String())
+	}
+	assert.Equal(t, []string{
+		"app/nginx@" + nginx1.Digest.String(),
+		"app/redis@" + redis1.Digest.String(),
+		"app/redis@" + amd64.Digest.String(),
+		"embedded-cluster/images-amd64@" + images1.Digest.String(),
+	}, deleted)
+	// the layers and configs only referenced by the deleted manifests.
+	assert.Equal(t, int64(10+100+10+500+10+5000), plan.FreedBytes)
+	assert.Equal(t, "5.5 KiB", plan.FreedSize())
+
+	require.NoError(t, forgetAppImages(context.Background(), kcli, plan.Kept))
+	images, err := recordedAppImages(context.Background(), kcli)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"20240102000000": {host + "/app/nginx:2.0"}}, images)
+}
+
+func TestBuildPlanKeepsEverythingReferenced(t *testing.T) {
+	reg := newTestRegistry()
+	reg.pushImage("embedded-cluster/images-amd64", testBlob("images-1-config", 10), []ocispec.Descriptor{testBlob("images-1", 5000)}, "v1")
+	reg.pushImage("embedded-cluster/images-amd64", testBlob("images-2-config", 10), []ocispec.Descriptor{testBlob("images-2", 5000)}, "v2")
+
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	installs := []v1beta1.Installation{
+		testInstallation("20240102000000", host+"/embedded-cluster/images-amd64:v2"),
+		testInstallation("20240101000000", host+"/embedded-cluster/images-amd64:v1"),
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).Build()
+
+	rcli, err := remote.NewRegistry(host)
+	require.NoError(t, err)
+	rcli.PlainHTTP = true
+
+	plan, err := BuildPlan(context.Background(), kcli, rcli, installs, 1)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Manifests)
+	assert.Zero(t, plan.FreedBytes)
+
+	plan, err = BuildPlan(context.Background(), kcli, rcli, installs, 0)
+	require.NoError(t, err)
+	assert.Len(t, plan.Manifests, 1)
+	assert.Equal(t, int64(5010), plan.FreedBytes)
+}
+
+func TestRecordAppImages(t *testing.T) {
+	in := testInstallation("20240101000000", "")
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "nginx"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: new(int32),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", Image: "registry:5000/app/init:1.0"}},
+				Containers:     []corev1.Container{{Name: "nginx", Image: "registry:5000/app/nginx:1.0"}},
+			}},
+		},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(deployment).Build()
+	ctx := context.Background()
+
+	require.NoError(t, RecordAppImages(ctx, kcli, &in))
+	recorded, err := recordedAppImages(ctx, kcli)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{in.Name: {"registry:5000/app/init:1.0", "registry:5000/app/nginx:1.0"}}, recorded)
+
+	// images are accumulated while the installation is the current one.
+	deployment.Spec.Template.Spec.Containers[0].Image = "registry:5000/app/nginx:1.1"
+	require.NoError(t, kcli.Update(ctx, deployment))
+	require.NoError(t, RecordAppImages(ctx, kcli, &in))
+	recorded, err = recordedAppImages(ctx, kcli)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"registry:5000/app/init:1.0", "registry:5000/app/nginx:1.0", "registry:5000/app/nginx:1.1"}, recorded[in.Name])
+}
+
+func Test_parseImage(t *testing.T) {
+	tests := []struct {
+		image      string
+		wantRepo   string
+		wantTag    string
+		wantDigest string
+	}{
+		{image: "nginx", wantRepo: "nginx"},
+		{image: "library/nginx:1.0", wantRepo: "library/nginx", wantTag: "1.0"},
+		{image: "10.96.0.11:5000/app/nginx:1.0", wantRepo: "app/nginx", wantTag: "1.0"},
+		{image: "localhost/app/nginx@sha256:abc", wantRepo: "app/nginx", wantDigest: "sha256:abc"},
+		{image: "proxy.replicated.com/anonymous/nginx:1.0@sha256:abc", wantRepo: "anonymous/nginx", wantTag: "1.0", wantDigest: "sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repo, tag, digest := parseImage(tt.image)
+			assert.Equal(t, tt.wantRepo, repo)
+			assert.Equal(t, tt.wantTag, tag)
+			assert.Equal(t, tt.wantDigest, digest)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RenewBefore is how long before it expires the certificate of the registry is renewed.
+	RenewBefore = 30 * 24 * time.Hour
+	// forceRestartBefore is how long before the certificate served by the registry expires
+	// the registry is restarted to serve the renewed one, even if some nodes do not trust it
+	// yet.
+	forceRestartBefore = 24 * time.Hour
+
+	// TLSHashAnnotation is the annotation holding the hash of the certificate served by the
+	// registry in its pod template, and the hash of the certificates trusted by the node in
+	// the trust jobs.
+	TLSHashAnnotation = "embedded-cluster.replicated.com/registry-tls-hash"
+	// nodeUIDAnnotation is the annotation holding the uid of the node a trust job ran on, so
+	// nodes joining again with the same name trust the registry again.
+	nodeUIDAnnotation = "embedded-cluster.replicated.com/node-uid"
+
+	caConfigMapName = "registry-ca"
+	trustJobPrefix  = "registry-trust-"
+	defaultImage    = "busybox:latest"
+)
+
+// trustJob is the job making containerd on a node trust the certificates of the registry. It
+// copies them next to the containerd configuration and points the configuration of the
+// registry to them. The node, the image and the configuration are set for each node.
+var trustJob = &batchv1.Job{
+	TypeMeta: metav1.TypeMeta{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+	},
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: ecNamespace,
+	},
+	Spec: batchv1.JobSpec{
+		BackoffLimit: ptr.To[int32](2),
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "embedded-cluster-operator",
+				Volumes: []corev1.Volume{
+					{
+						Name: "containerd",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: runtimeconfig.PathToK0sContainerdConfig(),
+								Type: ptr.To[corev1.HostPathType]("DirectoryOrCreate"),
+							},
+						},
+					},
+					{
+						Name: "ca",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: caConfigMapName},
+							},
+						},
+					},
+				},
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{
+					{
+						Name:  "embedded-cluster-registry-trust",
+						Image: defaultImage,
+						Command: []string{
+							"/bin/sh",
+							"-ex",
+							"-c",
+							fmt.Sprintf("cp /ca/ca.crt /containerd.d/%[1]s.tmp\n", airgap.RegistryCAFile) +
+								fmt.Sprintf("mv /containerd.d/%[1]s.tmp /containerd.d/%[1]s\n", airgap.RegistryCAFile) +
+								fmt.Sprintf("printf '%%s' \"$REGISTRY_CONFIG\" > /containerd.d/%[1]s.tmp\n", airgap.RegistryConfigFile) +
+								fmt.Sprintf("mv /containerd.d/%[1]s.tmp /containerd.d/%[1]s\n", airgap.RegistryConfigFile) +
+								"echo 'done'",
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "containerd",
+								MountPath: "/containerd.d",
+							},
+							{
+								Name:      "ca",
+								MountPath: "/ca",
+								ReadOnly:  true,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// TLSStatus reports what ReconcileTLS did.
+type TLSStatus struct {
+	// Renewed is true if the certificate of the registry was renewed.
+	Renewed bool
+	// Restarted is true if the registry was restarted to serve its current certificate.
+	Restarted bool
+	// NotAfter is when the current certificate of the registry expires.
+	NotAfter time.Time
+	// PendingNodes are the nodes not trusting the current certificate yet.
+	PendingNodes []string
+}
+
+// ReconcileTLS renews the certificate of the registry when it expires in less than
+// RenewBefore, keeping its subject and alternative names. Containerd on every node is made to
+// trust both the renewed certificate and the one served by the registry, and the registry is
+// restarted to serve the renewed one once all the nodes trust it, so image pulls keep working
+// during the rotation. Nothing is done, and nil is returned, for registries deployed without
+// tls. The trust jobs run the provided image, busybox if empty.
+func ReconcileTLS(ctx context.Context, cli client.Client, in *v1beta1.Installation, image string) (*TLSStatus, error) {
+	address, err := Address(in)
+	if err != nil {
+		return nil, fmt.Errorf("get registry address: %w", err)
+	}
+	return reconcileTLS(ctx, cli, in, image, address, time.Now())
+}
+
+func reconcileTLS(ctx context.Context, cli client.Client, in *v1beta1.Installation, image, address string, now time.Time) (*TLSStatus, error) {
+	crt, err := registry.TLSCertificate(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("get registry certificate: %w", err)
+	}
+	if crt == nil {
+		return nil, nil
+	}
+	served, err := servedCertificate(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("get certificate served by the registry: %w", err)
+	}
+
+	status := &TLSStatus{}
+	if now.Add(RenewBefore).After(crt.NotAfter) {
+		renewed, err := registry.RenewTLSSecret(ctx, cli, crt)
+		if err != nil {
+			return nil, fmt.Errorf("renew registry certificate: %w", err)
+		}
+		if crt, err = parseCertificate([]byte(renewed)); err != nil {
+			return nil, fmt.Errorf("parse renewed registry certificate: %w", err)
+		}
+		status.Renewed = true
+	}
+	status.NotAfter = crt.NotAfter
+
+	trusted := []*x509.Certificate{crt}
+	if !served.Equal(crt) {
+		trusted = append(trusted, served)
+	}
+	bundle, err := ensureCAConfigMap(ctx, cli, in, trusted)
+	if err != nil {
+		return nil, fmt.Errorf("ensure registry ca config map: %w", err)
+	}
+	status.PendingNodes, err = ensureTrustJobsForNodes(ctx, cli, in, image, address, hash(bundle))
+	if err != nil {
+		return nil, fmt.Errorf("ensure registry trust jobs: %w", err)
+	}
+
+	if served.Equal(crt) {
+		return status, nil
+	}
+	if len(status.PendingNodes) > 0 && now.Add(forceRestartBefore).Before(served.NotAfter) {
+		return status, nil
+	}
+	status.Restarted, err = registry.Restart(ctx, cli, TLSHashAnnotation, hash(crt.Raw))
+	if err != nil {
+		return nil, fmt.Errorf("restart registry: %w", err)
+	}
+	return status, nil
+}
+
+// servedCertificate returns the certificate the registry at the address serves.
+func servedCertificate(ctx context.Context, address string) (*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("dial registry: %w", err)
+	}
+	defer conn.Close()
+
+	peers := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("registry served no certificate")
+	}
+	return peers[0], nil
+}
+
+// ensureCAConfigMap stores the certificates the nodes trust the registry with in the config
+// map mounted by the trust jobs. Returns the certificates in PEM format.
+func ensureCAConfigMap(ctx context.Context, cli client.Client, in *v1beta1.Installation, trusted []*x509.Certificate) ([]byte, error) {
+	bundle := bytes.NewBuffer(nil)
+	for _, crt := range trusted {
+		if err := pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw}); err != nil {
+			return nil, fmt.Errorf("encode certificate: %w", err)
+		}
+	}
+
+	obj := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: caConfigMapName, Namespace: ecNamespace},
+	}
+	_, err := ctrl.CreateOrUpdate(ctx, cli, obj, func() error {
+		if in.GetUID() != "" {
+			if err := ctrl.SetControllerReference(in, obj, cli.Scheme()); err != nil {
+				return fmt.Errorf("set controller reference: %w", err)
+			}
+		}
+		obj.Data = map[string]string{"ca.crt": bundle.String()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create or update config map: %w", err)
+	}
+	return bundle.Bytes(), nil
+}
+
+// ensureTrustJobsForNodes ensures a trust job for the certificates with the provided hash ran
+// on every node. Jobs for other certificates, for a previous node with the same name or that
+// failed are replaced. Returns the nodes whose job did not succeed yet.
+func ensureTrustJobsForNodes(ctx context.Context, cli client.Client, in *v1beta1.Installation, image, address, cahash string) ([]string, error) {
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+
+	var pending []string
+	for _, node := range nodes.Items {
+		job, err := getTrustJobForNode(cli, in, node, image, address, cahash)
+		if err != nil {
+			return nil, fmt.Errorf("get job for node %s: %w", node.Name, err)
+		}
+		err = kubeutils.EnsureObject(ctx, cli, job, func(opts *kubeutils.EnsureObjectOptions) {
+			opts.DeleteOptions = append(opts.DeleteOptions, client.PropagationPolicy(metav1.DeletePropagationForeground))
+			opts.ShouldDelete = func(obj client.Object) bool {
+				annotations := obj.GetAnnotations()
+				if annotations[TLSHashAnnotation] != cahash || annotations[nodeUIDAnnotation] != string(node.UID) {
+					return true
+				}
+				return jobFailed(obj.(*batchv1.Job))
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ensure job for node %s: %w", node.Name, err)
+		}
+		if job.Status.Succeeded == 0 {
+			pending = append(pending, node.Name)
+		}
+	}
+	return pending, nil
+}
+
+func getTrustJobForNode(cli client.Client, in *v1beta1.Installation, node corev1.Node, image, address, cahash string) (*batchv1.Job, error) {
+	job := trustJob.DeepCopy()
+	job.ObjectMeta.Name = util.NameWithLengthLimit(trustJobPrefix, node.Name)
+	job.ObjectMeta.Labels = map[string]string{
+		"app.kubernetes.io/component":  "registry-trust",
+		"app.kubernetes.io/part-of":    "embedded-cluster",
+		"app.kubernetes.io/managed-by": "embedded-cluster-operator",
+	}
+	job.ObjectMeta.Annotations = map[string]string{
+		TLSHashAnnotation: cahash,
+		nodeUIDAnnotation: string(node.UID),
+	}
+	job.Spec.Template.Spec.NodeName = node.Name
+	if image != "" {
+		job.Spec.Template.Spec.Containers[0].Image = image
+	}
+	job.Spec.Template.Spec.Containers[0].Env = append(
+		job.Spec.Template.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: "REGISTRY_CONFIG", Value: airgap.TrustedRegistryConfig(address)},
+	)
+
+	if in.GetUID() != "" {
+		if err := ctrl.SetControllerReference(in, job, cli.Scheme()); err != nil {
+			return nil, fmt.Errorf("set controller reference: %w", err)
+		}
+	}
+	return job, nil
+}
+
+func jobFailed(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("decode certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func hash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:10]
+}
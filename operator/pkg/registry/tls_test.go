@@ -0,0 +1,213 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/certs"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testTLSSecret returns the registry tls secret holding a certificate expiring at the provided
+// time, and the certificate.
+func testTLSSecret(t *testing.T, expiration time.Time) (*corev1.Secret, *x509.Certificate) {
+	builder, err := certs.NewBuilder(
+		certs.WithCommonName("registry"),
+		certs.WithExpiration(expiration),
+		certs.WithIPAddress("10.96.0.11"),
+		certs.WithDNSName("registry.registry.svc"),
+	)
+	require.NoError(t, err)
+	crt, key, err := builder.Generate()
+	require.NoError(t, err)
+	parsed, err := parseCertificate([]byte(crt))
+	require.NoError(t, err)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-tls", Namespace: runtimeconfig.RegistryNamespace},
+		Data:       map[string][]byte{"tls.crt": []byte(crt), "tls.key": []byte(key)},
+	}
+	return secret, parsed
+}
+
+// testTLSServer starts a tls server serving the certificate in the secret and returns its
+// address.
+func testTLSServer(t *testing.T, secret *corev1.Secret) string {
+	pair, err := tls.X509KeyPair(secret.Data["tls.crt"], secret.Data["tls.key"])
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server.Listener.Addr().String()
+}
+
+func testTLSObjects(secret *corev1.Secret) []client.Object {
+	return []client.Object{
+		secret,
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: runtimeconfig.RegistryNamespace},
+		},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "uid1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", UID: "uid2"}},
+	}
+}
+
+func trustedCertificates(t *testing.T, kcli client.Client) []*x509.Certificate {
+	var cm corev1.ConfigMap
+	err := kcli.Get(context.Background(), client.ObjectKey{Namespace: ecNamespace, Name: caConfigMapName}, &cm)
+	require.NoError(t, err)
+
+	var trusted []*x509.Certificate
+	rest := []byte(cm.Data["ca.crt"])
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		crt, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		trusted = append(trusted, crt)
+	}
+	return trusted
+}
+
+func succeedTrustJob(t *testing.T, kcli client.Client, node string) {
+	var job batchv1.Job
+	err := kcli.Get(context.Background(), client.ObjectKey{Namespace: ecNamespace, Name: trustJobPrefix + node}, &job)
+	require.NoError(t, err)
+	job.Status.Succeeded = 1
+	require.NoError(t, kcli.Status().Update(context.Background(), &job))
+}
+
+func TestReconcileTLS(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	in := &v1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"}, Spec: v1beta1.InstallationSpec{AirGap: true}}
+
+	secret, old := testTLSSecret(t, now.Add(10*24*time.Hour))
+	address := testTLSServer(t, secret)
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(testTLSObjects(secret)...).Build()
+
+	// the certificate expires soon so it is renewed with the same names, and both the new
+	// and the served one are trusted until the registry serves the new one.
+	status, err := reconcileTLS(ctx, kcli, in, "utils:latest", address, now)
+	require.NoError(t, err)
+	assert.True(t, status.Renewed)
+	assert.False(t, status.Restarted)
+	assert.ElementsMatch(t, []string{"node1", "node2"}, status.PendingNodes)
+	assert.True(t, status.NotAfter.After(now.Add(360*24*time.Hour)))
+
+	var renewed corev1.Secret
+	err = kcli.Get(ctx, client.ObjectKeyFromObject(secret), &renewed)
+	require.NoError(t, err)
+	crt, err := parseCertificate(renewed.Data["tls.crt"])
+	require.NoError(t, err)
+	assert.False(t, crt.Equal(old))
+	assert.Equal(t, old.Subject.CommonName, crt.Subject.CommonName)
+	assert.Equal(t, old.DNSNames, crt.DNSNames)
+	assert.Equal(t, len(old.IPAddresses), len(crt.IPAddresses))
+	for i := range old.IPAddresses {
+		assert.True(t, old.IPAddresses[i].Equal(crt.IPAddresses[i]))
+	}
+	_, err = tls.X509KeyPair(renewed.Data["tls.crt"], renewed.Data["tls.key"])
+	require.NoError(t, err)
+
+	trusted := trustedCertificates(t, kcli)
+	require.Len(t, trusted, 2)
+	assert.True(t, trusted[0].Equal(crt))
+	assert.True(t, trusted[1].Equal(old))
+
+	var job batchv1.Job
+	err = kcli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: "registry-trust-node1"}, &job)
+	require.NoError(t, err)
+	assert.Equal(t, "node1", job.Spec.Template.Spec.NodeName)
+	assert.Equal(t, "utils:latest", job.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "uid1", job.Annotations[nodeUIDAnnotation])
+	config := job.Spec.Template.Spec.Containers[0].Env[0].Value
+	assert.Contains(t, config, `registry.configs."`+address+`".tls]`)
+	assert.Contains(t, config, `ca_file = "/etc/k0s/containerd.d/embedded-registry-ca.crt"`)
+	assert.NotContains(t, config, "insecure_skip_verify")
+
+	// the registry is not restarted until all the nodes trust the new certificate.
+	succeedTrustJob(t, kcli, "node1")
+	status, err = reconcileTLS(ctx, kcli, in, "utils:latest", address, now)
+	require.NoError(t, err)
+	assert.False(t, status.Renewed)
+	assert.False(t, status.Restarted)
+	assert.Equal(t, []string{"node2"}, status.PendingNodes)
+
+	succeedTrustJob(t, kcli, "node2")
+	status, err = reconcileTLS(ctx, kcli, in, "utils:latest", address, now)
+	require.NoError(t, err)
+	assert.False(t, status.Renewed)
+	assert.True(t, status.Restarted)
+	assert.Empty(t, status.PendingNodes)
+
+	var deploy appsv1.Deployment
+	err = kcli.Get(ctx, client.ObjectKey{Namespace: runtimeconfig.RegistryNamespace, Name: "registry"}, &deploy)
+	require.NoError(t, err)
+	assert.Equal(t, hash(crt.Raw), deploy.Spec.Template.Annotations[TLSHashAnnotation])
+
+	// once the registry serves the new certificate the previous one is no longer trusted.
+	address = testTLSServer(t, &renewed)
+	status, err = reconcileTLS(ctx, kcli, in, "utils:latest", address, now)
+	require.NoError(t, err)
+	assert.False(t, status.Renewed)
+	assert.False(t, status.Restarted)
+	assert.ElementsMatch(t, []string{"node1", "node2"}, status.PendingNodes)
+	trusted = trustedCertificates(t, kcli)
+	require.Len(t, trusted, 1)
+	assert.True(t, trusted[0].Equal(crt))
+}
+
+func TestReconcileTLSForcesRestartBeforeExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	in := &v1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"}, Spec: v1beta1.InstallationSpec{AirGap: true}}
+
+	secret, _ := testTLSSecret(t, now.Add(12*time.Hour))
+	address := testTLSServer(t, secret)
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(testTLSObjects(secret)...).Build()
+
+	status, err := reconcileTLS(ctx, kcli, in, "", address, now)
+	require.NoError(t, err)
+	assert.True(t, status.Renewed)
+	assert.True(t, status.Restarted)
+	assert.Len(t, status.PendingNodes, 2)
+
+	var job batchv1.Job
+	err = kcli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: "registry-trust-node1"}, &job)
+	require.NoError(t, err)
+	assert.Equal(t, "busybox:latest", job.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestReconcileTLSWithoutTLS(t *testing.T) {
+	in := &v1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"}, Spec: v1beta1.InstallationSpec{AirGap: true}}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).Build()
+
+	// the registry is not reached when it is deployed without tls.
+	status, err := reconcileTLS(context.Background(), kcli, in, "", "10.96.0.11:5000", time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, status)
+
+	var jobs batchv1.JobList
+	require.NoError(t, kcli.List(context.Background(), &jobs))
+	assert.Empty(t, jobs.Items)
+}
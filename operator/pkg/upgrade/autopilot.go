@@ -53,24 +53,9 @@ func startAutopilotUpgrade(ctx context.Context, cli client.Client, in *v1beta1.I
 		return fmt.Errorf("failed to determine upgrade targets: %w", err)
 	}
 
-	var k0surl string
-	if in.Spec.AirGap {
-		// if we are running in an airgap environment all assets are already present in the
-		// node and are served by the local-artifact-mirror binary listening on localhost
-		// port 50000. we just need to get autopilot to fetch the k0s binary from there.
-		k0surl = fmt.Sprintf("http://127.0.0.1:%d/bin/k0s-upgrade", runtimeconfig.LocalArtifactMirrorPort())
-	} else {
-		artifact := meta.Artifacts["k0s"]
-		if strings.HasPrefix(artifact, "https://") || strings.HasPrefix(artifact, "http://") {
-			// for dev and e2e tests we allow the url to be overridden
-			k0surl = artifact
-		} else {
-			k0surl = fmt.Sprintf(
-				"%s/embedded-cluster-public-files/%s",
-				in.Spec.MetricsBaseURL,
-				artifact,
-			)
-		}
+	platforms, err := k0sUpgradePlatforms(ctx, cli, in, meta)
+	if err != nil {
+		return fmt.Errorf("failed to determine upgrade platforms: %w", err)
 	}
 
 	plan := apv1b2.Plan{
@@ -86,11 +71,9 @@ func startAutopilotUpgrade(ctx context.Context, cli client.Client, in *v1beta1.I
 			Commands: []apv1b2.PlanCommand{
 				{
 					K0sUpdate: &apv1b2.PlanCommandK0sUpdate{
-						Version: meta.Versions["Kubernetes"],
-						Targets: targets,
-						Platforms: apv1b2.PlanPlatformResourceURLMap{
-							fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH): {URL: k0surl, Sha256: meta.K0sSHA},
-						},
+						Version:   meta.Versions["Kubernetes"],
+						Targets:   targets,
+						Platforms: platforms,
 					},
 				},
 			},
@@ -102,3 +85,54 @@ func startAutopilotUpgrade(ctx context.Context, cli client.Client, in *v1beta1.I
 	in.Status.SetState(v1beta1.InstallationStateEnqueued, "", nil)
 	return nil
 }
+
+// k0sUpgradePlatforms returns, for each architecture in the cluster, where autopilot can fetch
+// the k0s binary from. The release metadata only holds the checksum of the k0s binary for the
+// architecture it was generated on, assumed to be the one of the operator, so the binaries for
+// the other architectures are not verified.
+func k0sUpgradePlatforms(ctx context.Context, cli client.Client, in *v1beta1.Installation, meta *ectypes.ReleaseMetadata) (apv1b2.PlanPlatformResourceURLMap, error) {
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	archs := map[string]bool{runtime.GOARCH: true}
+	for _, node := range nodes.Items {
+		archs[artifacts.NodeArch(node)] = true
+	}
+
+	platforms := apv1b2.PlanPlatformResourceURLMap{}
+	for arch := range archs {
+		platform := apv1b2.PlanResourceURL{URL: k0sURLForArch(in, meta, arch)}
+		if arch == runtime.GOARCH {
+			platform.Sha256 = meta.K0sSHA
+		}
+		platforms[fmt.Sprintf("%s-%s", runtime.GOOS, arch)] = platform
+	}
+	return platforms, nil
+}
+
+func k0sURLForArch(in *v1beta1.Installation, meta *ectypes.ReleaseMetadata, arch string) string {
+	if in.Spec.AirGap {
+		// if we are running in an airgap environment all assets are already present in the
+		// node and are served by the local-artifact-mirror binary listening on localhost
+		// port 50000. we just need to get autopilot to fetch the k0s binary from there.
+		// the binary each node serves is the one for its own architecture.
+		return fmt.Sprintf("http://127.0.0.1:%d/bin/k0s-upgrade", runtimeconfig.LocalArtifactMirrorPort())
+	}
+
+	artifact := meta.Artifacts["k0s"]
+	if strings.HasPrefix(artifact, "https://") || strings.HasPrefix(artifact, "http://") {
+		// for dev and e2e tests we allow the url to be overridden
+		return artifact
+	}
+	// the k0s artifact is named after the architecture the release metadata was generated on.
+	if suffix := "-" + runtime.GOARCH; strings.HasSuffix(artifact, suffix) {
+		artifact = strings.TrimSuffix(artifact, suffix) + "-" + arch
+	}
+	return fmt.Sprintf(
+		"%s/embedded-cluster-public-files/%s",
+		in.Spec.MetricsBaseURL,
+		artifact,
+	)
+}
@@ -0,0 +1,78 @@
+package upgrade
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_k0sUpgradePlatforms(t *testing.T) {
+	otherArch := "arm64"
+	if runtime.GOARCH == "arm64" {
+		otherArch = "amd64"
+	}
+	nodes := []client.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "controller", Labels: map[string]string{corev1.LabelArchStable: runtime.GOARCH}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker", Labels: map[string]string{corev1.LabelArchStable: otherArch}}},
+	}
+	meta := &ectypes.ReleaseMetadata{
+		K0sSHA:    "sha",
+		Artifacts: map[string]string{"k0s": "k0s-binaries/v1.30.5+k0s.0-" + runtime.GOARCH},
+	}
+
+	tests := []struct {
+		name string
+		in   *v1beta1.Installation
+		want apv1b2.PlanPlatformResourceURLMap
+	}{
+		{
+			name: "online",
+			in: &v1beta1.Installation{
+				Spec: v1beta1.InstallationSpec{MetricsBaseURL: "https://replicated.app"},
+			},
+			want: apv1b2.PlanPlatformResourceURLMap{
+				runtime.GOOS + "-" + runtime.GOARCH: {
+					URL:    "https://replicated.app/embedded-cluster-public-files/k0s-binaries/v1.30.5+k0s.0-" + runtime.GOARCH,
+					Sha256: "sha",
+				},
+				runtime.GOOS + "-" + otherArch: {
+					URL: "https://replicated.app/embedded-cluster-public-files/k0s-binaries/v1.30.5+k0s.0-" + otherArch,
+				},
+			},
+		},
+		{
+			name: "airgap",
+			in: &v1beta1.Installation{
+				Spec: v1beta1.InstallationSpec{AirGap: true},
+			},
+			want: apv1b2.PlanPlatformResourceURLMap{
+				runtime.GOOS + "-" + runtime.GOARCH: {
+					URL:    "http://127.0.0.1:50000/bin/k0s-upgrade",
+					Sha256: "sha",
+				},
+				runtime.GOOS + "-" + otherArch: {
+					URL: "http://127.0.0.1:50000/bin/k0s-upgrade",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().WithObjects(nodes...).Build()
+
+			platforms, err := k0sUpgradePlatforms(context.Background(), cli, tt.in, meta)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, platforms)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package upgrade
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -21,7 +22,13 @@ func CreateInstallation(ctx context.Context, cli client.Client, original *ecv1be
 	}
 	slog.Info("Creating installation", "name", in.Name)
 
-	err := kubeutils.CreateInstallation(ctx, cli, in)
+	previous, err := kubeutils.GetPreviousInstallation(ctx, cli, in)
+	if err != nil && !errors.Is(err, kubeutils.ErrNoInstallations{}) && !errors.Is(err, kubeutils.ErrInstallationNotFound{}) {
+		return fmt.Errorf("get previous installation: %w", err)
+	}
+	keepInstallationSettings(in, previous)
+
+	err = kubeutils.CreateInstallation(ctx, cli, in)
 	if err != nil {
 		return fmt.Errorf("create installation: %w", err)
 	}
@@ -42,6 +49,17 @@ func CreateInstallation(ctx context.Context, cli client.Client, original *ecv1be
 	return nil
 }
 
+// keepInstallationSettings sets the storage and the ingress of the installation to the ones of
+// the previous installation. These are only set at installation time and are kept across
+// upgrades.
+func keepInstallationSettings(in *ecv1beta1.Installation, previous *ecv1beta1.Installation) {
+	if previous == nil {
+		return
+	}
+	in.Spec.Storage = previous.Spec.Storage.DeepCopy()
+	in.Spec.Ingress = previous.Spec.Ingress.DeepCopy()
+}
+
 // reApplyInstallation updates the installation spec to match what's in the configmap used by the upgrade job.
 // This is required because the installation CRD may have been updated as part of this upgrade, and additional fields may be present now.
 func reApplyInstallation(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
@@ -0,0 +1,229 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/extensions"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RollbackConditionType is the installation condition describing the rollback of the helm
+// releases after a failed upgrade.
+const RollbackConditionType = "UpgradeRollback"
+
+const releaseRevisionsKey = "revisions"
+
+// releaseRevision is the last deployed revision of a helm release before the upgrade.
+type releaseRevision struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Revision is zero if the release was not deployed before the upgrade.
+	Revision int  `json:"revision"`
+	AddOn    bool `json:"addOn"`
+}
+
+func rollbackPolicy(in *ecv1beta1.Installation) ecv1beta1.RollbackPolicy {
+	if in.Spec.Config == nil || in.Spec.Config.RollbackPolicy == "" {
+		return ecv1beta1.RollbackPolicyNever
+	}
+	return in.Spec.Config.RollbackPolicy
+}
+
+// releaseRevisionsConfigMap returns the config map holding the revisions of the helm releases
+// recorded before the upgrade to the provided installation.
+func releaseRevisionsConfigMap(in *ecv1beta1.Installation) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      fmt.Sprintf("upgrade-revisions-%s", in.Name),
+		Namespace: runtimeconfig.EmbeddedClusterNamespace,
+	}
+}
+
+// recordReleaseRevisions stores the revision of each helm release the upgrade may touch. The
+// revisions are recorded only once so a restarted upgrade job does not overwrite them with
+// the revisions of a partial upgrade.
+func recordReleaseRevisions(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1beta1.Installation) error {
+	nsn := releaseRevisionsConfigMap(in)
+	var cm corev1.ConfigMap
+	if err := cli.Get(ctx, nsn, &cm); err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("get release revisions config map: %w", err)
+	}
+
+	revisions, err := upgradeReleases(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("list releases: %w", err)
+	}
+	for i, r := range revisions {
+		history, err := hcli.History(ctx, r.Namespace, r.Name)
+		if err != nil {
+			return fmt.Errorf("get history of release %s: %w", r.Name, err)
+		}
+		revisions[i].Revision = lastDeployedRevision(history)
+	}
+
+	data, err := json.Marshal(revisions)
+	if err != nil {
+		return fmt.Errorf("marshal release revisions: %w", err)
+	}
+	cm = corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: nsn.Name, Namespace: nsn.Namespace},
+		Data:       map[string]string{releaseRevisionsKey: string(data)},
+	}
+	if err := cli.Create(ctx, &cm); err != nil {
+		return fmt.Errorf("create release revisions config map: %w", err)
+	}
+	slog.Info("Recorded helm release revisions", "releases", len(revisions))
+	return nil
+}
+
+// deleteReleaseRevisions removes the revisions recorded for the upgrade to the installation.
+func deleteReleaseRevisions(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	nsn := releaseRevisionsConfigMap(in)
+	cm := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: nsn.Name, Namespace: nsn.Namespace}}
+	if err := cli.Delete(ctx, &cm); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("delete release revisions config map: %w", err)
+	}
+	return nil
+}
+
+// upgradeReleases returns the helm releases of the addons and extensions, in the order they
+// are upgraded.
+func upgradeReleases(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) ([]releaseRevision, error) {
+	meta, err := release.MetadataFor(ctx, in, cli)
+	if err != nil {
+		return nil, fmt.Errorf("get release metadata: %w", err)
+	}
+	addOns, err := addons.GetAddOnsForUpgrade(in, meta)
+	if err != nil {
+		return nil, fmt.Errorf("get addons for upgrade: %w", err)
+	}
+
+	previous, err := kubeutils.GetPreviousInstallation(ctx, cli, in)
+	if err != nil {
+		return nil, fmt.Errorf("get previous installation: %w", err)
+	}
+
+	releases := []releaseRevision{}
+	for _, addon := range addOns {
+		releases = append(releases, releaseRevision{
+			Name:      addon.ReleaseName(),
+			Namespace: addon.Namespace(),
+			AddOn:     true,
+		})
+	}
+	for _, ext := range extensions.PlanUpgrade(previous.Spec.Config, in.Spec.Config) {
+		releases = append(releases, releaseRevision{
+			Name:      ext.Name,
+			Namespace: ext.Namespace,
+		})
+	}
+	return releases, nil
+}
+
+func lastDeployedRevision(history []*helmrelease.Release) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Info != nil && history[i].Info.Status == helmrelease.StatusDeployed {
+			return history[i].Version
+		}
+	}
+	return 0
+}
+
+// RollbackFailedUpgrade rolls back, in reverse upgrade order, the helm releases changed by a
+// failed upgrade to the revisions recorded before it started. Which releases are rolled back
+// is determined by the rollback policy of the installation. The outcome is recorded in the
+// RollbackConditionType installation condition.
+func RollbackFailedUpgrade(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1beta1.Installation) error {
+	policy := rollbackPolicy(in)
+	if policy == ecv1beta1.RollbackPolicyNever {
+		return nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := cli.Get(ctx, releaseRevisionsConfigMap(in), &cm); errors.IsNotFound(err) {
+		slog.Info("No helm release revisions recorded, nothing to roll back")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get release revisions config map: %w", err)
+	}
+
+	var revisions []releaseRevision
+	if err := json.Unmarshal([]byte(cm.Data[releaseRevisionsKey]), &revisions); err != nil {
+		return fmt.Errorf("unmarshal release revisions: %w", err)
+	}
+
+	var rolledBack, failed []string
+	for i := len(revisions) - 1; i >= 0; i-- {
+		r := revisions[i]
+		if !r.AddOn && policy == ecv1beta1.RollbackPolicyAddOnsOnly {
+			continue
+		}
+		if r.Revision == 0 {
+			slog.Info("Release did not exist before the upgrade, skipping rollback", "release", r.Name, "namespace", r.Namespace)
+			continue
+		}
+
+		history, err := hcli.History(ctx, r.Namespace, r.Name)
+		if err != nil {
+			slog.Error("Failed to get release history", "release", r.Name, "namespace", r.Namespace, "error", err)
+			failed = append(failed, r.Name)
+			continue
+		}
+		if len(history) == 0 || history[len(history)-1].Version == r.Revision {
+			// the release was not touched by the upgrade.
+			continue
+		}
+
+		slog.Info("Rolling back release", "release", r.Name, "namespace", r.Namespace, "revision", r.Revision)
+		err = hcli.Rollback(ctx, helm.RollbackOptions{
+			ReleaseName: r.Name,
+			Namespace:   r.Namespace,
+			Revision:    r.Revision,
+		})
+		if err != nil {
+			slog.Error("Failed to roll back release", "release", r.Name, "namespace", r.Namespace, "error", err)
+			failed = append(failed, r.Name)
+			continue
+		}
+		rolledBack = append(rolledBack, r.Name)
+	}
+
+	condition := metav1.Condition{
+		Type:    RollbackConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "RolledBack",
+		Message: fmt.Sprintf("Rolled back releases: %s", strings.Join(rolledBack, ", ")),
+	}
+	if len(rolledBack) == 0 {
+		condition.Message = "No releases needed to be rolled back"
+	}
+	if len(failed) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "RollbackFailed"
+		condition.Message = fmt.Sprintf("%s. Failed to roll back releases: %s", condition.Message, strings.Join(failed, ", "))
+	}
+	if err := kubeutils.SetInstallationConditionStatus(ctx, cli, in, condition); err != nil {
+		return fmt.Errorf("set rollback condition: %w", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to roll back releases: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
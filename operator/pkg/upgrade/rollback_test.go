@@ -0,0 +1,143 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_lastDeployedRevision(t *testing.T) {
+	rel := func(version int, status helmrelease.Status) *helmrelease.Release {
+		return &helmrelease.Release{Version: version, Info: &helmrelease.Info{Status: status}}
+	}
+	assert.Equal(t, 0, lastDeployedRevision(nil))
+	assert.Equal(t, 2, lastDeployedRevision([]*helmrelease.Release{
+		rel(1, helmrelease.StatusSuperseded),
+		rel(2, helmrelease.StatusDeployed),
+		rel(3, helmrelease.StatusFailed),
+	}))
+}
+
+func TestRollbackFailedUpgrade(t *testing.T) {
+	revisions := []releaseRevision{
+		{Name: "openebs", Namespace: "openebs", Revision: 3, AddOn: true},
+		{Name: "admin-console", Namespace: "kotsadm", Revision: 5, AddOn: true},
+		{Name: "velero", Namespace: "velero", Revision: 0, AddOn: true},
+		{Name: "my-ext", Namespace: "my-ns", Revision: 2},
+	}
+	history := func(versions ...int) []*helmrelease.Release {
+		result := []*helmrelease.Release{}
+		for _, v := range versions {
+			result = append(result, &helmrelease.Release{Version: v})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		policy        ecv1beta1.RollbackPolicy
+		setupHelm     func(hcli *helm.MockClient)
+		wantCondition *metav1.Condition
+		wantErr       bool
+	}{
+		{
+			name:      "never",
+			policy:    ecv1beta1.RollbackPolicyNever,
+			setupHelm: func(hcli *helm.MockClient) {},
+		},
+		{
+			name:   "addons only",
+			policy: ecv1beta1.RollbackPolicyAddOnsOnly,
+			setupHelm: func(hcli *helm.MockClient) {
+				hcli.On("History", mock.Anything, "kotsadm", "admin-console").Return(history(5), nil)
+				hcli.On("History", mock.Anything, "openebs", "openebs").Return(history(3, 4), nil)
+				hcli.On("Rollback", mock.Anything, helm.RollbackOptions{ReleaseName: "openebs", Namespace: "openebs", Revision: 3}).Return(nil)
+			},
+			wantCondition: &metav1.Condition{
+				Type:    RollbackConditionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "RolledBack",
+				Message: "Rolled back releases: openebs",
+			},
+		},
+		{
+			name:   "all in reverse order",
+			policy: ecv1beta1.RollbackPolicyAll,
+			setupHelm: func(hcli *helm.MockClient) {
+				mock.InOrder(
+					hcli.On("History", mock.Anything, "my-ns", "my-ext").Return(history(2, 3), nil),
+					hcli.On("Rollback", mock.Anything, helm.RollbackOptions{ReleaseName: "my-ext", Namespace: "my-ns", Revision: 2}).Return(nil),
+					hcli.On("History", mock.Anything, "kotsadm", "admin-console").Return(history(5, 6), nil),
+					hcli.On("Rollback", mock.Anything, helm.RollbackOptions{ReleaseName: "admin-console", Namespace: "kotsadm", Revision: 5}).Return(assert.AnError),
+					hcli.On("History", mock.Anything, "openebs", "openebs").Return(history(3, 4), nil),
+					hcli.On("Rollback", mock.Anything, helm.RollbackOptions{ReleaseName: "openebs", Namespace: "openebs", Revision: 3}).Return(nil),
+				)
+			},
+			wantCondition: &metav1.Condition{
+				Type:    RollbackConditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "RollbackFailed",
+				Message: "Rolled back releases: my-ext, openebs. Failed to roll back releases: admin-console",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &ecv1beta1.Installation{
+				ObjectMeta: metav1.ObjectMeta{Name: "20240102000000"},
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{RollbackPolicy: tt.policy},
+				},
+			}
+			data, err := json.Marshal(revisions)
+			require.NoError(t, err)
+			nsn := releaseRevisionsConfigMap(in)
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: nsn.Name, Namespace: nsn.Namespace},
+				Data:       map[string]string{releaseRevisionsKey: string(data)},
+			}
+			kcli := fake.NewClientBuilder().
+				WithScheme(kubeutils.Scheme).
+				WithObjects(in, cm).
+				WithStatusSubresource(in).
+				Build()
+
+			hcli := &helm.MockClient{}
+			tt.setupHelm(hcli)
+
+			err = RollbackFailedUpgrade(context.Background(), kcli, hcli, in)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			hcli.AssertExpectations(t)
+
+			var got ecv1beta1.Installation
+			require.NoError(t, kcli.Get(context.Background(), client.ObjectKey{Name: in.Name}, &got))
+			condition := meta.FindStatusCondition(got.Status.Conditions, RollbackConditionType)
+			if tt.wantCondition == nil {
+				assert.Nil(t, condition)
+				return
+			}
+			require.NotNil(t, condition)
+			assert.Equal(t, tt.wantCondition.Status, condition.Status)
+			assert.Equal(t, tt.wantCondition.Reason, condition.Reason)
+			assert.Equal(t, tt.wantCondition.Message, condition.Message)
+		})
+	}
+}
@@ -51,6 +51,13 @@ func Upgrade(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1b
 		return fmt.Errorf("cluster config update: %w", err)
 	}
 
+	if rollbackPolicy(in) != ecv1beta1.RollbackPolicyNever {
+		err = recordReleaseRevisions(ctx, cli, hcli, in)
+		if err != nil {
+			return fmt.Errorf("record release revisions: %w", err)
+		}
+	}
+
 	slog.Info("Upgrading addons")
 	err = upgradeAddons(ctx, cli, hcli, in)
 	if err != nil {
@@ -70,6 +77,10 @@ func Upgrade(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1b
 		return fmt.Errorf("unlock installation: %w", err)
 	}
 
+	if err := deleteReleaseRevisions(ctx, cli, in); err != nil {
+		slog.Error("Failed to delete release revisions", "error", err)
+	}
+
 	err = support.CreateHostSupportBundle()
 	if err != nil {
 		slog.Error("Failed to upgrade host support bundle", "error", err)
@@ -92,6 +103,7 @@ func maybeOverrideInstallationDataDirs(ctx context.Context, cli client.Client, i
 	if err != nil {
 		return in, fmt.Errorf("override installation data dirs: %w", err)
 	}
+	keepInstallationSettings(&next, previous)
 	return &next, nil
 }
 
@@ -20,6 +20,16 @@ type AdminConsole struct {
 	Password      string
 	PrivateCAs    []string
 	KotsInstaller KotsInstaller
+	// Hostname is the hostname the admin console is exposed at through the ingress
+	// controller. The admin console is only exposed through its node port if empty.
+	Hostname string
+	// TLSCert and TLSKey hold the PEM encoded certificate and key served for the hostname. A
+	// self signed certificate is generated if empty.
+	TLSCert string
+	TLSKey  string
+	// StorageClassName is the storage class of the rqlite volumes. The class in the chart
+	// values is used if empty.
+	StorageClassName string
 }
 
 type KotsInstaller func(msg *spinner.MessageWriter) error
@@ -86,6 +96,13 @@ func (a *AdminConsole) Namespace() string {
 	return namespace
 }
 
+func (a *AdminConsole) Dependencies() []string {
+	// kotsadm is stateful and, in air gap installations, pushes the application images to
+	// the registry. It is exposed at its hostname through the ingress controller. It creates
+	// the installation object the operator reconciles and takes backups with velero.
+	return []string{"Storage", "Registry", "Ingress", "Embedded Cluster Operator", "Velero"}
+}
+
 func getBackupLabels() map[string]string {
 	return map[string]string{
 		"replicated.com/disaster-recovery":       "infra",
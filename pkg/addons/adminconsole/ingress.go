@@ -0,0 +1,151 @@
+package adminconsole
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
+	"github.com/replicatedhq/embedded-cluster/pkg/certs"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	ingressName          = "kotsadm"
+	ingressTLSSecretName = "kotsadm-ingress-tls"
+	// kurlProxyServiceName and kurlProxyPort are the service and the port kurl-proxy serves
+	// the admin console on, over tls.
+	kurlProxyServiceName = "kurl-proxy-kotsadm"
+	kurlProxyPort        = 8800
+	// backendProtocolAnnotation makes the ingress controller connect to the backend over tls.
+	backendProtocolAnnotation = "nginx.ingress.kubernetes.io/backend-protocol"
+)
+
+// ValidateTLS checks that the certificate and key provided for the hostname form a valid key
+// pair.
+func ValidateTLS(certPEM, keyPEM string) error {
+	if certPEM == "" && keyPEM == "" {
+		return nil
+	}
+	if _, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM)); err != nil {
+		return errors.Wrap(err, "parse key pair")
+	}
+	return nil
+}
+
+// ensureIngress exposes the admin console at its hostname through the ingress controller,
+// terminating tls with the certificate provided. Traffic is routed to kurl-proxy, as with the
+// node port. A self signed certificate is generated for
+// the hostname if none is provided and none was stored before.
+func (a *AdminConsole) ensureIngress(ctx context.Context, kcli client.Client) error {
+	if a.Hostname == "" {
+		return nil
+	}
+
+	if err := ensureIngressTLSSecret(ctx, kcli, a.Hostname, a.TLSCert, a.TLSKey); err != nil {
+		return errors.Wrap(err, "ensure tls secret")
+	}
+
+	if err := ensureIngressObject(ctx, kcli, a.Hostname); err != nil {
+		return errors.Wrap(err, "ensure ingress")
+	}
+
+	return nil
+}
+
+func ensureIngressTLSSecret(ctx context.Context, kcli client.Client, hostname, certPEM, keyPEM string) error {
+	if certPEM == "" {
+		var existing corev1.Secret
+		err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ingressTLSSecretName}, &existing)
+		if err == nil {
+			return nil
+		} else if !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "get secret")
+		}
+
+		certPEM, keyPEM, err = generateIngressTLS(hostname)
+		if err != nil {
+			return errors.Wrap(err, "generate self signed certificate")
+		}
+	}
+
+	obj := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ingressTLSSecretName, Namespace: namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kcli, obj, func() error {
+		obj.Labels = getIngressLabels()
+		obj.Type = corev1.SecretTypeTLS
+		obj.Data = map[string][]byte{
+			corev1.TLSCertKey:       []byte(certPEM),
+			corev1.TLSPrivateKeyKey: []byte(keyPEM),
+		}
+		return nil
+	})
+	return err
+}
+
+func generateIngressTLS(hostname string) (string, string, error) {
+	builder, err := certs.NewBuilder(
+		certs.WithCommonName(hostname),
+		certs.WithDNSName(hostname),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create cert builder: %w", err)
+	}
+	return builder.Generate()
+}
+
+func ensureIngressObject(ctx context.Context, kcli client.Client, hostname string) error {
+	obj := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: ingressName, Namespace: namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kcli, obj, func() error {
+		obj.Labels = getIngressLabels()
+		if obj.Annotations == nil {
+			obj.Annotations = map[string]string{}
+		}
+		obj.Annotations[backendProtocolAnnotation] = "HTTPS"
+		obj.Spec = networkingv1.IngressSpec{
+			IngressClassName: ptr.To(ingressnginx.ClassName),
+			TLS: []networkingv1.IngressTLS{
+				{Hosts: []string{hostname}, SecretName: ingressTLSSecretName},
+			},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: hostname,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: ptr.To(networkingv1.PathTypePrefix),
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: kurlProxyServiceName,
+											Port: networkingv1.ServiceBackendPort{Number: kurlProxyPort},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		return nil
+	})
+	return err
+}
+
+func getIngressLabels() map[string]string {
+	labels := getBackupLabels()
+	labels["kots.io/kotsadm"] = "true"
+	return labels
+}
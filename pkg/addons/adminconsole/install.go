@@ -34,6 +34,7 @@ func (a *AdminConsole) Install(ctx context.Context, kcli client.Client, hcli hel
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
@@ -77,6 +78,10 @@ func (a *AdminConsole) createPreRequisites(ctx context.Context, kcli client.Clie
 		}
 	}
 
+	if err := a.ensureIngress(ctx, kcli); err != nil {
+		return errors.Wrap(err, "create ingress")
+	}
+
 	return nil
 }
 
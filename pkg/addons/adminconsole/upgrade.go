@@ -18,6 +18,10 @@ func (a *AdminConsole) Upgrade(ctx context.Context, kcli client.Client, hcli hel
 		return errors.New("admin console release not found")
 	}
 
+	if err := a.ensureIngress(ctx, kcli); err != nil {
+		return errors.Wrap(err, "ensure ingress")
+	}
+
 	values, err := a.GenerateHelmValues(ctx, kcli, overrides)
 	if err != nil {
 		return errors.Wrap(err, "generate helm values")
@@ -27,6 +31,7 @@ func (a *AdminConsole) Upgrade(ctx context.Context, kcli client.Client, hcli hel
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
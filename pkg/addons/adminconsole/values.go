@@ -62,6 +62,12 @@ func (a *AdminConsole) GenerateHelmValues(ctx context.Context, kcli client.Clien
 		return nil, errors.Wrap(err, "set kurlProxy.nodePort")
 	}
 
+	if a.StorageClassName != "" {
+		if err := helm.SetValue(copiedValues, "storageClass", a.StorageClassName); err != nil {
+			return nil, errors.Wrap(err, "set storageClass")
+		}
+	}
+
 	for _, override := range overrides {
 		copiedValues, err = helm.PatchValues(copiedValues, override)
 		if err != nil {
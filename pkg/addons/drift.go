@@ -0,0 +1,304 @@
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"helm.sh/helm/v3/pkg/chartutil"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// maxDriftedFields is the maximum number of drifted fields reported per object.
+const maxDriftedFields = 3
+
+// AddOnDrift describes how the deployed state of an addon differs from its expected state.
+type AddOnDrift struct {
+	AddOn types.AddOn
+	// Reasons is empty if the addon did not drift.
+	Reasons []string
+}
+
+// Drifted returns true if the deployed state of the addon differs from its expected state.
+func (d AddOnDrift) Drifted() bool {
+	return len(d.Reasons) > 0
+}
+
+// DriftConditionName returns the name of the installation condition reporting the drift of
+// the addon. As the other installation conditions, it is true when the addon is healthy, that
+// is when it is in sync with its expected state.
+func DriftConditionName(addon types.AddOn) string {
+	return fmt.Sprintf("%s-%s-InSync", addon.Namespace(), addon.ReleaseName())
+}
+
+// DriftCondition returns the installation condition reporting the drift of the addon.
+func DriftCondition(drift AddOnDrift) metav1.Condition {
+	if !drift.Drifted() {
+		return metav1.Condition{
+			Type:   DriftConditionName(drift.AddOn),
+			Status: metav1.ConditionTrue,
+			Reason: "InSync",
+		}
+	}
+	return metav1.Condition{
+		Type:    DriftConditionName(drift.AddOn),
+		Status:  metav1.ConditionFalse,
+		Reason:  "Drifted",
+		Message: strings.Join(drift.Reasons, "; "),
+	}
+}
+
+// DetectDrift compares, for each addon of the installation, its helm release and the objects
+// rendered by its chart with the expected ones. The expected manifests are rendered with the
+// chart of the deployed release, so objects are only compared when the deployed chart version
+// and values are the expected ones.
+func DetectDrift(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) ([]AddOnDrift, error) {
+	addOns, err := getAddOnsForUpgrade(in, meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "get addons")
+	}
+
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(in.Spec.Config, nil))
+	drifts := []AddOnDrift{}
+	for _, addon := range addOns {
+		reasons, err := detectAddOnDrift(ctx, kcli, hcli, in, addon)
+		if err != nil {
+			return nil, errors.Wrapf(err, "addon %s", addon.Name())
+		}
+		drifts = append(drifts, AddOnDrift{AddOn: addon, Reasons: reasons})
+	}
+	return drifts, nil
+}
+
+// RemediateDrift upgrades the addon release to its expected state.
+func RemediateDrift(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, addon types.AddOn) error {
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(in.Spec.Config, nil))
+	overrides := addOnOverrides(addon, in.Spec.Config, nil)
+	if err := addon.Upgrade(ctx, kcli, hcli, overrides); err != nil {
+		return errors.Wrap(err, "upgrade addon")
+	}
+	return nil
+}
+
+func detectAddOnDrift(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, addon types.AddOn) ([]string, error) {
+	history, err := hcli.History(ctx, addon.Namespace(), addon.ReleaseName())
+	if err != nil {
+		return nil, errors.Wrap(err, "get release history")
+	}
+	rel := lastDeployedRelease(history)
+	if rel == nil {
+		return []string{fmt.Sprintf("release %s has no deployed revision", addon.ReleaseName())}, nil
+	}
+	if version := rel.Chart.Metadata.Version; version != addon.Version() {
+		return []string{fmt.Sprintf("chart version is %s, expected %s", version, addon.Version())}, nil
+	}
+
+	overrides := addOnOverrides(addon, in.Spec.Config, nil)
+	values, err := addon.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate helm values")
+	}
+	changes, err := helm.DiffValues(rel.Config, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "diff values")
+	}
+	if len(changes) > 0 {
+		// only the paths are reported as values may hold credentials.
+		paths := []string{}
+		for _, change := range changes {
+			paths = append(paths, change.Path)
+		}
+		return []string{fmt.Sprintf("release values differ at %s", strings.Join(paths, ", "))}, nil
+	}
+
+	manifests, err := renderRelease(hcli, rel, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "render release")
+	}
+
+	reasons := []string{}
+	for _, manifest := range manifests {
+		reason, err := detectObjectDrift(ctx, kcli, addon.Namespace(), manifest)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons, nil
+}
+
+func lastDeployedRelease(history []*helmrelease.Release) *helmrelease.Release {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Info != nil && history[i].Info.Status == helmrelease.StatusDeployed {
+			return history[i]
+		}
+	}
+	return nil
+}
+
+// renderRelease renders the chart of the deployed release with the provided values.
+func renderRelease(hcli helm.Client, rel *helmrelease.Release, values map[string]interface{}) ([][]byte, error) {
+	tmpdir, err := os.MkdirTemp("", "drift-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "create temp dir")
+	}
+	defer os.RemoveAll(tmpdir)
+
+	chartPath, err := chartutil.Save(rel.Chart, tmpdir)
+	if err != nil {
+		return nil, errors.Wrap(err, "save release chart")
+	}
+	return hcli.Render(rel.Name, chartPath, values, rel.Namespace, nil, nil)
+}
+
+// detectObjectDrift compares a rendered object with the live one and returns why it drifted,
+// or an empty string if it did not. Only the fields set in the rendered object are compared.
+// Hooks and CRDs are not compared and the content of secrets is ignored as charts often
+// generate it.
+func detectObjectDrift(ctx context.Context, kcli client.Client, namespace string, manifest []byte) (string, error) {
+	expected := map[string]interface{}{}
+	if err := k8syaml.Unmarshal(manifest, &expected); err != nil {
+		return "", errors.Wrap(err, "unmarshal manifest")
+	}
+	if len(expected) == 0 {
+		return "", nil
+	}
+
+	obj := &unstructured.Unstructured{Object: expected}
+	if _, ok := obj.GetAnnotations()["helm.sh/hook"]; ok {
+		return "", nil
+	}
+	if obj.GetKind() == "CustomResourceDefinition" {
+		return "", nil
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	name := fmt.Sprintf("%s %s", obj.GetKind(), client.ObjectKeyFromObject(obj))
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := kcli.Get(ctx, client.ObjectKeyFromObject(obj), live); k8serrors.IsNotFound(err) {
+		return fmt.Sprintf("%s not found", name), nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "get %s", name)
+	}
+
+	liveObject, err := normalizeObject(live.Object)
+	if err != nil {
+		return "", errors.Wrapf(err, "normalize %s", name)
+	}
+
+	fields := []string{}
+	for key, value := range expected {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			metadata, _ := value.(map[string]interface{})
+			liveMetadata, _ := liveObject["metadata"].(map[string]interface{})
+			for _, k := range []string{"labels", "annotations"} {
+				diffFields("metadata."+k, metadata[k], liveMetadata[k], &fields)
+			}
+			continue
+		case "data", "stringData":
+			if obj.GetKind() == "Secret" {
+				continue
+			}
+		}
+		diffFields(key, value, liveObject[key], &fields)
+	}
+	if len(fields) == 0 {
+		return "", nil
+	}
+	if len(fields) > maxDriftedFields {
+		fields = append(fields[:maxDriftedFields], "...")
+	}
+	return fmt.Sprintf("%s differs at %s", name, strings.Join(fields, ", ")), nil
+}
+
+// normalizeObject converts the object to the types used when unmarshaling manifests.
+func normalizeObject(obj map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// diffFields appends to fields the paths of the values set in expected that differ in live.
+// Fields defaulted or added by the cluster are ignored, as are zero values missing from live.
+func diffFields(path string, expected interface{}, live interface{}, fields *[]string) {
+	switch exp := expected.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		if len(exp) == 0 && live == nil {
+			return
+		}
+		lv, ok := live.(map[string]interface{})
+		if !ok {
+			*fields = append(*fields, path)
+			return
+		}
+		for key, value := range exp {
+			diffFields(path+"."+key, value, lv[key], fields)
+		}
+	case []interface{}:
+		if len(exp) == 0 && live == nil {
+			return
+		}
+		lv, ok := live.([]interface{})
+		if !ok || len(lv) != len(exp) {
+			*fields = append(*fields, path)
+			return
+		}
+		for i := range exp {
+			diffFields(fmt.Sprintf("%s[%d]", path, i), exp[i], lv[i], fields)
+		}
+	default:
+		if live == nil && reflect.ValueOf(expected).IsZero() {
+			return
+		}
+		if !scalarsEqual(expected, live) {
+			*fields = append(*fields, path)
+		}
+	}
+}
+
+// scalarsEqual compares two scalar values, considering equal the quantities the api server
+// stores in their canonical form (e.g. 1000m and 1).
+func scalarsEqual(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	qa, err := resource.ParseQuantity(fmt.Sprint(a))
+	if err != nil {
+		return false
+	}
+	qb, err := resource.ParseQuantity(fmt.Sprint(b))
+	if err != nil {
+		return false
+	}
+	return qa.Cmp(qb) == 0
+}
@@ -0,0 +1,195 @@
+package addons
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type testAddOn struct {
+	values map[string]interface{}
+}
+
+func (a *testAddOn) Name() string           { return "Test" }
+func (a *testAddOn) Version() string        { return "1.0.0" }
+func (a *testAddOn) ReleaseName() string    { return "test" }
+func (a *testAddOn) Namespace() string      { return "test-ns" }
+func (a *testAddOn) Dependencies() []string { return nil }
+
+func (a *testAddOn) GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error) {
+	return a.values, nil
+}
+
+func (a *testAddOn) Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error {
+	return nil
+}
+
+func (a *testAddOn) Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error {
+	return nil
+}
+
+const testDeploymentManifest = `# Source: test/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  labels:
+    app: test
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+    spec:
+      containers:
+      - name: test
+        image: test:1.0.0
+        env:
+        - name: DEBUG
+          value: ""
+        resources:
+          limits:
+            cpu: 1000m
+`
+
+const testHookManifest = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: test-hook
+  annotations:
+    helm.sh/hook: post-install
+`
+
+const testSecretManifest = `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+stringData:
+  password: generated
+`
+
+func testDeployment(image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "test-ns",
+			Labels:      map[string]string{"app": "test", "app.kubernetes.io/managed-by": "Helm"},
+			Annotations: map[string]string{"meta.helm.sh/release-name": "test"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](1),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:            "test",
+						Image:           image,
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						Env:             []corev1.EnvVar{{Name: "DEBUG"}},
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+						},
+					}},
+					RestartPolicy: corev1.RestartPolicyAlways,
+				},
+			},
+		},
+	}
+}
+
+func testRelease(version string, status helmrelease.Status, values map[string]interface{}) *helmrelease.Release {
+	return &helmrelease.Release{
+		Name:      "test",
+		Namespace: "test-ns",
+		Info:      &helmrelease.Info{Status: status},
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "test", Version: version},
+		},
+		Config: values,
+	}
+}
+
+func Test_detectAddOnDrift(t *testing.T) {
+	values := map[string]interface{}{"replicas": 1}
+
+	tests := []struct {
+		name        string
+		history     []*helmrelease.Release
+		manifests   [][]byte
+		objects     []client.Object
+		wantReasons []string
+	}{
+		{
+			name:        "release not deployed",
+			history:     []*helmrelease.Release{testRelease("1.0.0", helmrelease.StatusFailed, values)},
+			wantReasons: []string{"release test has no deployed revision"},
+		},
+		{
+			name:        "chart upgraded by hand",
+			history:     []*helmrelease.Release{testRelease("1.0.0", helmrelease.StatusSuperseded, values), testRelease("1.1.0", helmrelease.StatusDeployed, values)},
+			wantReasons: []string{"chart version is 1.1.0, expected 1.0.0"},
+		},
+		{
+			name:        "values changed by hand",
+			history:     []*helmrelease.Release{testRelease("1.0.0", helmrelease.StatusDeployed, map[string]interface{}{"replicas": 3, "debug": true})},
+			wantReasons: []string{"release values differ at debug, replicas"},
+		},
+		{
+			name:        "in sync",
+			history:     []*helmrelease.Release{testRelease("1.0.0", helmrelease.StatusDeployed, values)},
+			manifests:   [][]byte{[]byte(testDeploymentManifest), []byte(testHookManifest), []byte(testSecretManifest)},
+			objects:     []client.Object{testDeployment("test:1.0.0"), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-ns"}, Data: map[string][]byte{"password": []byte("other")}}},
+			wantReasons: []string{},
+		},
+		{
+			name:        "object edited by hand",
+			history:     []*helmrelease.Release{testRelease("1.0.0", helmrelease.StatusDeployed, values)},
+			manifests:   [][]byte{[]byte(testDeploymentManifest)},
+			objects:     []client.Object{testDeployment("test:0.9.0")},
+			wantReasons: []string{"Deployment test-ns/test differs at spec.template.spec.containers[0].image"},
+		},
+		{
+			name:        "object deleted by hand",
+			history:     []*helmrelease.Release{testRelease("1.0.0", helmrelease.StatusDeployed, values)},
+			manifests:   [][]byte{[]byte(testDeploymentManifest)},
+			wantReasons: []string{"Deployment test-ns/test not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(tt.objects...).Build()
+
+			hcli := &helm.MockClient{}
+			hcli.On("History", mock.Anything, "test-ns", "test").Return(tt.history, nil)
+			if tt.manifests != nil {
+				hcli.On("Render", "test", mock.Anything, values, "test-ns", mock.Anything, mock.Anything).Return(tt.manifests, nil)
+			}
+
+			reasons, err := detectAddOnDrift(context.Background(), kcli, hcli, &ecv1beta1.Installation{}, &testAddOn{values: values})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReasons, reasons)
+			hcli.AssertExpectations(t)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/pkg/errors"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/versions"
 	"gopkg.in/yaml.v3"
@@ -19,6 +20,9 @@ type EmbeddedClusterOperator struct {
 	ImageRepoOverride     string
 	ImageTagOverride      string
 	UtilsImageOverride    string
+	// AutoRemediateDrift grants the operator write access to the objects of the built-in
+	// addons, it only needs read access to detect their drift.
+	AutoRemediateDrift bool
 }
 
 const (
@@ -68,6 +72,10 @@ func (e *EmbeddedClusterOperator) Namespace() string {
 	return namespace
 }
 
+func (e *EmbeddedClusterOperator) Dependencies() []string {
+	return nil
+}
+
 func (e *EmbeddedClusterOperator) ChartLocation() string {
 	if e.ChartLocationOverride != "" {
 		return e.ChartLocationOverride
@@ -82,6 +90,15 @@ func (e *EmbeddedClusterOperator) ChartVersion() string {
 	return Metadata.Version
 }
 
+// chartVerification returns the verification of the chart. The digest pinned in the metadata
+// only applies to the chart it describes, so overridden charts are not verified.
+func (e *EmbeddedClusterOperator) chartVerification() *helm.ChartVerification {
+	if e.ChartLocationOverride != "" || e.ChartVersionOverride != "" {
+		return nil
+	}
+	return &helm.ChartVerification{Digest: Metadata.ChartDigest}
+}
+
 func getBackupLabels() map[string]string {
 	return map[string]string{
 		"replicated.com/disaster-recovery":       "infra",
@@ -19,6 +19,7 @@ func (e *EmbeddedClusterOperator) Install(ctx context.Context, kcli client.Clien
 		ReleaseName:  releaseName,
 		ChartPath:    e.ChartLocation(),
 		ChartVersion: e.ChartVersion(),
+		Verification: e.chartVerification(),
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
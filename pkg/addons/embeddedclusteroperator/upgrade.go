@@ -31,6 +31,7 @@ func (e *EmbeddedClusterOperator) Upgrade(ctx context.Context, kcli client.Clien
 		ReleaseName:  releaseName,
 		ChartPath:    e.ChartLocation(),
 		ChartVersion: e.ChartVersion(),
+		Verification: e.chartVerification(),
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
@@ -43,6 +43,8 @@ func (e *EmbeddedClusterOperator) GenerateHelmValues(ctx context.Context, kcli c
 		copiedValues["isAirgap"] = "true"
 	}
 
+	copiedValues["autoRemediateDrift"] = e.AutoRemediateDrift
+
 	if e.Proxy != nil {
 		copiedValues["extraEnv"] = []map[string]interface{}{
 			{
@@ -0,0 +1,118 @@
+package addons
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+)
+
+// installFn installs a single addon.
+type installFn func(ctx context.Context, addon types.AddOn) error
+
+// dependencyGraph returns, for each addon, the indexes of the addons that depend on it and
+// the number of addons each one is waiting for. Dependencies on addons that are not in the
+// list are considered satisfied. An error is returned if the dependencies have a cycle.
+func dependencyGraph(addOns []types.AddOn) ([][]int, []int, error) {
+	indexes := map[string]int{}
+	for i, addon := range addOns {
+		indexes[addon.Name()] = i
+	}
+
+	dependents := make([][]int, len(addOns))
+	pending := make([]int, len(addOns))
+	for i, addon := range addOns {
+		for _, dep := range addon.Dependencies() {
+			j, ok := indexes[dep]
+			if !ok {
+				continue
+			}
+			dependents[j] = append(dependents[j], i)
+			pending[i]++
+		}
+	}
+
+	// make sure every addon can eventually be installed.
+	remaining := append([]int{}, pending...)
+	ready := []int{}
+	for i := range addOns {
+		if remaining[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	visited := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		visited++
+		for _, j := range dependents[i] {
+			remaining[j]--
+			if remaining[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	if visited != len(addOns) {
+		return nil, nil, errors.New("addon dependencies have a cycle")
+	}
+
+	return dependents, pending, nil
+}
+
+// installGraph installs the addons, starting each one as soon as all the addons it depends
+// on are installed. At most concurrency addons are installed at the same time. Once an
+// install fails no new installs are started and the first error is returned after the
+// installs in progress finish.
+func installGraph(ctx context.Context, addOns []types.AddOn, concurrency int, install installFn) error {
+	dependents, pending, err := dependencyGraph(addOns)
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type result struct {
+		index int
+		err   error
+	}
+	results := make(chan result)
+
+	ready := []int{}
+	for i := range addOns {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var firstErr error
+	running := 0
+	for {
+		for firstErr == nil && running < concurrency && len(ready) > 0 {
+			i := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- result{index: i, err: install(ctx, addOns[i])}
+			}()
+		}
+		if running == 0 {
+			return firstErr
+		}
+
+		res := <-results
+		running--
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			continue
+		}
+		for _, j := range dependents[res.index] {
+			pending[j]--
+			if pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+}
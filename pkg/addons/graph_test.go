@@ -0,0 +1,112 @@
+package addons
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeAddOn struct {
+	name string
+	deps []string
+}
+
+func (f *fakeAddOn) Name() string           { return f.name }
+func (f *fakeAddOn) Version() string        { return "1.0.0" }
+func (f *fakeAddOn) ReleaseName() string    { return f.name }
+func (f *fakeAddOn) Namespace() string      { return f.name }
+func (f *fakeAddOn) Dependencies() []string { return f.deps }
+
+func (f *fakeAddOn) GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (f *fakeAddOn) Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error {
+	return nil
+}
+
+func (f *fakeAddOn) Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error {
+	return nil
+}
+
+func Test_dependencyGraph(t *testing.T) {
+	_, _, err := dependencyGraph([]types.AddOn{
+		&fakeAddOn{name: "a", deps: []string{"b"}},
+		&fakeAddOn{name: "b", deps: []string{"a"}},
+	})
+	assert.ErrorContains(t, err, "cycle")
+
+	dependents, pending, err := dependencyGraph([]types.AddOn{
+		&fakeAddOn{name: "storage"},
+		&fakeAddOn{name: "registry", deps: []string{"storage"}},
+		&fakeAddOn{name: "console", deps: []string{"storage", "registry", "missing"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1, 2}, {2}, nil}, dependents)
+	assert.Equal(t, []int{0, 1, 2}, pending)
+}
+
+func Test_installGraph(t *testing.T) {
+	addOns := []types.AddOn{
+		&fakeAddOn{name: "storage"},
+		&fakeAddOn{name: "operator"},
+		&fakeAddOn{name: "velero"},
+		&fakeAddOn{name: "registry", deps: []string{"storage"}},
+		&fakeAddOn{name: "console", deps: []string{"storage", "registry"}},
+	}
+
+	var mtx sync.Mutex
+	installed := map[string]bool{}
+	running, maxRunning := 0, 0
+	err := installGraph(context.Background(), addOns, 2, func(ctx context.Context, addon types.AddOn) error {
+		mtx.Lock()
+		for _, dep := range addon.Dependencies() {
+			assert.True(t, installed[dep], "%s installed before %s", addon.Name(), dep)
+		}
+		running++
+		maxRunning = max(maxRunning, running)
+		mtx.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mtx.Lock()
+		running--
+		installed[addon.Name()] = true
+		mtx.Unlock()
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, installed, len(addOns))
+	assert.Equal(t, 2, maxRunning)
+}
+
+func Test_installGraphError(t *testing.T) {
+	addOns := []types.AddOn{
+		&fakeAddOn{name: "storage"},
+		&fakeAddOn{name: "registry", deps: []string{"storage"}},
+		&fakeAddOn{name: "operator"},
+	}
+
+	var mtx sync.Mutex
+	installed := []string{}
+	err := installGraph(context.Background(), addOns, 1, func(ctx context.Context, addon types.AddOn) error {
+		if addon.Name() == "storage" {
+			return errors.New("storage failed")
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		installed = append(installed, addon.Name())
+		return nil
+	})
+	assert.EqualError(t, err, "storage failed")
+	assert.Empty(t, installed, "no installs should start after a failure")
+}
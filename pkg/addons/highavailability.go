@@ -49,12 +49,20 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 
 	logrus.Debugf("Enabling high availability")
 
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(cfgspec, nil))
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return errors.Wrap(err, "get latest installation")
+	}
+
 	if isAirgap {
 		loading.Infof("Enabling high availability")
 
 		// TODO (@salah): add support for end user overrides
 		sw := &seaweedfs.SeaweedFS{
-			ServiceCIDR: serviceCIDR,
+			ServiceCIDR:      serviceCIDR,
+			StorageClassName: storageClassName(in.Spec.Storage),
 		}
 		exists, err := hcli.ReleaseExists(ctx, sw.Namespace(), sw.ReleaseName())
 		if err != nil {
@@ -90,17 +98,12 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 	loading.Infof("Updating the Admin Console for high availability")
 
 	logrus.Debugf("Enabling admin console high availability")
-	err := EnableAdminConsoleHA(ctx, kcli, hcli, isAirgap, serviceCIDR, proxy, cfgspec)
+	err = EnableAdminConsoleHA(ctx, kcli, hcli, isAirgap, serviceCIDR, proxy, in.Spec.Storage, cfgspec)
 	if err != nil {
 		return errors.Wrap(err, "enable admin console high availability")
 	}
 	logrus.Debugf("Admin console high availability enabled!")
 
-	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
-	if err != nil {
-		return errors.Wrap(err, "get latest installation")
-	}
-
 	if err := kubeutils.UpdateInstallation(ctx, kcli, in, func(in *ecv1beta1.Installation) {
 		in.Spec.HighAvailability = true
 	}); err != nil {
@@ -114,13 +117,16 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 }
 
 // EnableAdminConsoleHA enables high availability for the admin console.
-func EnableAdminConsoleHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec) error {
+func EnableAdminConsoleHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, storage *ecv1beta1.StorageSpec, cfgspec *ecv1beta1.ConfigSpec) error {
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(cfgspec, nil))
+
 	// TODO (@salah): add support for end user overrides
 	ac := &adminconsole.AdminConsole{
-		IsAirgap:    isAirgap,
-		IsHA:        true,
-		Proxy:       proxy,
-		ServiceCIDR: serviceCIDR,
+		IsAirgap:         isAirgap,
+		IsHA:             true,
+		Proxy:            proxy,
+		ServiceCIDR:      serviceCIDR,
+		StorageClassName: storageClassName(storage),
 	}
 	if err := ac.Upgrade(ctx, kcli, hcli, addOnOverrides(ac, cfgspec, nil)); err != nil {
 		return errors.Wrap(err, "upgrade admin console")
@@ -0,0 +1,83 @@
+package addons
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// IngressForInstall returns the ingress a new cluster is installed with. The admin console
+// hostname provided takes precedence over the one of the end user config, which takes
+// precedence over the one of the embedded config. Nil is returned if the ingress controller
+// is not enabled.
+func IngressForInstall(embCfg, euCfg *ecv1beta1.ConfigSpec, adminConsoleHostname string) (*ecv1beta1.IngressSpec, error) {
+	ingress := ecv1beta1.IngressSpec{}
+	for _, spec := range []*ecv1beta1.ConfigSpec{embCfg, euCfg} {
+		if spec == nil {
+			continue
+		}
+		ingress.Enabled = ingress.Enabled || spec.Ingress.Enabled
+		if spec.Ingress.AdminConsoleHostname != "" {
+			ingress.AdminConsoleHostname = spec.Ingress.AdminConsoleHostname
+		}
+	}
+	if adminConsoleHostname != "" {
+		ingress.AdminConsoleHostname = adminConsoleHostname
+	}
+
+	if !ingress.IsEnabled() {
+		return nil, nil
+	}
+	if hostname := ingress.AdminConsoleHostname; hostname != "" {
+		if msgs := validation.IsDNS1123Subdomain(hostname); len(msgs) > 0 {
+			return nil, errors.Errorf("invalid admin console hostname %q: %s", hostname, strings.Join(msgs, ", "))
+		}
+	}
+	ingress.Enabled = true
+	return &ingress, nil
+}
+
+// adminConsoleHostname returns the hostname the admin console is exposed at through the
+// ingress controller, if any.
+func adminConsoleHostname(ingress *ecv1beta1.IngressSpec) string {
+	if !ingress.IsEnabled() {
+		return ""
+	}
+	return ingress.AdminConsoleHostname
+}
+
+// RestoreIngress installs the ingress controller when restoring an installation that was
+// installed with it. The objects exposing the admin console are restored from its backup.
+func RestoreIngress(ctx context.Context, hcli helm.Client, in *ecv1beta1.Installation) error {
+	if !in.Spec.Ingress.IsEnabled() {
+		return nil
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return errors.Wrap(err, "create kube client")
+	}
+
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(in.Spec.Config, nil))
+
+	addon := &ingressnginx.IngressNginx{}
+	loading := spinner.Start()
+	loading.Infof("Installing %s", addon.Name())
+
+	// the release may exist if a previous restore attempt was interrupted.
+	overrides := addOnOverrides(addon, in.Spec.Config, nil)
+	if err := addon.Upgrade(ctx, kcli, hcli, overrides); err != nil {
+		loading.CloseWithError()
+		return errors.Wrapf(err, "install %s", addon.Name())
+	}
+
+	loading.Closef("%s is ready!", addon.Name())
+	return nil
+}
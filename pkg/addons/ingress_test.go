@@ -0,0 +1,57 @@
+package addons
+
+import (
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngressForInstall(t *testing.T) {
+	tests := []struct {
+		name                 string
+		embCfg               *ecv1beta1.ConfigSpec
+		euCfg                *ecv1beta1.ConfigSpec
+		adminConsoleHostname string
+		want                 *ecv1beta1.IngressSpec
+		wantErr              string
+	}{
+		{
+			name: "disabled by default",
+		},
+		{
+			name:   "enabled in the embedded config",
+			embCfg: &ecv1beta1.ConfigSpec{Ingress: ecv1beta1.IngressSpec{Enabled: true}},
+			want:   &ecv1beta1.IngressSpec{Enabled: true},
+		},
+		{
+			name:   "end user config hostname takes precedence",
+			embCfg: &ecv1beta1.ConfigSpec{Ingress: ecv1beta1.IngressSpec{AdminConsoleHostname: "kots.example.com"}},
+			euCfg:  &ecv1beta1.ConfigSpec{Ingress: ecv1beta1.IngressSpec{AdminConsoleHostname: "admin.example.com"}},
+			want:   &ecv1beta1.IngressSpec{Enabled: true, AdminConsoleHostname: "admin.example.com"},
+		},
+		{
+			name:                 "hostname flag takes precedence",
+			euCfg:                &ecv1beta1.ConfigSpec{Ingress: ecv1beta1.IngressSpec{AdminConsoleHostname: "admin.example.com"}},
+			adminConsoleHostname: "console.example.com",
+			want:                 &ecv1beta1.IngressSpec{Enabled: true, AdminConsoleHostname: "console.example.com"},
+		},
+		{
+			name:                 "invalid hostname",
+			adminConsoleHostname: "Admin_Console",
+			wantErr:              `invalid admin console hostname "Admin_Console"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IngressForInstall(tt.embCfg, tt.euCfg, tt.adminConsoleHostname)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
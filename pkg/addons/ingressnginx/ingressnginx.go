@@ -0,0 +1,64 @@
+package ingressnginx
+
+import (
+	_ "embed"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// IngressNginx deploys the ingress-nginx controller on every node, bound to the ports 80 and
+// 443 of the hosts.
+type IngressNginx struct{}
+
+const (
+	releaseName = "ingress-nginx"
+	namespace   = runtimeconfig.IngressNamespace
+	// ClassName is the name of the ingress class handled by the controller. It is the default
+	// ingress class of the cluster so applications can use it without setting a class.
+	ClassName = "nginx"
+)
+
+var (
+	//go:embed static/values.tpl.yaml
+	rawvalues []byte
+	// helmValues is the unmarshal version of rawvalues.
+	helmValues map[string]interface{}
+	//go:embed static/metadata.yaml
+	rawmetadata []byte
+	// Metadata is the unmarshal version of rawmetadata.
+	Metadata release.AddonMetadata
+)
+
+func init() {
+	if err := yaml.Unmarshal(rawmetadata, &Metadata); err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal metadata"))
+	}
+	hv, err := release.RenderHelmValues(rawvalues, Metadata)
+	if err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal values"))
+	}
+	helmValues = hv
+}
+
+func (n *IngressNginx) Name() string {
+	return "Ingress"
+}
+
+func (n *IngressNginx) Version() string {
+	return Metadata.Version
+}
+
+func (n *IngressNginx) ReleaseName() string {
+	return releaseName
+}
+
+func (n *IngressNginx) Namespace() string {
+	return namespace
+}
+
+func (n *IngressNginx) Dependencies() []string {
+	return nil
+}
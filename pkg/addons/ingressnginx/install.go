@@ -0,0 +1,50 @@
+package ingressnginx
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (n *IngressNginx) Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error {
+	if err := createNamespace(ctx, kcli, namespace); err != nil {
+		return errors.Wrap(err, "create namespace")
+	}
+
+	values, err := n.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return errors.Wrap(err, "generate helm values")
+	}
+
+	_, err = hcli.Install(ctx, helm.InstallOptions{
+		ReleaseName:  releaseName,
+		ChartPath:    Metadata.Location,
+		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
+		Values:       values,
+		Namespace:    namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm install")
+	}
+
+	return nil
+}
+
+func createNamespace(ctx context.Context, kcli client.Client, namespace string) error {
+	ns := corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+	}
+	if err := kcli.Create(ctx, &ns); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
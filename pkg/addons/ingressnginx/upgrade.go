@@ -0,0 +1,44 @@
+package ingressnginx
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (n *IngressNginx) Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error {
+	exists, err := hcli.ReleaseExists(ctx, namespace, releaseName)
+	if err != nil {
+		return errors.Wrap(err, "check if release exists")
+	}
+	if !exists {
+		slog.Info("Release not found, installing", "release", releaseName, "namespace", namespace)
+		if err := n.Install(ctx, kcli, hcli, overrides, nil); err != nil {
+			return errors.Wrap(err, "install")
+		}
+		return nil
+	}
+
+	values, err := n.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return errors.Wrap(err, "generate helm values")
+	}
+
+	_, err = hcli.Upgrade(ctx, helm.UpgradeOptions{
+		ReleaseName:  releaseName,
+		ChartPath:    Metadata.Location,
+		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
+		Values:       values,
+		Namespace:    namespace,
+		Force:        false,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm upgrade")
+	}
+
+	return nil
+}
@@ -2,12 +2,14 @@ package addons
 
 import (
 	"context"
+	"slices"
+	"sync"
 
 	"github.com/pkg/errors"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
-	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
@@ -15,8 +17,13 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/sirupsen/logrus"
 )
 
+// defaultInstallConcurrency is the number of addons installed at the same time when no helm
+// concurrency level is configured.
+const defaultInstallConcurrency = 3
+
 type InstallOptions struct {
 	AdminConsolePwd         string
 	License                 *kotsv1beta1.License
@@ -28,7 +35,22 @@ type InstallOptions struct {
 	EmbeddedConfigSpec      *ecv1beta1.ConfigSpec
 	EndUserConfigSpec       *ecv1beta1.ConfigSpec
 	KotsInstaller           adminconsole.KotsInstaller
-	IsRestore               bool
+	// Storage is the storage the cluster is installed with, as returned by StorageForInstall.
+	// OpenEBS is used if nil.
+	Storage *ecv1beta1.StorageSpec
+	// Ingress is the ingress the cluster is installed with, as returned by IngressForInstall.
+	// The ingress controller is not installed if nil.
+	Ingress *ecv1beta1.IngressSpec
+	// AdminConsoleTLSCert and AdminConsoleTLSKey hold the PEM encoded certificate and key
+	// served for the admin console hostname. A self signed certificate is used if empty.
+	AdminConsoleTLSCert string
+	AdminConsoleTLSKey  string
+	IsRestore           bool
+	// SkipAddOns holds the names of the addons installed by a previous attempt. These are
+	// not installed again.
+	SkipAddOns []string
+	// OnAddOnInstalled, if set, is called with the name of each addon once it is ready.
+	OnAddOnInstalled func(name string) error
 }
 
 func Install(ctx context.Context, hcli helm.Client, opts InstallOptions) error {
@@ -42,8 +64,22 @@ func Install(ctx context.Context, hcli helm.Client, opts InstallOptions) error {
 		addons = getAddOnsForRestore(opts)
 	}
 
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec))
+
+	pending := []types.AddOn{}
 	for _, addon := range addons {
-		loading := spinner.Start()
+		if slices.Contains(opts.SkipAddOns, addon.Name()) {
+			logrus.Debugf("%s already installed, skipping", addon.Name())
+			continue
+		}
+		pending = append(pending, addon)
+	}
+
+	// independent addons are installed concurrently, each with its own spinner line.
+	group := spinner.StartGroup()
+	var mtx sync.Mutex
+	return installGraph(ctx, pending, installConcurrency(opts), func(ctx context.Context, addon types.AddOn) error {
+		loading := group.Start()
 		loading.Infof("Installing %s", addon.Name())
 
 		overrides := addOnOverrides(addon, opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
@@ -54,50 +90,79 @@ func Install(ctx context.Context, hcli helm.Client, opts InstallOptions) error {
 		}
 
 		loading.Closef("%s is ready!", addon.Name())
-	}
 
-	return nil
+		if opts.OnAddOnInstalled != nil {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err := opts.OnAddOnInstalled(addon.Name()); err != nil {
+				return errors.Wrapf(err, "record %s installed", addon.Name())
+			}
+		}
+		return nil
+	})
+}
+
+// installConcurrency returns the maximum number of addons installed at the same time. It
+// is read from the helm concurrency level of the end user config first and then from the
+// embedded config.
+func installConcurrency(opts InstallOptions) int {
+	for _, spec := range []*ecv1beta1.ConfigSpec{opts.EndUserConfigSpec, opts.EmbeddedConfigSpec} {
+		if spec != nil && spec.Extensions.Helm != nil && spec.Extensions.Helm.ConcurrencyLevel > 0 {
+			return spec.Extensions.Helm.ConcurrencyLevel
+		}
+	}
+	return defaultInstallConcurrency
 }
 
 func getAddOnsForInstall(opts InstallOptions) []types.AddOn {
-	addOns := []types.AddOn{
-		&openebs.OpenEBS{},
+	addOns := withStorageProvider(opts.Storage,
 		&embeddedclusteroperator.EmbeddedClusterOperator{
-			IsAirgap: opts.IsAirgap,
-			Proxy:    opts.Proxy,
+			IsAirgap:           opts.IsAirgap,
+			Proxy:              opts.Proxy,
+			AutoRemediateDrift: opts.EmbeddedConfigSpec != nil && opts.EmbeddedConfigSpec.AutoRemediateDrift,
 		},
-	}
+	)
 
 	if opts.IsAirgap {
 		addOns = append(addOns, &registry.Registry{
-			ServiceCIDR: opts.ServiceCIDR,
+			ServiceCIDR:      opts.ServiceCIDR,
+			StorageClassName: storageClassName(opts.Storage),
 		})
 	}
 
 	if opts.DisasterRecoveryEnabled {
 		addOns = append(addOns, &velero.Velero{
-			Proxy: opts.Proxy,
+			Proxy:            opts.Proxy,
+			StorageClassName: storageClassName(opts.Storage),
 		})
 	}
 
+	if opts.Ingress.IsEnabled() {
+		addOns = append(addOns, &ingressnginx.IngressNginx{})
+	}
+
 	addOns = append(addOns, &adminconsole.AdminConsole{
-		IsAirgap:      opts.IsAirgap,
-		Proxy:         opts.Proxy,
-		ServiceCIDR:   opts.ServiceCIDR,
-		Password:      opts.AdminConsolePwd,
-		PrivateCAs:    opts.PrivateCAs,
-		KotsInstaller: opts.KotsInstaller,
+		IsAirgap:         opts.IsAirgap,
+		Proxy:            opts.Proxy,
+		ServiceCIDR:      opts.ServiceCIDR,
+		Password:         opts.AdminConsolePwd,
+		PrivateCAs:       opts.PrivateCAs,
+		KotsInstaller:    opts.KotsInstaller,
+		Hostname:         adminConsoleHostname(opts.Ingress),
+		TLSCert:          opts.AdminConsoleTLSCert,
+		TLSKey:           opts.AdminConsoleTLSKey,
+		StorageClassName: storageClassName(opts.Storage),
 	})
 
 	return addOns
 }
 
 func getAddOnsForRestore(opts InstallOptions) []types.AddOn {
-	addOns := []types.AddOn{
-		&openebs.OpenEBS{},
+	addOns := withStorageProvider(opts.Storage,
 		&velero.Velero{
-			Proxy: opts.Proxy,
+			Proxy:            opts.Proxy,
+			StorageClassName: storageClassName(opts.Storage),
 		},
-	}
+	)
 	return addOns
 }
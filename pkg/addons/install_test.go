@@ -183,6 +183,37 @@ func Test_getAddOnsForInstall(t *testing.T) {
 				assert.Equal(t, "password123", adminConsole.Password)
 			},
 		},
+		{
+			name: "airgap with disaster recovery and existing storage class",
+			opts: InstallOptions{
+				IsAirgap:                true,
+				DisasterRecoveryEnabled: true,
+				ServiceCIDR:             "10.96.0.0/12",
+				AdminConsolePwd:         "password123",
+				Storage: &ecv1beta1.StorageSpec{
+					Provider:         ecv1beta1.StorageProviderExisting,
+					StorageClassName: "san",
+				},
+			},
+			verify: func(t *testing.T, addons []types.AddOn) {
+				assert.Len(t, addons, 4)
+
+				_, ok := addons[0].(*embeddedclusteroperator.EmbeddedClusterOperator)
+				require.True(t, ok, "first addon should be EmbeddedClusterOperator")
+
+				reg, ok := addons[1].(*registry.Registry)
+				require.True(t, ok, "second addon should be Registry")
+				assert.Equal(t, "san", reg.StorageClassName)
+
+				vel, ok := addons[2].(*velero.Velero)
+				require.True(t, ok, "third addon should be Velero")
+				assert.Equal(t, "san", vel.StorageClassName)
+
+				ac, ok := addons[3].(*adminconsole.AdminConsole)
+				require.True(t, ok, "fourth addon should be AdminConsole")
+				assert.Equal(t, "san", ac.StorageClassName)
+			},
+		},
 	}
 
 	for _, tt := range tests {
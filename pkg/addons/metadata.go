@@ -6,6 +6,7 @@ import (
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
@@ -30,6 +31,9 @@ func Versions() map[string]string {
 	for k, v := range velero.Version() {
 		versions[k] = v
 	}
+	for k, v := range ingressnginx.Version() {
+		versions[k] = v
+	}
 	for k, v := range adminconsole.Version() {
 		versions[k] = v
 	}
@@ -81,6 +85,14 @@ func GenerateChartConfigs() ([]ecv1beta1.Chart, []k0sv1beta1.Repository, error)
 	charts = append(charts, chart...)
 	repositories = append(repositories, repos...)
 
+	// ingress-nginx
+	chart, repos, err = ingressnginx.GenerateChartConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate chart config for ingressnginx")
+	}
+	charts = append(charts, chart...)
+	repositories = append(repositories, repos...)
+
 	// admin console
 	chart, repos, err = adminconsole.GenerateChartConfig()
 	if err != nil {
@@ -100,6 +112,7 @@ func GetImages() []string {
 	images = append(images, registry.GetImages()...)
 	images = append(images, seaweedfs.GetImages()...)
 	images = append(images, velero.GetImages()...)
+	images = append(images, ingressnginx.GetImages()...)
 	images = append(images, adminconsole.GetImages()...)
 
 	return images
@@ -113,6 +126,7 @@ func GetAdditionalImages() []string {
 	images = append(images, registry.GetAdditionalImages()...)
 	images = append(images, seaweedfs.GetAdditionalImages()...)
 	images = append(images, velero.GetAdditionalImages()...)
+	images = append(images, ingressnginx.GetAdditionalImages()...)
 	images = append(images, adminconsole.GetAdditionalImages()...)
 
 	return images
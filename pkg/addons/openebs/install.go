@@ -19,6 +19,7 @@ func (o *OpenEBS) Install(ctx context.Context, kcli client.Client, hcli helm.Cli
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 	})
@@ -11,6 +11,9 @@ import (
 type OpenEBS struct{}
 
 const (
+	// StorageClassName is the name of the hostpath storage class deployed by OpenEBS.
+	StorageClassName = "openebs-hostpath"
+
 	releaseName = "openebs"
 	namespace   = "openebs"
 )
@@ -52,3 +55,11 @@ func (o *OpenEBS) ReleaseName() string {
 func (o *OpenEBS) Namespace() string {
 	return namespace
 }
+
+func (o *OpenEBS) Dependencies() []string {
+	return nil
+}
+
+func (o *OpenEBS) StorageClassName() string {
+	return StorageClassName
+}
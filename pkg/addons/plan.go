@@ -0,0 +1,101 @@
+package addons
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AddOnPlan describes what upgrading to a new release does to a built-in addon.
+type AddOnPlan struct {
+	Name        string
+	Namespace   string
+	ReleaseName string
+	// FromVersion is empty if the addon is not installed yet.
+	FromVersion string
+	ToVersion   string
+	// ValuesDiff holds the changes to the helm values, with the credentials masked.
+	ValuesDiff []helm.ValueChange
+}
+
+// PlanUpgrade computes, without changing anything in the cluster, the chart version and the
+// helm values changes each addon goes through when the installation is upgraded to the
+// release described by meta. The current chart versions are read from currentMeta and the
+// current values from the deployed helm releases.
+func PlanUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, currentMeta *ectypes.ReleaseMetadata) ([]AddOnPlan, error) {
+	addons, err := getAddOnsForUpgrade(in, meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "get addons for upgrade")
+	}
+
+	plans := []AddOnPlan{}
+	for _, addon := range addons {
+		plan, err := planAddOnUpgrade(ctx, kcli, hcli, in, addon, meta, currentMeta)
+		if err != nil {
+			return nil, errors.Wrapf(err, "addon %s", addon.Name())
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+func planAddOnUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, addon types.AddOn, meta *ectypes.ReleaseMetadata, currentMeta *ectypes.ReleaseMetadata) (AddOnPlan, error) {
+	plan := AddOnPlan{
+		Name:        addon.Name(),
+		Namespace:   addon.Namespace(),
+		ReleaseName: addon.ReleaseName(),
+		ToVersion:   chartVersion(meta, addon.ReleaseName()),
+	}
+	if plan.ToVersion == "" {
+		plan.ToVersion = addon.Version()
+	}
+
+	overrides := addOnOverrides(addon, in.Spec.Config, nil)
+	values, err := addon.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return plan, errors.Wrap(err, "generate helm values")
+	}
+
+	currentValues := map[string]interface{}{}
+	exists, err := hcli.ReleaseExists(ctx, addon.Namespace(), addon.ReleaseName())
+	if err != nil {
+		return plan, errors.Wrap(err, "check if release exists")
+	}
+	if exists {
+		plan.FromVersion = chartVersion(currentMeta, addon.ReleaseName())
+		currentValues, err = hcli.GetValues(ctx, addon.Namespace(), addon.ReleaseName())
+		if err != nil {
+			return plan, errors.Wrap(err, "get current helm values")
+		}
+	}
+
+	diff, err := helm.DiffValues(currentValues, values)
+	if err != nil {
+		return plan, errors.Wrap(err, "diff helm values")
+	}
+	// the plan is printed, the values of the addons include the registry and object store
+	// credentials.
+	plan.ValuesDiff = helm.MaskSecretValues(diff)
+
+	return plan, nil
+}
+
+// chartVersion returns the version of the chart deployed with the provided release name in
+// the release metadata, or an empty string if the chart is not part of it.
+func chartVersion(meta *ectypes.ReleaseMetadata, releaseName string) string {
+	if meta == nil {
+		return ""
+	}
+	for _, chart := range meta.Configs.Charts {
+		if chart.Name == releaseName {
+			return chart.Version
+		}
+	}
+	return ""
+}
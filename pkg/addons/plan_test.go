@@ -0,0 +1,56 @@
+package addons
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_planAddOnUpgrade(t *testing.T) {
+	in := &ecv1beta1.Installation{
+		Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{}},
+	}
+	meta := &ectypes.ReleaseMetadata{
+		Configs: ecv1beta1.Helm{Charts: []ecv1beta1.Chart{{Name: "openebs", Version: "4.1.1"}}},
+	}
+	currentMeta := &ectypes.ReleaseMetadata{
+		Configs: ecv1beta1.Helm{Charts: []ecv1beta1.Chart{{Name: "openebs", Version: "4.1.0"}}},
+	}
+	addon := &openebs.OpenEBS{}
+	kcli := fake.NewClientBuilder().Build()
+
+	values, err := addon.GenerateHelmValues(context.Background(), kcli, nil)
+	require.NoError(t, err)
+	currentValues, err := helm.PatchValues(values, `{"engines": {"replicated": {"mayastor": {"enabled": true}}}}`)
+	require.NoError(t, err)
+
+	hcli := &helm.MockClient{}
+	hcli.On("ReleaseExists", mock.Anything, "openebs", "openebs").Return(true, nil)
+	hcli.On("GetValues", mock.Anything, "openebs", "openebs").Return(currentValues, nil)
+
+	plan, err := planAddOnUpgrade(context.Background(), kcli, hcli, in, addon, meta, currentMeta)
+	require.NoError(t, err)
+	assert.Equal(t, "4.1.0", plan.FromVersion)
+	assert.Equal(t, "4.1.1", plan.ToVersion)
+	assert.Equal(t, []helm.ValueChange{
+		{Path: "engines.replicated.mayastor.enabled", From: "true", To: "false"},
+	}, plan.ValuesDiff)
+	hcli.AssertExpectations(t)
+
+	hcli = &helm.MockClient{}
+	hcli.On("ReleaseExists", mock.Anything, "openebs", "openebs").Return(false, nil)
+
+	plan, err = planAddOnUpgrade(context.Background(), kcli, hcli, in, addon, meta, currentMeta)
+	require.NoError(t, err)
+	assert.Empty(t, plan.FromVersion)
+	assert.NotEmpty(t, plan.ValuesDiff)
+	hcli.AssertExpectations(t)
+}
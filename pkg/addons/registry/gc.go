@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// configPath is where the chart mounts the registry configuration.
+	configPath = "/etc/docker/registry/config.yml"
+	// readOnlyEnv puts the registry storage in maintenance mode, pushes are refused while
+	// it is set. Blobs uploaded while the garbage collection runs could otherwise be removed.
+	readOnlyEnv = "REGISTRY_STORAGE_MAINTENANCE_READONLY"
+	// containerName is the name of the registry container in the registry pods.
+	containerName = "docker-registry"
+)
+
+// RunGarbageCollection removes from the registry storage the blobs no longer referenced by any
+// manifest. Manifests are expected to be deleted through the registry api beforehand. The
+// registry is read-only while the garbage collection runs, pulls keep working.
+func RunGarbageCollection(ctx context.Context, kcli client.Client) (finalErr error) {
+	if err := setReadOnly(ctx, kcli, true); err != nil {
+		return errors.Wrap(err, "set registry read-only")
+	}
+	defer func() {
+		if err := setReadOnly(ctx, kcli, false); err != nil && finalErr == nil {
+			finalErr = errors.Wrap(err, "set registry read-write")
+		}
+	}()
+
+	command := []string{"registry", "garbage-collect", configPath}
+	if err := execInPod(ctx, command, io.Discard); err != nil {
+		return errors.Wrap(err, "exec in pod")
+	}
+	return nil
+}
+
+// setReadOnly sets, or removes, the read-only maintenance mode of the registry and waits for
+// the registry pods to be replaced.
+func setReadOnly(ctx context.Context, kcli client.Client, readOnly bool) error {
+	var deploy appsv1.Deployment
+	if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: deploymentName}, &deploy); err != nil {
+		return errors.Wrap(err, "get registry deployment")
+	}
+
+	patch := client.MergeFrom(deploy.DeepCopy())
+	if !setReadOnlyEnv(&deploy, readOnly) {
+		return nil
+	}
+	if err := kcli.Patch(ctx, &deploy, patch); err != nil {
+		return errors.Wrap(err, "patch registry deployment")
+	}
+	return waitForRollout(ctx, kcli)
+}
+
+// setReadOnlyEnv sets, or removes, the read-only environment variable of the registry
+// container. Returns true if the deployment was changed.
+func setReadOnlyEnv(deploy *appsv1.Deployment, readOnly bool) bool {
+	for i, container := range deploy.Spec.Template.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+		env := []corev1.EnvVar{}
+		found := false
+		for _, e := range container.Env {
+			if e.Name == readOnlyEnv {
+				found = true
+				continue
+			}
+			env = append(env, e)
+		}
+		if found == readOnly {
+			return false
+		}
+		if readOnly {
+			env = append(env, corev1.EnvVar{Name: readOnlyEnv, Value: `{"enabled":true}`})
+		}
+		deploy.Spec.Template.Spec.Containers[i].Env = env
+		return true
+	}
+	return false
+}
+
+// waitForRollout waits until all the registry pods run the latest revision of the deployment
+// and the previous ones are gone.
+func waitForRollout(ctx context.Context, kcli client.Client) error {
+	var lasterr error
+	if err := wait.PollUntilContextTimeout(ctx, 2*time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+		var deploy appsv1.Deployment
+		if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: deploymentName}, &deploy); err != nil {
+			lasterr = errors.Wrap(err, "get registry deployment")
+			return false, nil
+		}
+		replicas := int32(1)
+		if deploy.Spec.Replicas != nil {
+			replicas = *deploy.Spec.Replicas
+		}
+		status := deploy.Status
+		return status.ObservedGeneration >= deploy.Generation &&
+			status.UpdatedReplicas == replicas &&
+			status.ReadyReplicas == replicas &&
+			status.Replicas == replicas, nil
+	}); err != nil {
+		if lasterr != nil {
+			return errors.Wrap(lasterr, "wait for registry rollout")
+		}
+		return errors.Wrap(err, "wait for registry rollout")
+	}
+	return nil
+}
@@ -3,14 +3,12 @@ package registry
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
 	"github.com/replicatedhq/embedded-cluster/pkg/certs"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
-	"golang.org/x/crypto/bcrypt"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,6 +34,7 @@ func (r *Registry) Install(ctx context.Context, kcli client.Client, hcli helm.Cl
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
@@ -80,29 +79,29 @@ func createNamespace(ctx context.Context, kcli client.Client, namespace string)
 }
 
 func createAuthSecret(ctx context.Context, kcli client.Client) error {
-	hashPassword, err := bcrypt.GenerateFromPassword([]byte(registryPassword), bcrypt.DefaultCost)
+	htpasswd, err := Htpasswd(registryPassword)
 	if err != nil {
-		return errors.Wrap(err, "hash registry password")
+		return errors.Wrap(err, "generate htpasswd")
 	}
 
-	htpasswd := corev1.Secret{
+	secret := corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Secret",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "registry-auth",
+			Name:      AuthSecretName,
 			Namespace: namespace,
 			Labels: map[string]string{
 				"app": "docker-registry", // this is the backup/restore label for the registry component
 			},
 		},
 		StringData: map[string]string{
-			"htpasswd": fmt.Sprintf("embedded-cluster:%s", string(hashPassword)),
+			"htpasswd": htpasswd,
 		},
 		Type: "Opaque",
 	}
-	if err := kcli.Create(ctx, &htpasswd); err != nil {
+	if err := kcli.Create(ctx, &secret); err != nil {
 		return err
 	}
 
@@ -137,7 +136,7 @@ func createTLSSecret(ctx context.Context, kcli client.Client, registryIP string)
 func generateRegistryTLS(registryIP string) (string, string, error) {
 	opts := []certs.Option{
 		certs.WithCommonName("registry"),
-		certs.WithDuration(365 * 24 * time.Hour),
+		certs.WithDuration(tlsDuration),
 		certs.WithIPAddress(registryIP),
 	}
 
@@ -186,10 +186,17 @@ func execInPod(ctx context.Context, command []string, stdout io.Writer) error {
 	if err != nil {
 		return errors.Wrap(err, "list registry pods")
 	}
-	if len(pods.Items) == 0 {
-		return errors.New("no registry pods found")
+	// pods of a previous revision may still be terminating after a rollout.
+	podName := ""
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning {
+			podName = pod.Name
+			break
+		}
+	}
+	if podName == "" {
+		return errors.New("no running registry pods found")
 	}
-	podName := pods.Items[0].Name
 
 	req := clientSet.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -200,7 +207,7 @@ func execInPod(ctx context.Context, command []string, stdout io.Writer) error {
 	parameterCodec := runtime.NewParameterCodec(scheme)
 	req.VersionedParams(&corev1.PodExecOptions{
 		Command:   command,
-		Container: "docker-registry",
+		Container: containerName,
 		Stdout:    true,
 		Stderr:    true,
 	}, parameterCodec)
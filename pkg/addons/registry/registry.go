@@ -2,6 +2,7 @@ package registry
 
 import (
 	_ "embed"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
@@ -13,12 +14,22 @@ import (
 type Registry struct {
 	ServiceCIDR string
 	IsHA        bool
+	// StorageClassName is the storage class of the registry volume when not highly available.
+	// The class in the chart values is used if empty.
+	StorageClassName string
 }
 
 const (
+	// AuthSecretName is the name of the secret holding the htpasswd file of the registry.
+	AuthSecretName = "registry-auth"
+	// Username is the user the cluster authenticates to the registry with.
+	Username = "embedded-cluster"
+
 	releaseName      = "docker-registry"
 	namespace        = runtimeconfig.RegistryNamespace
 	tlsSecretName    = "registry-tls"
+	tlsDuration      = 365 * 24 * time.Hour
+	deploymentName   = "registry"
 	lowerBandIPIndex = 10
 )
 
@@ -73,6 +84,13 @@ func (r *Registry) Namespace() string {
 	return namespace
 }
 
+func (r *Registry) Dependencies() []string {
+	if r.IsHA {
+		return []string{"SeaweedFS"}
+	}
+	return []string{"Storage"}
+}
+
 func GetRegistryPassword() string {
 	return registryPassword
 }
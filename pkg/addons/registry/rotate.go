@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/certs"
+	"golang.org/x/crypto/bcrypt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Htpasswd returns the content of the htpasswd file authenticating the registry user with the
+// provided password.
+func Htpasswd(password string) (string, error) {
+	hashPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "hash registry password")
+	}
+	return fmt.Sprintf("%s:%s", Username, string(hashPassword)), nil
+}
+
+// TLSCertificate returns the certificate stored in the registry tls secret. Nil is returned
+// if the secret does not exist, i.e. the registry was deployed without tls.
+func TLSCertificate(ctx context.Context, kcli client.Client) (*x509.Certificate, error) {
+	var secret corev1.Secret
+	nsn := k8stypes.NamespacedName{Namespace: namespace, Name: tlsSecretName}
+	if err := kcli.Get(ctx, nsn, &secret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get tls secret")
+	}
+
+	block, _ := pem.Decode(secret.Data["tls.crt"])
+	if block == nil {
+		return nil, errors.New("decode tls certificate")
+	}
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse tls certificate")
+	}
+	return crt, nil
+}
+
+// RenewTLSSecret issues a new certificate for the registry, with the subject and alternative
+// names of the provided one, and stores it in the registry tls secret. The new certificate is
+// returned in PEM format. The registry serves it once restarted.
+func RenewTLSSecret(ctx context.Context, kcli client.Client, crt *x509.Certificate) (string, error) {
+	builder, err := certs.NewBuilder(certs.WithSubjectOf(crt), certs.WithDuration(tlsDuration))
+	if err != nil {
+		return "", errors.Wrap(err, "create cert builder")
+	}
+	tlsCert, tlsKey, err := builder.Generate()
+	if err != nil {
+		return "", errors.Wrap(err, "generate registry tls")
+	}
+
+	var secret corev1.Secret
+	nsn := k8stypes.NamespacedName{Namespace: namespace, Name: tlsSecretName}
+	if err := kcli.Get(ctx, nsn, &secret); err != nil {
+		return "", errors.Wrap(err, "get tls secret")
+	}
+	secret.Data = map[string][]byte{"tls.crt": []byte(tlsCert), "tls.key": []byte(tlsKey)}
+	if err := kcli.Update(ctx, &secret); err != nil {
+		return "", errors.Wrap(err, "update tls secret")
+	}
+	return tlsCert, nil
+}
+
+// Restart rolls out the registry pods by setting the provided annotation in their template.
+// Nothing is restarted if the annotation already has the provided value. Returns true if the
+// registry was restarted.
+func Restart(ctx context.Context, kcli client.Client, annotation, value string) (bool, error) {
+	var deploy appsv1.Deployment
+	nsn := k8stypes.NamespacedName{Namespace: namespace, Name: deploymentName}
+	if err := kcli.Get(ctx, nsn, &deploy); err != nil {
+		return false, errors.Wrap(err, "get registry deployment")
+	}
+	if deploy.Spec.Template.Annotations[annotation] == value {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(deploy.DeepCopy())
+	if deploy.Spec.Template.Annotations == nil {
+		deploy.Spec.Template.Annotations = map[string]string{}
+	}
+	deploy.Spec.Template.Annotations[annotation] = value
+	if err := kcli.Patch(ctx, &deploy, patch); err != nil {
+		return false, errors.Wrap(err, "patch registry deployment")
+	}
+	return true, nil
+}
@@ -49,6 +49,13 @@ func (r *Registry) GenerateHelmValues(ctx context.Context, kcli client.Client, o
 		"clusterIP": registryIP,
 	}
 
+	if !r.IsHA && r.StorageClassName != "" {
+		err = helm.SetValue(copiedValues, "persistence.storageClass", r.StorageClassName)
+		if err != nil {
+			return nil, errors.Wrap(err, "set helm value persistence.storageClass")
+		}
+	}
+
 	if r.IsHA {
 		seaweedFSEndpoint, err := seaweedfs.GetS3Endpoint(r.ServiceCIDR)
 		if err != nil {
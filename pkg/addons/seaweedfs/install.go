@@ -30,6 +30,7 @@ func (s *SeaweedFS) Install(ctx context.Context, kcli client.Client, hcli helm.C
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
@@ -11,6 +11,9 @@ import (
 
 type SeaweedFS struct {
 	ServiceCIDR string
+	// StorageClassName is the storage class of the volume and filer volumes. The class in the
+	// chart values is used if empty.
+	StorageClassName string
 }
 
 const (
@@ -68,6 +71,11 @@ func (s *SeaweedFS) Namespace() string {
 	return namespace
 }
 
+func (s *SeaweedFS) Dependencies() []string {
+	// the seaweedfs volumes are backed by the storage addon.
+	return []string{"Storage"}
+}
+
 func getBackupLabels() map[string]string {
 	return map[string]string{
 		"app.kubernetes.io/name": "seaweedfs",
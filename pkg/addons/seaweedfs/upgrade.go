@@ -31,6 +31,7 @@ func (s *SeaweedFS) Upgrade(ctx context.Context, kcli client.Client, hcli helm.C
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 		Labels:       getBackupLabels(),
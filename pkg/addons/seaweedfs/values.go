@@ -33,6 +33,14 @@ func (s *SeaweedFS) GenerateHelmValues(ctx context.Context, kcli client.Client,
 		return nil, errors.Wrap(err, "set helm values global.logs.hostPathPrefix")
 	}
 
+	if s.StorageClassName != "" {
+		for _, path := range []string{"volume.dataDirs[0].storageClass", "filer.data.storageClass", "filer.logs.storageClass"} {
+			if err := helm.SetValue(copiedValues, path, s.StorageClassName); err != nil {
+				return nil, errors.Wrapf(err, "set helm value %s", path)
+			}
+		}
+	}
+
 	for _, override := range overrides {
 		copiedValues, err = helm.PatchValues(copiedValues, override)
 		if err != nil {
@@ -0,0 +1,81 @@
+package addons
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StorageForInstall returns the storage a new cluster is installed with. It is read from the
+// end user config first and then from the embedded config, OpenEBS is used if none is set.
+func StorageForInstall(embCfg, euCfg *ecv1beta1.ConfigSpec) (*ecv1beta1.StorageSpec, error) {
+	storage := ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderOpenEBS}
+	for _, spec := range []*ecv1beta1.ConfigSpec{euCfg, embCfg} {
+		if spec != nil && spec.Storage.Provider != "" {
+			storage = spec.Storage
+			break
+		}
+	}
+
+	switch storage.Provider {
+	case ecv1beta1.StorageProviderOpenEBS:
+		storage.StorageClassName = openebs.StorageClassName
+	case ecv1beta1.StorageProviderExisting:
+		if storage.StorageClassName == "" {
+			return nil, errors.Errorf("storage class name is required with the %s storage provider", storage.Provider)
+		}
+	default:
+		return nil, errors.Errorf("unsupported storage provider %q", storage.Provider)
+	}
+	return &storage, nil
+}
+
+// storageProvider returns the addon providing the storage class of the cluster. Nil is
+// returned if an existing storage class is used.
+func storageProvider(storage *ecv1beta1.StorageSpec) types.StorageProvider {
+	if storage.IsOpenEBS() {
+		return &openebs.OpenEBS{}
+	}
+	return nil
+}
+
+// storageClassName returns the name of the storage class the volumes of the addons are
+// provisioned with.
+func storageClassName(storage *ecv1beta1.StorageSpec) string {
+	if provider := storageProvider(storage); provider != nil {
+		return provider.StorageClassName()
+	}
+	return storage.StorageClassName
+}
+
+// withStorageProvider prepends the storage provider addon, if any, to the list of addons.
+func withStorageProvider(storage *ecv1beta1.StorageSpec, addOns ...types.AddOn) []types.AddOn {
+	if provider := storageProvider(storage); provider != nil {
+		return append([]types.AddOn{provider}, addOns...)
+	}
+	return addOns
+}
+
+// EnsureStorageClass makes sure the storage class the addons are provisioned with exists
+// when it is not deployed by an addon. An existing storage class may be deployed by an
+// extension, e.g. a CSI driver, in which case this is to be called once the extensions are
+// installed and before the addons are.
+func EnsureStorageClass(ctx context.Context, kcli client.Client, storage *ecv1beta1.StorageSpec) error {
+	if storageProvider(storage) != nil {
+		return nil
+	}
+	name := storageClassName(storage)
+	if err := kcli.Get(ctx, client.ObjectKey{Name: name}, &storagev1.StorageClass{}); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return errors.Errorf("storage class %s not found", name)
+		}
+		return errors.Wrapf(err, "get storage class %s", name)
+	}
+	return nil
+}
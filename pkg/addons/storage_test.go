@@ -0,0 +1,76 @@
+package addons
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStorageForInstall(t *testing.T) {
+	existing := ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderExisting, StorageClassName: "san"}
+	tests := []struct {
+		name    string
+		embCfg  *ecv1beta1.ConfigSpec
+		euCfg   *ecv1beta1.ConfigSpec
+		want    *ecv1beta1.StorageSpec
+		wantErr string
+	}{
+		{
+			name: "defaults to openebs",
+			want: &ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderOpenEBS, StorageClassName: "openebs-hostpath"},
+		},
+		{
+			name:   "existing storage class from the embedded config",
+			embCfg: &ecv1beta1.ConfigSpec{Storage: existing},
+			want:   &existing,
+		},
+		{
+			name:   "end user config takes precedence",
+			embCfg: &ecv1beta1.ConfigSpec{Storage: existing},
+			euCfg:  &ecv1beta1.ConfigSpec{Storage: ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderOpenEBS}},
+			want:   &ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderOpenEBS, StorageClassName: "openebs-hostpath"},
+		},
+		{
+			name:    "existing storage without storage class",
+			euCfg:   &ecv1beta1.ConfigSpec{Storage: ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderExisting}},
+			wantErr: "storage class name is required with the existing storage provider",
+		},
+		{
+			name:    "unsupported provider",
+			euCfg:   &ecv1beta1.ConfigSpec{Storage: ecv1beta1.StorageSpec{Provider: "ceph"}},
+			wantErr: `unsupported storage provider "ceph"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StorageForInstall(tt.embCfg, tt.euCfg)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnsureStorageClass(t *testing.T) {
+	ctx := context.Background()
+	existing := &ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderExisting, StorageClassName: "san"}
+
+	kcli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	assert.NoError(t, EnsureStorageClass(ctx, kcli, nil), "openebs deploys its own storage class")
+	assert.EqualError(t, EnsureStorageClass(ctx, kcli, existing), "storage class san not found")
+
+	kcli = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "san"}},
+	).Build()
+	assert.NoError(t, EnsureStorageClass(ctx, kcli, existing))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
@@ -19,14 +20,28 @@ type AddOn interface {
 	Version() string
 	ReleaseName() string
 	Namespace() string
+	// Dependencies returns the names of the addons that must be ready before this one is
+	// installed.
+	Dependencies() []string
 	GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error)
 	Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error
 	Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error
 }
 
+// StorageProvider is an addon providing the storage class the volumes of the other addons are
+// provisioned with.
+type StorageProvider interface {
+	AddOn
+	// StorageClassName returns the name of the storage class provided by the addon.
+	StorageClassName() string
+}
+
 var _ AddOn = (*adminconsole.AdminConsole)(nil)
 var _ AddOn = (*openebs.OpenEBS)(nil)
 var _ AddOn = (*registry.Registry)(nil)
 var _ AddOn = (*seaweedfs.SeaweedFS)(nil)
 var _ AddOn = (*velero.Velero)(nil)
 var _ AddOn = (*embeddedclusteroperator.EmbeddedClusterOperator)(nil)
+var _ AddOn = (*ingressnginx.IngressNginx)(nil)
+
+var _ StorageProvider = (*openebs.OpenEBS)(nil)
@@ -10,7 +10,7 @@ import (
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
-	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingressnginx"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
@@ -32,6 +32,8 @@ func Upgrade(ctx context.Context, hcli helm.Client, in *ecv1beta1.Installation,
 	if err != nil {
 		return errors.Wrap(err, "get addons for upgrade")
 	}
+
+	hcli = helm.WithPostRenderers(hcli, addOnPostRenderers(in.Spec.Config, nil))
 	for _, addon := range addons {
 		if err := upgradeAddOn(ctx, hcli, kcli, in, addon); err != nil {
 			return errors.Wrapf(err, "addon %s", addon.Name())
@@ -41,10 +43,14 @@ func Upgrade(ctx context.Context, hcli helm.Client, in *ecv1beta1.Installation,
 	return nil
 }
 
+// GetAddOnsForUpgrade returns the addons processed by Upgrade for the installation, in the
+// order they are upgraded.
+func GetAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) ([]types.AddOn, error) {
+	return getAddOnsForUpgrade(in, meta)
+}
+
 func getAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) ([]types.AddOn, error) {
-	addOns := []types.AddOn{
-		&openebs.OpenEBS{},
-	}
+	addOns := withStorageProvider(in.Spec.Storage)
 
 	serviceCIDR := ""
 	if in.Spec.Network != nil {
@@ -72,32 +78,42 @@ func getAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetada
 		ImageRepoOverride:     ecoImageRepo,
 		ImageTagOverride:      ecoImageTag,
 		UtilsImageOverride:    ecoUtilsImage,
+		AutoRemediateDrift:    in.Spec.Config != nil && in.Spec.Config.AutoRemediateDrift,
 	})
 
 	if in.Spec.AirGap {
 		addOns = append(addOns, &registry.Registry{
-			ServiceCIDR: serviceCIDR,
-			IsHA:        in.Spec.HighAvailability,
+			ServiceCIDR:      serviceCIDR,
+			IsHA:             in.Spec.HighAvailability,
+			StorageClassName: storageClassName(in.Spec.Storage),
 		})
 
 		if in.Spec.HighAvailability {
 			addOns = append(addOns, &seaweedfs.SeaweedFS{
-				ServiceCIDR: serviceCIDR,
+				ServiceCIDR:      serviceCIDR,
+				StorageClassName: storageClassName(in.Spec.Storage),
 			})
 		}
 	}
 
 	if in.Spec.LicenseInfo != nil && in.Spec.LicenseInfo.IsDisasterRecoverySupported {
 		addOns = append(addOns, &velero.Velero{
-			Proxy: in.Spec.Proxy,
+			Proxy:            in.Spec.Proxy,
+			StorageClassName: storageClassName(in.Spec.Storage),
 		})
 	}
 
+	if in.Spec.Ingress.IsEnabled() {
+		addOns = append(addOns, &ingressnginx.IngressNginx{})
+	}
+
 	addOns = append(addOns, &adminconsole.AdminConsole{
-		IsAirgap:    in.Spec.AirGap,
-		IsHA:        in.Spec.HighAvailability,
-		Proxy:       in.Spec.Proxy,
-		ServiceCIDR: serviceCIDR,
+		IsAirgap:         in.Spec.AirGap,
+		IsHA:             in.Spec.HighAvailability,
+		Proxy:            in.Spec.Proxy,
+		ServiceCIDR:      serviceCIDR,
+		Hostname:         adminConsoleHostname(in.Spec.Ingress),
+		StorageClassName: storageClassName(in.Spec.Storage),
 	})
 
 	return addOns, nil
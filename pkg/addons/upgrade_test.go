@@ -231,6 +231,49 @@ func Test_getAddOnsForUpgrade(t *testing.T) {
 				assert.Equal(t, "10.96.0.0/12", adminConsole.ServiceCIDR)
 			},
 		},
+		{
+			name: "airgap HA with existing storage class",
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					AirGap:           true,
+					HighAvailability: true,
+					Network: &ecv1beta1.NetworkSpec{
+						ServiceCIDR: "10.96.0.0/12",
+					},
+					LicenseInfo: &ecv1beta1.LicenseInfo{
+						IsDisasterRecoverySupported: true,
+					},
+					Storage: &ecv1beta1.StorageSpec{
+						Provider:         ecv1beta1.StorageProviderExisting,
+						StorageClassName: "san",
+					},
+					BinaryName: "test-binary-name",
+				},
+			},
+			meta: meta,
+			verify: func(t *testing.T, addons []types.AddOn, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, addons, 5)
+
+				_, ok := addons[0].(*embeddedclusteroperator.EmbeddedClusterOperator)
+				require.True(t, ok, "first addon should be EmbeddedClusterOperator")
+
+				reg, ok := addons[1].(*registry.Registry)
+				require.True(t, ok, "second addon should be Registry")
+				assert.Equal(t, "san", reg.StorageClassName)
+
+				seaweed, ok := addons[2].(*seaweedfs.SeaweedFS)
+				require.True(t, ok, "third addon should be SeaweedFS")
+				assert.Equal(t, "san", seaweed.StorageClassName)
+
+				vel, ok := addons[3].(*velero.Velero)
+				require.True(t, ok, "fourth addon should be Velero")
+				assert.Equal(t, "san", vel.StorageClassName)
+
+				_, ok = addons[4].(*adminconsole.AdminConsole)
+				require.True(t, ok, "fifth addon should be AdminConsole")
+			},
+		},
 		{
 			name: "invalid metadata - missing chart",
 			in: &ecv1beta1.Installation{
@@ -7,6 +7,8 @@ import (
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"helm.sh/helm/v3/pkg/postrender"
 )
 
 func addOnOverrides(addon types.AddOn, embCfgSpec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) []string {
@@ -20,6 +22,26 @@ func addOnOverrides(addon types.AddOn, embCfgSpec *ecv1beta1.ConfigSpec, euCfgSp
 	return overrides
 }
 
+// addOnPostRenderers returns the post-renderers applying the kustomize patches configured for
+// the built-in extensions, indexed by release name. The end user patches are applied after
+// the embedded ones.
+func addOnPostRenderers(embCfgSpec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) map[string]postrender.PostRenderer {
+	postRenderers := map[string]postrender.PostRenderer{}
+	for _, cfgSpec := range []*ecv1beta1.ConfigSpec{embCfgSpec, euCfgSpec} {
+		if cfgSpec == nil {
+			continue
+		}
+		for _, ext := range cfgSpec.UnsupportedOverrides.BuiltInExtensions {
+			pr := helm.NewPostRenderer(ext.Patches, ext.Kustomization)
+			if pr == nil {
+				continue
+			}
+			postRenderers[ext.Name] = helm.ChainPostRenderers(postRenderers[ext.Name], pr)
+		}
+	}
+	return postRenderers
+}
+
 func operatorChart(meta *ectypes.ReleaseMetadata) (string, string, error) {
 	// search through for the operator chart, and find the location
 	for _, chart := range meta.Configs.Charts {
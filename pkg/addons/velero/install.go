@@ -26,6 +26,7 @@ func (v *Velero) Install(ctx context.Context, kcli client.Client, hcli helm.Clie
 		ReleaseName:  releaseName,
 		ChartPath:    Metadata.Location,
 		ChartVersion: Metadata.Version,
+		Verification: &helm.ChartVerification{Digest: Metadata.ChartDigest},
 		Values:       values,
 		Namespace:    namespace,
 	})
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,6 +32,25 @@ func (v *Velero) GenerateHelmValues(ctx context.Context, kcli client.Client, ove
 		}
 	}
 
+	// volumes backed up from an openebs cluster are restored into the storage class of the
+	// cluster.
+	if v.StorageClassName != "" && v.StorageClassName != openebs.StorageClassName {
+		configMaps, _ := copiedValues["configMaps"].(map[string]interface{})
+		if configMaps == nil {
+			configMaps = map[string]interface{}{}
+		}
+		configMaps["change-storage-class-config"] = map[string]interface{}{
+			"labels": map[string]interface{}{
+				"velero.io/plugin-config":        "",
+				"velero.io/change-storage-class": "RestoreItemAction",
+			},
+			"data": map[string]interface{}{
+				openebs.StorageClassName: v.StorageClassName,
+			},
+		}
+		copiedValues["configMaps"] = configMaps
+	}
+
 	podVolumePath := filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), "kubelet/pods")
 	err = helm.SetValue(copiedValues, "nodeAgent.podVolumePath", podVolumePath)
 	if err != nil {
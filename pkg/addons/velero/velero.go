@@ -12,6 +12,9 @@ import (
 
 type Velero struct {
 	Proxy *ecv1beta1.ProxySpec
+	// StorageClassName is the storage class of the cluster. Volumes backed up from the OpenEBS
+	// storage class are restored into it.
+	StorageClassName string
 }
 
 const (
@@ -57,3 +60,7 @@ func (v *Velero) ReleaseName() string {
 func (v *Velero) Namespace() string {
 	return namespace
 }
+
+func (v *Velero) Dependencies() []string {
+	return nil
+}
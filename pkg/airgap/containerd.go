@@ -8,6 +8,14 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 )
 
+const (
+	// RegistryConfigFile is the name of the containerd configuration file for the registry.
+	RegistryConfigFile = "embedded-registry.toml"
+	// RegistryCAFile is the name of the file, next to the containerd configuration file for
+	// the registry, holding the certificates the registry is trusted with.
+	RegistryCAFile = "embedded-registry-ca.crt"
+)
+
 const registryConfigTemplate = `
 [plugins."io.containerd.grpc.v1.cri".registry]
   [plugins."io.containerd.grpc.v1.cri".registry.configs]
@@ -15,6 +23,13 @@ const registryConfigTemplate = `
       insecure_skip_verify = true
 `
 
+const trustedRegistryConfigTemplate = `
+[plugins."io.containerd.grpc.v1.cri".registry]
+  [plugins."io.containerd.grpc.v1.cri".registry.configs]
+    [plugins."io.containerd.grpc.v1.cri".registry.configs."%s".tls]
+      ca_file = "%s"
+`
+
 // AddInsecureRegistry adds a registry to the list of registries that
 // are allowed to be accessed over HTTP.
 func AddInsecureRegistry(registry string) error {
@@ -25,10 +40,17 @@ func AddInsecureRegistry(registry string) error {
 		return fmt.Errorf("failed to ensure containerd directory exists: %w", err)
 	}
 
-	err := os.WriteFile(filepath.Join(parentDir, "embedded-registry.toml"), []byte(contents), 0644)
+	err := os.WriteFile(filepath.Join(parentDir, RegistryConfigFile), []byte(contents), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write embedded-registry.toml: %w", err)
 	}
 
 	return nil
 }
+
+// TrustedRegistryConfig returns the containerd configuration verifying the certificate of the
+// registry against the certificates in the registry ca file.
+func TrustedRegistryConfig(registry string) string {
+	caFile := filepath.Join(runtimeconfig.PathToK0sContainerdConfig(), RegistryCAFile)
+	return fmt.Sprintf(trustedRegistryConfigTemplate, registry, caFile)
+}
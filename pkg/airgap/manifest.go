@@ -0,0 +1,280 @@
+package airgap
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/signatures"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ManifestName is the name of the airgap bundle member listing the size and digest of all
+	// the other members. It must be the first member of the bundle so the bundle can be
+	// verified while it is streamed.
+	ManifestName = "manifest.json"
+	// ManifestSignatureName is the name of the airgap bundle member holding the base64 encoded
+	// signature of the manifest. It must follow the manifest.
+	ManifestSignatureName = "manifest.json.sig"
+
+	// maxManifestSize is the maximum size of the manifest and of its signature.
+	maxManifestSize = 10 << 20
+	// maxMissingFilesReported is the maximum number of missing files listed in errors.
+	maxMissingFilesReported = 3
+)
+
+// Manifest lists the regular files of an airgap bundle along with their size and digest.
+type Manifest struct {
+	Files map[string]ManifestFile `json:"files"`
+}
+
+// ManifestFile holds the size and the hex encoded sha256 digest of an airgap bundle member.
+type ManifestFile struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Verify reads the whole airgap bundle and verifies the signature of its manifest and the size
+// and digest of each of its members. If publicKey is empty the signature is not verified. The
+// manifest is returned, or nil if the bundle predates manifests and publicKey is empty.
+func Verify(reader io.Reader, publicKey string) (*Manifest, error) {
+	br, err := newBundleReader(reader, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, _, err := br.Next(); err == io.EOF {
+			return br.manifest, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// bundleReader iterates over the members of an airgap bundle, verifying each of them against
+// the bundle manifest as they are read.
+type bundleReader struct {
+	tr       *tar.Reader
+	manifest *Manifest
+	// pending is a header read while looking for the manifest and not returned yet.
+	pending *tar.Header
+	current *memberReader
+	seen    map[string]bool
+}
+
+func newBundleReader(reader io.Reader, publicKey string) (*bundleReader, error) {
+	ungzip, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress airgap file: %w", readError(err))
+	}
+
+	br := &bundleReader{tr: tar.NewReader(ungzip), seen: map[string]bool{}}
+	if err := br.readManifest(publicKey); err != nil {
+		return nil, err
+	}
+	return br, nil
+}
+
+func (b *bundleReader) readManifest(publicKey string) error {
+	hdr, err := b.tr.Next()
+	if err != nil {
+		return fmt.Errorf("failed to read airgap file: %w", readError(err))
+	}
+	if hdr.Name != ManifestName {
+		if publicKey != "" {
+			return fmt.Errorf("airgap bundle is not signed, %s not found", ManifestName)
+		}
+		logrus.Debugf("Airgap bundle has no manifest, skipping verification")
+		b.pending = hdr
+		return nil
+	}
+
+	data, err := readMember(b.tr, hdr)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ManifestName, err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", ManifestName, err)
+	}
+
+	hdr, err = b.tr.Next()
+	if err == io.EOF {
+		hdr = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read airgap file: %w", readError(err))
+	}
+	if hdr == nil || hdr.Name != ManifestSignatureName {
+		if publicKey != "" {
+			return fmt.Errorf("airgap bundle is not signed, %s not found", ManifestSignatureName)
+		}
+		b.pending = hdr
+	} else {
+		sig, err := readMember(b.tr, hdr)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", ManifestSignatureName, err)
+		}
+		if publicKey == "" {
+			logrus.Debugf("No airgap signing key found, skipping verification of the airgap bundle signature")
+		} else if err := verifyManifestSignature(data, sig, publicKey); err != nil {
+			return fmt.Errorf("airgap bundle signature is invalid: %w", err)
+		}
+	}
+
+	b.manifest = manifest
+	return nil
+}
+
+func verifyManifestSignature(manifest []byte, signature []byte, publicKey string) error {
+	pub, err := signatures.ParsePublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("parse public key: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	return signatures.Verify(pub, manifest, sig)
+}
+
+// Next verifies the member previously returned and advances to the next one. The returned
+// reader verifies the member as it is read, members do not need to be read to the end. At the
+// end of the bundle io.EOF is returned if all the members listed in the manifest were found.
+func (b *bundleReader) Next() (*tar.Header, io.Reader, error) {
+	if err := b.finishCurrent(); err != nil {
+		return nil, nil, err
+	}
+
+	hdr := b.pending
+	b.pending = nil
+	if hdr == nil {
+		var err error
+		hdr, err = b.tr.Next()
+		if err == io.EOF {
+			return nil, nil, b.checkComplete()
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read airgap file: %w", readError(err))
+		}
+	}
+
+	if b.manifest == nil || hdr.Typeflag != tar.TypeReg {
+		return hdr, b.tr, nil
+	}
+
+	expected, ok := b.manifest.Files[hdr.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("airgap bundle member %s is not listed in its manifest", hdr.Name)
+	}
+	if hdr.Size != expected.Size {
+		return nil, nil, fmt.Errorf("airgap bundle member %s is %d bytes, %d bytes expected", hdr.Name, hdr.Size, expected.Size)
+	}
+	b.seen[hdr.Name] = true
+	b.current = &memberReader{name: hdr.Name, expected: expected.SHA256, reader: b.tr, hash: sha256.New()}
+	return hdr, b.current, nil
+}
+
+// finishCurrent reads what is left of the current member and verifies its digest.
+func (b *bundleReader) finishCurrent() error {
+	if b.current == nil {
+		return nil
+	}
+	current := b.current
+	b.current = nil
+	if _, err := io.Copy(io.Discard, current); err != nil {
+		return fmt.Errorf("failed to read %s: %w", current.name, readError(err))
+	}
+	if digest := hex.EncodeToString(current.hash.Sum(nil)); digest != current.expected {
+		return fmt.Errorf("airgap bundle member %s is corrupted, its sha256 digest is %s but %s is expected", current.name, digest, current.expected)
+	}
+	return nil
+}
+
+// checkComplete returns io.EOF if all the members listed in the manifest were read.
+func (b *bundleReader) checkComplete() error {
+	if b.manifest == nil {
+		return io.EOF
+	}
+	missing := []string{}
+	for name := range b.manifest.Files {
+		if !b.seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return io.EOF
+	}
+	sort.Strings(missing)
+	count := len(missing)
+	if count > maxMissingFilesReported {
+		missing = append(missing[:maxMissingFilesReported], "...")
+	}
+	return fmt.Errorf("airgap bundle is incomplete, %d files listed in its manifest are missing: %s", count, strings.Join(missing, ", "))
+}
+
+// memberReader hashes an airgap bundle member as it is read.
+type memberReader struct {
+	name     string
+	expected string
+	reader   io.Reader
+	hash     hash.Hash
+}
+
+func (m *memberReader) Read(p []byte) (int, error) {
+	n, err := m.reader.Read(p)
+	m.hash.Write(p[:n])
+	return n, err
+}
+
+func readMember(reader io.Reader, hdr *tar.Header) ([]byte, error) {
+	if hdr.Size > maxManifestSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", hdr.Name, maxManifestSize)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, readError(err)
+	}
+	return data, nil
+}
+
+// readError makes errors caused by truncated bundles explicit.
+func readError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("airgap bundle is truncated: %w", err)
+	}
+	return err
+}
+
+// fileMatches returns true if the file at path has the size and digest of the manifest file.
+func fileMatches(path string, expected ManifestFile) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != expected.Size {
+		return false, nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)) == expected.SHA256, nil
+}
@@ -0,0 +1,194 @@
+package airgap
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSignedFiles returns the manifest of the files, its signature and the files, in the order
+// they are expected in an airgap bundle.
+func testSignedFiles(t *testing.T, key *ecdsa.PrivateKey, files ...testFile) []testFile {
+	manifest := Manifest{Files: map[string]ManifestFile{}}
+	for _, f := range files {
+		digest := sha256.Sum256(f.content)
+		manifest.Files[f.name] = ManifestFile{Size: int64(len(f.content)), SHA256: hex.EncodeToString(digest[:])}
+	}
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	return append([]testFile{
+		{ManifestName, data},
+		{ManifestSignatureName, []byte(base64.StdEncoding.EncodeToString(sig))},
+	}, files...)
+}
+
+func testPublicKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	airgapYaml := testFile{"airgap.yaml", []byte("kind: Airgap")}
+	images := testFile{"embedded-cluster/images-amd64.tar", []byte("images")}
+	signed := testSignedFiles(t, key, airgapYaml, images)
+	bundle := testTarGz(t, signed...)
+
+	tests := []struct {
+		name      string
+		bundle    []byte
+		publicKey string
+		wantFiles int
+		wantErr   string
+	}{
+		{
+			name:      "signed bundle",
+			bundle:    bundle,
+			publicKey: testPublicKey(t, key),
+			wantFiles: 2,
+		},
+		{
+			name:      "signed bundle without signing key",
+			bundle:    bundle,
+			wantFiles: 2,
+		},
+		{
+			name:      "signed with another key",
+			bundle:    bundle,
+			publicKey: testPublicKey(t, other),
+			wantErr:   "airgap bundle signature is invalid",
+		},
+		{
+			name:   "legacy bundle without signing key",
+			bundle: testTarGz(t, airgapYaml, images),
+		},
+		{
+			name:      "legacy bundle with signing key",
+			bundle:    testTarGz(t, airgapYaml, images),
+			publicKey: testPublicKey(t, key),
+			wantErr:   "airgap bundle is not signed, manifest.json not found",
+		},
+		{
+			name:      "corrupted member",
+			bundle:    testTarGz(t, signed[0], signed[1], airgapYaml, testFile{images.name, []byte("imagez")}),
+			publicKey: testPublicKey(t, key),
+			wantErr:   "airgap bundle member embedded-cluster/images-amd64.tar is corrupted",
+		},
+		{
+			name:      "member with another size",
+			bundle:    testTarGz(t, signed[0], signed[1], airgapYaml, testFile{images.name, []byte("more images")}),
+			publicKey: testPublicKey(t, key),
+			wantErr:   "airgap bundle member embedded-cluster/images-amd64.tar is 11 bytes, 6 bytes expected",
+		},
+		{
+			name:      "unlisted member",
+			bundle:    testTarGz(t, append(signed, testFile{"extra", []byte("extra")})...),
+			publicKey: testPublicKey(t, key),
+			wantErr:   "airgap bundle member extra is not listed in its manifest",
+		},
+		{
+			name:      "missing member",
+			bundle:    testTarGz(t, signed[0], signed[1], airgapYaml),
+			publicKey: testPublicKey(t, key),
+			wantErr:   "airgap bundle is incomplete, 1 files listed in its manifest are missing: embedded-cluster/images-amd64.tar",
+		},
+		{
+			name:      "truncated bundle",
+			bundle:    bundle[:len(bundle)-40],
+			publicKey: testPublicKey(t, key),
+			wantErr:   "airgap bundle is truncated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := Verify(bytes.NewReader(tt.bundle), tt.publicKey)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantFiles == 0 {
+				assert.Nil(t, manifest)
+				return
+			}
+			require.NotNil(t, manifest)
+			assert.Len(t, manifest.Files, tt.wantFiles)
+		})
+	}
+}
+
+func Test_materializeAirgapVerified(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	charts := testFile{"embedded-cluster/charts.tar.gz", testTarGz(t, testFile{"chart.tgz", []byte("chart")})}
+	images := testFile{"embedded-cluster/images-amd64.tar", []byte("images")}
+	app := testFile{"airgap.yaml", []byte("kind: Airgap")}
+	publicKey := testPublicKey(t, key)
+
+	runtimeconfig.SetDataDir(t.TempDir())
+	dst := filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), "images", "images-amd64.tar")
+
+	// a corrupted image bundle is not left behind.
+	corrupted := testSignedFiles(t, key, images, charts, app)
+	corrupted[2] = testFile{images.name, []byte("imagez")}
+	err = materializeAirgap(bytes.NewReader(testTarGz(t, corrupted...)), publicKey, "amd64")
+	assert.ErrorContains(t, err, "is corrupted")
+	assert.NoFileExists(t, dst)
+
+	// charts from a corrupted archive are not placed on disk. the archive is still valid,
+	// only the modification time in its gzip header is changed.
+	tampered := append([]byte{}, charts.content...)
+	tampered[4] = 1
+	corrupted = testSignedFiles(t, key, images, charts, app)
+	corrupted[3] = testFile{charts.name, tampered}
+	err = materializeAirgap(bytes.NewReader(testTarGz(t, corrupted...)), publicKey, "amd64")
+	assert.ErrorContains(t, err, "airgap bundle member embedded-cluster/charts.tar.gz is corrupted")
+	assert.NoFileExists(t, filepath.Join(runtimeconfig.EmbeddedClusterChartsSubDir(), "chart.tgz"))
+
+	// members after the embedded cluster files are verified too.
+	corrupted = testSignedFiles(t, key, images, charts, app)
+	corrupted[4] = testFile{app.name, []byte("kind: Airgaq")}
+	err = materializeAirgap(bytes.NewReader(testTarGz(t, corrupted...)), publicKey, "amd64")
+	assert.ErrorContains(t, err, "airgap bundle member airgap.yaml is corrupted")
+
+	bundle := testTarGz(t, testSignedFiles(t, key, images, charts, app)...)
+	err = materializeAirgap(bytes.NewReader(bundle), publicKey, "amd64")
+	require.NoError(t, err)
+	got, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "images", string(got))
+
+	// an image bundle already in place is not written again.
+	info, err := os.Stat(dst)
+	require.NoError(t, err)
+	err = materializeAirgap(bytes.NewReader(bundle), publicKey, "amd64")
+	require.NoError(t, err)
+	again, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), again.ModTime())
+}
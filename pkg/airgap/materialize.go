@@ -7,55 +7,159 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/sirupsen/logrus"
 )
 
-const K0sImagePath = "images/images-amd64.tar"
+// K0sImagePath is the path, relative to the k0s directory, where the image bundle for the host
+// architecture is placed.
+const K0sImagePath = "images/images-" + runtime.GOARCH + ".tar"
 
 // MaterializeAirgap places the airgap image bundle for k0s and the embedded cluster charts on disk.
-// - image bundle should be located at 'images-amd64.tar' within the embedded-cluster directory within the airgap bundle.
-// - charts should be located at 'charts.tar.gz' within the embedded-cluster directory within the airgap bundle.
-func MaterializeAirgap(airgapReader io.Reader) error {
-	// decompress tarball
-	ungzip, err := gzip.NewReader(airgapReader)
+// Airgap bundles may carry artifacts for several architectures, only the ones for the host
+// architecture are placed on disk.
+// - image bundle should be located at 'images-<arch>.tar' within the embedded-cluster directory within the airgap bundle.
+// - charts should be located at 'charts-<arch>.tar.gz' or, if the charts do not depend on the architecture, at
+// 'charts.tar.gz' within the embedded-cluster directory within the airgap bundle.
+// Bundles carrying a manifest are read to the end so all their members are verified while the
+// files are placed, see Verify. An image bundle already on disk with the expected digest, left
+// by a previous attempt, is not written again.
+func MaterializeAirgap(airgapReader io.Reader, publicKey string) error {
+	return materializeAirgap(airgapReader, publicKey, runtime.GOARCH)
+}
+
+func materializeAirgap(airgapReader io.Reader, publicKey string, arch string) error {
+	br, err := newBundleReader(airgapReader, publicKey)
 	if err != nil {
-		return fmt.Errorf("failed to decompress airgap file: %w", err)
+		return err
 	}
 
+	imagesName := fmt.Sprintf("embedded-cluster/images-%s.tar", arch)
+	archChartsName := fmt.Sprintf("embedded-cluster/charts-%s.tar.gz", arch)
+
 	// iterate through tarball
-	tarreader := tar.NewReader(ungzip)
-	foundCharts, foundImages := false, false
-	var nextFile *tar.Header
+	foundImages, foundArchCharts, foundCharts := false, false, false
 	for {
-		nextFile, err = tarreader.Next()
+		nextFile, reader, err := br.Next()
 		if err != nil {
 			if err == io.EOF {
-				return fmt.Errorf("embedded-cluster.tar.gz not found in airgap file")
+				break
 			}
-			return fmt.Errorf("failed to read airgap file: %w", err)
+			return err
+		}
+
+		// the embedded-cluster directory is written as a whole to the bundle, once we are
+		// past it there are no charts for the host architecture to look for. bundles with
+		// a manifest are read to the end to be verified.
+		if br.manifest == nil && foundImages && foundCharts && !strings.HasPrefix(nextFile.Name, "embedded-cluster/") {
+			return nil
 		}
 
-		if nextFile.Name == "embedded-cluster/images-amd64.tar" {
-			err = writeOneFile(tarreader, filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), K0sImagePath), nextFile.Mode)
-			if err != nil {
-				return fmt.Errorf("failed to write k0s images file: %w", err)
+		switch nextFile.Name {
+		case imagesName:
+			if err := materializeImages(br, reader, nextFile, arch); err != nil {
+				return err
 			}
 			foundImages = true
-		}
 
-		if nextFile.Name == "embedded-cluster/charts.tar.gz" {
-			err = writeChartFiles(tarreader)
-			if err != nil {
-				return fmt.Errorf("failed to write chart files: %w", err)
+		case archChartsName:
+			// charts for the host architecture take precedence over the generic ones, if
+			// those were already written they are overwritten here.
+			if err := materializeCharts(br, reader); err != nil {
+				return err
+			}
+			foundArchCharts = true
+
+		case "embedded-cluster/charts.tar.gz":
+			if foundArchCharts {
+				continue
+			}
+			if err := materializeCharts(br, reader); err != nil {
+				return err
 			}
 			foundCharts = true
 		}
 
-		if foundCharts && foundImages {
+		// the charts for the host architecture may come after the generic ones so we can
+		// only stop early once they were found.
+		if br.manifest == nil && foundImages && foundArchCharts {
 			return nil
 		}
 	}
+
+	if !foundImages {
+		return fmt.Errorf("images-%s.tar not found in airgap file, the bundle may not support the %s architecture", arch, arch)
+	}
+	if !foundCharts && !foundArchCharts {
+		return fmt.Errorf("charts.tar.gz not found in airgap file")
+	}
+	return nil
+}
+
+// materializeImages writes the k0s image bundle to disk, unless it is already there, and
+// verifies it.
+func materializeImages(br *bundleReader, reader io.Reader, hdr *tar.Header, arch string) error {
+	dst := filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), fmt.Sprintf("images/images-%s.tar", arch))
+
+	if br.manifest != nil {
+		matches, err := fileMatches(dst, br.manifest.Files[hdr.Name])
+		if err != nil {
+			return fmt.Errorf("failed to check existing k0s images file: %w", err)
+		}
+		if matches {
+			logrus.Debugf("K0s images file %s already materialized, skipping", dst)
+			return br.finishCurrent()
+		}
+	}
+
+	if err := writeOneFile(reader, dst, hdr.Mode); err != nil {
+		return readError(fmt.Errorf("failed to write k0s images file: %w", err))
+	}
+	if err := br.finishCurrent(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// materializeCharts extracts the charts to a temporary directory and, once the charts archive
+// is verified, moves them to the charts directory. Charts from a corrupted archive are never
+// placed on disk.
+func materializeCharts(br *bundleReader, reader io.Reader) error {
+	chartsDir := runtimeconfig.EmbeddedClusterChartsSubDir()
+	tmpdir, err := os.MkdirTemp(filepath.Dir(chartsDir), ".charts-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp charts dir: %w", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := writeChartFiles(reader, tmpdir); err != nil {
+		return readError(fmt.Errorf("failed to write chart files: %w", err))
+	}
+	if err := br.finishCurrent(); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(tmpdir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(tmpdir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(chartsDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("failed to create chart directory: %w", err)
+		}
+		if err := os.Rename(path, dst); err != nil {
+			return fmt.Errorf("failed to move chart file: %w", err)
+		}
+		return nil
+	})
 }
 
 func writeOneFile(reader io.Reader, path string, mode int64) error {
@@ -81,8 +185,8 @@ func writeOneFile(reader io.Reader, path string, mode int64) error {
 	return nil
 }
 
-// take in a stream of a tarball and write the charts contained within to disk
-func writeChartFiles(reader io.Reader) error {
+// take in a stream of a tarball and write the charts contained within to dir
+func writeChartFiles(reader io.Reader, dir string) error {
 	// decompress tarball
 	ungzip, err := gzip.NewReader(reader)
 	if err != nil {
@@ -105,8 +209,10 @@ func writeChartFiles(reader io.Reader) error {
 			continue
 		}
 
-		subdir := runtimeconfig.EmbeddedClusterChartsSubDir()
-		dst := filepath.Join(subdir, nextFile.Name)
+		if !filepath.IsLocal(nextFile.Name) {
+			return fmt.Errorf("invalid chart file path %s", nextFile.Name)
+		}
+		dst := filepath.Join(dir, nextFile.Name)
 		if err := writeOneFile(tarreader, dst, nextFile.Mode); err != nil {
 			return fmt.Errorf("failed to write chart file: %w", err)
 		}
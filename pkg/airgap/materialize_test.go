@@ -0,0 +1,113 @@
+package airgap
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFile struct {
+	name    string
+	content []byte
+}
+
+func testTarGz(t *testing.T, files ...testFile) []byte {
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))})
+		require.NoError(t, err)
+		_, err = tw.Write(f.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func Test_materializeAirgap(t *testing.T) {
+	genericCharts := testFile{"embedded-cluster/charts.tar.gz", testTarGz(t, testFile{"chart.tgz", []byte("generic")})}
+	arm64Charts := testFile{"embedded-cluster/charts-arm64.tar.gz", testTarGz(t, testFile{"chart.tgz", []byte("arm64")})}
+	amd64Images := testFile{"embedded-cluster/images-amd64.tar", []byte("amd64 images")}
+	arm64Images := testFile{"embedded-cluster/images-arm64.tar", []byte("arm64 images")}
+	app := testFile{"airgap.yaml", []byte("kind: Airgap")}
+
+	tests := []struct {
+		name       string
+		files      []testFile
+		arch       string
+		wantImages string
+		wantChart  string
+		wantErr    string
+	}{
+		{
+			name:       "single architecture bundle",
+			files:      []testFile{amd64Images, genericCharts, app},
+			arch:       "amd64",
+			wantImages: "amd64 images",
+			wantChart:  "generic",
+		},
+		{
+			name:       "multi architecture bundle with generic charts",
+			files:      []testFile{amd64Images, arm64Images, genericCharts, app},
+			arch:       "arm64",
+			wantImages: "arm64 images",
+			wantChart:  "generic",
+		},
+		{
+			name:       "charts for the architecture after the generic ones",
+			files:      []testFile{genericCharts, arm64Charts, amd64Images, arm64Images, app},
+			arch:       "arm64",
+			wantImages: "arm64 images",
+			wantChart:  "arm64",
+		},
+		{
+			name:       "charts for the architecture before the generic ones",
+			files:      []testFile{arm64Charts, genericCharts, amd64Images, arm64Images, app},
+			arch:       "arm64",
+			wantImages: "arm64 images",
+			wantChart:  "arm64",
+		},
+		{
+			name:       "charts for other architectures are ignored",
+			files:      []testFile{arm64Charts, genericCharts, amd64Images, arm64Images, app},
+			arch:       "amd64",
+			wantImages: "amd64 images",
+			wantChart:  "generic",
+		},
+		{
+			name:    "architecture not in bundle",
+			files:   []testFile{amd64Images, genericCharts, app},
+			arch:    "arm64",
+			wantErr: "images-arm64.tar not found in airgap file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtimeconfig.SetDataDir(t.TempDir())
+
+			err := materializeAirgap(bytes.NewReader(testTarGz(t, tt.files...)), "", tt.arch)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			images, err := os.ReadFile(filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), "images", "images-"+tt.arch+".tar"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantImages, string(images))
+
+			chart, err := os.ReadFile(filepath.Join(runtimeconfig.EmbeddedClusterChartsSubDir(), "chart.tgz"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChart, string(chart))
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package artifacts
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 
+	"oras.land/oras-go/v2/registry/remote"
 	"oras.land/oras-go/v2/registry/remote/auth"
 	"oras.land/oras-go/v2/registry/remote/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
@@ -27,3 +31,24 @@ func newInsecureAuthClient() *auth.Client {
 		Cache: auth.DefaultCache,
 	}
 }
+
+// NewRegistry returns a client for the registry at the given address, authenticated with the
+// credentials read from the 'registry-creds' secret.
+func NewRegistry(ctx context.Context, cli client.Client, address string, plainHTTP bool) (*remote.Registry, error) {
+	reg, err := remote.NewRegistry(address)
+	if err != nil {
+		return nil, fmt.Errorf("new registry: %w", err)
+	}
+
+	authClient := newInsecureAuthClient()
+
+	store, err := registryAuth(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("get registry auth: %w", err)
+	}
+	authClient.Credential = store.Get
+
+	reg.Client = authClient
+	reg.PlainHTTP = plainHTTP
+	return reg, nil
+}
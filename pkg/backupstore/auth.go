@@ -0,0 +1,506 @@
+package backupstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	signatureAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat      = "20060102T150405Z"
+	// maxClockSkew is how far the signing time of a request can be from the server time.
+	maxClockSkew = 15 * time.Minute
+
+	// unsignedPayload is the payload hash of requests whose body is not signed.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// streamingSignedPayload is the payload hash of requests sent in aws-chunked encoding
+	// with a signature per chunk.
+	streamingSignedPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	// streamingUnsignedPayloadTrailer is the payload hash of requests sent in aws-chunked
+	// encoding with a checksum of the payload in the trailers.
+	streamingUnsignedPayloadTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	// chunkSignatureAlgorithm prefixes the string signed for each chunk of a payload.
+	chunkSignatureAlgorithm = "AWS4-HMAC-SHA256-PAYLOAD"
+)
+
+// signature holds the signature of a request and what it was computed with.
+type signature struct {
+	accessKeyID   string
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+	signingTime   time.Time
+}
+
+// authenticate verifies the AWS signature version 4 of the request, either in the
+// authorization header or in the query string of a presigned url. The body of the request is
+// replaced by its decoded payload, which fails to be read to the end if it does not match the
+// signed payload hash.
+func (s *Server) authenticate(r *http.Request) error {
+	query := r.URL.Query()
+	if query.Has("X-Amz-Algorithm") {
+		return s.authenticatePresigned(r, query)
+	}
+
+	sig, err := parseAuthorization(r.Header.Get("Authorization"), r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+	if err := s.checkSignature(sig); err != nil {
+		return err
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return newS3Error(http.StatusBadRequest, "InvalidRequest", "missing x-amz-content-sha256 header")
+	}
+
+	req, err := signedRequest(r, r.URL, sig.signedHeaders)
+	if err != nil {
+		return err
+	}
+	signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+	if err := signer.SignHTTP(context.Background(), s.credentials(), req, payloadHash, sig.service, sig.region, sig.signingTime); err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+
+	expected, err := parseAuthorization(req.Header.Get("Authorization"), req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return fmt.Errorf("parse expected signature: %w", err)
+	}
+	if !hmac.Equal([]byte(expected.signature), []byte(sig.signature)) {
+		return newS3Error(http.StatusForbidden, "SignatureDoesNotMatch", "the request signature does not match")
+	}
+
+	body, err := s.payloadReader(r, sig, payloadHash)
+	if err != nil {
+		return err
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+	return nil
+}
+
+func (s *Server) authenticatePresigned(r *http.Request, query url.Values) error {
+	if algorithm := query.Get("X-Amz-Algorithm"); algorithm != signatureAlgorithm {
+		return newS3Error(http.StatusBadRequest, "InvalidArgument", "unsupported algorithm %q", algorithm)
+	}
+	sig, err := parseCredential(query.Get("X-Amz-Credential"), query.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+	sig.signedHeaders = strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	sig.signature = query.Get("X-Amz-Signature")
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 {
+		return newS3Error(http.StatusBadRequest, "AuthorizationQueryParametersError", "invalid X-Amz-Expires")
+	}
+	if s.now().After(sig.signingTime.Add(time.Duration(expires) * time.Second)) {
+		return newS3Error(http.StatusForbidden, "AccessDenied", "request has expired")
+	}
+	if err := s.checkAccessKey(sig); err != nil {
+		return err
+	}
+
+	// the signer adds back all the authentication parameters but the expiration.
+	unsigned := *r.URL
+	q := r.URL.Query()
+	for _, key := range []string{"X-Amz-Algorithm", "X-Amz-Credential", "X-Amz-Date", "X-Amz-SignedHeaders", "X-Amz-Signature"} {
+		q.Del(key)
+	}
+	unsigned.RawQuery = q.Encode()
+
+	req, err := signedRequest(r, &unsigned, sig.signedHeaders)
+	if err != nil {
+		return err
+	}
+	signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+	signedURL, _, err := signer.PresignHTTP(context.Background(), s.credentials(), req, "UNSIGNED-PAYLOAD", sig.service, sig.region, sig.signingTime)
+	if err != nil {
+		return fmt.Errorf("presign request: %w", err)
+	}
+
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		return fmt.Errorf("parse presigned url: %w", err)
+	}
+	if !hmac.Equal([]byte(u.Query().Get("X-Amz-Signature")), []byte(sig.signature)) {
+		return newS3Error(http.StatusForbidden, "SignatureDoesNotMatch", "the request signature does not match")
+	}
+	return nil
+}
+
+func (s *Server) credentials() aws.Credentials {
+	return aws.Credentials{AccessKeyID: s.accessKeyID, SecretAccessKey: s.secretAccessKey}
+}
+
+// checkSignature checks the access key and the signing time of a request signed in the
+// authorization header.
+func (s *Server) checkSignature(sig *signature) error {
+	if err := s.checkAccessKey(sig); err != nil {
+		return err
+	}
+	if skew := s.now().Sub(sig.signingTime); skew > maxClockSkew || skew < -maxClockSkew {
+		return newS3Error(http.StatusForbidden, "RequestTimeTooSkewed", "the difference between the request time and the server time is too large")
+	}
+	return nil
+}
+
+func (s *Server) checkAccessKey(sig *signature) error {
+	if !hmac.Equal([]byte(sig.accessKeyID), []byte(s.accessKeyID)) {
+		return newS3Error(http.StatusForbidden, "InvalidAccessKeyId", "the access key id does not exist")
+	}
+	return nil
+}
+
+// signedRequest returns a copy of the request holding only what is covered by the signature,
+// so it can be signed again with the server credentials.
+func signedRequest(r *http.Request, u *url.URL, signedHeaders []string) (*http.Request, error) {
+	req, err := http.NewRequest(r.Method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.URL.RawPath = u.RawPath
+	req.Host = r.Host
+	for _, h := range signedHeaders {
+		switch h {
+		case "host":
+		case "content-length":
+			req.ContentLength = r.ContentLength
+		default:
+			values := r.Header.Values(h)
+			if len(values) == 0 {
+				return nil, newS3Error(http.StatusBadRequest, "AccessDenied", "signed header %s not found", h)
+			}
+			req.Header[http.CanonicalHeaderKey(h)] = values
+		}
+	}
+	return req, nil
+}
+
+// parseAuthorization parses an authorization header in the
+// "AWS4-HMAC-SHA256 Credential=..., SignedHeaders=..., Signature=..." format.
+func parseAuthorization(header, date string) (*signature, error) {
+	value, ok := strings.CutPrefix(header, signatureAlgorithm+" ")
+	if !ok {
+		return nil, newS3Error(http.StatusForbidden, "AccessDenied", "requests must be signed with %s", signatureAlgorithm)
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Split(value, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(field), "=")
+		fields[k] = v
+	}
+	sig, err := parseCredential(fields["Credential"], date)
+	if err != nil {
+		return nil, err
+	}
+	sig.signedHeaders = strings.Split(fields["SignedHeaders"], ";")
+	sig.signature = fields["Signature"]
+	if sig.signature == "" || fields["SignedHeaders"] == "" {
+		return nil, newS3Error(http.StatusBadRequest, "AuthorizationHeaderMalformed", "missing signature or signed headers")
+	}
+	return sig, nil
+}
+
+// parseCredential parses a credential in the "key/date/region/service/aws4_request" format.
+func parseCredential(credential, date string) (*signature, error) {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return nil, newS3Error(http.StatusBadRequest, "AuthorizationHeaderMalformed", "invalid credential %q", credential)
+	}
+	signingTime, err := time.Parse(amzDateFormat, date)
+	if err != nil {
+		return nil, newS3Error(http.StatusBadRequest, "AuthorizationHeaderMalformed", "invalid date %q", date)
+	}
+	return &signature{
+		accessKeyID: parts[0],
+		region:      parts[2],
+		service:     parts[3],
+		signingTime: signingTime,
+	}, nil
+}
+
+// payloadReader returns a reader of the payload of a request signed in the authorization
+// header. Payloads sent in a single piece are verified against the signed sha256 digest,
+// payloads sent in aws-chunked encoding against the signature of each chunk or against the
+// checksum in their trailers. The error is returned once the whole payload is read, so the
+// objects written from it must only be stored once it is.
+func (s *Server) payloadReader(r *http.Request, sig *signature, payloadHash string) (io.Reader, error) {
+	switch payloadHash {
+	case unsignedPayload:
+		return r.Body, nil
+	case streamingSignedPayload:
+		return &chunkedReader{
+			r: bufio.NewReader(r.Body),
+			signer: &chunkSigner{
+				key:           signingKey(s.secretAccessKey, sig),
+				date:          sig.signingTime.Format(amzDateFormat),
+				scope:         credentialScope(sig),
+				prevSignature: sig.signature,
+			},
+		}, nil
+	case streamingUnsignedPayloadTrailer:
+		checksum, err := newTrailerChecksum(r.Header.Get("X-Amz-Trailer"))
+		if err != nil {
+			return nil, err
+		}
+		return &chunkedReader{r: bufio.NewReader(r.Body), checksum: checksum}, nil
+	}
+	if strings.HasPrefix(payloadHash, "STREAMING-") {
+		return nil, newS3Error(http.StatusNotImplemented, "NotImplemented", "payload %s is not supported", payloadHash)
+	}
+
+	expected, err := hex.DecodeString(payloadHash)
+	if err != nil || len(expected) != sha256.Size {
+		return nil, newS3Error(http.StatusBadRequest, "InvalidArgument", "invalid x-amz-content-sha256 header %q", payloadHash)
+	}
+	return &payloadHashReader{r: r.Body, hash: sha256.New(), expected: expected}, nil
+}
+
+// payloadHashReader reads a payload and fails at its end if its sha256 digest is not the
+// expected one.
+type payloadHashReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected []byte
+}
+
+func (h *payloadHashReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(h.hash.Sum(nil), h.expected) {
+		return n, newS3Error(http.StatusBadRequest, "XAmzContentSHA256Mismatch", "the payload does not match the x-amz-content-sha256 header")
+	}
+	return n, err
+}
+
+// signingKey derives the key the request signature and the chunk signatures are computed with.
+func signingKey(secretAccessKey string, sig *signature) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), sig.signingTime.Format("20060102"))
+	for _, value := range []string{sig.region, sig.service, "aws4_request"} {
+		key = hmacSHA256(key, value)
+	}
+	return key
+}
+
+func credentialScope(sig *signature) string {
+	return strings.Join([]string{sig.signingTime.Format("20060102"), sig.region, sig.service, "aws4_request"}, "/")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// chunkSigner computes the signatures of the chunks of a payload. Each chunk signature covers
+// the chunk data and the signature of the previous chunk, starting with the request signature.
+type chunkSigner struct {
+	key           []byte
+	date          string
+	scope         string
+	prevSignature string
+}
+
+// verify checks the signature of the next chunk, given the sha256 digest of its data.
+func (c *chunkSigner) verify(signature string, chunkHash []byte) error {
+	emptyHash := sha256.Sum256(nil)
+	stringToSign := strings.Join([]string{
+		chunkSignatureAlgorithm,
+		c.date,
+		c.scope,
+		c.prevSignature,
+		hex.EncodeToString(emptyHash[:]),
+		hex.EncodeToString(chunkHash),
+	}, "\n")
+	expected := hex.EncodeToString(hmacSHA256(c.key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return newS3Error(http.StatusForbidden, "SignatureDoesNotMatch", "the chunk signature does not match")
+	}
+	c.prevSignature = signature
+	return nil
+}
+
+// trailerChecksum is the checksum of a payload sent in its trailers.
+type trailerChecksum struct {
+	name string
+	hash hash.Hash
+}
+
+// newTrailerChecksum returns the checksum named in the x-amz-trailer header.
+func newTrailerChecksum(trailer string) (*trailerChecksum, error) {
+	name := strings.ToLower(strings.TrimSpace(trailer))
+	var h hash.Hash
+	switch name {
+	case "x-amz-checksum-crc32":
+		h = crc32.NewIEEE()
+	case "x-amz-checksum-crc32c":
+		h = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "x-amz-checksum-crc64nvme":
+		h = crc64.New(crc64.MakeTable(0x9a6c9329ac4bc9b5))
+	case "x-amz-checksum-sha1":
+		h = sha1.New()
+	case "x-amz-checksum-sha256":
+		h = sha256.New()
+	default:
+		return nil, newS3Error(http.StatusBadRequest, "InvalidRequest", "unsupported x-amz-trailer %q", trailer)
+	}
+	return &trailerChecksum{name: name, hash: h}, nil
+}
+
+// verify checks the trailers of the payload hold its checksum.
+func (c *trailerChecksum) verify(trailers map[string]string) error {
+	value, ok := trailers[c.name]
+	if !ok {
+		return newS3Error(http.StatusBadRequest, "InvalidRequest", "missing trailer %s", c.name)
+	}
+	if value != base64.StdEncoding.EncodeToString(c.hash.Sum(nil)) {
+		return newS3Error(http.StatusBadRequest, "BadDigest", "the payload does not match the %s trailer", c.name)
+	}
+	return nil
+}
+
+// chunkedReader decodes a body in the aws-chunked encoding, made of "size[;extensions]\r\n"
+// lines each followed by that many bytes and "\r\n", ending with a zero size chunk and the
+// trailers. If a signer is set, each chunk must hold a chunk-signature extension matching its
+// data. If a checksum is set, it must be found in the trailers.
+type chunkedReader struct {
+	r        *bufio.Reader
+	signer   *chunkSigner
+	checksum *trailerChecksum
+
+	left      int64
+	done      bool
+	signature string
+	chunkHash hash.Hash
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.left == 0 {
+		if err := c.readChunkHeader(); err != nil {
+			return 0, err
+		}
+		if c.done {
+			return 0, io.EOF
+		}
+	}
+
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	c.chunkHash.Write(p[:n])
+	if c.checksum != nil {
+		c.checksum.hash.Write(p[:n])
+	}
+	if err == io.EOF {
+		return n, newS3Error(http.StatusBadRequest, "IncompleteBody", "unexpected end of chunk")
+	} else if err != nil {
+		return n, err
+	}
+	if c.left == 0 {
+		if _, err := c.r.Discard(2); err != nil {
+			return n, fmt.Errorf("read chunk end: %w", err)
+		}
+		if err := c.verifyChunk(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// readChunkHeader reads the size and the signature of the next chunk. The zero size chunk is
+// verified right away and is followed by the trailers.
+func (c *chunkedReader) readChunkHeader() error {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read chunk header: %w", err)
+	}
+	size, extensions, _ := strings.Cut(strings.TrimSpace(line), ";")
+	n, err := strconv.ParseInt(size, 16, 64)
+	if err != nil || n < 0 {
+		return newS3Error(http.StatusBadRequest, "IncompleteBody", "invalid chunk size %q", size)
+	}
+	c.signature = ""
+	for _, ext := range strings.Split(extensions, ";") {
+		if value, ok := strings.CutPrefix(ext, "chunk-signature="); ok {
+			c.signature = value
+		}
+	}
+	c.left = n
+	c.chunkHash = sha256.New()
+	if n > 0 {
+		return nil
+	}
+
+	if err := c.verifyChunk(); err != nil {
+		return err
+	}
+	if c.checksum != nil {
+		trailers, err := c.readTrailers()
+		if err != nil {
+			return err
+		}
+		if err := c.checksum.verify(trailers); err != nil {
+			return err
+		}
+	}
+	c.done = true
+	return nil
+}
+
+func (c *chunkedReader) verifyChunk() error {
+	if c.signer == nil {
+		return nil
+	}
+	if c.signature == "" {
+		return newS3Error(http.StatusForbidden, "SignatureDoesNotMatch", "missing chunk signature")
+	}
+	return c.signer.verify(c.signature, c.chunkHash.Sum(nil))
+}
+
+// readTrailers reads the "name:value\r\n" trailers up to the empty line ending the body.
+func (c *chunkedReader) readTrailers() (map[string]string, error) {
+	trailers := map[string]string{}
+	for {
+		line, err := c.r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line != "" {
+			name, value, _ := strings.Cut(line, ":")
+			trailers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+		if err == io.EOF || (err == nil && line == "") {
+			return trailers, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read trailers: %w", err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package backupstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// AccessKeyIDEnv and SecretAccessKeyEnv are the environment variables holding the
+	// credentials the server accepts.
+	AccessKeyIDEnv     = "BACKUP_STORE_ACCESS_KEY_ID"
+	SecretAccessKeyEnv = "BACKUP_STORE_SECRET_ACCESS_KEY"
+
+	name      = "backup-store"
+	namespace = runtimeconfig.VeleroNamespace
+	mountPath = "/backups"
+
+	accessKeyIDKey     = "access-key-id"
+	secretAccessKeyKey = "secret-access-key"
+)
+
+// DeployOptions are the options to run the server in the cluster.
+type DeployOptions struct {
+	Store *Store
+	// Image is the embedded cluster operator image, which embeds the server.
+	Image string
+	// NodeName is the node holding the directory of a file store.
+	NodeName string
+}
+
+// Deployment holds what is needed to reach the server running in the cluster.
+type Deployment struct {
+	// Endpoint is the url of the server from within the cluster.
+	Endpoint string
+	// ServiceEndpoint is the url of the server through its cluster ip, reachable from the
+	// nodes.
+	ServiceEndpoint string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Deploy runs the server in the velero namespace, serving the directory of the store, and
+// waits for it to be ready. The credentials are generated once and kept across calls.
+func Deploy(ctx context.Context, kcli client.Client, opts DeployOptions) (*Deployment, error) {
+	if opts.Store.Type == TypeFile && opts.NodeName == "" {
+		return nil, fmt.Errorf("node name is required for file backup stores")
+	}
+
+	accessKeyID, secretAccessKey, err := ensureSecret(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("ensure secret: %w", err)
+	}
+	if err := ensureDeployment(ctx, kcli, opts); err != nil {
+		return nil, fmt.Errorf("ensure deployment: %w", err)
+	}
+	svc, err := ensureService(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("ensure service: %w", err)
+	}
+
+	if err := kubeutils.WaitForDeployment(ctx, kcli, namespace, name, nil); err != nil {
+		return nil, fmt.Errorf("wait for deployment: %w", err)
+	}
+
+	return &Deployment{
+		Endpoint:        fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", name, namespace, Port),
+		ServiceEndpoint: fmt.Sprintf("http://%s:%d", svc.Spec.ClusterIP, Port),
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+	}, nil
+}
+
+func ensureSecret(ctx context.Context, kcli client.Client) (string, string, error) {
+	var secret corev1.Secret
+	err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret)
+	if err == nil {
+		return string(secret.Data[accessKeyIDKey]), string(secret.Data[secretAccessKeyKey]), nil
+	} else if !k8serrors.IsNotFound(err) {
+		return "", "", fmt.Errorf("get secret: %w", err)
+	}
+
+	accessKeyID, err := randomID()
+	if err != nil {
+		return "", "", err
+	}
+	secretAccessKey, err := randomID()
+	if err != nil {
+		return "", "", err
+	}
+
+	secret = corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels(),
+		},
+		Data: map[string][]byte{
+			accessKeyIDKey:     []byte(accessKeyID),
+			secretAccessKeyKey: []byte(secretAccessKey),
+		},
+	}
+	if err := kcli.Create(ctx, &secret); err != nil {
+		return "", "", fmt.Errorf("create secret: %w", err)
+	}
+	return accessKeyID, secretAccessKey, nil
+}
+
+func ensureDeployment(ctx context.Context, kcli client.Client, opts DeployOptions) error {
+	obj := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kcli, obj, func() error {
+		obj.Labels = labels()
+		obj.Spec.Replicas = ptr.To[int32](1)
+		// the directory must not be served by two servers at once.
+		obj.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+		obj.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels()}
+		obj.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels()},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:    "backup-store",
+					Image:   opts.Image,
+					Command: []string{"/manager", "serve-backup-store", "--dir", mountPath},
+					Env: []corev1.EnvVar{
+						secretEnv(AccessKeyIDEnv, accessKeyIDKey),
+						secretEnv(SecretAccessKeyEnv, secretAccessKeyKey),
+					},
+					Ports: []corev1.ContainerPort{{Name: "s3", ContainerPort: Port}},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{
+							TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(Port)},
+						},
+					},
+					VolumeMounts: []corev1.VolumeMount{{Name: "backups", MountPath: mountPath}},
+				}},
+				Volumes: []corev1.Volume{{Name: "backups", VolumeSource: volumeSource(opts.Store)}},
+			},
+		}
+		if opts.Store.Type == TypeFile {
+			// the directory is only available on the node the restore is run from.
+			obj.Spec.Template.Spec.NodeName = opts.NodeName
+		}
+		return nil
+	})
+	return err
+}
+
+func ensureService(ctx context.Context, kcli client.Client) (*corev1.Service, error) {
+	obj := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, kcli, obj, func() error {
+		obj.Labels = labels()
+		obj.Spec.Type = corev1.ServiceTypeClusterIP
+		obj.Spec.Selector = labels()
+		obj.Spec.Ports = []corev1.ServicePort{{
+			Name:       "s3",
+			Port:       Port,
+			TargetPort: intstr.FromString("s3"),
+			Protocol:   corev1.ProtocolTCP,
+		}}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func volumeSource(store *Store) corev1.VolumeSource {
+	if store.Type == TypeNFS {
+		return corev1.VolumeSource{
+			NFS: &corev1.NFSVolumeSource{Server: store.Server, Path: store.Path},
+		}
+	}
+	return corev1.VolumeSource{
+		HostPath: &corev1.HostPathVolumeSource{
+			Path: store.Path,
+			Type: ptr.To(corev1.HostPathDirectory),
+		},
+	}
+}
+
+func secretEnv(env, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: env,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+func labels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/managed-by": "embedded-cluster",
+	}
+}
@@ -0,0 +1,78 @@
+package backupstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeploy(t *testing.T) {
+	ctx := context.Background()
+	kcli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		// the fake client neither runs pods nor allocates cluster ips.
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.50"},
+		},
+	).Build()
+
+	_, err := Deploy(ctx, kcli, DeployOptions{
+		Store: &Store{Type: TypeFile, Path: "/mnt/backups"},
+		Image: "operator:latest",
+	})
+	assert.ErrorContains(t, err, "node name is required")
+
+	got, err := Deploy(ctx, kcli, DeployOptions{
+		Store:    &Store{Type: TypeFile, Path: "/mnt/backups"},
+		Image:    "operator:latest",
+		NodeName: "node-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://backup-store.velero.svc.cluster.local:9000", got.Endpoint)
+	assert.Equal(t, "http://10.96.0.50:9000", got.ServiceEndpoint)
+	assert.NotEmpty(t, got.AccessKeyID)
+	assert.NotEmpty(t, got.SecretAccessKey)
+	assert.NotEqual(t, got.AccessKeyID, got.SecretAccessKey)
+
+	var deploy appsv1.Deployment
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &deploy))
+	podSpec := deploy.Spec.Template.Spec
+	assert.Equal(t, "node-1", podSpec.NodeName)
+	require.Len(t, podSpec.Containers, 1)
+	assert.Equal(t, "operator:latest", podSpec.Containers[0].Image)
+	assert.Equal(t, []string{"/manager", "serve-backup-store", "--dir", "/backups"}, podSpec.Containers[0].Command)
+	require.Len(t, podSpec.Volumes, 1)
+	require.NotNil(t, podSpec.Volumes[0].HostPath)
+	assert.Equal(t, "/mnt/backups", podSpec.Volumes[0].HostPath.Path)
+
+	var svc corev1.Service
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &svc))
+	assert.Equal(t, deploy.Spec.Selector.MatchLabels, svc.Spec.Selector)
+
+	// the credentials are kept when the server is deployed again.
+	again, err := Deploy(ctx, kcli, DeployOptions{
+		Store: &Store{Type: TypeNFS, Server: "10.0.0.5", Path: "/exports/backups"},
+		Image: "operator:latest",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, got.AccessKeyID, again.AccessKeyID)
+	assert.Equal(t, got.SecretAccessKey, again.SecretAccessKey)
+
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &deploy))
+	podSpec = deploy.Spec.Template.Spec
+	assert.Empty(t, podSpec.NodeName)
+	require.Len(t, podSpec.Volumes, 1)
+	assert.Equal(t, &corev1.NFSVolumeSource{Server: "10.0.0.5", Path: "/exports/backups"}, podSpec.Volumes[0].NFS)
+}
@@ -0,0 +1,683 @@
+package backupstore
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// uploadsDir is the directory, relative to the store directory, holding the temporary files
+	// and the parts of the multipart uploads in progress. It is never listed.
+	uploadsDir = ".multipart"
+
+	defaultMaxKeys = 1000
+	// maxXMLBodySize is the maximum size of the xml documents sent in request bodies.
+	maxXMLBodySize = 1 << 20
+	s3Namespace    = "http://s3.amazonaws.com/doc/2006-03-01/"
+)
+
+// Server implements the subset of the S3 API used by the velero S3 object store plugin on top
+// of a directory. A single bucket is served and its objects are stored as regular files, so
+// backups copied to the directory by other means can be restored.
+type Server struct {
+	dir             string
+	accessKeyID     string
+	secretAccessKey string
+	now             func() time.Time
+}
+
+// NewServer returns a server storing the objects in the provided directory. Requests must be
+// signed with the provided credentials.
+func NewServer(dir, accessKeyID, secretAccessKey string) *Server {
+	return &Server{
+		dir:             dir,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		now:             time.Now,
+	}
+}
+
+// s3Error is an error returned to the client in the S3 format.
+type s3Error struct {
+	status  int
+	Code    string
+	Message string
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func newS3Error(status int, code, format string, a ...any) *s3Error {
+	return &s3Error{status: status, Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.serve(w, r); err != nil {
+		s.writeError(w, r, err)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) error {
+	if err := s.authenticate(r); err != nil {
+		return err
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		if r.Method != http.MethodGet {
+			return newS3Error(http.StatusMethodNotAllowed, "MethodNotAllowed", "method %s not allowed", r.Method)
+		}
+		return s.listBuckets(w)
+	}
+	if bucket != Bucket {
+		return newS3Error(http.StatusNotFound, "NoSuchBucket", "bucket %s does not exist", bucket)
+	}
+	if key == "" {
+		return s.serveBucket(w, r)
+	}
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.serveObject(w, r, key)
+}
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodHead:
+		return nil
+	case r.Method == http.MethodGet && query.Has("location"):
+		return writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+			Xmlns   string   `xml:"xmlns,attr"`
+		}{Xmlns: s3Namespace})
+	case r.Method == http.MethodGet:
+		return s.listObjects(w, r)
+	case r.Method == http.MethodPost && query.Has("delete"):
+		return s.deleteObjects(w, r)
+	}
+	return newS3Error(http.StatusNotImplemented, "NotImplemented", "operation not supported")
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, key string) error {
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return s.getObject(w, r, key)
+	case http.MethodPut:
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			break
+		}
+		if query.Has("uploadId") {
+			return s.uploadPart(w, r, query.Get("uploadId"), query.Get("partNumber"))
+		}
+		return s.putObject(w, r, key)
+	case http.MethodPost:
+		if query.Has("uploads") {
+			return s.createMultipartUpload(w, key)
+		}
+		if query.Has("uploadId") {
+			return s.completeMultipartUpload(w, r, key, query.Get("uploadId"))
+		}
+	case http.MethodDelete:
+		if query.Has("uploadId") {
+			return s.abortMultipartUpload(w, query.Get("uploadId"))
+		}
+		return s.deleteObject(w, key)
+	}
+	return newS3Error(http.StatusNotImplemented, "NotImplemented", "operation not supported")
+}
+
+func (s *Server) listBuckets(w http.ResponseWriter) error {
+	type bucket struct {
+		Name         string
+		CreationDate string
+	}
+	info, err := os.Stat(s.dir)
+	if err != nil {
+		return fmt.Errorf("stat store directory: %w", err)
+	}
+	return writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Buckets []bucket `xml:"Buckets>Bucket"`
+	}{
+		Xmlns:   s3Namespace,
+		Buckets: []bucket{{Name: Bucket, CreationDate: info.ModTime().UTC().Format(time.RFC3339)}},
+	})
+}
+
+type listEntry struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Marker                string         `xml:"Marker,omitempty"`
+	NextMarker            string         `xml:"NextMarker,omitempty"`
+	KeyCount              *int           `xml:"KeyCount,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	Contents              []listEntry    `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// listObjects implements both versions of the list objects operation. Keys are returned in
+// lexicographical order, grouped by the delimiter if any.
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	isV2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+
+	maxKeys := defaultMaxKeys
+	if v := query.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return newS3Error(http.StatusBadRequest, "InvalidArgument", "invalid max-keys %q", v)
+		}
+		maxKeys = min(n, defaultMaxKeys)
+	}
+
+	result := listBucketResult{
+		Xmlns:     s3Namespace,
+		Name:      Bucket,
+		Prefix:    prefix,
+		Delimiter: delimiter,
+		MaxKeys:   maxKeys,
+	}
+	after := query.Get("marker")
+	if isV2 {
+		result.ContinuationToken = query.Get("continuation-token")
+		result.StartAfter = query.Get("start-after")
+		after = max(result.ContinuationToken, result.StartAfter)
+	} else {
+		result.Marker = after
+	}
+
+	objects, err := s.walkObjects(prefix)
+	if err != nil {
+		return err
+	}
+
+	last := ""
+	for _, obj := range objects {
+		entry := obj.Key
+		if delimiter != "" {
+			if i := strings.Index(obj.Key[len(prefix):], delimiter); i >= 0 {
+				entry = obj.Key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		// a common prefix is returned once and skipped entirely when paginating past it.
+		if entry <= after || entry == last {
+			continue
+		}
+		if len(result.Contents)+len(result.CommonPrefixes) >= maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		if entry == obj.Key {
+			result.Contents = append(result.Contents, obj)
+		} else {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: entry})
+		}
+		last = entry
+	}
+
+	if isV2 {
+		count := len(result.Contents) + len(result.CommonPrefixes)
+		result.KeyCount = &count
+		if result.IsTruncated {
+			result.NextContinuationToken = last
+		}
+	} else if result.IsTruncated {
+		result.NextMarker = last
+	}
+	return writeXML(w, http.StatusOK, result)
+}
+
+// walkObjects returns all the objects whose key starts with the prefix, sorted by key.
+func (s *Server) walkObjects(prefix string) ([]listEntry, error) {
+	// only the deepest directory holding all the matching keys is walked.
+	root := s.dir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		if validateKey(prefix[:i]) != nil {
+			return nil, nil
+		}
+		root = filepath.Join(s.dir, filepath.FromSlash(prefix[:i]))
+	}
+
+	objects := []listEntry{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if key == uploadsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, listEntry{
+			Key:          key,
+			LastModified: info.ModTime().UTC().Format(time.RFC3339),
+			ETag:         fileETag(info),
+			Size:         info.Size(),
+			StorageClass: "STANDARD",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk store directory: %w", err)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects, nil
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, key string) error {
+	f, err := os.Open(s.objectPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return newS3Error(http.StatusNotFound, "NoSuchKey", "key %s does not exist", key)
+	} else if err != nil {
+		return fmt.Errorf("open object: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat object: %w", err)
+	}
+	if info.IsDir() {
+		return newS3Error(http.StatusNotFound, "NoSuchKey", "key %s does not exist", key)
+	}
+
+	w.Header().Set("ETag", fileETag(info))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	// handles range and conditional requests as well as the HEAD method.
+	http.ServeContent(w, r, "", info.ModTime(), f)
+	return nil
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, key string) error {
+	if strings.HasSuffix(key, "/") {
+		return newS3Error(http.StatusBadRequest, "InvalidArgument", "keys ending with a slash are not supported")
+	}
+	etag, err := s.writeFile(s.objectPath(key), r.Body)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, key string) error {
+	if err := s.removeObject(key); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) deleteObjects(w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		Quiet   bool `xml:"Quiet"`
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	if err := decodeXMLBody(r, &req); err != nil {
+		return err
+	}
+
+	type deleted struct {
+		Key string `xml:"Key"`
+	}
+	type deleteError struct {
+		Key     string `xml:"Key"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	result := struct {
+		XMLName xml.Name      `xml:"DeleteResult"`
+		Xmlns   string        `xml:"xmlns,attr"`
+		Deleted []deleted     `xml:"Deleted"`
+		Errors  []deleteError `xml:"Error"`
+	}{Xmlns: s3Namespace}
+
+	for _, obj := range req.Objects {
+		err := validateKey(obj.Key)
+		if err == nil {
+			err = s.removeObject(obj.Key)
+		}
+		if err != nil {
+			e := toS3Error(err)
+			result.Errors = append(result.Errors, deleteError{Key: obj.Key, Code: e.Code, Message: e.Message})
+		} else if !req.Quiet {
+			result.Deleted = append(result.Deleted, deleted{Key: obj.Key})
+		}
+	}
+	return writeXML(w, http.StatusOK, result)
+}
+
+// removeObject removes the object and the directories left empty. Removing an object that
+// does not exist is not an error.
+func (s *Server) removeObject(key string) error {
+	p := s.objectPath(key)
+	if info, err := os.Stat(p); err == nil && info.IsDir() {
+		return nil
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove object: %w", err)
+	}
+	for dir := filepath.Dir(p); dir != s.dir && strings.HasPrefix(dir, s.dir); dir = filepath.Dir(dir) {
+		// fails when the directory is not empty.
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Server) createMultipartUpload(w http.ResponseWriter, key string) error {
+	uploadID, err := randomID()
+	if err != nil {
+		return err
+	}
+	dir := s.uploadPath(uploadID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create upload directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key"), []byte(key), 0600); err != nil {
+		return fmt.Errorf("write upload key: %w", err)
+	}
+	return writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Xmlns: s3Namespace, Bucket: Bucket, Key: key, UploadID: uploadID})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, uploadID, partNumber string) error {
+	dir, err := s.getUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(partNumber)
+	if err != nil || n < 1 || n > 10000 {
+		return newS3Error(http.StatusBadRequest, "InvalidArgument", "invalid part number %q", partNumber)
+	}
+	etag, err := s.writeFile(filepath.Join(dir, strconv.Itoa(n)), r.Body)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, key, uploadID string) error {
+	dir, err := s.getUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	if stored, err := os.ReadFile(filepath.Join(dir, "key")); err != nil || string(stored) != key {
+		return newS3Error(http.StatusNotFound, "NoSuchUpload", "upload %s is not for key %s", uploadID, key)
+	}
+
+	var req struct {
+		Parts []struct {
+			PartNumber int    `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		} `xml:"Part"`
+	}
+	if err := decodeXMLBody(r, &req); err != nil {
+		return err
+	}
+	if len(req.Parts) == 0 {
+		return newS3Error(http.StatusBadRequest, "MalformedXML", "no parts provided")
+	}
+
+	files := []io.Reader{}
+	sums := md5.New()
+	for i, part := range req.Parts {
+		if i > 0 && part.PartNumber <= req.Parts[i-1].PartNumber {
+			return newS3Error(http.StatusBadRequest, "InvalidPartOrder", "parts must be in ascending order")
+		}
+		f, err := os.Open(filepath.Join(dir, strconv.Itoa(part.PartNumber)))
+		if err != nil {
+			return newS3Error(http.StatusBadRequest, "InvalidPart", "part %d not found", part.PartNumber)
+		}
+		defer f.Close()
+
+		sum := md5.New()
+		if _, err := io.Copy(sum, f); err != nil {
+			return fmt.Errorf("read part %d: %w", part.PartNumber, err)
+		}
+		if etag := hex.EncodeToString(sum.Sum(nil)); etag != strings.Trim(part.ETag, `"`) {
+			return newS3Error(http.StatusBadRequest, "InvalidPart", "part %d etag does not match", part.PartNumber)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("read part %d: %w", part.PartNumber, err)
+		}
+		sums.Write(sum.Sum(nil))
+		files = append(files, f)
+	}
+
+	if _, err := s.writeFile(s.objectPath(key), io.MultiReader(files...)); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		slog.Warn("Unable to remove completed upload", "upload", uploadID, "error", err)
+	}
+
+	return writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Location string   `xml:"Location"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		ETag     string   `xml:"ETag"`
+	}{
+		Xmlns:    s3Namespace,
+		Location: path.Join("/", Bucket, key),
+		Bucket:   Bucket,
+		Key:      key,
+		ETag:     fmt.Sprintf("\"%s-%d\"", hex.EncodeToString(sums.Sum(nil)), len(req.Parts)),
+	})
+}
+
+func (s *Server) abortMultipartUpload(w http.ResponseWriter, uploadID string) error {
+	dir, err := s.getUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("remove upload: %w", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// getUpload returns the directory holding the parts of the upload.
+func (s *Server) getUpload(uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", newS3Error(http.StatusNotFound, "NoSuchUpload", "upload %s does not exist", uploadID)
+	}
+	dir := s.uploadPath(uploadID)
+	if _, err := os.Stat(dir); err != nil {
+		return "", newS3Error(http.StatusNotFound, "NoSuchUpload", "upload %s does not exist", uploadID)
+	}
+	return dir, nil
+}
+
+// writeFile atomically writes the content of the reader to the file and returns its etag.
+func (s *Server) writeFile(dst string, r io.Reader) (string, error) {
+	tmpDir := filepath.Join(s.dir, uploadsDir)
+	if err := os.MkdirAll(tmpDir, 0700); err != nil {
+		return "", fmt.Errorf("create temporary directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(tmpDir, "tmp-")
+	if err != nil {
+		return "", fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sum := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, sum), r); err != nil {
+		return "", fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("create parent directory: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", fmt.Errorf("chmod file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", fmt.Errorf("rename file: %w", err)
+	}
+	return fmt.Sprintf("%q", hex.EncodeToString(sum.Sum(nil))), nil
+}
+
+// decodeXMLBody decodes the xml body of the request. The body is read whole so that it is
+// verified against the payload hash before it is used.
+func decodeXMLBody(r *http.Request, v any) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxXMLBodySize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxXMLBodySize {
+		return newS3Error(http.StatusBadRequest, "MalformedXML", "request body is larger than %d bytes", maxXMLBodySize)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return newS3Error(http.StatusBadRequest, "MalformedXML", "decode request: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) objectPath(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *Server) uploadPath(uploadID string) string {
+	return filepath.Join(s.dir, uploadsDir, "upload-"+uploadID)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := toS3Error(err)
+	if e.status >= http.StatusInternalServerError {
+		slog.Error("Request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(e.status)
+		return
+	}
+	_ = writeXML(w, e.status, struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Message  string   `xml:"Message"`
+		Resource string   `xml:"Resource"`
+	}{Code: e.Code, Message: e.Message, Resource: r.URL.Path})
+}
+
+func toS3Error(err error) *s3Error {
+	var e *s3Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return newS3Error(http.StatusInternalServerError, "InternalError", "%v", err)
+}
+
+// validateKey makes sure the key can not be used to reach files outside of the store or the
+// temporary files of the server.
+func validateKey(key string) error {
+	for _, segment := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return newS3Error(http.StatusBadRequest, "InvalidArgument", "invalid key %q", key)
+		}
+	}
+	if key == uploadsDir || strings.HasPrefix(key, uploadsDir+"/") {
+		return newS3Error(http.StatusBadRequest, "InvalidArgument", "invalid key %q", key)
+	}
+	return nil
+}
+
+// fileETag returns an etag for files not written by the server, derived from the size and the
+// modification time of the file so it does not need to be read.
+func fileETag(info fs.FileInfo) string {
+	return fmt.Sprintf("\"%x-%x\"", info.Size(), info.ModTime().UnixNano())
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate random id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+	return nil
+}
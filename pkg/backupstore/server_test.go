@@ -0,0 +1,373 @@
+package backupstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	s3manager "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (string, *s3.Client) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(NewServer(dir, "access-key", "secret-key"))
+	t.Cleanup(srv.Close)
+	return dir, newTestClient(srv.URL, "access-key", "secret-key")
+}
+
+func newTestClient(endpoint, accessKey, secretKey string) *s3.Client {
+	return s3.New(s3.Options{
+		Credentials:  credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		Region:       Region,
+		BaseEndpoint: aws.String(endpoint),
+		UsePathStyle: true,
+	})
+}
+
+func TestServer_Objects(t *testing.T) {
+	ctx := context.Background()
+	dir, client := newTestServer(t)
+
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(Bucket)})
+	require.NoError(t, err)
+
+	keys := []string{
+		"backups/backup-1/velero-backup.json",
+		"backups/backup-1/backup-1.tar.gz",
+		"backups/backup-2/velero-backup.json",
+		"restic/default/config",
+	}
+	for _, key := range keys {
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(Bucket),
+			Key:    aws.String(key),
+			Body:   strings.NewReader("content of " + key),
+		})
+		require.NoError(t, err)
+	}
+
+	// objects are stored as regular files.
+	data, err := os.ReadFile(filepath.Join(dir, "backups/backup-1/velero-backup.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "content of backups/backup-1/velero-backup.json", string(data))
+
+	// files copied to the store directory are served as well.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "backups/backup-3"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "backups/backup-3/velero-backup.json"), []byte("copied"), 0644))
+
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(Bucket),
+		Key:    aws.String("backups/backup-3/velero-backup.json"),
+	})
+	require.NoError(t, err)
+	data, err = io.ReadAll(out.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "copied", string(data))
+
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(Bucket),
+		Key:    aws.String("backups/backup-1/backup-1.tar.gz"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("content of backups/backup-1/backup-1.tar.gz")), aws.ToInt64(head.ContentLength))
+
+	// velero lists the backups using the delimiter.
+	list, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(Bucket),
+		Prefix:    aws.String("backups/"),
+		Delimiter: aws.String("/"),
+	})
+	require.NoError(t, err)
+	prefixes := []string{}
+	for _, p := range list.CommonPrefixes {
+		prefixes = append(prefixes, aws.ToString(p.Prefix))
+	}
+	assert.Equal(t, []string{"backups/backup-1/", "backups/backup-2/", "backups/backup-3/"}, prefixes)
+	assert.Empty(t, list.Contents)
+
+	// pagination goes through all the keys in order.
+	listed := []string{}
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(Bucket),
+		MaxKeys: aws.Int32(2),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		require.NoError(t, err)
+		for _, obj := range page.Contents {
+			listed = append(listed, aws.ToString(obj.Key))
+		}
+	}
+	assert.Equal(t, []string{
+		"backups/backup-1/backup-1.tar.gz",
+		"backups/backup-1/velero-backup.json",
+		"backups/backup-2/velero-backup.json",
+		"backups/backup-3/velero-backup.json",
+		"restic/default/config",
+	}, listed)
+
+	_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(Bucket),
+		Key:    aws.String("backups/backup-2/velero-backup.json"),
+	})
+	require.NoError(t, err)
+	// the directories left empty are removed.
+	_, err = os.Stat(filepath.Join(dir, "backups/backup-2"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(Bucket),
+		Delete: &types.Delete{Objects: []types.ObjectIdentifier{
+			{Key: aws.String("backups/backup-1/velero-backup.json")},
+			{Key: aws.String("backups/backup-1/backup-1.tar.gz")},
+		}},
+	})
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "backups/backup-1"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(Bucket),
+		Key:    aws.String("backups/backup-1/velero-backup.json"),
+	})
+	var noSuchKey *types.NoSuchKey
+	assert.ErrorAs(t, err, &noSuchKey)
+}
+
+func TestServer_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	dir, client := newTestServer(t)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<20)
+	uploader := s3manager.NewUploader(client, func(u *s3manager.Uploader) {
+		u.PartSize = s3manager.MinUploadPartSize
+	})
+	out, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(Bucket),
+		Key:    aws.String("kopia/default/data"),
+		Body:   bytes.NewReader(content),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, out.UploadID)
+
+	data, err := os.ReadFile(filepath.Join(dir, "kopia/default/data"))
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// the parts are cleaned up and never listed.
+	entries, err := os.ReadDir(filepath.Join(dir, uploadsDir))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	list, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(Bucket)})
+	require.NoError(t, err)
+	require.Len(t, list.Contents, 1)
+	assert.Equal(t, "kopia/default/data", aws.ToString(list.Contents[0].Key))
+}
+
+func TestServer_Authentication(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "object"), []byte("content"), 0644))
+
+	server := NewServer(dir, "access-key", "secret-key")
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name      string
+		accessKey string
+		secretKey string
+		code      string
+	}{
+		{name: "unknown access key", accessKey: "other", secretKey: "secret-key", code: "InvalidAccessKeyId"},
+		{name: "wrong secret key", accessKey: "access-key", secretKey: "other", code: "SignatureDoesNotMatch"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(srv.URL, tt.accessKey, tt.secretKey)
+			_, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(Bucket)})
+			assert.ErrorContains(t, err, tt.code)
+		})
+	}
+
+	t.Run("unsigned request", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/" + Bucket + "/object")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("keys outside of the store", func(t *testing.T) {
+		client := newTestClient(srv.URL, "access-key", "secret-key")
+		_, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(Bucket),
+			Key:    aws.String(uploadsDir + "/upload"),
+		})
+		assert.ErrorContains(t, err, "InvalidArgument")
+	})
+
+	t.Run("presigned url", func(t *testing.T) {
+		presigner := s3.NewPresignClient(newTestClient(srv.URL, "access-key", "secret-key"))
+		req, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(Bucket),
+			Key:    aws.String("object"),
+		}, s3.WithPresignExpires(time.Minute))
+		require.NoError(t, err)
+
+		resp, err := http.Get(req.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+
+		// the url can not be used once expired.
+		server.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		defer func() { server.now = time.Now }()
+		resp, err = http.Get(req.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestServer_PayloadHash(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(NewServer(dir, "access-key", "secret-key"))
+	defer srv.Close()
+
+	put := func(t *testing.T, body, payloadHash string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/"+Bucket+"/object", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+		signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+		creds := aws.Credentials{AccessKeyID: "access-key", SecretAccessKey: "secret-key"}
+		require.NoError(t, signer.SignHTTP(context.Background(), creds, req, payloadHash, "s3", Region, time.Now()))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("payload matching the signed hash", func(t *testing.T) {
+		resp := put(t, "content", testSHA256("content"))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := os.ReadFile(filepath.Join(dir, "object"))
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+	})
+
+	t.Run("payload replaced after signing", func(t *testing.T) {
+		resp := put(t, "tampered", testSHA256("content"))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "XAmzContentSHA256Mismatch")
+
+		// the object is not overwritten.
+		content, err := os.ReadFile(filepath.Join(dir, "object"))
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+	})
+
+	t.Run("unsigned payload", func(t *testing.T) {
+		resp := put(t, "unsigned", unsignedPayload)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func Test_chunkedReader(t *testing.T) {
+	body := "5;chunk-signature=abcd\r\nhello\r\n6;chunk-signature=efgh\r\n world\r\n0;chunk-signature=ijkl\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"
+	r := &chunkedReader{r: bufio.NewReader(strings.NewReader(body))}
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	r = &chunkedReader{r: bufio.NewReader(strings.NewReader("5\r\nhel"))}
+	_, err = io.ReadAll(r)
+	var e *s3Error
+	assert.True(t, errors.As(err, &e))
+}
+
+func Test_chunkedReaderSignatures(t *testing.T) {
+	// the example of the aws documentation about signing chunked uploads.
+	sig := &signature{
+		region:      "us-east-1",
+		service:     "s3",
+		signature:   "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9",
+		signingTime: time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC),
+	}
+	newSigner := func() *chunkSigner {
+		return &chunkSigner{
+			key:           signingKey("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", sig),
+			date:          sig.signingTime.Format(amzDateFormat),
+			scope:         credentialScope(sig),
+			prevSignature: sig.signature,
+		}
+	}
+	payload := strings.Repeat("a", 65536+1024)
+	body := "10000;chunk-signature=ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648\r\n" +
+		payload[:65536] + "\r\n" +
+		"400;chunk-signature=0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497\r\n" +
+		payload[65536:] + "\r\n" +
+		"0;chunk-signature=b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9\r\n\r\n"
+
+	r := &chunkedReader{r: bufio.NewReader(strings.NewReader(body)), signer: newSigner()}
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, payload, string(data))
+
+	tampered := strings.Replace(body, "aaaa", "aaab", 1)
+	r = &chunkedReader{r: bufio.NewReader(strings.NewReader(tampered)), signer: newSigner()}
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "the chunk signature does not match")
+
+	unsigned := "5\r\nhello\r\n0\r\n\r\n"
+	r = &chunkedReader{r: bufio.NewReader(strings.NewReader(unsigned)), signer: newSigner()}
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "missing chunk signature")
+}
+
+func Test_chunkedReaderTrailerChecksum(t *testing.T) {
+	// crc32 of "hello world".
+	body := "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:DUoRhQ==\r\n\r\n"
+	checksum, err := newTrailerChecksum("x-amz-checksum-crc32")
+	require.NoError(t, err)
+	r := &chunkedReader{r: bufio.NewReader(strings.NewReader(body)), checksum: checksum}
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	checksum, err = newTrailerChecksum("x-amz-checksum-crc32")
+	require.NoError(t, err)
+	tampered := strings.Replace(body, "world", "WORLD", 1)
+	r = &chunkedReader{r: bufio.NewReader(strings.NewReader(tampered)), checksum: checksum}
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "BadDigest")
+
+	_, err = newTrailerChecksum("x-amz-checksum-md5")
+	assert.ErrorContains(t, err, "unsupported x-amz-trailer")
+}
+
+func testSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
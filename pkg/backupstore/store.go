@@ -0,0 +1,97 @@
+// Package backupstore serves backups kept in a local directory or in an NFS share through a
+// minimal S3 API, so velero can use them with its S3 object store plugin.
+package backupstore
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// Bucket is the name of the only bucket served. The objects are stored at the root of the
+	// store directory.
+	Bucket = "velero"
+	// Region is the region velero is configured with, it is not used by the server.
+	Region = "us-east-1"
+	// Port is the port the server listens on.
+	Port = 9000
+)
+
+// Type is the type of storage backing the store.
+type Type string
+
+const (
+	// TypeFile is a directory on the host the server runs on.
+	TypeFile Type = "file"
+	// TypeNFS is an NFS share mounted by the kubelet.
+	TypeNFS Type = "nfs"
+)
+
+// Store is a backup storage location not reachable through S3.
+type Store struct {
+	Type Type
+	// Server is the NFS server, empty for the file type.
+	Server string
+	// Path is the absolute path to the directory in the host or in the NFS share.
+	Path string
+}
+
+// Parse parses a backup store URL. Both file:///path and nfs://server/path are supported.
+func Parse(raw string) (*Store, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("backup store url must not have credentials, a query or a fragment")
+	}
+
+	store := &Store{Type: Type(u.Scheme), Path: path.Clean("/" + u.Path)}
+	switch store.Type {
+	case TypeFile:
+		if u.Host != "" {
+			return nil, fmt.Errorf("file backup store url must have an absolute path and no host, e.g. file:///mnt/backups")
+		}
+	case TypeNFS:
+		if u.Host == "" {
+			return nil, fmt.Errorf("nfs backup store url must have a server, e.g. nfs://server/path")
+		}
+		store.Server = u.Host
+	default:
+		return nil, fmt.Errorf("unsupported backup store scheme %q, must be file or nfs", u.Scheme)
+	}
+	if u.Path == "" || (store.Type == TypeFile && store.Path == "/") {
+		return nil, fmt.Errorf("backup store url must have a path")
+	}
+	return store, nil
+}
+
+// String returns the URL of the store.
+func (s *Store) String() string {
+	u := url.URL{Scheme: string(s.Type), Host: s.Server, Path: s.Path}
+	return u.String()
+}
+
+// ValidateLocal checks that a file store holds velero backups. Stores of other types can only
+// be validated once served in the cluster.
+func (s *Store) ValidateLocal() error {
+	if s.Type != TypeFile {
+		return nil
+	}
+	dir := filepath.Join(s.Path, "backups")
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no backups found in %s", s.Path)
+	} else if err != nil {
+		return fmt.Errorf("read backups directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			return nil
+		}
+	}
+	return fmt.Errorf("no backups found in %s", s.Path)
+}
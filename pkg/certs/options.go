@@ -1,6 +1,7 @@
 package certs
 
 import (
+	"crypto/x509"
 	"fmt"
 	"net"
 	"os"
@@ -84,3 +85,15 @@ func SignWith(certPath, keyPath []byte) Option {
 		return nil
 	}
 }
+
+// WithSubjectOf sets the common name, organizations, DNS names and IP addresses of the
+// certificate to the ones of an existing certificate. This is used to re-issue a certificate.
+func WithSubjectOf(crt *x509.Certificate) Option {
+	return func(b *Builder) error {
+		b.commonName = crt.Subject.CommonName
+		b.organizations = append([]string(nil), crt.Subject.Organization...)
+		b.dnsNames = append([]string(nil), crt.DNSNames...)
+		b.ipAddresses = append([]net.IP(nil), crt.IPAddresses...)
+		return nil
+	}
+}
@@ -103,6 +103,35 @@ func ensureKernelModulesLoaded() (finalErr error) {
 	return
 }
 
+// UnloadKernelModules unloads the kernel modules listed in the embedded config file, in
+// reverse order. Modules still in use by something else on the host can't be unloaded and
+// an error is returned for those.
+func UnloadKernelModules() error {
+	if _, err := exec.LookPath("modprobe"); err != nil {
+		return fmt.Errorf("find modprobe binary: %w", err)
+	}
+	return unloadKernelModules()
+}
+
+func unloadKernelModules() (finalErr error) {
+	var modules []string
+	scanner := bufio.NewScanner(bytes.NewReader(embeddedClusterModulesConf))
+	for scanner.Scan() {
+		module := strings.TrimSpace(scanner.Text())
+		if module != "" && !strings.HasPrefix(module, "#") {
+			modules = append(modules, module)
+		}
+	}
+
+	for i := len(modules) - 1; i >= 0; i-- {
+		if _, err := helpers.RunCommand("modprobe", "-r", modules[i]); err != nil {
+			err = fmt.Errorf("modprobe -r %s: %w", modules[i], err)
+			finalErr = multierr.Append(finalErr, err)
+		}
+	}
+	return
+}
+
 func modprobe(module string) error {
 	_, err := helpers.RunCommand("modprobe", module)
 	return err
@@ -82,3 +82,26 @@ func Test_ensureKernelModulesLoaded(t *testing.T) {
 		}
 	}
 }
+
+func Test_unloadKernelModules(t *testing.T) {
+	mock := &helpers.MockHelpers{
+		Commands: make([]string, 0),
+	}
+
+	helpers.Set(mock)
+	t.Cleanup(func() {
+		helpers.Set(&helpers.Helpers{})
+	})
+
+	err := unloadKernelModules()
+	assert.NoError(t, err)
+
+	// modules are unloaded in the reverse order they are loaded
+	expectedCommands := []string{
+		"modprobe -r nf_conntrack",
+		"modprobe -r br_netfilter",
+		"modprobe -r ip_tables",
+		"modprobe -r overlay",
+	}
+	assert.Equal(t, expectedCommands, mock.Commands)
+}
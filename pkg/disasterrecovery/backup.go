@@ -11,6 +11,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"github.com/vmware-tanzu/velero/pkg/label"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -48,12 +49,21 @@ const (
 	// instance backup.
 	InstanceBackupResoreSpecAnnotation = "replicated.com/restore-spec"
 
+	// PreRestoreBackupAnnotation is the annotation marking the backups taken before a restore
+	// into a running cluster. These are not picked when restoring again as they hold the state
+	// the restore was meant to replace.
+	PreRestoreBackupAnnotation = "replicated.com/pre-restore-backup"
+
 	// InstanceBackupTypeInfra indicates that the backup is of type infrastructure.
 	InstanceBackupTypeInfra = "infra"
 	// InstanceBackupTypeApp indicates that the backup is of type application.
 	InstanceBackupTypeApp = "app"
 	// InstanceBackupTypeLegacy indicates that the backup is of type legacy (combined infra + app).
 	InstanceBackupTypeLegacy = "legacy"
+
+	// BackupPhaseIncomplete is the phase of an instance backup for which all velero backups are
+	// completed but some of the expected ones are missing.
+	BackupPhaseIncomplete velerov1.BackupPhase = "Incomplete"
 )
 
 var (
@@ -208,6 +218,40 @@ func (b ReplicatedBackup) GetCompletionTimestamp() metav1.Time {
 	return completionTimestamp
 }
 
+// GetPhase returns the phase of the instance backup. This is the phase of the first velero backup
+// that is not completed, or completed if all the expected backups are.
+func (b ReplicatedBackup) GetPhase() velerov1.BackupPhase {
+	for _, backup := range b {
+		switch backup.Status.Phase {
+		case velerov1.BackupPhaseCompleted:
+			continue
+		case "":
+			return velerov1.BackupPhaseNew
+		default:
+			return backup.Status.Phase
+		}
+	}
+	if len(b) != b.GetExpectedBackupCount() {
+		return BackupPhaseIncomplete
+	}
+	return velerov1.BackupPhaseCompleted
+}
+
+// IsInProgress returns true if velero is still processing any of the backups of the instance
+// backup.
+func (b ReplicatedBackup) IsInProgress() bool {
+	switch b.GetPhase() {
+	case velerov1.BackupPhaseNew,
+		velerov1.BackupPhaseInProgress,
+		velerov1.BackupPhaseWaitingForPluginOperations,
+		velerov1.BackupPhaseWaitingForPluginOperationsPartiallyFailed,
+		velerov1.BackupPhaseFinalizing,
+		velerov1.BackupPhaseFinalizingPartiallyFailed:
+		return true
+	}
+	return false
+}
+
 // GetAnnotation returns the value of the specified annotation key from the velero infra backup
 // object or the first backup in the slice if the infra backup is not found.
 func (b ReplicatedBackup) GetAnnotation(key string) (string, bool) {
@@ -224,6 +268,78 @@ func (b ReplicatedBackup) GetAnnotation(key string) (string, bool) {
 	return "", false
 }
 
+// IsPreRestoreBackup returns true if the backup was taken before a restore into a running
+// cluster.
+func (b ReplicatedBackup) IsPreRestoreBackup() bool {
+	val, _ := b.GetAnnotation(PreRestoreBackupAnnotation)
+	return val == "true"
+}
+
+// MarkPreRestoreBackup annotates all the backups of the instance backup as taken before a
+// restore into a running cluster.
+func MarkPreRestoreBackup(ctx context.Context, cli client.Client, backup ReplicatedBackup) error {
+	for _, b := range backup {
+		patch := client.MergeFrom(b.DeepCopy())
+		if b.Annotations == nil {
+			b.Annotations = map[string]string{}
+		}
+		b.Annotations[PreRestoreBackupAnnotation] = "true"
+		if err := cli.Patch(ctx, &b, patch); err != nil {
+			return fmt.Errorf("unable to annotate backup %s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
+
+// DeleteReplicatedBackup requests velero to delete all the backups of the instance backup, both
+// the objects in the cluster and the data in the backup storage location. The deletion happens
+// asynchronously.
+func DeleteReplicatedBackup(ctx context.Context, cli client.Client, backup ReplicatedBackup) error {
+	for _, b := range backup {
+		req := &velerov1.DeleteBackupRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: b.Name + "-",
+				Namespace:    b.Namespace,
+				Labels: map[string]string{
+					velerov1.BackupNameLabel: label.GetValidName(b.Name),
+					velerov1.BackupUIDLabel:  string(b.UID),
+				},
+			},
+			Spec: velerov1.DeleteBackupRequestSpec{
+				BackupName: b.Name,
+			},
+		}
+		if err := cli.Create(ctx, req); err != nil {
+			return fmt.Errorf("unable to create delete backup request for %s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
+
+// BackupsToPrune returns the instance backups to delete to keep only the specified number of
+// completed backups. The most recent completed backups are kept, as are the backups still in
+// progress or already being deleted. All other backups, including the failed ones, are returned
+// from the oldest to the newest.
+func BackupsToPrune(backups []ReplicatedBackup, keep int) []ReplicatedBackup {
+	sorted := make([]ReplicatedBackup, len(backups))
+	copy(sorted, backups)
+	sort.Sort(sort.Reverse(ReplicatedBackups(sorted)))
+
+	prune := []ReplicatedBackup{}
+	kept := 0
+	for _, backup := range sorted {
+		if backup.IsInProgress() || backup.GetPhase() == velerov1.BackupPhaseDeleting {
+			continue
+		}
+		if backup.GetPhase() == velerov1.BackupPhaseCompleted && kept < keep {
+			kept++
+			continue
+		}
+		prune = append([]ReplicatedBackup{backup}, prune...)
+	}
+	return prune
+}
+
 // IsInstanceBackup returns true if the backup is an instance backup.
 func IsInstanceBackup(veleroBackup velerov1.Backup) bool {
 	if GetInstanceBackupVersion(veleroBackup) != "" {
@@ -1498,3 +1498,166 @@ func TestReplicatedBackup_GetRestore(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicatedBackup_GetPhase(t *testing.T) {
+	newBackup := func(backupType string, phase velerov1.BackupPhase) velerov1.Backup {
+		return velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					BackupIsECAnnotation:            "true",
+					InstanceBackupVersionAnnotation: InstanceBackupVersionCurrent,
+					InstanceBackupTypeAnnotation:    backupType,
+					InstanceBackupCountAnnotation:   "2",
+				},
+			},
+			Status: velerov1.BackupStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name string
+		b    ReplicatedBackup
+		want velerov1.BackupPhase
+	}{
+		{
+			name: "all backups completed",
+			b: ReplicatedBackup{
+				newBackup(InstanceBackupTypeInfra, velerov1.BackupPhaseCompleted),
+				newBackup(InstanceBackupTypeApp, velerov1.BackupPhaseCompleted),
+			},
+			want: velerov1.BackupPhaseCompleted,
+		},
+		{
+			name: "one backup failed",
+			b: ReplicatedBackup{
+				newBackup(InstanceBackupTypeInfra, velerov1.BackupPhaseCompleted),
+				newBackup(InstanceBackupTypeApp, velerov1.BackupPhasePartiallyFailed),
+			},
+			want: velerov1.BackupPhasePartiallyFailed,
+		},
+		{
+			name: "backup not processed yet",
+			b: ReplicatedBackup{
+				newBackup(InstanceBackupTypeInfra, ""),
+			},
+			want: velerov1.BackupPhaseNew,
+		},
+		{
+			name: "missing backup",
+			b: ReplicatedBackup{
+				newBackup(InstanceBackupTypeInfra, velerov1.BackupPhaseCompleted),
+			},
+			want: BackupPhaseIncomplete,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.b.GetPhase())
+		})
+	}
+}
+
+func TestBackupsToPrune(t *testing.T) {
+	newBackup := func(name string, day int, phase velerov1.BackupPhase) ReplicatedBackup {
+		return ReplicatedBackup{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Annotations: map[string]string{
+						InstanceBackupTypeAnnotation: InstanceBackupTypeLegacy,
+					},
+				},
+				Status: velerov1.BackupStatus{
+					Phase:          phase,
+					StartTimestamp: &metav1.Time{Time: time.Date(2022, 1, day, 0, 0, 0, 0, time.Local)},
+				},
+			},
+		}
+	}
+
+	backups := []ReplicatedBackup{
+		newBackup("day-5", 5, velerov1.BackupPhaseInProgress),
+		newBackup("day-1", 1, velerov1.BackupPhaseCompleted),
+		newBackup("day-4", 4, velerov1.BackupPhaseFailed),
+		newBackup("day-2", 2, velerov1.BackupPhaseDeleting),
+		newBackup("day-3", 3, velerov1.BackupPhaseCompleted),
+		newBackup("day-0", 0, velerov1.BackupPhaseCompleted),
+	}
+
+	names := func(backups []ReplicatedBackup) []string {
+		result := []string{}
+		for _, b := range backups {
+			result = append(result, b.GetName())
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"day-0", "day-1", "day-4"}, names(BackupsToPrune(backups, 1)))
+	assert.Equal(t, []string{"day-0", "day-4"}, names(BackupsToPrune(backups, 2)))
+	assert.Equal(t, []string{"day-4"}, names(BackupsToPrune(backups, 5)))
+	assert.Equal(t, "day-5", backups[0].GetName(), "the provided slice should not be modified")
+}
+
+func TestDeleteReplicatedBackup(t *testing.T) {
+	scheme := scheme.Scheme
+	velerov1.AddToScheme(scheme)
+	cli := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	backup := ReplicatedBackup{
+		{ObjectMeta: metav1.ObjectMeta{Name: "instance-abcd", Namespace: "velero", UID: "uid-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "application-abcd", Namespace: "velero", UID: "uid-2"}},
+	}
+	err := DeleteReplicatedBackup(context.Background(), cli, backup)
+	require.NoError(t, err)
+
+	var requests velerov1.DeleteBackupRequestList
+	err = cli.List(context.Background(), &requests, client.InNamespace("velero"))
+	require.NoError(t, err)
+	require.Len(t, requests.Items, 2)
+
+	got := map[string]string{}
+	for _, req := range requests.Items {
+		assert.Equal(t, req.Spec.BackupName, req.Labels[velerov1.BackupNameLabel])
+		got[req.Spec.BackupName] = req.Labels[velerov1.BackupUIDLabel]
+	}
+	assert.Equal(t, map[string]string{"instance-abcd": "uid-1", "application-abcd": "uid-2"}, got)
+}
+
+func TestMarkPreRestoreBackup(t *testing.T) {
+	scheme := scheme.Scheme
+	velerov1.AddToScheme(scheme)
+
+	newBackup := func(name, backupType string) *velerov1.Backup {
+		return &velerov1.Backup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "velero",
+				Labels:    map[string]string{InstanceBackupNameLabel: "app-slug-abcd"},
+				Annotations: map[string]string{
+					BackupIsECAnnotation:            "true",
+					InstanceBackupVersionAnnotation: InstanceBackupVersionCurrent,
+					InstanceBackupTypeAnnotation:    backupType,
+					InstanceBackupCountAnnotation:   "2",
+				},
+			},
+		}
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newBackup("instance-abcd", InstanceBackupTypeInfra),
+		newBackup("application-abcd", InstanceBackupTypeApp),
+	).Build()
+
+	backup, err := GetReplicatedBackup(context.Background(), cli, "velero", "app-slug-abcd")
+	require.NoError(t, err)
+	assert.False(t, backup.IsPreRestoreBackup())
+
+	require.NoError(t, MarkPreRestoreBackup(context.Background(), cli, backup))
+
+	backup, err = GetReplicatedBackup(context.Background(), cli, "velero", "app-slug-abcd")
+	require.NoError(t, err)
+	require.Len(t, backup, 2)
+	assert.True(t, backup.IsPreRestoreBackup())
+	for _, b := range backup {
+		assert.Equal(t, "true", b.Annotations[PreRestoreBackupAnnotation])
+	}
+}
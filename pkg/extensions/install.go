@@ -2,15 +2,28 @@ package extensions
 
 import (
 	"context"
+	"slices"
 	"sort"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/embedded-cluster/pkg/config"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func Install(ctx context.Context, hcli helm.Client) error {
+type InstallOptions struct {
+	// SkipExtensions holds the names of the extensions installed by a previous attempt.
+	// These are not installed again.
+	SkipExtensions []string
+	// OnExtensionInstalled, if set, is called with the name of each extension once it is
+	// installed.
+	OnExtensionInstalled func(name string) error
+}
+
+func Install(ctx context.Context, kcli client.Client, hcli helm.Client, opts InstallOptions) error {
 	// check if there are any extensions
 	if len(config.AdditionalCharts()) == 0 {
 		return nil
@@ -23,6 +36,15 @@ func Install(ctx context.Context, hcli helm.Client) error {
 		return errors.Wrap(err, "add additional helm repositories")
 	}
 
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return errors.Wrap(err, "get latest installation")
+	}
+	vctx, err := newValuesContext(ctx, kcli, in)
+	if err != nil {
+		return errors.Wrap(err, "build values context")
+	}
+
 	// sort by order first
 	sorted := config.AdditionalCharts()
 	sort.Slice(sorted, func(i, j int) bool {
@@ -30,11 +52,22 @@ func Install(ctx context.Context, hcli helm.Client) error {
 	})
 
 	for _, ext := range sorted {
+		if slices.Contains(opts.SkipExtensions, ext.Name) {
+			logrus.Debugf("Extension %s already installed, skipping", ext.Name)
+			continue
+		}
+
 		loading.Infof("Installing %s", ext.Name)
 
-		if err := install(ctx, hcli, ext); err != nil {
+		if err := install(ctx, kcli, hcli, vctx, ext); err != nil {
 			return errors.Wrapf(err, "install extension %s", ext.Name)
 		}
+
+		if opts.OnExtensionInstalled != nil {
+			if err := opts.OnExtensionInstalled(ext.Name); err != nil {
+				return errors.Wrapf(err, "record extension %s installed", ext.Name)
+			}
+		}
 	}
 
 	loading.Infof("Extensions installed!")
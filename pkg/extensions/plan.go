@@ -0,0 +1,56 @@
+package extensions
+
+import (
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+)
+
+// ExtensionPlan describes what upgrading to a new config does to a helm extension.
+type ExtensionPlan struct {
+	Name      string
+	Namespace string
+	// Action is one of Install, Upgrade, Uninstall or NoChange.
+	Action string
+	// FromVersion is empty for extensions being installed.
+	FromVersion string
+	// ToVersion is empty for extensions being uninstalled.
+	ToVersion string
+}
+
+// PlanUpgrade returns the actions taken on each extension when upgrading from the prev
+// config to the next one, in the order they are going to be applied.
+func PlanUpgrade(prev, next *ecv1beta1.ConfigSpec) []ExtensionPlan {
+	var prevExts, nextExts ecv1beta1.Extensions
+	if prev != nil {
+		prevExts = prev.Extensions
+	}
+	if next != nil {
+		nextExts = next.Extensions
+	}
+
+	prevVersions := map[string]string{}
+	if prevExts.Helm != nil {
+		for _, chart := range prevExts.Helm.Charts {
+			prevVersions[chart.Name] = chart.Version
+		}
+	}
+
+	plans := []ExtensionPlan{}
+	for _, result := range diffExtensions(prevExts, nextExts) {
+		plan := ExtensionPlan{
+			Name:      result.Ext.Name,
+			Namespace: result.Ext.TargetNS,
+			Action:    string(result.Action),
+		}
+		switch result.Action {
+		case actionInstall:
+			plan.ToVersion = result.Ext.Version
+		case actionUninstall:
+			plan.FromVersion = result.Ext.Version
+		default:
+			plan.FromVersion = prevVersions[result.Ext.Name]
+			plan.ToVersion = result.Ext.Version
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
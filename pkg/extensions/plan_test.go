@@ -0,0 +1,48 @@
+package extensions
+
+import (
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanUpgrade(t *testing.T) {
+	prev := &ecv1beta1.ConfigSpec{
+		Extensions: ecv1beta1.Extensions{
+			Helm: &ecv1beta1.Helm{
+				Charts: []ecv1beta1.Chart{
+					{Name: "unchanged", TargetNS: "ns", Version: "1.0.0", Order: 1},
+					{Name: "upgraded", TargetNS: "ns", Version: "1.0.0", Order: 2},
+					{Name: "removed", TargetNS: "ns", Version: "1.0.0", Order: 3},
+					{Name: "patched", TargetNS: "ns", Version: "1.0.0", Order: 5},
+				},
+			},
+		},
+	}
+	next := &ecv1beta1.ConfigSpec{
+		Extensions: ecv1beta1.Extensions{
+			Helm: &ecv1beta1.Helm{
+				Charts: []ecv1beta1.Chart{
+					{Name: "unchanged", TargetNS: "ns", Version: "1.0.0", Order: 1},
+					{Name: "upgraded", TargetNS: "ns", Version: "2.0.0", Order: 2},
+					{Name: "added", TargetNS: "other", Version: "0.1.0", Order: 4},
+					{
+						Name: "patched", TargetNS: "ns", Version: "1.0.0", Order: 5,
+						Patches: []ecv1beta1.ChartPatch{{Patch: "metadata:\n  labels:\n    extra: label"}},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []ExtensionPlan{
+		{Name: "unchanged", Namespace: "ns", Action: "NoChange", FromVersion: "1.0.0", ToVersion: "1.0.0"},
+		{Name: "upgraded", Namespace: "ns", Action: "Upgrade", FromVersion: "1.0.0", ToVersion: "2.0.0"},
+		{Name: "removed", Namespace: "ns", Action: "Uninstall", FromVersion: "1.0.0"},
+		{Name: "added", Namespace: "other", Action: "Install", ToVersion: "0.1.0"},
+		{Name: "patched", Namespace: "ns", Action: "Upgrade", FromVersion: "1.0.0", ToVersion: "1.0.0"},
+	}, PlanUpgrade(prev, next))
+
+	assert.Empty(t, PlanUpgrade(nil, nil))
+}
@@ -0,0 +1,78 @@
+package extensions
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type ReapplyOptions struct {
+	// Namespaces, if set, limits the extensions reapplied to the ones deployed to these
+	// namespaces.
+	Namespaces []string
+}
+
+// Reapply brings the extensions of the installation back to their configuration. Missing
+// releases are installed and existing ones are upgraded. Extensions are not part of the
+// instance backups, this is how they are restored into a running cluster.
+func Reapply(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, opts ReapplyOptions) error {
+	if in.Spec.Config == nil || in.Spec.Config.Extensions.Helm == nil {
+		return nil
+	}
+
+	var charts []ecv1beta1.Chart
+	for _, ext := range in.Spec.Config.Extensions.Helm.Charts {
+		if len(opts.Namespaces) == 0 || slices.Contains(opts.Namespaces, ext.TargetNS) {
+			charts = append(charts, ext)
+		}
+	}
+	if len(charts) == 0 {
+		return nil
+	}
+
+	loading := spinner.Start()
+	defer loading.Close()
+
+	if err := addRepos(hcli, in.Spec.Config.Extensions.Helm.Repositories); err != nil {
+		return errors.Wrap(err, "add repos")
+	}
+
+	vctx, err := newValuesContext(ctx, kcli, in)
+	if err != nil {
+		return errors.Wrap(err, "build values context")
+	}
+
+	// sort by order first
+	sort.SliceStable(charts, func(i, j int) bool {
+		return charts[i].Order < charts[j].Order
+	})
+
+	for _, ext := range charts {
+		exists, err := hcli.ReleaseExists(ctx, ext.TargetNS, ext.Name)
+		if err != nil {
+			return errors.Wrapf(err, "check if release %s exists", ext.Name)
+		}
+
+		if exists {
+			loading.Infof("Upgrading %s", ext.Name)
+			if err := upgrade(ctx, kcli, hcli, vctx, ext); err != nil {
+				return errors.Wrapf(err, "upgrade extension %s", ext.Name)
+			}
+		} else {
+			loading.Infof("Installing %s", ext.Name)
+			if err := install(ctx, kcli, hcli, vctx, ext); err != nil {
+				return errors.Wrapf(err, "install extension %s", ext.Name)
+			}
+		}
+	}
+
+	loading.Infof("Extensions restored!")
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package extensions
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReapply(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	in := &ecv1beta1.Installation{
+		Spec: ecv1beta1.InstallationSpec{
+			Config: &ecv1beta1.ConfigSpec{
+				Extensions: ecv1beta1.Extensions{
+					Helm: &ecv1beta1.Helm{
+						Charts: []ecv1beta1.Chart{
+							{
+								Name:      "second-chart",
+								ChartName: "test/second",
+								Version:   "1.0.0",
+								TargetNS:  "test-ns",
+								Order:     2,
+							},
+							{
+								Name:      "first-chart",
+								ChartName: "test/first",
+								Version:   "1.0.0",
+								Values:    "abc: xyz",
+								TargetNS:  "test-ns",
+								Order:     1,
+							},
+							{
+								Name:      "other-chart",
+								ChartName: "test/other",
+								Version:   "1.0.0",
+								TargetNS:  "other-ns",
+								Order:     3,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("installs missing and upgrades existing releases in order", func(t *testing.T) {
+		kcli := fake.NewClientBuilder().WithScheme(scheme).Build()
+		hcli := &helm.MockClient{}
+		mock.InOrder(
+			hcli.On("ReleaseExists", mock.Anything, "test-ns", "first-chart").Once().Return(true, nil),
+			hcli.On("Upgrade", mock.Anything, helm.UpgradeOptions{
+				ReleaseName:  "first-chart",
+				ChartPath:    "test/first",
+				ChartVersion: "1.0.0",
+				Values:       map[string]interface{}{"abc": "xyz"},
+				Namespace:    "test-ns",
+				Force:        true,
+			}).Once().Return(nil, nil),
+			hcli.On("ReleaseExists", mock.Anything, "test-ns", "second-chart").Once().Return(false, nil),
+			hcli.On("Install", mock.Anything, helm.InstallOptions{
+				ReleaseName:  "second-chart",
+				ChartPath:    "test/second",
+				ChartVersion: "1.0.0",
+				Values:       map[string]interface{}{},
+				Namespace:    "test-ns",
+			}).Once().Return(nil, nil),
+			hcli.On("ReleaseExists", mock.Anything, "other-ns", "other-chart").Once().Return(true, nil),
+			hcli.On("Upgrade", mock.Anything, mock.Anything).Once().Return(nil, nil),
+		)
+
+		require.NoError(t, Reapply(context.Background(), kcli, hcli, in, ReapplyOptions{}))
+		hcli.AssertExpectations(t)
+	})
+
+	t.Run("only reapplies the extensions in the namespaces", func(t *testing.T) {
+		kcli := fake.NewClientBuilder().WithScheme(scheme).Build()
+		hcli := &helm.MockClient{}
+		mock.InOrder(
+			hcli.On("ReleaseExists", mock.Anything, "other-ns", "other-chart").Once().Return(false, nil),
+			hcli.On("Install", mock.Anything, mock.Anything).Once().Return(nil, nil),
+		)
+
+		require.NoError(t, Reapply(context.Background(), kcli, hcli, in, ReapplyOptions{Namespaces: []string{"other-ns"}}))
+		hcli.AssertExpectations(t)
+	})
+}
@@ -41,6 +41,27 @@ func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ec
 
 	results := diffExtensions(prevExts, inExts)
 
+	vctx, err := newValuesContext(ctx, kcli, in)
+	if err != nil {
+		return errors.Wrap(err, "build values context")
+	}
+
+	// extensions with values resolved from the cluster are upgraded when the resolved values
+	// differ from the ones they were deployed with, for instance when a referenced secret
+	// changed.
+	for i, result := range results {
+		if result.Action != actionNoChange || !hasDynamicValues(result.Ext) {
+			continue
+		}
+		changed, err := valuesChanged(ctx, kcli, hcli, vctx, result.Ext)
+		if err != nil {
+			return errors.Wrapf(err, "check values of extension %s", result.Ext.Name)
+		}
+		if changed {
+			results[i].Action = actionUpgrade
+		}
+	}
+
 	// first uninstall removed extensions in reverse order
 	for i := len(results) - 1; i >= 0; i-- {
 		result := results[i]
@@ -55,11 +76,11 @@ func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ec
 	for _, result := range results {
 		switch result.Action {
 		case actionInstall:
-			if err := handleExtensionInstall(ctx, kcli, hcli, in, result.Ext); err != nil {
+			if err := handleExtensionInstall(ctx, kcli, hcli, vctx, in, result.Ext); err != nil {
 				return errors.Wrapf(err, "install extension %s", result.Ext.Name)
 			}
 		case actionUpgrade:
-			if err := handleExtensionUpgrade(ctx, kcli, hcli, in, result.Ext); err != nil {
+			if err := handleExtensionUpgrade(ctx, kcli, hcli, vctx, in, result.Ext); err != nil {
 				return errors.Wrapf(err, "upgrade extension %s", result.Ext.Name)
 			}
 		case actionNoChange:
@@ -74,7 +95,7 @@ func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ec
 	return nil
 }
 
-func handleExtensionInstall(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
+func handleExtensionInstall(ctx context.Context, kcli client.Client, hcli helm.Client, vctx *ValuesContext, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
 	return handleExtension(ctx, kcli, in, ext, actionInstall, func() error {
 		exists, err := hcli.ReleaseExists(ctx, ext.TargetNS, ext.Name)
 		if err != nil {
@@ -84,16 +105,16 @@ func handleExtensionInstall(ctx context.Context, kcli client.Client, hcli helm.C
 			slog.Info("Extension already installed", "name", ext.Name)
 			return nil
 		}
-		if err := install(ctx, hcli, ext); err != nil {
+		if err := install(ctx, kcli, hcli, vctx, ext); err != nil {
 			return errors.Wrap(err, "install")
 		}
 		return nil
 	})
 }
 
-func handleExtensionUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
+func handleExtensionUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, vctx *ValuesContext, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
 	return handleExtension(ctx, kcli, in, ext, actionUpgrade, func() error {
-		if err := upgrade(ctx, hcli, ext); err != nil {
+		if err := upgrade(ctx, kcli, hcli, vctx, ext); err != nil {
 			return errors.Wrap(err, "upgrade")
 		}
 		return nil
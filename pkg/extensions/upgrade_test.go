@@ -10,9 +10,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -23,11 +25,13 @@ func TestUpgrade(t *testing.T) {
 
 	scheme := runtime.NewScheme()
 	require.NoError(t, ecv1beta1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
 
 	tests := []struct {
 		name             string
 		prev             *ecv1beta1.Installation
 		in               *ecv1beta1.Installation
+		objects          []client.Object
 		setupMockHelmCli func(t *testing.T) *helm.MockClient
 		validateIn       func(t *testing.T, in *ecv1beta1.Installation)
 		wantErr          bool
@@ -246,6 +250,169 @@ func TestUpgrade(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "upgrade if referenced values changed",
+			prev: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						Extensions: ecv1beta1.Extensions{
+							Helm: &ecv1beta1.Helm{
+								Charts: []ecv1beta1.Chart{
+									{
+										Name:      "test-chart",
+										ChartName: "test/chart",
+										Version:   "1.0.0",
+										Values:    "abc: xyz",
+										TargetNS:  "test-ns",
+										Order:     1,
+										ValuesFrom: []ecv1beta1.ValuesReference{
+											{Kind: "Secret", Name: "test-values"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						Extensions: ecv1beta1.Extensions{
+							Helm: &ecv1beta1.Helm{
+								Charts: []ecv1beta1.Chart{
+									{
+										Name:      "test-chart",
+										ChartName: "test/chart",
+										Version:   "1.0.0",
+										Values:    "abc: xyz",
+										TargetNS:  "test-ns",
+										Order:     1,
+										ValuesFrom: []ecv1beta1.ValuesReference{
+											{Kind: "Secret", Name: "test-values"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-values", Namespace: "test-ns"},
+					Data:       map[string][]byte{"values.yaml": []byte("password: new")},
+				},
+			},
+			setupMockHelmCli: func(t *testing.T) *helm.MockClient {
+				helmCli := &helm.MockClient{}
+				mock.InOrder(
+					helmCli.
+						On("ReleaseExists", mock.Anything, "test-ns", "test-chart").
+						Once().
+						Return(true, nil),
+					helmCli.
+						On("GetValues", mock.Anything, "test-ns", "test-chart").
+						Once().
+						Return(map[string]interface{}{"abc": "xyz", "password": "old"}, nil),
+					helmCli.
+						On("Upgrade", mock.Anything, helm.UpgradeOptions{
+							ReleaseName:  "test-chart",
+							ChartPath:    "test/chart",
+							ChartVersion: "1.0.0",
+							Values:       map[string]interface{}{"abc": "xyz", "password": "new"},
+							Namespace:    "test-ns",
+							Force:        true,
+						}).
+						Once().
+						Return(nil, nil),
+				)
+				return helmCli
+			},
+			validateIn: func(t *testing.T, in *ecv1beta1.Installation) {
+				assert.Len(t, in.Status.Conditions, 1, "expected 1 condition")
+				assert.Equal(t, "test-ns-test-chart", in.Status.Conditions[0].Type, "expected condition type")
+				assert.Equal(t, metav1.ConditionTrue, in.Status.Conditions[0].Status, "expected condition status")
+				assert.Equal(t, "Upgraded", in.Status.Conditions[0].Reason, "expected condition reason")
+			},
+			wantErr: false,
+		},
+		{
+			name: "skip upgrade if referenced values did not change",
+			prev: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						Extensions: ecv1beta1.Extensions{
+							Helm: &ecv1beta1.Helm{
+								Charts: []ecv1beta1.Chart{
+									{
+										Name:      "test-chart",
+										ChartName: "test/chart",
+										Version:   "1.0.0",
+										Values:    "abc: xyz",
+										TargetNS:  "test-ns",
+										Order:     1,
+										ValuesFrom: []ecv1beta1.ValuesReference{
+											{Kind: "Secret", Name: "test-values"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						Extensions: ecv1beta1.Extensions{
+							Helm: &ecv1beta1.Helm{
+								Charts: []ecv1beta1.Chart{
+									{
+										Name:      "test-chart",
+										ChartName: "test/chart",
+										Version:   "1.0.0",
+										Values:    "abc: xyz",
+										TargetNS:  "test-ns",
+										Order:     1,
+										ValuesFrom: []ecv1beta1.ValuesReference{
+											{Kind: "Secret", Name: "test-values"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-values", Namespace: "test-ns"},
+					Data:       map[string][]byte{"values.yaml": []byte("password: old")},
+				},
+			},
+			setupMockHelmCli: func(t *testing.T) *helm.MockClient {
+				helmCli := &helm.MockClient{}
+				mock.InOrder(
+					helmCli.
+						On("ReleaseExists", mock.Anything, "test-ns", "test-chart").
+						Once().
+						Return(true, nil),
+					helmCli.
+						On("GetValues", mock.Anything, "test-ns", "test-chart").
+						Once().
+						Return(map[string]interface{}{"abc": "xyz", "password": "old"}, nil),
+				)
+				return helmCli
+			},
+			validateIn: func(t *testing.T, in *ecv1beta1.Installation) {
+				assert.Len(t, in.Status.Conditions, 1, "expected 1 condition")
+				assert.Equal(t, "test-ns-test-chart", in.Status.Conditions[0].Type, "expected condition type")
+				assert.Equal(t, metav1.ConditionTrue, in.Status.Conditions[0].Status, "expected condition status")
+				assert.Equal(t, "Upgraded", in.Status.Conditions[0].Reason, "expected condition reason")
+			},
+			wantErr: false,
+		},
 		{
 			name: "remove if release exists",
 			prev: &ecv1beta1.Installation{
@@ -514,7 +681,7 @@ func TestUpgrade(t *testing.T) {
 			kcli := fake.NewClientBuilder().
 				WithScheme(scheme).
 				WithStatusSubresource(&ecv1beta1.Installation{}).
-				WithObjects(tt.in).
+				WithObjects(append(tt.objects, tt.in)...).
 				Build()
 			mockHelmCli := tt.setupMockHelmCli(t)
 
@@ -12,6 +12,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/sirupsen/logrus"
 	helmrepo "helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func addRepos(hcli helm.Client, repos []k0sv1beta1.Repository) error {
@@ -38,10 +39,10 @@ func addRepos(hcli helm.Client, repos []k0sv1beta1.Repository) error {
 	return nil
 }
 
-func install(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
-	values, err := helm.UnmarshalValues(ext.Values)
+func install(ctx context.Context, kcli client.Client, hcli helm.Client, vctx *ValuesContext, ext ecv1beta1.Chart) error {
+	values, err := resolveValues(ctx, kcli, vctx, ext)
 	if err != nil {
-		return errors.Wrap(err, "unmarshal values")
+		return errors.Wrap(err, "resolve values")
 	}
 
 	_, err = hcli.Install(ctx, helm.InstallOptions{
@@ -51,6 +52,8 @@ func install(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 		Values:       values,
 		Namespace:    ext.TargetNS,
 		Timeout:      ext.Timeout.Duration,
+		Verification: chartVerification(ext),
+		PostRenderer: helm.NewPostRenderer(ext.Patches, ext.Kustomization),
 	})
 	if err != nil {
 		return errors.Wrap(err, "helm install")
@@ -59,10 +62,10 @@ func install(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 	return nil
 }
 
-func upgrade(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
-	values, err := helm.UnmarshalValues(ext.Values)
+func upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, vctx *ValuesContext, ext ecv1beta1.Chart) error {
+	values, err := resolveValues(ctx, kcli, vctx, ext)
 	if err != nil {
-		return errors.Wrap(err, "unmarshal values")
+		return errors.Wrap(err, "resolve values")
 	}
 
 	opts := helm.UpgradeOptions{
@@ -73,6 +76,8 @@ func upgrade(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 		Namespace:    ext.TargetNS,
 		Timeout:      ext.Timeout.Duration,
 		Force:        true, // this was the default in k0s
+		Verification: chartVerification(ext),
+		PostRenderer: helm.NewPostRenderer(ext.Patches, ext.Kustomization),
 	}
 	if ext.ForceUpgrade != nil {
 		opts.Force = *ext.ForceUpgrade
@@ -85,6 +90,19 @@ func upgrade(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 	return nil
 }
 
+// chartVerification returns how the extension chart is verified before it is installed or
+// upgraded, or nil if the chart is not verified.
+func chartVerification(ext ecv1beta1.Chart) *helm.ChartVerification {
+	if ext.Verification == nil {
+		return nil
+	}
+	return &helm.ChartVerification{
+		Digest:          ext.Verification.Digest,
+		Keyring:         ext.Verification.Keyring,
+		CosignPublicKey: ext.Verification.CosignPublicKey,
+	}
+}
+
 func uninstall(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 	err := hcli.Uninstall(ctx, helm.UninstallOptions{
 		ReleaseName: ext.Name,
@@ -129,6 +147,7 @@ func diffExtensions(oldExts, newExts ecv1beta1.Extensions) []diffResult {
 			// chart was added.
 			r.Action = actionInstall
 		} else if !reflect.DeepEqual(oldChart, newChart) {
+			// any change, including to the values or the patches, requires an upgrade.
 			r.Action = actionUpgrade
 		} else {
 			r.Action = actionNoChange
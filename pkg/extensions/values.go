@@ -0,0 +1,214 @@
+package extensions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultValuesKey = "values.yaml"
+
+// ValuesContext holds the runtime facts available to the templated values of the extensions.
+type ValuesContext struct {
+	DataDir     string
+	ServiceCIDR string
+	HTTPProxy   string
+	HTTPSProxy  string
+	NoProxy     string
+	NodeCount   int
+	IsAirgap    bool
+	// RegistryAddress is the address (ip:port) of the cluster registry. It is only set in
+	// airgap installations.
+	RegistryAddress string
+}
+
+// newValuesContext builds the values context from the installation and the cluster.
+func newValuesContext(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation) (*ValuesContext, error) {
+	vctx := &ValuesContext{
+		DataDir: runtimeconfig.EmbeddedClusterHomeDirectory(),
+	}
+	if in != nil {
+		if in.Spec.RuntimeConfig != nil && in.Spec.RuntimeConfig.DataDir != "" {
+			vctx.DataDir = in.Spec.RuntimeConfig.DataDir
+		}
+		if in.Spec.Network != nil {
+			vctx.ServiceCIDR = in.Spec.Network.ServiceCIDR
+		}
+		if in.Spec.Proxy != nil {
+			vctx.HTTPProxy = in.Spec.Proxy.HTTPProxy
+			vctx.HTTPSProxy = in.Spec.Proxy.HTTPSProxy
+			vctx.NoProxy = in.Spec.Proxy.NoProxy
+		}
+		vctx.IsAirgap = in.Spec.AirGap
+	}
+
+	if vctx.IsAirgap {
+		registryIP, err := registry.GetRegistryClusterIP(vctx.ServiceCIDR)
+		if err != nil {
+			return nil, errors.Wrap(err, "get registry cluster IP")
+		}
+		vctx.RegistryAddress = fmt.Sprintf("%s:5000", registryIP)
+	}
+
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+	vctx.NodeCount = len(nodes.Items)
+
+	return vctx, nil
+}
+
+// hasDynamicValues returns true if the values of the extension depend on more than its
+// config and have to be resolved to know if they changed.
+func hasDynamicValues(ext ecv1beta1.Chart) bool {
+	return ext.TemplateValues || len(ext.ValuesFrom) > 0
+}
+
+// resolveValues returns the values the extension chart is installed or upgraded with: its
+// values, rendered with the values context if templated, with the referenced Secrets and
+// ConfigMaps merged on top in order.
+func resolveValues(ctx context.Context, kcli client.Client, vctx *ValuesContext, ext ecv1beta1.Chart) (map[string]interface{}, error) {
+	raw := ext.Values
+	if ext.TemplateValues {
+		rendered, err := renderValues(raw, vctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "render values")
+		}
+		raw = rendered
+	}
+
+	values, err := helm.UnmarshalValues(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal values")
+	}
+
+	for _, ref := range ext.ValuesFrom {
+		values, err = applyValuesReference(ctx, kcli, ext, ref, values)
+		if err != nil {
+			return nil, errors.Wrapf(err, "values from %s %s", ref.Kind, ref.Name)
+		}
+	}
+
+	return values, nil
+}
+
+func renderValues(values string, vctx *ValuesContext) (string, error) {
+	tmpl, err := template.New("values").Option("missingkey=error").Parse(values)
+	if err != nil {
+		return "", errors.Wrap(err, "parse template")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buf, vctx); err != nil {
+		return "", errors.Wrap(err, "execute template")
+	}
+	return buf.String(), nil
+}
+
+func applyValuesReference(ctx context.Context, kcli client.Client, ext ecv1beta1.Chart, ref ecv1beta1.ValuesReference, values map[string]interface{}) (map[string]interface{}, error) {
+	data, found, err := getReferencedValues(ctx, kcli, ext, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return values, nil
+	}
+
+	if ref.TargetPath != "" {
+		if err := helm.SetValue(values, ref.TargetPath, data); err != nil {
+			return nil, errors.Wrapf(err, "set value %s", ref.TargetPath)
+		}
+		return values, nil
+	}
+
+	values, err = helm.PatchValues(values, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "merge values")
+	}
+	return values, nil
+}
+
+// getReferencedValues returns the data the reference points to. Missing objects and keys
+// are reported as not found for optional references and as errors otherwise.
+func getReferencedValues(ctx context.Context, kcli client.Client, ext ecv1beta1.Chart, ref ecv1beta1.ValuesReference) (string, bool, error) {
+	key := client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+	if key.Namespace == "" {
+		key.Namespace = ext.TargetNS
+	}
+	valuesKey := ref.ValuesKey
+	if valuesKey == "" {
+		valuesKey = defaultValuesKey
+	}
+
+	var data string
+	var ok bool
+	switch ref.Kind {
+	case "Secret":
+		var secret corev1.Secret
+		if err := kcli.Get(ctx, key, &secret); err != nil {
+			if k8serrors.IsNotFound(err) && ref.Optional {
+				return "", false, nil
+			}
+			return "", false, errors.Wrap(err, "get secret")
+		}
+		var raw []byte
+		raw, ok = secret.Data[valuesKey]
+		data = string(raw)
+	case "ConfigMap":
+		var cm corev1.ConfigMap
+		if err := kcli.Get(ctx, key, &cm); err != nil {
+			if k8serrors.IsNotFound(err) && ref.Optional {
+				return "", false, nil
+			}
+			return "", false, errors.Wrap(err, "get configmap")
+		}
+		data, ok = cm.Data[valuesKey]
+	default:
+		return "", false, errors.Errorf("unsupported kind %q", ref.Kind)
+	}
+
+	if !ok {
+		if ref.Optional {
+			return "", false, nil
+		}
+		return "", false, errors.Errorf("key %s not found", valuesKey)
+	}
+	return data, true, nil
+}
+
+// valuesChanged returns true if the resolved values of the extension differ from the values
+// of its deployed release.
+func valuesChanged(ctx context.Context, kcli client.Client, hcli helm.Client, vctx *ValuesContext, ext ecv1beta1.Chart) (bool, error) {
+	exists, err := hcli.ReleaseExists(ctx, ext.TargetNS, ext.Name)
+	if err != nil {
+		return false, errors.Wrap(err, "check if release exists")
+	}
+	if !exists {
+		return false, nil
+	}
+
+	values, err := resolveValues(ctx, kcli, vctx, ext)
+	if err != nil {
+		return false, errors.Wrap(err, "resolve values")
+	}
+	current, err := hcli.GetValues(ctx, ext.TargetNS, ext.Name)
+	if err != nil {
+		return false, errors.Wrap(err, "get release values")
+	}
+
+	changes, err := helm.DiffValues(current, values)
+	if err != nil {
+		return false, errors.Wrap(err, "diff values")
+	}
+	return len(changes) > 0, nil
+}
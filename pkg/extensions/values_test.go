@@ -0,0 +1,159 @@
+package extensions
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_resolveValues(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	kcli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "test-ns"},
+				Data: map[string][]byte{
+					"values.yaml": []byte("auth:\n  username: admin\n  password: secret"),
+					"token":       []byte("abc123"),
+				},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "other-ns"},
+				Data: map[string]string{
+					"config": "replicas: 3\nauth:\n  username: operator",
+				},
+			},
+		).
+		Build()
+
+	vctx := &ValuesContext{
+		DataDir:         "/var/lib/embedded-cluster",
+		NodeCount:       3,
+		IsAirgap:        true,
+		RegistryAddress: "10.96.0.11:5000",
+	}
+
+	tests := []struct {
+		name    string
+		ext     ecv1beta1.Chart
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "static values",
+			ext: ecv1beta1.Chart{
+				Values: `image: "{{ .RegistryAddress }}/app"`,
+			},
+			want: map[string]interface{}{"image": "{{ .RegistryAddress }}/app"},
+		},
+		{
+			name: "templated values",
+			ext: ecv1beta1.Chart{
+				Values:         "image: {{ .RegistryAddress }}/app\nreplicas: {{ .NodeCount }}\npath: {{ .DataDir }}/app\n{{- if .IsAirgap }}\nairgap: true\n{{- end }}",
+				TemplateValues: true,
+			},
+			want: map[string]interface{}{
+				"image":    "10.96.0.11:5000/app",
+				"replicas": float64(3),
+				"path":     "/var/lib/embedded-cluster/app",
+				"airgap":   true,
+			},
+		},
+		{
+			name: "unknown template field",
+			ext: ecv1beta1.Chart{
+				Values:         "image: {{ .Registry }}",
+				TemplateValues: true,
+			},
+			wantErr: "render values",
+		},
+		{
+			name: "values from secret and configmap merged in order",
+			ext: ecv1beta1.Chart{
+				Values:   "replicas: 1\nauth:\n  enabled: true",
+				TargetNS: "test-ns",
+				ValuesFrom: []ecv1beta1.ValuesReference{
+					{Kind: "Secret", Name: "credentials"},
+					{Kind: "ConfigMap", Name: "settings", Namespace: "other-ns", ValuesKey: "config"},
+				},
+			},
+			want: map[string]interface{}{
+				"replicas": float64(3),
+				"auth": map[string]interface{}{
+					"enabled":  true,
+					"username": "operator",
+					"password": "secret",
+				},
+			},
+		},
+		{
+			name: "values from secret key set to target path",
+			ext: ecv1beta1.Chart{
+				Values:   "auth:\n  enabled: true",
+				TargetNS: "test-ns",
+				ValuesFrom: []ecv1beta1.ValuesReference{
+					{Kind: "Secret", Name: "credentials", ValuesKey: "token", TargetPath: "auth.token"},
+				},
+			},
+			want: map[string]interface{}{
+				"auth": map[string]interface{}{
+					"enabled": true,
+					"token":   "abc123",
+				},
+			},
+		},
+		{
+			name: "optional missing references are ignored",
+			ext: ecv1beta1.Chart{
+				Values:   "replicas: 1",
+				TargetNS: "test-ns",
+				ValuesFrom: []ecv1beta1.ValuesReference{
+					{Kind: "Secret", Name: "missing", Optional: true},
+					{Kind: "Secret", Name: "credentials", ValuesKey: "missing", Optional: true},
+				},
+			},
+			want: map[string]interface{}{"replicas": float64(1)},
+		},
+		{
+			name: "missing object",
+			ext: ecv1beta1.Chart{
+				TargetNS: "test-ns",
+				ValuesFrom: []ecv1beta1.ValuesReference{
+					{Kind: "ConfigMap", Name: "settings"},
+				},
+			},
+			wantErr: "get configmap",
+		},
+		{
+			name: "missing key",
+			ext: ecv1beta1.Chart{
+				TargetNS: "test-ns",
+				ValuesFrom: []ecv1beta1.ValuesReference{
+					{Kind: "Secret", Name: "credentials", ValuesKey: "missing"},
+				},
+			},
+			wantErr: "key missing not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveValues(context.Background(), kcli, vctx, tt.ext)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
@@ -20,6 +22,7 @@ import (
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/pusher"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
@@ -107,6 +110,8 @@ type InstallOptions struct {
 	Namespace    string
 	Labels       map[string]string
 	Timeout      time.Duration
+	Verification *ChartVerification
+	PostRenderer postrender.PostRenderer
 }
 
 type UpgradeOptions struct {
@@ -118,6 +123,17 @@ type UpgradeOptions struct {
 	Labels       map[string]string
 	Timeout      time.Duration
 	Force        bool
+	Verification *ChartVerification
+	PostRenderer postrender.PostRenderer
+}
+
+type RollbackOptions struct {
+	ReleaseName string
+	Namespace   string
+	// Revision is the release revision to roll back to.
+	Revision int
+	Timeout  time.Duration
+	Force    bool
 }
 
 type UninstallOptions struct {
@@ -128,6 +144,8 @@ type UninstallOptions struct {
 }
 
 type HelmClient struct {
+	// mtx protects the repositories as charts may be pulled concurrently.
+	mtx           sync.Mutex
 	tmpdir        string
 	kversion      *semver.Version
 	kubeconfig    string
@@ -141,6 +159,9 @@ type HelmClient struct {
 }
 
 func (h *HelmClient) prepare() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
 	// NOTE: this is a hack and should be refactored
 	if !h.reposChanged {
 		return nil
@@ -179,13 +200,19 @@ func (h *HelmClient) Close() error {
 }
 
 func (h *HelmClient) AddRepo(repo *repo.Entry) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
 	h.repos = append(h.repos, repo)
 	h.reposChanged = true
 	return nil
 }
 
 func (h *HelmClient) Latest(reponame, chart string) (string, error) {
-	for _, repository := range h.repos {
+	h.mtx.Lock()
+	repos := append([]*repo.Entry{}, h.repos...)
+	h.mtx.Unlock()
+
+	for _, repository := range repos {
 		if repository.Name != reponame {
 			continue
 		}
@@ -225,6 +252,12 @@ func (h *HelmClient) Pull(reponame, chart string, version string) (string, error
 }
 
 func (h *HelmClient) PullByRef(ref string, version string) (string, error) {
+	return h.pullByRef(ref, version, false)
+}
+
+// pullByRef downloads the chart and, if withProvenance is set, its provenance file. The
+// provenance file is stored next to the chart archive but is not verified.
+func (h *HelmClient) pullByRef(ref string, version string, withProvenance bool) (string, error) {
 	if !isOCIChart(ref) {
 		if err := h.prepare(); err != nil {
 			return "", fmt.Errorf("prepare: %w", err)
@@ -238,6 +271,9 @@ func (h *HelmClient) PullByRef(ref string, version string) (string, error) {
 		RepositoryCache:  h.tmpdir,
 		Getters:          getters,
 	}
+	if withProvenance {
+		dl.Verify = downloader.VerifyLater
+	}
 
 	dst, _, err := dl.DownloadTo(ref, version, os.TempDir())
 	if err != nil {
@@ -295,6 +331,26 @@ func isReleaseUninstalled(versions []*release.Release) bool {
 	return len(versions) > 0 && versions[len(versions)-1].Info.Status == release.StatusUninstalled
 }
 
+// GetValues returns the user supplied values of the deployed release, the equivalent of
+// "helm get values".
+func (h *HelmClient) GetValues(ctx context.Context, namespace string, releaseName string) (map[string]interface{}, error) {
+	cfg, err := h.getActionCfg(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("get action configuration: %w", err)
+	}
+
+	client := action.NewGetValues(cfg)
+	values, err := client.Run(releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("get release values: %w", err)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	return values, nil
+}
+
 func (h *HelmClient) Install(ctx context.Context, opts InstallOptions) (*release.Release, error) {
 	cfg, err := h.getActionCfg(opts.Namespace)
 	if err != nil {
@@ -309,6 +365,7 @@ func (h *HelmClient) Install(ctx context.Context, opts InstallOptions) (*release
 	client.CreateNamespace = true
 	client.WaitForJobs = true
 	client.Wait = true
+	client.PostRenderer = opts.PostRenderer
 	// we don't set client.Atomic = true on install as it makes installation failures difficult to
 	// debug since it will rollback the release.
 
@@ -321,16 +378,20 @@ func (h *HelmClient) Install(ctx context.Context, opts InstallOptions) (*release
 	var localPath string
 	if h.airgapPath == "" {
 		// online, pull chart from remote
-		localPath, err = h.PullByRef(opts.ChartPath, opts.ChartVersion)
+		localPath, err = h.pullByRef(opts.ChartPath, opts.ChartVersion, opts.Verification.pullProvenance())
 		if err != nil {
 			return nil, fmt.Errorf("pull: %w", err)
 		}
-		defer os.RemoveAll(localPath)
+		defer removeChart(localPath)
 	} else {
 		// airgapped, use chart from airgap path
 		localPath = filepath.Join(h.airgapPath, fmt.Sprintf("%s-%s.tgz", opts.ReleaseName, opts.ChartVersion))
 	}
 
+	if err := h.verifyChart(ctx, opts.ChartPath, opts.ChartVersion, localPath, opts.Verification); err != nil {
+		return nil, fmt.Errorf("verify chart %s: %w", opts.ChartPath, err)
+	}
+
 	chartRequested, err := loader.Load(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("load chart: %w", err)
@@ -368,6 +429,7 @@ func (h *HelmClient) Upgrade(ctx context.Context, opts UpgradeOptions) (*release
 	client.Wait = true
 	client.Atomic = true
 	client.Force = opts.Force
+	client.PostRenderer = opts.PostRenderer
 
 	if opts.Timeout != 0 {
 		client.Timeout = opts.Timeout
@@ -378,16 +440,20 @@ func (h *HelmClient) Upgrade(ctx context.Context, opts UpgradeOptions) (*release
 	var localPath string
 	if h.airgapPath == "" {
 		// online, pull chart from remote
-		localPath, err = h.PullByRef(opts.ChartPath, opts.ChartVersion)
+		localPath, err = h.pullByRef(opts.ChartPath, opts.ChartVersion, opts.Verification.pullProvenance())
 		if err != nil {
 			return nil, fmt.Errorf("pull: %w", err)
 		}
-		defer os.RemoveAll(localPath)
+		defer removeChart(localPath)
 	} else {
 		// airgapped, use chart from airgap path
 		localPath = filepath.Join(h.airgapPath, fmt.Sprintf("%s-%s.tgz", opts.ReleaseName, opts.ChartVersion))
 	}
 
+	if err := h.verifyChart(ctx, opts.ChartPath, opts.ChartVersion, localPath, opts.Verification); err != nil {
+		return nil, fmt.Errorf("verify chart %s: %w", opts.ChartPath, err)
+	}
+
 	chartRequested, err := loader.Load(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("load chart: %w", err)
@@ -412,6 +478,54 @@ func (h *HelmClient) Upgrade(ctx context.Context, opts UpgradeOptions) (*release
 	return release, nil
 }
 
+// History returns the revisions of a release sorted from the oldest to the newest. An empty
+// list is returned if the release does not exist.
+func (h *HelmClient) History(ctx context.Context, namespace string, releaseName string) ([]*release.Release, error) {
+	cfg, err := h.getActionCfg(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("get action configuration: %w", err)
+	}
+
+	client := action.NewHistory(cfg)
+	versions, err := client.Run(releaseName)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return []*release.Release{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get release history: %w", err)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+func (h *HelmClient) Rollback(ctx context.Context, opts RollbackOptions) error {
+	cfg, err := h.getActionCfg(opts.Namespace)
+	if err != nil {
+		return fmt.Errorf("get action configuration: %w", err)
+	}
+
+	client := action.NewRollback(cfg)
+	client.Version = opts.Revision
+	client.Wait = true
+	client.WaitForJobs = true
+	client.Force = opts.Force
+
+	if opts.Timeout != 0 {
+		client.Timeout = opts.Timeout
+	} else {
+		client.Timeout = 5 * time.Minute
+	}
+
+	if err := client.Run(opts.ReleaseName); err != nil {
+		return fmt.Errorf("rollback release: %w", err)
+	}
+
+	return nil
+}
+
 func (h *HelmClient) Uninstall(ctx context.Context, opts UninstallOptions) error {
 	cfg, err := h.getActionCfg(opts.Namespace)
 	if err != nil {
@@ -433,7 +547,7 @@ func (h *HelmClient) Uninstall(ctx context.Context, opts UninstallOptions) error
 	return nil
 }
 
-func (h *HelmClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string) ([][]byte, error) {
+func (h *HelmClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string, postRenderer postrender.PostRenderer) ([][]byte, error) {
 	cfg := &action.Configuration{}
 
 	client := action.NewInstall(cfg)
@@ -444,6 +558,7 @@ func (h *HelmClient) Render(releaseName string, chartPath string, values map[str
 	client.IncludeCRDs = true
 	client.Namespace = namespace
 	client.Labels = labels
+	client.PostRenderer = postRenderer
 
 	if h.kversion != nil {
 		// since ClientOnly is true we need to initialize KubeVersion otherwise resorts defaults
@@ -51,7 +51,7 @@ func ExtractImagesFromChart(hcli Client, ref string, version string, values map[
 }
 
 func ExtractImagesFromLocalChart(hcli Client, name, path string, values map[string]interface{}) ([]string, error) {
-	manifests, err := hcli.Render(name, path, values, "default", nil)
+	manifests, err := hcli.Render(name, path, values, "default", nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("render: %w", err)
 	}
@@ -81,6 +81,18 @@ func GetChartMetadata(hcli Client, ref string, version string) (*chart.Metadata,
 	return hcli.GetChartMetadata(chartPath)
 }
 
+// GetChartDigest returns the sha256 digest of the chart archive, in the form it is pinned in
+// the addon metadata.
+func GetChartDigest(hcli Client, ref string, version string) (string, error) {
+	chartPath, err := hcli.PullByRef(ref, version)
+	if err != nil {
+		return "", fmt.Errorf("pull: %w", err)
+	}
+	defer os.RemoveAll(chartPath)
+
+	return fileDigest(chartPath)
+}
+
 func extractImagesFromK8sManifest(resource []byte) ([]string, error) {
 	images := []string{}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -22,10 +23,13 @@ type Client interface {
 	Push(path, dst string) error
 	GetChartMetadata(chartPath string) (*chart.Metadata, error)
 	ReleaseExists(ctx context.Context, namespace string, releaseName string) (bool, error)
+	GetValues(ctx context.Context, namespace string, releaseName string) (map[string]interface{}, error)
 	Install(ctx context.Context, opts InstallOptions) (*release.Release, error)
 	Upgrade(ctx context.Context, opts UpgradeOptions) (*release.Release, error)
 	Uninstall(ctx context.Context, opts UninstallOptions) error
-	Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string) ([][]byte, error)
+	History(ctx context.Context, namespace string, releaseName string) ([]*release.Release, error)
+	Rollback(ctx context.Context, opts RollbackOptions) error
+	Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string, postRenderer postrender.PostRenderer) ([][]byte, error)
 }
 
 type ClientFactory func(opts HelmOptions) (Client, error)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -63,6 +64,14 @@ func (m *MockClient) ReleaseExists(ctx context.Context, namespace string, releas
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockClient) GetValues(ctx context.Context, namespace string, releaseName string) (map[string]interface{}, error) {
+	args := m.Called(ctx, namespace, releaseName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]interface{}), args.Error(1)
+}
+
 func (m *MockClient) Install(ctx context.Context, opts InstallOptions) (*release.Release, error) {
 	args := m.Called(ctx, opts)
 	if args.Get(0) == nil {
@@ -84,8 +93,21 @@ func (m *MockClient) Uninstall(ctx context.Context, opts UninstallOptions) error
 	return args.Error(0)
 }
 
-func (m *MockClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string) ([][]byte, error) {
-	args := m.Called(releaseName, chartPath, values, namespace, labels)
+func (m *MockClient) History(ctx context.Context, namespace string, releaseName string) ([]*release.Release, error) {
+	args := m.Called(ctx, namespace, releaseName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*release.Release), args.Error(1)
+}
+
+func (m *MockClient) Rollback(ctx context.Context, opts RollbackOptions) error {
+	args := m.Called(ctx, opts)
+	return args.Error(0)
+}
+
+func (m *MockClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string, postRenderer postrender.PostRenderer) ([][]byte, error) {
+	args := m.Called(releaseName, chartPath, values, namespace, labels, postRenderer)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -0,0 +1,178 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const (
+	kustomizeDir       = "/kustomize"
+	kustomizeResources = "manifests.yaml"
+)
+
+var _ postrender.PostRenderer = (*KustomizePostRenderer)(nil)
+
+// KustomizePostRenderer is a helm post-renderer applying kustomize patches to the manifests
+// rendered by a chart. As with any helm post-renderer, chart hooks and the CRDs in the crds
+// directory of the chart are not patched.
+type KustomizePostRenderer struct {
+	Patches []ecv1beta1.ChartPatch
+	// Kustomization is a YAML-formatted kustomization. Its resources are replaced by the
+	// rendered manifests and the patches are appended to its own.
+	Kustomization string
+}
+
+// NewPostRenderer returns a post-renderer applying the patches and the kustomization to the
+// rendered manifests, or nil if there is nothing to apply.
+func NewPostRenderer(patches []ecv1beta1.ChartPatch, kustomization string) postrender.PostRenderer {
+	if len(patches) == 0 && strings.TrimSpace(kustomization) == "" {
+		return nil
+	}
+	return &KustomizePostRenderer{
+		Patches:       patches,
+		Kustomization: kustomization,
+	}
+}
+
+func (k *KustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	kustomization, err := k.kustomization()
+	if err != nil {
+		return nil, err
+	}
+
+	fs := filesys.MakeFsInMemory()
+	if err := fs.WriteFile(filepath.Join(kustomizeDir, kustomizeResources), renderedManifests.Bytes()); err != nil {
+		return nil, fmt.Errorf("write manifests: %w", err)
+	}
+	if err := fs.WriteFile(filepath.Join(kustomizeDir, "kustomization.yaml"), kustomization); err != nil {
+		return nil, fmt.Errorf("write kustomization: %w", err)
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, kustomizeDir)
+	if err != nil {
+		return nil, fmt.Errorf("run kustomize: %w", err)
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("marshal kustomized manifests: %w", err)
+	}
+	return bytes.NewBuffer(out), nil
+}
+
+func (k *KustomizePostRenderer) kustomization() ([]byte, error) {
+	kustomization := map[string]interface{}{}
+	if err := k8syaml.Unmarshal([]byte(k.Kustomization), &kustomization); err != nil {
+		return nil, fmt.Errorf("unmarshal kustomization: %w", err)
+	}
+	if kustomization == nil {
+		kustomization = map[string]interface{}{}
+	}
+	if _, ok := kustomization["apiVersion"]; !ok {
+		kustomization["apiVersion"] = "kustomize.config.k8s.io/v1beta1"
+	}
+	if _, ok := kustomization["kind"]; !ok {
+		kustomization["kind"] = "Kustomization"
+	}
+	kustomization["resources"] = []interface{}{kustomizeResources}
+
+	patches := []interface{}{}
+	if existing, ok := kustomization["patches"]; ok {
+		list, ok := existing.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("kustomization patches must be a list")
+		}
+		patches = append(patches, list...)
+	}
+	for _, p := range k.Patches {
+		patch := map[string]interface{}{"patch": p.Patch}
+		if p.Target != nil {
+			patch["target"] = p.Target
+		}
+		patches = append(patches, patch)
+	}
+	if len(patches) > 0 {
+		kustomization["patches"] = patches
+	}
+
+	data, err := k8syaml.Marshal(kustomization)
+	if err != nil {
+		return nil, fmt.Errorf("marshal kustomization: %w", err)
+	}
+	return data, nil
+}
+
+// ChainPostRenderers returns a post-renderer running the provided ones in order. Nil
+// post-renderers are skipped and nil is returned if none is left.
+func ChainPostRenderers(postRenderers ...postrender.PostRenderer) postrender.PostRenderer {
+	chain := chainPostRenderer{}
+	for _, pr := range postRenderers {
+		if pr != nil {
+			chain = append(chain, pr)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return chain
+}
+
+type chainPostRenderer []postrender.PostRenderer
+
+func (c chainPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	var err error
+	for _, pr := range c {
+		renderedManifests, err = pr.Run(renderedManifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renderedManifests, nil
+}
+
+// WithPostRenderers returns a client that applies the provided post-renderers, indexed by
+// release name, to the releases installed, upgraded or rendered with it.
+func WithPostRenderers(hcli Client, postRenderers map[string]postrender.PostRenderer) Client {
+	if len(postRenderers) == 0 {
+		return hcli
+	}
+	return &postRenderingClient{Client: hcli, postRenderers: postRenderers}
+}
+
+type postRenderingClient struct {
+	Client
+	postRenderers map[string]postrender.PostRenderer
+}
+
+func (c *postRenderingClient) Install(ctx context.Context, opts InstallOptions) (*release.Release, error) {
+	if pr, ok := c.postRenderers[opts.ReleaseName]; ok && opts.PostRenderer == nil {
+		opts.PostRenderer = pr
+	}
+	return c.Client.Install(ctx, opts)
+}
+
+func (c *postRenderingClient) Upgrade(ctx context.Context, opts UpgradeOptions) (*release.Release, error) {
+	if pr, ok := c.postRenderers[opts.ReleaseName]; ok && opts.PostRenderer == nil {
+		opts.PostRenderer = pr
+	}
+	return c.Client.Upgrade(ctx, opts)
+}
+
+func (c *postRenderingClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string, postRenderer postrender.PostRenderer) ([][]byte, error) {
+	if pr, ok := c.postRenderers[releaseName]; ok && postRenderer == nil {
+		postRenderer = pr
+	}
+	return c.Client.Render(releaseName, chartPath, values, namespace, labels, postRenderer)
+}
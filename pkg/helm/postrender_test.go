@@ -0,0 +1,142 @@
+package helm
+
+import (
+	"bytes"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const testManifests = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  namespace: test
+spec:
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: migrate:1.0.0
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: test
+data:
+  key: value
+`
+
+func TestKustomizePostRenderer_Run(t *testing.T) {
+	tests := []struct {
+		name          string
+		patches       []ecv1beta1.ChartPatch
+		kustomization string
+		assertFn      func(t *testing.T, objects map[string]map[string]interface{})
+	}{
+		{
+			name: "strategic merge patch",
+			patches: []ecv1beta1.ChartPatch{
+				{
+					Patch: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  namespace: test
+spec:
+  template:
+    spec:
+      tolerations:
+      - key: dedicated
+        operator: Exists`,
+				},
+			},
+			assertFn: func(t *testing.T, objects map[string]map[string]interface{}) {
+				spec := objects["Job"]["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"key": "dedicated", "operator": "Exists"},
+				}, spec["tolerations"])
+				assert.Len(t, spec["containers"], 1)
+			},
+		},
+		{
+			name: "json6902 patch with target",
+			patches: []ecv1beta1.ChartPatch{
+				{
+					Patch: `- op: add
+  path: /metadata/labels
+  value:
+    extra: label`,
+					Target: &ecv1beta1.PatchTarget{Kind: "ConfigMap", Name: "config"},
+				},
+			},
+			assertFn: func(t *testing.T, objects map[string]map[string]interface{}) {
+				metadata := objects["ConfigMap"]["metadata"].(map[string]interface{})
+				assert.Equal(t, map[string]interface{}{"extra": "label"}, metadata["labels"])
+				assert.NotContains(t, objects["Job"]["metadata"], "labels")
+			},
+		},
+		{
+			name: "kustomization with patches",
+			kustomization: `commonAnnotations:
+  owner: embedded-cluster
+resources:
+- ignored.yaml`,
+			patches: []ecv1beta1.ChartPatch{
+				{
+					Patch: `- op: replace
+  path: /data/key
+  value: patched`,
+					Target: &ecv1beta1.PatchTarget{Kind: "ConfigMap"},
+				},
+			},
+			assertFn: func(t *testing.T, objects map[string]map[string]interface{}) {
+				for _, obj := range objects {
+					metadata := obj["metadata"].(map[string]interface{})
+					assert.Equal(t, map[string]interface{}{"owner": "embedded-cluster"}, metadata["annotations"])
+				}
+				assert.Equal(t, map[string]interface{}{"key": "patched"}, objects["ConfigMap"]["data"])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := NewPostRenderer(tt.patches, tt.kustomization)
+			require.NotNil(t, pr)
+
+			out, err := pr.Run(bytes.NewBufferString(testManifests))
+			require.NoError(t, err)
+
+			objects := map[string]map[string]interface{}{}
+			for _, doc := range bytes.Split(out.Bytes(), []byte("\n---\n")) {
+				obj := map[string]interface{}{}
+				require.NoError(t, k8syaml.Unmarshal(doc, &obj))
+				objects[obj["kind"].(string)] = obj
+			}
+			require.Len(t, objects, 2)
+			tt.assertFn(t, objects)
+		})
+	}
+}
+
+func TestNewPostRenderer(t *testing.T) {
+	assert.Nil(t, NewPostRenderer(nil, ""))
+	assert.Nil(t, NewPostRenderer([]ecv1beta1.ChartPatch{}, "  \n"))
+	assert.NotNil(t, NewPostRenderer(nil, "namePrefix: test-"))
+}
+
+func TestChainPostRenderers(t *testing.T) {
+	assert.Nil(t, ChainPostRenderers(nil, nil))
+
+	first := NewPostRenderer(nil, "namePrefix: first-")
+	assert.Equal(t, first, ChainPostRenderers(nil, first))
+
+	chain := ChainPostRenderers(first, nil, NewPostRenderer(nil, "namePrefix: second-"))
+	out, err := chain.Run(bytes.NewBufferString(testManifests))
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "name: second-first-config")
+}
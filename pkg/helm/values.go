@@ -3,6 +3,8 @@ package helm
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/ohler55/ojg/jp"
@@ -98,3 +100,102 @@ func PatchValues(values map[string]interface{}, patchYAML string) (map[string]in
 
 	return result, nil
 }
+
+// ValueChange is a single difference between two values maps. Paths use the dot notation
+// understood by the helm "--set" flag. From is empty when the value was added and To is
+// empty when it was removed.
+type ValueChange struct {
+	Path string
+	From string
+	To   string
+}
+
+// DiffValues returns the leaf values that differ between the two values maps, sorted by
+// path. Lists are compared as a whole.
+func DiffValues(from, to map[string]interface{}) ([]ValueChange, error) {
+	fromFlat := map[string]string{}
+	if err := flattenValues("", from, fromFlat); err != nil {
+		return nil, fmt.Errorf("flatten original values: %w", err)
+	}
+	toFlat := map[string]string{}
+	if err := flattenValues("", to, toFlat); err != nil {
+		return nil, fmt.Errorf("flatten new values: %w", err)
+	}
+
+	changes := []ValueChange{}
+	for path, value := range toFlat {
+		if old, ok := fromFlat[path]; !ok || old != value {
+			changes = append(changes, ValueChange{Path: path, From: fromFlat[path], To: value})
+		}
+	}
+	for path, value := range fromFlat {
+		if _, ok := toFlat[path]; !ok {
+			changes = append(changes, ValueChange{Path: path, From: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// maskedValue replaces the values that look like credentials in the output of MaskSecretValues.
+const maskedValue = "(masked)"
+
+// secretValueKeywords are the words that, found in the path or in the content of a list or
+// map value, mark the value as a possible credential.
+var secretValueKeywords = []string{"password", "passwd", "secret", "token", "credential", "key", "cert"}
+
+// MaskSecretValues returns the changes with the values that may hold credentials masked, so
+// the changes can be shown to users. Values are masked if their path contains a secret-like
+// word, as do lists and maps whose content does. Whether a value was added, removed or
+// changed is preserved.
+func MaskSecretValues(changes []ValueChange) []ValueChange {
+	masked := make([]ValueChange, 0, len(changes))
+	for _, change := range changes {
+		if isSecretValue(change.Path, change.From) || isSecretValue(change.Path, change.To) {
+			if change.From != "" {
+				change.From = maskedValue
+			}
+			if change.To != "" {
+				change.To = maskedValue
+			}
+		}
+		masked = append(masked, change)
+	}
+	return masked
+}
+
+func isSecretValue(path string, value string) bool {
+	text := strings.ToLower(path)
+	if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+		text += " " + strings.ToLower(value)
+	}
+	for _, keyword := range secretValueKeywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func flattenValues(prefix string, values map[string]interface{}, dst map[string]string) error {
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			if err := flattenValues(path, nested, dst); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("marshal value %q: %w", path, err)
+		}
+		dst[path] = string(data)
+	}
+	return nil
+}
@@ -345,3 +345,69 @@ func TestGetValue(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffValues(t *testing.T) {
+	tests := []struct {
+		name string
+		from map[string]interface{}
+		to   map[string]interface{}
+		want []ValueChange
+	}{
+		{
+			name: "no changes",
+			from: map[string]interface{}{"foo": map[string]interface{}{"bar": "baz"}},
+			to:   map[string]interface{}{"foo": map[string]interface{}{"bar": "baz"}},
+			want: []ValueChange{},
+		},
+		{
+			name: "added, changed and removed values",
+			from: map[string]interface{}{
+				"image":    map[string]interface{}{"tag": "1.0.0", "repository": "foo"},
+				"replicas": 1,
+				"old":      true,
+			},
+			to: map[string]interface{}{
+				"image":    map[string]interface{}{"tag": "1.1.0", "repository": "foo"},
+				"replicas": 1,
+				"args":     []interface{}{"--debug"},
+			},
+			want: []ValueChange{
+				{Path: "args", To: `["--debug"]`},
+				{Path: "image.tag", From: `"1.0.0"`, To: `"1.1.0"`},
+				{Path: "old", From: "true"},
+			},
+		},
+		{
+			name: "empty maps are leaves",
+			from: map[string]interface{}{"tolerations": map[string]interface{}{}},
+			to:   map[string]interface{}{},
+			want: []ValueChange{{Path: "tolerations", From: "{}"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffValues(tt.from, tt.to)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMaskSecretValues(t *testing.T) {
+	changes := []ValueChange{
+		{Path: "image.tag", From: `"1.0.0"`, To: `"1.1.0"`},
+		{Path: "secrets.htpasswd", From: `"user:$2y$05$abc"`, To: `"user:$2y$05$def"`},
+		{Path: "secrets.s3.secretKey", To: `"s3cr3t"`},
+		{Path: "passwordSecretRef.name", From: `"kotsadm-password"`},
+		{Path: "extraEnv", To: `[{"name":"API_TOKEN","value":"abc"}]`},
+		{Path: "args", To: `["--debug"]`},
+	}
+	assert.Equal(t, []ValueChange{
+		{Path: "image.tag", From: `"1.0.0"`, To: `"1.1.0"`},
+		{Path: "secrets.htpasswd", From: "(masked)", To: "(masked)"},
+		{Path: "secrets.s3.secretKey", To: "(masked)"},
+		{Path: "passwordSecretRef.name", From: "(masked)"},
+		{Path: "extraEnv", To: "(masked)"},
+		{Path: "args", To: `["--debug"]`},
+	}, MaskSecretValues(changes))
+}
@@ -0,0 +1,317 @@
+package helm
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/signatures"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/openpgp/armor" //nolint:staticcheck // helm provenance still relies on x/crypto/openpgp
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
+	orasregistry "oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const (
+	// helmChartContentMediaType is the media type of the layer holding the chart archive in
+	// helm oci artifacts.
+	helmChartContentMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// cosignSignatureAnnotation is the annotation holding the base64 encoded signature of
+	// each layer of a cosign signature manifest.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// maxManifestSize is the maximum size of the manifests and signature payloads read from
+	// the registry.
+	maxManifestSize = 4 * 1024 * 1024
+)
+
+// ChartVerification holds what a chart archive is verified against before it is installed or
+// upgraded. Empty fields are not verified.
+type ChartVerification struct {
+	// Digest is the expected sha256 digest of the chart archive, in the sha256:<hex> form.
+	Digest string
+	// RequireDigest fails the verification when no digest is pinned.
+	RequireDigest bool
+	// Keyring is an ASCII armored PGP public keyring used to verify the chart provenance
+	// (.prov) file.
+	Keyring string
+	// CosignPublicKey is a PEM encoded public key used to verify the cosign signature of the
+	// chart. It can only be used with oci chart references.
+	CosignPublicKey string
+}
+
+// verifyChart verifies the chart archive in localPath, pulled from chartPath, against the
+// provided verification. Provenance files are expected next to the archive.
+func (h *HelmClient) verifyChart(ctx context.Context, chartPath string, chartVersion string, localPath string, v *ChartVerification) error {
+	if v == nil {
+		return nil
+	}
+
+	if v.Digest != "" {
+		if err := verifyChartDigest(localPath, v.Digest); err != nil {
+			return fmt.Errorf("digest: %w", err)
+		}
+	} else if v.RequireDigest {
+		return fmt.Errorf("digest: no digest is pinned for chart %s version %s", chartPath, chartVersion)
+	}
+
+	if v.Keyring != "" {
+		if err := verifyChartProvenance(localPath, v.Keyring, h.tmpdir); err != nil {
+			return fmt.Errorf("provenance: %w", err)
+		}
+	}
+
+	if v.CosignPublicKey != "" {
+		if !isOCIChart(chartPath) {
+			return fmt.Errorf("cosign: chart %s is not an oci reference", chartPath)
+		}
+		if h.airgapPath != "" {
+			// the registry the chart was signed in is not reachable in airgap installations,
+			// the chart from the airgap bundle can only be trusted through its pinned digest.
+			if v.Digest == "" {
+				return fmt.Errorf("cosign: signature of chart %s cannot be verified in airgap installations, a digest is required", chartPath)
+			}
+			logrus.Debugf("Chart %s matches its pinned digest, skipping cosign verification in airgap mode", chartPath)
+			return nil
+		}
+		repo, err := newChartRepository(chartPath)
+		if err != nil {
+			return fmt.Errorf("cosign: %w", err)
+		}
+		if err := verifyCosignSignature(ctx, repo, ociTag(chartVersion), localPath, v.CosignPublicKey); err != nil {
+			return fmt.Errorf("cosign: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// verifyChartDigest makes sure the sha256 digest of the file in path matches the expected one.
+func verifyChartDigest(path string, expected string) error {
+	actual, err := fileDigest(path)
+	if err != nil {
+		return fmt.Errorf("compute digest: %w", err)
+	}
+	if actual != expected {
+		return fmt.Errorf("chart digest %s does not match the expected %s", actual, expected)
+	}
+	return nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// verifyChartProvenance verifies the provenance file stored next to the chart archive using
+// the provided armored keyring.
+func verifyChartProvenance(path string, keyring string, tmpdir string) error {
+	block, err := armor.Decode(strings.NewReader(keyring))
+	if err != nil {
+		return fmt.Errorf("decode keyring: %w", err)
+	}
+	data, err := io.ReadAll(block.Body)
+	if err != nil {
+		return fmt.Errorf("read keyring: %w", err)
+	}
+
+	// helm only loads binary keyrings from disk.
+	f, err := os.CreateTemp(tmpdir, "keyring-*.gpg")
+	if err != nil {
+		return fmt.Errorf("create keyring file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write keyring file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close keyring file: %w", err)
+	}
+
+	if _, err := downloader.VerifyChart(path, f.Name()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ociTag returns the tag of a chart version in an oci registry. Helm replaces the + in
+// versions as it is not allowed in tags.
+func ociTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// newChartRepository returns a client for the oci repository of the chart, authenticated
+// with the credentials stored by helm registry logins.
+func newChartRepository(chartPath string) (*remote.Repository, error) {
+	repo, err := remote.NewRepository(strings.TrimPrefix(chartPath, "oci://"))
+	if err != nil {
+		return nil, fmt.Errorf("new repository: %w", err)
+	}
+	store, err := credentials.NewStore(helmpath.ConfigPath(registry.CredentialsFileBasename), credentials.StoreOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("load registry credentials: %w", err)
+	}
+	repo.Client = &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      auth.DefaultCache,
+		Credential: credentials.Credential(store),
+	}
+	return repo, nil
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// cosignPayload is the simple signing payload signed by cosign.
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifyCosignSignature makes sure the chart manifest tagged with tag in the repository is
+// signed with the provided public key and that the chart archive in localPath is the one it
+// references.
+func verifyCosignSignature(ctx context.Context, repo orasregistry.Repository, tag string, localPath string, publicKey string) error {
+	pub, err := signatures.ParsePublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("parse public key: %w", err)
+	}
+
+	data, err := fetchReference(ctx, repo.Manifests(), tag)
+	if err != nil {
+		return fmt.Errorf("fetch chart manifest: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	manifestDigest := fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:]))
+
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("unmarshal chart manifest: %w", err)
+	}
+	chartDigest := ""
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == helmChartContentMediaType {
+			chartDigest = layer.Digest
+			break
+		}
+	}
+	if chartDigest == "" {
+		return fmt.Errorf("chart manifest %s has no chart content layer", manifestDigest)
+	}
+	if err := verifyChartDigest(localPath, chartDigest); err != nil {
+		return err
+	}
+
+	sigTag := fmt.Sprintf("%s.sig", strings.Replace(manifestDigest, ":", "-", 1))
+	data, err = fetchReference(ctx, repo.Manifests(), sigTag)
+	if err != nil {
+		return fmt.Errorf("fetch signature manifest %s: %w", sigTag, err)
+	}
+	var signatures ociManifest
+	if err := json.Unmarshal(data, &signatures); err != nil {
+		return fmt.Errorf("unmarshal signature manifest: %w", err)
+	}
+
+	var errs []error
+	for _, layer := range signatures.Layers {
+		sig, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		err := verifyCosignLayer(ctx, repo, layer.Digest, sig, manifestDigest, pub)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no signatures found for chart manifest %s", manifestDigest)
+	}
+	return fmt.Errorf("no valid signature found for chart manifest %s: %w", manifestDigest, errors.Join(errs...))
+}
+
+// verifyCosignLayer verifies a single cosign signature and makes sure the signed payload
+// references the chart manifest.
+func verifyCosignLayer(ctx context.Context, repo orasregistry.Repository, payloadDigest string, signature string, manifestDigest string, pub crypto.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	payload, err := fetchReference(ctx, repo.Blobs(), payloadDigest)
+	if err != nil {
+		return fmt.Errorf("fetch signature payload: %w", err)
+	}
+	if err := signatures.Verify(pub, payload, sig); err != nil {
+		return err
+	}
+
+	var p cosignPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("unmarshal signature payload: %w", err)
+	}
+	if p.Critical.Image.DockerManifestDigest != manifestDigest {
+		return fmt.Errorf("signature is for manifest %s", p.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+func fetchReference(ctx context.Context, fetcher orasregistry.ReferenceFetcher, reference string) ([]byte, error) {
+	_, rc, err := fetcher.FetchReference(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", reference, maxManifestSize)
+	}
+	return data, nil
+}
+
+// pullProvenance returns true if the provenance file of the chart has to be downloaded with it.
+func (v *ChartVerification) pullProvenance() bool {
+	return v != nil && v.Keyring != ""
+}
+
+// removeChart removes a pulled chart archive and its provenance file.
+func removeChart(localPath string) {
+	os.RemoveAll(localPath)
+	os.RemoveAll(localPath + ".prov")
+}
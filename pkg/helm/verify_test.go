@@ -0,0 +1,213 @@
+package helm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+func Test_verifyChartDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart-1.0.0.tgz")
+	require.NoError(t, os.WriteFile(path, []byte("chart"), 0644))
+
+	err := verifyChartDigest(path, testDigest([]byte("chart")))
+	assert.NoError(t, err)
+
+	err = verifyChartDigest(path, testDigest([]byte("other chart")))
+	assert.ErrorContains(t, err, "does not match the expected")
+}
+
+func Test_verifyChart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart-1.0.0.tgz")
+	require.NoError(t, os.WriteFile(path, []byte("chart"), 0644))
+	chartPath := "oci://registry.example.com/charts/chart"
+
+	online := &HelmClient{}
+	airgap := &HelmClient{airgapPath: t.TempDir()}
+
+	err := online.verifyChart(context.Background(), chartPath, "1.0.0", path, &ChartVerification{RequireDigest: true})
+	assert.ErrorContains(t, err, "no digest is pinned for chart oci://registry.example.com/charts/chart version 1.0.0")
+
+	err = online.verifyChart(context.Background(), chartPath, "1.0.0", path, &ChartVerification{
+		Digest:        testDigest([]byte("chart")),
+		RequireDigest: true,
+	})
+	assert.NoError(t, err)
+
+	// cosign signatures cannot be fetched in airgap installations, only the pinned digest
+	// makes the chart from the airgap bundle trusted.
+	err = airgap.verifyChart(context.Background(), chartPath, "1.0.0", path, &ChartVerification{
+		CosignPublicKey: "public key",
+	})
+	assert.ErrorContains(t, err, "cannot be verified in airgap installations, a digest is required")
+
+	err = airgap.verifyChart(context.Background(), chartPath, "1.0.0", path, &ChartVerification{
+		Digest:          testDigest([]byte("other chart")),
+		CosignPublicKey: "public key",
+	})
+	assert.ErrorContains(t, err, "does not match the expected")
+
+	err = airgap.verifyChart(context.Background(), chartPath, "1.0.0", path, &ChartVerification{
+		Digest:          testDigest([]byte("chart")),
+		CosignPublicKey: "public key",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_verifyCosignSignature(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	chart := []byte("chart")
+	manifest := testJSON(t, ociManifest{
+		Layers: []ociDescriptor{{MediaType: helmChartContentMediaType, Digest: testDigest(chart)}},
+	})
+
+	tests := []struct {
+		name          string
+		localChart    []byte
+		signedDigest  string
+		signer        *ecdsa.PrivateKey
+		withSignature bool
+		wantErr       string
+	}{
+		{
+			name:          "valid signature",
+			localChart:    chart,
+			signedDigest:  testDigest(manifest),
+			signer:        signer,
+			withSignature: true,
+		},
+		{
+			name:          "signed with a different key",
+			localChart:    chart,
+			signedDigest:  testDigest(manifest),
+			signer:        other,
+			withSignature: true,
+			wantErr:       "invalid ecdsa signature",
+		},
+		{
+			name:          "signature for a different manifest",
+			localChart:    chart,
+			signedDigest:  testDigest([]byte("other manifest")),
+			signer:        signer,
+			withSignature: true,
+			wantErr:       "signature is for manifest",
+		},
+		{
+			name:          "pulled chart is not the signed one",
+			localChart:    []byte("tampered chart"),
+			signedDigest:  testDigest(manifest),
+			signer:        signer,
+			withSignature: true,
+			wantErr:       "does not match the expected",
+		},
+		{
+			name:       "chart is not signed",
+			localChart: chart,
+			wantErr:    "fetch signature manifest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs := map[string][]byte{}
+			manifests := map[string][]byte{
+				"1.0.0":              manifest,
+				testDigest(manifest): manifest,
+			}
+			if tt.withSignature {
+				payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"charts/test"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, tt.signedDigest))
+				digest := sha256.Sum256(payload)
+				sig, err := ecdsa.SignASN1(rand.Reader, tt.signer, digest[:])
+				require.NoError(t, err)
+				blobs[testDigest(payload)] = payload
+				manifests[strings.Replace(testDigest(manifest), ":", "-", 1)+".sig"] = testJSON(t, ociManifest{
+					Layers: []ociDescriptor{{
+						MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+						Digest:      testDigest(payload),
+						Annotations: map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+					}},
+				})
+			}
+			server := httptest.NewServer(testRegistryHandler(manifests, blobs))
+			defer server.Close()
+
+			repo, err := remote.NewRepository(fmt.Sprintf("%s/charts/test", strings.TrimPrefix(server.URL, "http://")))
+			require.NoError(t, err)
+			repo.PlainHTTP = true
+
+			localPath := filepath.Join(t.TempDir(), "test-1.0.0.tgz")
+			require.NoError(t, os.WriteFile(localPath, tt.localChart, 0644))
+
+			err = verifyCosignSignature(context.Background(), repo, "1.0.0", localPath, testPublicKeyPEM(t, &signer.PublicKey))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// testRegistryHandler serves the manifests and blobs of the charts/test repository.
+func testRegistryHandler(manifests map[string][]byte, blobs map[string][]byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data []byte
+		var ok bool
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/charts/test/manifests/"):
+			data, ok = manifests[strings.TrimPrefix(r.URL.Path, "/v2/charts/test/manifests/")]
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		case strings.HasPrefix(r.URL.Path, "/v2/charts/test/blobs/"):
+			data, ok = blobs[strings.TrimPrefix(r.URL.Path, "/v2/charts/test/blobs/")]
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", testDigest(data))
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	})
+}
+
+func testDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:]))
+}
+
+func testJSON(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func testPublicKeyPEM(t *testing.T, pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
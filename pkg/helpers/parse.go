@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	embeddedclusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
@@ -30,6 +31,25 @@ func ParseEndUserConfig(fpath string) (*embeddedclusterv1beta1.Config, error) {
 	return &cfg, nil
 }
 
+// ParseInstallConfig parses the InstallConfig from the given file. Unknown fields are
+// rejected and relative paths are resolved against the directory containing the file.
+func ParseInstallConfig(fpath string) (*embeddedclusterv1beta1.InstallConfig, error) {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read install config file: %w", err)
+	}
+	var cfg embeddedclusterv1beta1.InstallConfig
+	if err := kyaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal install config file: %w", err)
+	}
+	abs, err := filepath.Abs(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve install config path: %w", err)
+	}
+	cfg.ResolvePaths(filepath.Dir(abs))
+	return &cfg, nil
+}
+
 // ParseLicense parses the license from the given file.
 func ParseLicense(fpath string) (*kotsv1beta1.License, error) {
 	data, err := os.ReadFile(fpath)
@@ -18,6 +18,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// NodeVersionAnnotation is set by the operator on every node with the embedded cluster version
+// the node runs. Labels can't hold the "+" in version strings.
+const NodeVersionAnnotation = "kots.io/embedded-cluster-version"
+
 type ErrNoInstallations struct{}
 
 func (e ErrNoInstallations) Error() string {
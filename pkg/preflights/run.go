@@ -37,6 +37,17 @@ type PrepareAndRunOptions struct {
 	TCPConnectionsRequired []string
 	MetricsReporter        MetricsReporter
 	IsJoin                 bool
+	// IngressEnabled checks the ports the ingress controller binds to on the host are
+	// available.
+	IngressEnabled bool
+	// IsUpgrade runs the host preflights on a node that already runs the cluster.
+	IsUpgrade bool
+	// AdminConsolePort and LocalArtifactMirrorPort default to the ones in the runtime config.
+	AdminConsolePort        int
+	LocalArtifactMirrorPort int
+	// HostPreflightSpec, if set, is run instead of the host preflights embedded in this
+	// binary. This is used to run the host preflights of a release we are updating to.
+	HostPreflightSpec *v1beta2.HostPreflightSpec
 }
 
 type MetricsReporter interface {
@@ -44,9 +55,12 @@ type MetricsReporter interface {
 }
 
 func PrepareAndRun(ctx context.Context, opts PrepareAndRunOptions) error {
-	hpf, err := release.GetHostPreflights()
-	if err != nil {
-		return fmt.Errorf("read host preflights: %w", err)
+	hpf := opts.HostPreflightSpec
+	if hpf == nil {
+		var err error
+		if hpf, err = release.GetHostPreflights(); err != nil {
+			return fmt.Errorf("read host preflights: %w", err)
+		}
 	}
 
 	privateCA := ""
@@ -54,12 +68,21 @@ func PrepareAndRun(ctx context.Context, opts PrepareAndRunOptions) error {
 		privateCA = opts.PrivateCAs[0]
 	}
 
+	adminConsolePort := opts.AdminConsolePort
+	if adminConsolePort == 0 {
+		adminConsolePort = runtimeconfig.AdminConsolePort()
+	}
+	localArtifactMirrorPort := opts.LocalArtifactMirrorPort
+	if localArtifactMirrorPort == 0 {
+		localArtifactMirrorPort = runtimeconfig.LocalArtifactMirrorPort()
+	}
+
 	data, err := types.TemplateData{
 		ReplicatedAPIURL:        opts.ReplicatedAPIURL,
 		ProxyRegistryURL:        opts.ProxyRegistryURL,
 		IsAirgap:                opts.IsAirgap,
-		AdminConsolePort:        runtimeconfig.AdminConsolePort(),
-		LocalArtifactMirrorPort: runtimeconfig.LocalArtifactMirrorPort(),
+		AdminConsolePort:        adminConsolePort,
+		LocalArtifactMirrorPort: localArtifactMirrorPort,
 		DataDir:                 runtimeconfig.EmbeddedClusterHomeDirectory(),
 		K0sDataDir:              runtimeconfig.EmbeddedClusterK0sSubDir(),
 		OpenEBSDataDir:          runtimeconfig.EmbeddedClusterOpenEBSLocalSubDir(),
@@ -70,6 +93,8 @@ func PrepareAndRun(ctx context.Context, opts PrepareAndRunOptions) error {
 		TCPConnectionsRequired:  opts.TCPConnectionsRequired,
 		NodeIP:                  opts.NodeIP,
 		IsJoin:                  opts.IsJoin,
+		IngressEnabled:          opts.IngressEnabled,
+		IsUpgrade:               opts.IsUpgrade,
 	}.WithCIDRData(opts.PodCIDR, opts.ServiceCIDR, opts.GlobalCIDR)
 
 	if err != nil {
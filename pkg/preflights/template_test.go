@@ -617,3 +617,48 @@ func TestTemplateTCPConnectionsRequired(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateUpgrade(t *testing.T) {
+	req := require.New(t)
+
+	countChecks := func(spec v1beta2.HostPreflightSpec) (ports int, subnets int, symlink bool) {
+		for _, collector := range spec.Collectors {
+			if collector.TCPPortStatus != nil || collector.UDPPortStatus != nil {
+				ports++
+			}
+			if collector.SubnetAvailable != nil {
+				subnets++
+			}
+			if collector.HostRun != nil && collector.HostRun.CollectorName == "check-data-dir-symlink" {
+				symlink = true
+			}
+		}
+		for _, analyzer := range spec.Analyzers {
+			if analyzer.TCPPortStatus != nil || analyzer.UDPPortStatus != nil {
+				ports++
+			}
+			if analyzer.SubnetAvailable != nil {
+				subnets++
+			}
+			if analyzer.TextAnalyze != nil && analyzer.TextAnalyze.CheckName == "Data Dir Symlink Check" {
+				symlink = true
+			}
+		}
+		return
+	}
+
+	hpfc, err := GetClusterHostPreflights(context.Background(), types.TemplateData{IngressEnabled: true})
+	req.NoError(err)
+	ports, subnets, symlink := countChecks(hpfc[0].Spec)
+	req.NotZero(ports)
+	req.NotZero(subnets)
+	req.True(symlink)
+
+	hpfc, err = GetClusterHostPreflights(context.Background(), types.TemplateData{IngressEnabled: true, IsUpgrade: true})
+	req.NoError(err)
+	ports, subnets, symlink = countChecks(hpfc[0].Spec)
+	req.Zero(ports)
+	req.Zero(subnets)
+	req.False(symlink)
+	req.NotEmpty(hpfc[0].Spec.Analyzers)
+}
@@ -35,6 +35,10 @@ type TemplateData struct {
 	TCPConnectionsRequired  []string
 	NodeIP                  string
 	IsJoin                  bool
+	IngressEnabled          bool
+	// IsUpgrade skips the checks that fail on a node that already runs the cluster, i.e.
+	// the port, data directory and CIDR availability checks.
+	IsUpgrade bool
 }
 
 // WithCIDRData sets the respective CIDR properties in the TemplateData struct based on the provided CIDR strings
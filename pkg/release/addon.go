@@ -38,6 +38,7 @@ const valuesPreface = `#
 type AddonMetadata struct {
 	Version       string                `yaml:"version"`
 	Location      string                `yaml:"location"`
+	ChartDigest   string                `yaml:"chartDigest,omitempty"`
 	Images        map[string]AddonImage `yaml:"images"`
 	ReplaceImages bool                  `yaml:"-"`
 	GOARCH        string                `yaml:"-"`
@@ -203,6 +203,9 @@ type ChannelRelease struct {
 	ChannelSlug  string `yaml:"channelSlug"`
 	AppSlug      string `yaml:"appSlug"`
 	Airgap       bool   `yaml:"airgap"`
+	// AirgapSigningKey is the PEM encoded public key the manifest of the airgap bundles of
+	// the release is signed with. If set, unsigned airgap bundles are rejected.
+	AirgapSigningKey string `yaml:"airgapSigningKey"`
 }
 
 // GetChannelRelease reads the embedded channel release object. If no channel release
@@ -17,6 +17,7 @@ const KotsadmServiceAccount = "kotsadm"
 const SeaweedFSNamespace = "seaweedfs"
 const RegistryNamespace = "registry"
 const VeleroNamespace = "velero"
+const IngressNamespace = "ingress-nginx"
 const EmbeddedClusterNamespace = "embedded-cluster"
 
 // BinaryName returns the binary name, this is useful for places where we
@@ -121,6 +121,12 @@ func PathToKubeConfig() string {
 	return filepath.Join(EmbeddedClusterK0sSubDir(), "pki/admin.conf")
 }
 
+// PathToInstallState returns the path to the file where the progress of an installation
+// is recorded so a failed installation can be resumed.
+func PathToInstallState() string {
+	return filepath.Join(EmbeddedClusterHomeDirectory(), "install-state.yaml")
+}
+
 // EmbeddedClusterSupportSubDir returns the path to the directory where embedded-cluster
 // support files are stored. Things that are useful when providing end user support in
 // a running cluster should be stored into this directory.
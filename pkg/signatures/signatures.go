@@ -0,0 +1,46 @@
+// Package signatures verifies the signatures of the artifacts embedded cluster consumes.
+package signatures
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ParsePublicKey parses a PEM encoded PKIX public key.
+func ParsePublicKey(publicKey string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return nil, errors.New("no pem data found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// Verify verifies the signature of the payload the way cosign signs it: a sha256
+// digest for ecdsa and rsa (pkcs1 v1.5) keys and the raw payload for ed25519 keys.
+func Verify(pub crypto.PublicKey, payload []byte, sig []byte) error {
+	digest := sha256.Sum256(payload)
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("invalid ecdsa signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("invalid rsa signature: %w", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, sig) {
+			return errors.New("invalid ed25519 signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return nil
+}
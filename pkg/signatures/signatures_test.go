@@ -0,0 +1,58 @@
+package signatures
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte("payload")
+	digest := sha256.Sum256(payload)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edSig := ed25519.Sign(edKey, payload)
+
+	tests := []struct {
+		name    string
+		pub     crypto.PublicKey
+		sig     []byte
+		wantErr bool
+	}{
+		{name: "valid ecdsa signature", pub: &ecKey.PublicKey, sig: ecSig},
+		{name: "valid rsa signature", pub: &rsaKey.PublicKey, sig: rsaSig},
+		{name: "valid ed25519 signature", pub: edPub, sig: edSig},
+		{name: "invalid ecdsa signature", pub: &ecKey.PublicKey, sig: rsaSig, wantErr: true},
+		{name: "invalid rsa signature", pub: &rsaKey.PublicKey, sig: ecSig, wantErr: true},
+		{name: "invalid ed25519 signature", pub: edPub, sig: ecSig, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.pub, payload, tt.sig)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package spinner
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Group renders multiple spinners at the same time, one per line. Lines are kept in the
+// order the spinners were started and remain on screen once their spinner is closed.
+type Group struct {
+	mtx    sync.Mutex
+	lines  []string
+	drawn  int
+	printf WriteFn
+	tty    bool
+}
+
+// StartGroup creates an empty group of spinners. Only the WithWriter option is honored.
+func StartGroup(opts ...Option) *Group {
+	tmpl := &MessageWriter{printf: fmt.Printf, tty: hasTTY}
+	for _, opt := range opts {
+		opt(tmpl)
+	}
+	return &Group{printf: tmpl.printf, tty: tmpl.tty}
+}
+
+// Start starts a new spinner in its own line at the bottom of the group.
+func (g *Group) Start(opts ...Option) *MessageWriter {
+	g.mtx.Lock()
+	line := len(g.lines)
+	g.lines = append(g.lines, "")
+	g.mtx.Unlock()
+
+	mw := &MessageWriter{
+		ch:     make(chan string, 1024),
+		end:    make(chan struct{}),
+		printf: g.printf,
+		tty:    g.tty,
+		group:  g,
+		line:   line,
+	}
+	for _, opt := range opts {
+		opt(mw)
+	}
+	go mw.loop()
+	return mw
+}
+
+// draw updates the content of a line. On a terminal the whole group is redrawn in place,
+// otherwise only the updated line is printed.
+func (g *Group) draw(line int, content string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.lines[line] = content
+	if !g.tty {
+		g.printf("%s\n", content)
+		return
+	}
+
+	if g.drawn > 0 {
+		g.printf("\033[%dA", g.drawn)
+	}
+	for _, l := range g.lines {
+		g.printf("\033[K\r%s\n", l)
+	}
+	g.drawn = len(g.lines)
+}
@@ -0,0 +1,55 @@
+package spinner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupNoTTY(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	group := StartGroup(
+		WithWriter(writeTo(buf)),
+		func(m *MessageWriter) {
+			m.tty = false
+		},
+	)
+
+	first := group.Start()
+	second := group.Start()
+	first.Infof("Installing first")
+	time.Sleep(100 * time.Millisecond)
+	second.Infof("Installing second")
+	time.Sleep(100 * time.Millisecond)
+	first.Closef("First is ready!")
+	second.CloseWithError()
+
+	assert.Equal(t, "○  Installing first\n○  Installing second\n✔  First is ready!\n✗  Installing second\n", buf.String())
+}
+
+func TestGroupTTY(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	group := StartGroup(
+		WithWriter(writeTo(buf)),
+		func(m *MessageWriter) {
+			m.tty = true
+		},
+	)
+
+	first := group.Start()
+	second := group.Start()
+	first.Infof("Installing first")
+	second.Infof("Installing second")
+	time.Sleep(200 * time.Millisecond)
+	first.Closef("First is ready!")
+	second.Closef("Second is ready!")
+
+	// the group is redrawn in place by moving the cursor up.
+	assert.Contains(t, buf.String(), "\033[2A")
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Contains(t, lines[len(lines)-2], "✔  First is ready!")
+	assert.Contains(t, lines[len(lines)-1], "✔  Second is ready!")
+}
@@ -35,6 +35,12 @@ type MessageWriter struct {
 	mask   MaskFn
 	lbreak LineBreakerFn
 	tty    bool
+	// final is the message set by Closef. It replaces the current message once the channel
+	// is closed so it is only printed with the final prefix.
+	final *string
+	// group and line are set for spinners rendered as part of a Group.
+	group *Group
+	line  int
 }
 
 // Write implements io.Writer for the MessageWriter.
@@ -52,7 +58,8 @@ func (m *MessageWriter) Write(p []byte) (n int, err error) {
 
 // Closef closes the MessageWriter after writing a message.
 func (m *MessageWriter) Closef(format string, args ...interface{}) {
-	m.ch <- fmt.Sprintf(format, args...)
+	final := fmt.Sprintf(format, args...)
+	m.final = &final
 	m.Close()
 }
 
@@ -109,6 +116,9 @@ func (m *MessageWriter) loop() {
 		case msg, open := <-m.ch:
 			if !open {
 				end = true
+				if m.final != nil {
+					message = *m.final
+				}
 			} else {
 				message = msg
 			}
@@ -122,6 +132,13 @@ func (m *MessageWriter) loop() {
 
 		changed = previous != message
 
+		if m.group != nil {
+			if m.drawInGroup(counter, message, changed, end) {
+				return
+			}
+			continue
+		}
+
 		if m.lbreak != nil && changed {
 			if lbreak, lcontent := m.lbreak(message); lbreak {
 				if diff := len(previous) - len(lcontent); diff > 0 {
@@ -160,6 +177,27 @@ func (m *MessageWriter) loop() {
 	}
 }
 
+// drawInGroup renders the spinner in its group line. Spinners in a group own a single
+// line so line breakers are not supported. Returns true once the spinner has ended.
+func (m *MessageWriter) drawInGroup(counter int, message string, changed bool, end bool) bool {
+	if !end {
+		if m.tty {
+			m.group.draw(m.line, fmt.Sprintf("%s  %s", blocks[counter%len(blocks)], message))
+		} else if changed {
+			m.group.draw(m.line, fmt.Sprintf("○  %s", message))
+		}
+		return false
+	}
+
+	prefix := "✔"
+	if m.err {
+		prefix = "✗"
+	}
+	m.group.draw(m.line, fmt.Sprintf("%s  %s", prefix, message))
+	close(m.end)
+	return true
+}
+
 // Start starts a progress bar.
 func Start(opts ...Option) *MessageWriter {
 	mw := &MessageWriter{